@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <planFile>",
+	Short: "Execute a plan saved by asqlcp plan",
+	Long: `Copy exactly the tables of a plan saved by asqlcp plan, with the options it was made with. The tables are
+	planned again before the target is changed and the run stops without changing anything when the databases drifted
+	from the plan: tables that are missing, changed schemas, tables created or dropped in the target, other strategies
+	or other foreign keys referencing the tables. Tables added to the source since are not copied. Changed row counts are not drift. Like a copy, apply
+	asks to type the name of the target database before it truncates or deletes, unless --yes is set.
+
+	Example:
+
+	asqlcp apply refresh.plan.json
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cli.Apply(args[0], confirmed(cmd))
+	},
+}
+
+func init() {
+	addConfirmFlags(applyCmd)
+
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Remove the leftovers of copy runs that crashed",
+	Long: `Restore the foreign keys that were dropped or disabled, rebuild the indexes, enable the triggers
+	that were disabled, turn system versioning on again and drop the merge staging tables left behind by copy runs that did not finish. These
+	changes are recorded in the target database before they are made, which is what this command restores.
+	Tables that are locked by a running copy are not touched. A leftover that can not be cleaned up does not stop the
+	others, the command exits with 7 when any of them failed.
+
+	Example:
+
+	asqlcp cleanup --targetHost target.database.windows.net --targetDB targetDB
+	asqlcp cleanup --to staging
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		targetHost, _ := cmd.Flags().GetString("targetHost")
+		targetDB, _ := cmd.Flags().GetString("targetDB")
+		to, _ := cmd.Flags().GetString("to")
+		profiles, _ := cmd.Flags().GetString("profiles")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		// restoring what a crashed run left behind is allowed on protected profiles
+		target := cli.Endpoint{Host: targetHost, Database: targetDB}
+		err := cli.ApplyProfiles(profiles, "", to, &cli.Endpoint{}, &target, false)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if target.Host == "" || target.Database == "" {
+			fmt.Println("--targetHost and --targetDB or --to are required")
+			os.Exit(1)
+		}
+
+		cli.Cleanup(target, dryRun)
+	},
+}
+
+func init() {
+	cleanupCmd.Flags().String("targetHost", "", "The target database host")
+	cleanupCmd.Flags().String("targetDB", "", "The target database name")
+	cleanupCmd.Flags().String("to", "", "The profile of the target database, instead of --targetHost and --targetDB")
+	cleanupCmd.Flags().String("profiles", "", "The profiles file, ~/.asqlcp/profiles.yaml by default")
+	cleanupCmd.Flags().Bool("dry-run", false, "Print what would be cleaned up without changing the target")
+
+	rootCmd.AddCommand(cleanupCmd)
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// addConfirmFlags adds --yes and its alias --force, which confirm truncating or deleting from the target tables
+func addConfirmFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("yes", false, "Truncate or delete from the target tables without asking to type the name of the target database, required without a terminal or with --ci")
+	cmd.Flags().Bool("force", false, "The same as --yes")
+}
+
+// confirmed reports whether --yes or --force is set
+func confirmed(cmd *cobra.Command) bool {
+	yes, _ := cmd.Flags().GetBool("yes")
+	force, _ := cmd.Flags().GetBool("force")
+
+	return yes || force
+}
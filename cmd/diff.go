@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the rows of the tables of the source and the target",
+	Long: `Compare the rows of all tables matching the filter between the source and the target database by their
+	primary key, and list the rows only in the source, only in the target or with other values in the target.
+	The rows are hashed in buckets of their primary key on both sides, only the buckets that differ are compared
+	row by row. Tables without a primary key are only compared as a whole. Exits with an error when a table differs,
+	so it can decide whether a copy is needed or check a copy afterwards. The tables are selected like the tables of a copy,
+	and compared with the target tables --tableMap and --config copy them into.
+
+	Example:
+
+	asqlcp diff --sourceHost source.database.windows.net --sourceDB sourceDB --targetHost target.database.windows.net --targetDB targetDB --schema dbo --tableFilter "Orders%" --limit 50 --samples 10
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, _ := cmd.Flags().GetString("schema")
+		tableFilter, _ := cmd.Flags().GetString("tableFilter")
+		tableRegex, _ := cmd.Flags().GetString("tableRegex")
+		excludeTables, _ := cmd.Flags().GetStringArray("excludeTables")
+		includeSystemTables, _ := cmd.Flags().GetBool("includeSystemTables")
+		configFile, _ := cmd.Flags().GetString("config")
+		tableMap, _ := cmd.Flags().GetStringToString("tableMap")
+		queryFilter, _ := cmd.Flags().GetString("queryFilter")
+		limit, _ := cmd.Flags().GetInt("limit")
+		samples, _ := cmd.Flags().GetInt("samples")
+		format, _ := cmd.Flags().GetString("format")
+
+		source, target, err := endpointsFromFlags(cmd, false)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if source.Host == "" || source.Database == "" || target.Host == "" || target.Database == "" || schema == "" {
+			fmt.Println(endpointsRequired + ", and --schema are required")
+			os.Exit(1)
+		}
+
+		if _, err := mssql.ParseTableExclusions(excludeTables); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if _, err := regexp.Compile(tableRegex); err != nil {
+			fmt.Printf("invalid --tableRegex, %s\n", err)
+			os.Exit(1)
+		}
+
+		if limit < 0 || samples < 0 {
+			fmt.Println("--limit and --samples must be positive")
+			os.Exit(1)
+		}
+
+		if format != "text" && format != "json" {
+			fmt.Printf("unknown format %q\n", format)
+			os.Exit(1)
+		}
+
+		cli.DataDiff(cli.DataDiffOptions{
+			SourceHost:          source.Host,
+			SourceDB:            source.Database,
+			TargetHost:          target.Host,
+			TargetDB:            target.Database,
+			SourceAuth:          source.Auth,
+			TargetAuth:          target.Auth,
+			Schema:              schema,
+			TableFilter:         tableFilter,
+			TableRegex:          tableRegex,
+			ExcludeTables:       excludeTables,
+			IncludeSystemTables: includeSystemTables,
+			ConfigFile:          configFile,
+			TableMap:            tableMap,
+			QueryFilter:         queryFilter,
+			Limit:               limit,
+			Samples:             samples,
+			JSON:                format == "json",
+		})
+	},
+}
+
+func init() {
+	diffCmd.Flags().String("sourceHost", "", "The source database host")
+	diffCmd.Flags().String("sourceDB", "", "The source database name")
+	diffCmd.Flags().String("targetHost", "", "The target database host")
+	diffCmd.Flags().String("targetDB", "", "The target database name")
+	addProfileFlags(diffCmd)
+	diffCmd.Flags().String("schema", "", "The schemas to compare, separated by commas, the names may contain * wildcards")
+	diffCmd.Flags().String("tableFilter", "%", "The filter to apply to the tables")
+	diffCmd.Flags().String("tableRegex", "", "Only the tables of which the name matches the regular expression, like --tableRegex of the root command")
+	diffCmd.Flags().StringArray("excludeTables", nil, "Leave out the tables matching the pattern, like --excludeTables of the root command, repeatable")
+	diffCmd.Flags().Bool("includeSystemTables", false, "Also compare the system tables the schemas and filters match, like --includeSystemTables of the root command")
+	diffCmd.Flags().String("config", "", "JSON file with per table settings, of which the targets are compared")
+	diffCmd.Flags().StringToString("tableMap", nil, "Compare tables with a table of another schema or name in the target, as source=target, like --tableMap of the root command")
+	diffCmd.Flags().String("queryFilter", "", "The filter selecting the rows compared on both sides")
+	diffCmd.Flags().Int("limit", 100, "The number of differing rows listed per table")
+	diffCmd.Flags().Int("samples", 5, "The number of listed rows per table printed with their source and target values")
+	diffCmd.Flags().String("format", "text", "Output format: text or json")
+
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the connections and the permissions a copy needs",
+	Long: `Connect to the source and the target and check the permissions a copy of the tables matching the filter
+	needs with the given mode: SELECT and VIEW DEFINITION in the source, and INSERT, ALTER for truncates and bulk loads,
+	DELETE, UPDATE and SELECT depending on the mode, and CREATE TABLE for created and staging tables in the target.
+	Every missing permission is printed with the statement granting it, followed by the estimated size of the copied
+	data and the space left in the target. Exits with an error when a connection fails, a required permission is
+	missing or the data does not fit. A copy runs the same checks before it changes the target.
+
+	Example:
+
+	asqlcp doctor --sourceHost source.database.windows.net --sourceDB sourceDB --targetHost target.database.windows.net --targetDB targetDB --schema dbo --mode merge
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, _ := cmd.Flags().GetString("schema")
+		tableFilter, _ := cmd.Flags().GetString("tableFilter")
+		modeFlag, _ := cmd.Flags().GetString("mode")
+		createTables, _ := cmd.Flags().GetBool("createTables")
+		configFile, _ := cmd.Flags().GetString("config")
+		excludeTables, _ := cmd.Flags().GetStringArray("excludeTables")
+		tableRegex, _ := cmd.Flags().GetString("tableRegex")
+		includeSystemTables, _ := cmd.Flags().GetBool("includeSystemTables")
+
+		source, target, err := endpointsFromFlags(cmd, true)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if source.Host == "" || source.Database == "" || target.Host == "" || target.Database == "" || schema == "" {
+			fmt.Println(endpointsRequired + ", and --schema are required")
+			os.Exit(1)
+		}
+
+		mode, err := copy.ParseMode(modeFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if _, err := mssql.ParseTableExclusions(excludeTables); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if _, err := regexp.Compile(tableRegex); err != nil {
+			fmt.Printf("invalid --tableRegex, %s\n", err)
+			os.Exit(1)
+		}
+
+		cli.Doctor(cli.DoctorOptions{
+			SourceHost:          source.Host,
+			SourceDB:            source.Database,
+			TargetHost:          target.Host,
+			TargetDB:            target.Database,
+			SourceAuth:          source.Auth,
+			TargetAuth:          target.Auth,
+			Schema:              schema,
+			TableFilter:         tableFilter,
+			Mode:                mode,
+			CreateTables:        createTables,
+			ConfigFile:          configFile,
+			TableRegex:          tableRegex,
+			ExcludeTables:       excludeTables,
+			IncludeSystemTables: includeSystemTables,
+		})
+	},
+}
+
+func init() {
+	doctorCmd.Flags().String("sourceHost", "", "The source database host")
+	doctorCmd.Flags().String("sourceDB", "", "The source database name")
+	doctorCmd.Flags().String("targetHost", "", "The target database host")
+	doctorCmd.Flags().String("targetDB", "", "The target database name")
+	addProfileFlags(doctorCmd)
+	doctorCmd.Flags().String("schema", "", "The schemas of the tables to copy, separated by commas, the names may contain * wildcards")
+	doctorCmd.Flags().String("tableFilter", "%", "The filter to apply to the tables")
+	doctorCmd.Flags().String("tableRegex", "", "Only the tables of which the name matches the regular expression, like --tableRegex of the root command")
+	doctorCmd.Flags().StringArray("excludeTables", nil, "Leave out the tables matching the pattern, like --excludeTables of the root command, repeatable")
+	doctorCmd.Flags().Bool("includeSystemTables", false, "Also check the system tables the schemas and filters match, like --includeSystemTables of the root command")
+	doctorCmd.Flags().String("mode", string(copy.ModeTruncate), "The mode of the copy: truncate, append, merge, delete, incremental or sync")
+	doctorCmd.Flags().Bool("createTables", false, "The copy creates the tables missing in the target")
+	doctorCmd.Flags().String("config", "", "JSON file with per table settings, of which the strategies are checked")
+
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+var filterCmd = &cobra.Command{
+	Use:   "filter",
+	Short: "Inspect query filter expressions",
+}
+
+var filterValidateCmd = &cobra.Command{
+	Use:   "validate [queryFilter]",
+	Short: "Parse a query filter and print the generated SQL",
+	Long: `Parse a query filter and print the generated SQL, without copying anything.
+	When --schema and --table are given the full SELECT statement is printed, when
+	--sourceHost and --sourceDB are given as well the statement is validated against
+	the source table and its estimated plan is printed.
+
+	Example:
+
+	asqlcp filter validate "modified > '2024-01-01' AND status = 'open'" --sourceHost source.database.windows.net --sourceDB sourceDB --schema dbo --table Orders
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sourceHost, _ := cmd.Flags().GetString("sourceHost")
+		sourceDB, _ := cmd.Flags().GetString("sourceDB")
+		from, _ := cmd.Flags().GetString("from")
+		profiles, _ := cmd.Flags().GetString("profiles")
+		schema, _ := cmd.Flags().GetString("schema")
+		table, _ := cmd.Flags().GetString("table")
+
+		source := cli.Endpoint{Host: sourceHost, Database: sourceDB}
+		err := cli.ApplyProfiles(profiles, from, "", &source, &cli.Endpoint{}, false)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		cli.ValidateFilter(args[0], source, schema, table)
+	},
+}
+
+func init() {
+	filterValidateCmd.Flags().String("sourceHost", "", "The source database host")
+	filterValidateCmd.Flags().String("sourceDB", "", "The source database name")
+	filterValidateCmd.Flags().String("from", "", "The profile of the source database, instead of --sourceHost and --sourceDB")
+	filterValidateCmd.Flags().String("profiles", "", "The profiles file, ~/.asqlcp/profiles.yaml by default")
+	filterValidateCmd.Flags().String("schema", "", "The schema of the table to validate against")
+	filterValidateCmd.Flags().String("table", "", "The table to validate against")
+
+	filterCmd.AddCommand(filterValidateCmd)
+	rootCmd.AddCommand(filterCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan <planFile>",
+	Short: "Save what a copy would do to a plan file for review",
+	Long: `Resolve the tables, their schemas, row counts and the actions on the target of a copy without changing
+	the target, print them and save them with the options of the copy to a plan file. It takes the flags of a copy.
+	The plan is not saved when the copy of a table would fail. Execute the reviewed plan with asqlcp apply.
+
+	Example:
+
+	asqlcp plan refresh.plan.json --from prod --to staging --schema dbo --tableFilter "%"
+	asqlcp apply refresh.plan.json
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts, err := copyOptionsFromFlags(cmd)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if opts.SourceHost == "" || opts.SourceDB == "" || opts.TargetHost == "" || opts.TargetDB == "" || (!opts.ListsTables() && (opts.Schema == "" || opts.TableFilter == "")) {
+			fmt.Println("the source, the target and the tables are required")
+			os.Exit(1)
+		}
+
+		if opts.VerifyOnly {
+			fmt.Println("--verifyOnly does not change the target and can not be planned")
+			os.Exit(1)
+		}
+
+		opts.DryRun = true
+		opts.PlanFile = args[0]
+		cli.Copy(opts)
+	},
+}
+
+func init() {
+	addCopyFlags(planCmd)
+
+	rootCmd.AddCommand(planCmd)
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+// endpointsRequired is the usage error of a command missing its source or target
+const endpointsRequired = "--sourceHost and --sourceDB or --from, and --targetHost and --targetDB or --to"
+
+// addProfileFlags adds --from, --to and --profiles to a command connecting to a source and a target
+func addProfileFlags(cmd *cobra.Command) {
+	cmd.Flags().String("from", "", "The profile of the source database, instead of --sourceHost and --sourceDB")
+	cmd.Flags().String("to", "", "The profile of the target database, instead of --targetHost and --targetDB")
+	cmd.Flags().String("profiles", "", "The profiles file, ~/.asqlcp/profiles.yaml by default")
+}
+
+// endpointsFromFlags returns the source and target of the host and database flags or of the --from and --to profiles,
+// writesTarget refuses a protected target profile
+func endpointsFromFlags(cmd *cobra.Command, writesTarget bool) (cli.Endpoint, cli.Endpoint, error) {
+	sourceHost, _ := cmd.Flags().GetString("sourceHost")
+	sourceDB, _ := cmd.Flags().GetString("sourceDB")
+	targetHost, _ := cmd.Flags().GetString("targetHost")
+	targetDB, _ := cmd.Flags().GetString("targetDB")
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	profiles, _ := cmd.Flags().GetString("profiles")
+
+	source := cli.Endpoint{Host: sourceHost, Database: sourceDB}
+	target := cli.Endpoint{Host: targetHost, Database: targetDB}
+	err := cli.ApplyProfiles(profiles, from, to, &source, &target, writesTarget)
+
+	return source, target, err
+}
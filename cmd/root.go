@@ -2,9 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
 	"github.com/spf13/cobra"
 )
 
@@ -17,33 +25,411 @@ var rootCmd = &cobra.Command{
 	
 	asqlcp -s source.database.windows.net -d sourceDB -t target.database.windows.net -p targetDB -c schema -f filter -o 5
 
+	The source and the target can also be named profiles of ~/.asqlcp/profiles.yaml, with their host, database and auth:
+
+	asqlcp --from prod --to staging --schema dbo --tableFilter "%"
+
+	Before the truncate and delete modes change the target, the target and its tables are shown and the name of the
+	target database has to be typed. --yes confirms it up front, which is required in pipelines and with --ci.
+
+	The spans of a run are exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set.
+
+	Exit codes:
+	  0  all tables were copied
+	  1  the run failed before copying, the target is not changed
+	  2  the source or the target could not be connected to or authenticated with
+	  3  tables failed only because their target schema does not match the source, the target is not changed
+	  4  tables failed, were not finished or differ in row count with --verifyCounts, they may have been emptied
+	  5  all tables were copied, but --verify, --verifyChecksums, --verifyOnly or --validateForeignKeys failed
+	  6  the run was cancelled with q or an interrupt, the tables not finished may have been emptied
 
 	`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	Run: func(cmd *cobra.Command, args []string) {
+		opts, err := copyOptionsFromFlags(cmd)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 
-		sourceHost, _ := cmd.Flags().GetString("sourceHost")
-		sourceDB, _ := cmd.Flags().GetString("sourceDB")
-		targetHost, _ := cmd.Flags().GetString("targetHost")
-		targetDB, _ := cmd.Flags().GetString("targetDB")
-		schema, _ := cmd.Flags().GetString("schema")
-		tableFilter, _ := cmd.Flags().GetString("tableFilter")
-		queryFilter, _ := cmd.Flags().GetString("queryFilter")
-		parrallel, _ := cmd.Flags().GetInt("parrallel")
-		ci, _ := cmd.Flags().GetBool("ci")
-
-		if sourceHost == "" || sourceDB == "" || targetHost == "" || targetDB == "" || schema == "" || tableFilter == "" {
+		if opts.SourceHost == "" || opts.SourceDB == "" || opts.TargetHost == "" || opts.TargetDB == "" || (!opts.ListsTables() && (opts.Schema == "" || opts.TableFilter == "")) {
 			fmt.Println("Not all required flags are set, redirecting to interactive mode")
-			cli.Wizard(sourceHost, sourceDB, targetHost, targetDB, schema, tableFilter, queryFilter, parrallel, ci)
+			cli.Wizard(opts)
 			os.Exit(1)
 		}
 
-		cli.Copy(sourceHost, sourceDB, targetHost, targetDB, schema, tableFilter, queryFilter, parrallel, ci)
+		cli.Copy(opts)
 
 	},
 }
 
+func copyOptionsFromFlags(cmd *cobra.Command) (cli.CopyOptions, error) {
+	source, target, err := endpointsFromFlags(cmd, true)
+	if err != nil {
+		return cli.CopyOptions{}, err
+	}
+	profilesFile, _ := cmd.Flags().GetString("profiles")
+	allowSameDatabase, _ := cmd.Flags().GetBool("allowSameDatabase")
+
+	// copying a database into itself truncates the source, aliases of the host are detected after connecting
+	if !allowSameDatabase && source.Host != "" && strings.EqualFold(source.Host, target.Host) && strings.EqualFold(source.Database, target.Database) {
+		return cli.CopyOptions{}, fmt.Errorf("the source and the target are the same database %s/%s, set --allowSameDatabase to copy into other tables of it with --tableMap", source.Host, source.Database)
+	}
+
+	schema, _ := cmd.Flags().GetString("schema")
+	allSchemas, _ := cmd.Flags().GetBool("allSchemas")
+	tableFilter, _ := cmd.Flags().GetString("tableFilter")
+	excludeTables, _ := cmd.Flags().GetStringArray("excludeTables")
+	tableRegex, _ := cmd.Flags().GetString("tableRegex")
+	tables, _ := cmd.Flags().GetStringSlice("tables")
+	tablesFile, _ := cmd.Flags().GetString("tablesFile")
+	includeSystemTables, _ := cmd.Flags().GetBool("includeSystemTables")
+	queryFilter, _ := cmd.Flags().GetString("queryFilter")
+	parrallel, _ := cmd.Flags().GetInt("parrallel")
+	ci, _ := cmd.Flags().GetBool("ci")
+	viewFlag, _ := cmd.Flags().GetString("view")
+	outputFlag, _ := cmd.Flags().GetString("output")
+	outputFile, _ := cmd.Flags().GetString("outputFile")
+	metricsAddr, _ := cmd.Flags().GetString("metricsAddr")
+	notifyWebhook, _ := cmd.Flags().GetString("notifyWebhook")
+	logFile, _ := cmd.Flags().GetString("logFile")
+	logLevelFlag, _ := cmd.Flags().GetString("logLevel")
+	debug, _ := cmd.Flags().GetBool("debug")
+	ciProgressTemplate, _ := cmd.Flags().GetString("ciTemplate")
+	ciSummaryTemplate, _ := cmd.Flags().GetString("ciSummaryTemplate")
+	ciInterval, _ := cmd.Flags().GetDuration("ciInterval")
+	ciPercent, _ := cmd.Flags().GetInt("ciPercent")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	noANSI, _ := cmd.Flags().GetBool("no-ansi")
+	modeFlag, _ := cmd.Flags().GetString("mode")
+	schemaCheckFlag, _ := cmd.Flags().GetString("schemaCheck")
+	watermark, _ := cmd.Flags().GetString("watermarkColumn")
+	checkpointFile, _ := cmd.Flags().GetString("checkpointFile")
+	resume, _ := cmd.Flags().GetBool("resume")
+	configFile, _ := cmd.Flags().GetString("config")
+	verify, _ := cmd.Flags().GetBool("verify")
+	verifyOnly, _ := cmd.Flags().GetBool("verifyOnly")
+	verifyCounts, _ := cmd.Flags().GetBool("verifyCounts")
+	verifyChecksums, _ := cmd.Flags().GetBool("verifyChecksums")
+	validateForeignKeys, _ := cmd.Flags().GetBool("validateForeignKeys")
+	disableIndexes, _ := cmd.Flags().GetBool("disableIndexes")
+	triggersFlag, _ := cmd.Flags().GetString("triggers")
+	readIsolationFlag, _ := cmd.Flags().GetString("readIsolation")
+	commitCount, _ := cmd.Flags().GetInt("commitCount")
+	tablock, _ := cmd.Flags().GetBool("tablock")
+	keepNulls, _ := cmd.Flags().GetBool("keepNulls")
+	keepIdentity, _ := cmd.Flags().GetBool("keepIdentity")
+	checkConstraints, _ := cmd.Flags().GetBool("checkConstraints")
+	rowsPerBatch, _ := cmd.Flags().GetInt("rowsPerBatch")
+	maxErrors, _ := cmd.Flags().GetInt("maxErrors")
+	rejectFile, _ := cmd.Flags().GetString("rejectFile")
+	recoveryFile, _ := cmd.Flags().GetString("recoveryFile")
+	auditFile, _ := cmd.Flags().GetString("auditFile")
+	reportFile, _ := cmd.Flags().GetString("reportFile")
+	junitFile, _ := cmd.Flags().GetString("junitFile")
+	retries, _ := cmd.Flags().GetInt("retries")
+	retryDelay, _ := cmd.Flags().GetDuration("retryDelay")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	tableTimeout, _ := cmd.Flags().GetDuration("tableTimeout")
+	partitions, _ := cmd.Flags().GetInt("partitions")
+	writers, _ := cmd.Flags().GetInt("writers")
+	pageSize, _ := cmd.Flags().GetInt("pageSize")
+	maxRowsPerSecond, _ := cmd.Flags().GetInt("maxRowsPerSecond")
+	maxMBPerSecond, _ := cmd.Flags().GetFloat64("maxMBPerSecond")
+	maxUtilization, _ := cmd.Flags().GetFloat64("maxUtilization")
+	adaptiveBatches, _ := cmd.Flags().GetBool("adaptiveBatches")
+	bufferRows, _ := cmd.Flags().GetInt("bufferRows")
+	bufferMB, _ := cmd.Flags().GetFloat64("bufferMB")
+	eventBuffer, _ := cmd.Flags().GetInt("eventBuffer")
+	progressRows, _ := cmd.Flags().GetInt("progressRows")
+	progressInterval, _ := cmd.Flags().GetDuration("progressInterval")
+	sampleRows, _ := cmd.Flags().GetInt("sampleRows")
+	subset, _ := cmd.Flags().GetBool("subset")
+	subsetChildren, _ := cmd.Flags().GetBool("subsetChildren")
+	samplePercent, _ := cmd.Flags().GetFloat64("samplePercent")
+	exactCounts, _ := cmd.Flags().GetBool("exactCounts")
+	consistentSnapshot, _ := cmd.Flags().GetBool("consistentSnapshot")
+	noLock, _ := cmd.Flags().GetBool("noLock")
+	skipPermissionCheck, _ := cmd.Flags().GetBool("skipPermissionCheck")
+	skipSpaceCheck, _ := cmd.Flags().GetBool("skipSpaceCheck")
+	referencesFlag, _ := cmd.Flags().GetString("references")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	reseedIdentity, _ := cmd.Flags().GetBool("reseedIdentity")
+	createTables, _ := cmd.Flags().GetBool("createTables")
+	dependencyOrder, _ := cmd.Flags().GetBool("dependencyOrder")
+
+	mode, err := copy.ParseMode(modeFlag)
+	if err != nil {
+		return cli.CopyOptions{}, err
+	}
+
+	if allSchemas {
+		if schema != "" {
+			return cli.CopyOptions{}, fmt.Errorf("--allSchemas copies every schema, leave out --schema")
+		}
+		schema = "*"
+	}
+
+	schemaCheck, err := copy.ParseSchemaCheck(schemaCheckFlag)
+	if err != nil {
+		return cli.CopyOptions{}, err
+	}
+
+	triggers, err := copy.ParseTriggerMode(triggersFlag)
+	if err != nil {
+		return cli.CopyOptions{}, err
+	}
+
+	readIsolation, err := mssql.ParseReadIsolation(readIsolationFlag)
+	if err != nil {
+		return cli.CopyOptions{}, err
+	}
+
+	var logLevel slog.Level
+	err = logLevel.UnmarshalText([]byte(logLevelFlag))
+	if err != nil {
+		return cli.CopyOptions{}, fmt.Errorf("unknown log level %q", logLevelFlag)
+	}
+	if debug {
+		logLevel = slog.LevelDebug
+	}
+
+	if consistentSnapshot && readIsolation != mssql.ReadCommitted && readIsolation != mssql.ReadSnapshot {
+		return cli.CopyOptions{}, fmt.Errorf("--readIsolation %s can not be combined with --consistentSnapshot", readIsolation)
+	}
+
+	view, err := monitor.ParseView(viewFlag)
+	if err != nil {
+		return cli.CopyOptions{}, err
+	}
+
+	output, err := monitor.ParseOutput(outputFlag)
+	if err != nil {
+		return cli.CopyOptions{}, err
+	}
+	if quiet {
+		output = monitor.OutputErrors
+	}
+
+	references, err := cli.ParseReferencePolicy(referencesFlag)
+	if err != nil {
+		return cli.CopyOptions{}, err
+	}
+
+	tableMap, _ := cmd.Flags().GetStringToString("tableMap")
+
+	if _, err := mssql.ParseTableExclusions(excludeTables); err != nil {
+		return cli.CopyOptions{}, err
+	}
+
+	if (len(tables) > 0 || tablesFile != "") && (schema != "" || tableFilter != "" || tableRegex != "" || len(excludeTables) > 0) {
+		return cli.CopyOptions{}, fmt.Errorf("--tables and --tablesFile list the tables to copy, leave out --schema, --allSchemas, --tableFilter, --tableRegex and --excludeTables")
+	}
+
+	if tableRegex != "" {
+		if _, err := regexp.Compile(tableRegex); err != nil {
+			return cli.CopyOptions{}, fmt.Errorf("invalid --tableRegex, %w", err)
+		}
+		// the regular expression selects the tables on its own
+		if tableFilter == "" {
+			tableFilter = "%"
+		}
+	}
+
+	excludeColumns, _ := cmd.Flags().GetStringSlice("excludeColumns")
+	if _, err := copy.ParseExcludeColumns(excludeColumns); err != nil {
+		return cli.CopyOptions{}, err
+	}
+
+	if _, err := monitor.ParseCITemplates(ciProgressTemplate, ciSummaryTemplate); err != nil {
+		return cli.CopyOptions{}, err
+	}
+
+	if mode == copy.ModeDelete && queryFilter == "" {
+		return cli.CopyOptions{}, fmt.Errorf("--mode delete requires a --queryFilter")
+	}
+
+	if mode == copy.ModeIncremental && watermark == "" {
+		return cli.CopyOptions{}, fmt.Errorf("--mode incremental requires a --watermarkColumn")
+	}
+
+	if sampleRows < 0 || samplePercent < 0 || samplePercent > 100 {
+		return cli.CopyOptions{}, fmt.Errorf("--sampleRows must be positive and --samplePercent between 0 and 100")
+	}
+
+	if (sampleRows > 0 || samplePercent > 0) && (verify || verifyOnly || verifyCounts || verifyChecksums || resume) {
+		return cli.CopyOptions{}, fmt.Errorf("a sampled copy can not be verified or resumed")
+	}
+
+	if verifyCounts && mode != copy.ModeTruncate && mode != copy.ModeDelete {
+		return cli.CopyOptions{}, fmt.Errorf("--verifyCounts requires the truncate or delete mode, the other modes keep target rows the source does not have")
+	}
+
+	if subsetChildren && !subset {
+		return cli.CopyOptions{}, fmt.Errorf("--subsetChildren requires --subset")
+	}
+
+	if subset && (queryFilter == "" || (mode != copy.ModeTruncate && mode != copy.ModeAppend)) {
+		return cli.CopyOptions{}, fmt.Errorf("--subset requires a --queryFilter and the truncate or append mode")
+	}
+
+	if commitCount < 1 {
+		return cli.CopyOptions{}, fmt.Errorf("--commitCount must be positive")
+	}
+
+	if rowsPerBatch < 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--rowsPerBatch must be positive")
+	}
+
+	if maxErrors < 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--maxErrors must be positive")
+	}
+
+	if retries < 0 || retryDelay <= 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--retries and --retryDelay must be positive")
+	}
+
+	if timeout < 0 || tableTimeout < 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--timeout and --tableTimeout must be positive")
+	}
+
+	if partitions < 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--partitions must be positive")
+	}
+
+	if writers < 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--writers must be positive")
+	}
+
+	if pageSize < 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--pageSize must be positive")
+	}
+
+	if maxRowsPerSecond < 0 || maxMBPerSecond < 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--maxRowsPerSecond and --maxMBPerSecond must be positive")
+	}
+
+	if maxUtilization < 0 || maxUtilization > 100 {
+		return cli.CopyOptions{}, fmt.Errorf("--maxUtilization must be a percentage")
+	}
+
+	if bufferRows <= 0 || eventBuffer <= 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--bufferRows and --eventBuffer must be positive")
+	}
+
+	if progressRows <= 0 || progressInterval <= 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--progressRows and --progressInterval must be positive")
+	}
+
+	if bufferMB < 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--bufferMB must be positive")
+	}
+
+	if rejectFile != "" && maxErrors == 0 {
+		return cli.CopyOptions{}, fmt.Errorf("--rejectFile requires --maxErrors")
+	}
+
+	if resume && checkpointFile == "" {
+		return cli.CopyOptions{}, fmt.Errorf("--resume requires a --checkpointFile")
+	}
+
+	return cli.CopyOptions{
+		SourceHost:          source.Host,
+		SourceDB:            source.Database,
+		SourceAuth:          source.Auth,
+		SourceProfile:       source.Profile,
+		TargetHost:          target.Host,
+		TargetDB:            target.Database,
+		TargetAuth:          target.Auth,
+		TargetProfile:       target.Profile,
+		ProfilesFile:        profilesFile,
+		Schema:              schema,
+		TableFilter:         tableFilter,
+		TableRegex:          tableRegex,
+		ExcludeTables:       excludeTables,
+		Tables:              tables,
+		TablesFile:          tablesFile,
+		IncludeSystemTables: includeSystemTables,
+		QueryFilter:         queryFilter,
+		Parrallel:           parrallel,
+		CI:                  ci,
+		View:                view,
+		SchemaCheck:         schemaCheck,
+		CIProgressTemplate:  ciProgressTemplate,
+		CISummaryTemplate:   ciSummaryTemplate,
+		CIInterval:          ciInterval,
+		CIPercent:           ciPercent,
+		Mode:                mode,
+		Watermark:           watermark,
+		ExactCounts:         exactCounts,
+		ConsistentSnapshot:  consistentSnapshot,
+		DryRun:              dryRun,
+		Yes:                 confirmed(cmd),
+		ReseedIdentity:      reseedIdentity,
+		CreateTables:        createTables,
+		References:          references,
+		NoLock:              noLock,
+		AllowSameDatabase:   allowSameDatabase,
+		SkipPermissionCheck: skipPermissionCheck,
+		SkipSpaceCheck:      skipSpaceCheck,
+		DependencyOrder:     dependencyOrder,
+		CheckpointFile:      checkpointFile,
+		Resume:              resume,
+		ConfigFile:          configFile,
+		Verify:              verify,
+		VerifyOnly:          verifyOnly,
+		VerifyCounts:        verifyCounts,
+		VerifyChecksums:     verifyChecksums,
+		SampleRows:          sampleRows,
+		SamplePercent:       samplePercent,
+		Subset:              subset,
+		SubsetChildren:      subsetChildren,
+		ExcludeColumns:      excludeColumns,
+		TableMap:            tableMap,
+		ValidateForeignKeys: validateForeignKeys,
+		DisableIndexes:      disableIndexes,
+		Triggers:            triggers,
+		ReadIsolation:       readIsolation,
+		Output:              output,
+		OutputFile:          outputFile,
+		NoANSI:              noANSI,
+		MetricsAddr:         metricsAddr,
+		NotifyWebhook:       notifyWebhook,
+		LogFile:             logFile,
+		LogLevel:            logLevel,
+		Debug:               debug,
+		CommitCount:         commitCount,
+		Tablock:             tablock,
+		KeepNulls:           keepNulls,
+		KeepIdentity:        keepIdentity,
+		CheckConstraints:    checkConstraints,
+		RowsPerBatch:        rowsPerBatch,
+		MaxErrors:           maxErrors,
+		RejectFile:          rejectFile,
+		RecoveryFile:        recoveryFile,
+		AuditFile:           auditFile,
+		ReportFile:          reportFile,
+		JUnitFile:           junitFile,
+		Retries:             retries,
+		RetryDelay:          retryDelay,
+		Timeout:             timeout,
+		TableTimeout:        tableTimeout,
+		Partitions:          partitions,
+		Writers:             writers,
+		PageSize:            pageSize,
+		MaxRowsPerSecond:    maxRowsPerSecond,
+		MaxMBPerSecond:      maxMBPerSecond,
+		MaxUtilization:      maxUtilization,
+		AdaptiveBatches:     adaptiveBatches,
+		BufferRows:          bufferRows,
+		BufferMB:            bufferMB,
+		EventBuffer:         eventBuffer,
+		ProgressRows:        progressRows,
+		ProgressInterval:    progressInterval,
+	}, nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -54,15 +440,99 @@ func Execute() {
 }
 
 func init() {
+	addCopyFlags(rootCmd)
+	addConfirmFlags(rootCmd)
+}
 
-	rootCmd.Flags().String("sourceHost", "", "The source database host")
-	rootCmd.Flags().String("sourceDB", "", "The source database name")
-	rootCmd.Flags().String("targetHost", "", "The target database host")
-	rootCmd.Flags().String("targetDB", "", "The target database name")
-	rootCmd.Flags().String("schema", "", "The schema to copy")
-	rootCmd.Flags().String("tableFilter", "", "The filter to apply to the tables")
-	rootCmd.Flags().String("queryFilter", "", "The filter to apply to the tables")
-	rootCmd.Flags().Int("parrallel", 5, "The number of tables to copy in parallel")
-	rootCmd.Flags().Bool("ci", false, "Enables CI runner output instead of interactive mode")
-
+// addCopyFlags adds the flags of a copy, read by copyOptionsFromFlags, to cmd
+func addCopyFlags(cmd *cobra.Command) {
+	cmd.Flags().String("sourceHost", "", "The source database host")
+	cmd.Flags().String("sourceDB", "", "The source database name")
+	cmd.Flags().String("targetHost", "", "The target database host")
+	cmd.Flags().String("targetDB", "", "The target database name")
+	addProfileFlags(cmd)
+	cmd.Flags().String("schema", "", "The schemas to copy, separated by commas, the names may contain * wildcards, e.g. --schema dbo,sales,staging_*")
+	cmd.Flags().Bool("allSchemas", false, "Copy the tables of all schemas, like --schema \"*\"")
+	cmd.Flags().String("tableFilter", "", "The filter to apply to the tables")
+	cmd.Flags().StringSlice("tables", nil, "The tables to copy as schema.table, instead of the tables --schema and --tableFilter select, e.g. --tables dbo.Orders,dbo.Lines")
+	cmd.Flags().String("tablesFile", "", "A file with the tables to copy, a schema.table per line, lines starting with # are skipped. Combines with --tables")
+	cmd.Flags().Bool("includeSystemTables", false, "Also copy the system tables --schema and --tableFilter match, which are skipped by default: tables shipped with the server (change data capture, replication), the history tables of temporal tables and sysdiagrams. Tables listed with --tables are always copied")
+	cmd.Flags().String("tableRegex", "", "Only copy the tables of which the name matches the regular expression, within the schemas and --tableFilter selected, e.g. --tableRegex \"^(Fact|Dim)\". Sets --tableFilter to % when it is not given")
+	cmd.Flags().StringArray("excludeTables", nil, "Leave out the tables of which the name or schema.table matches the pattern, a glob with * and ? or a regular expression between slashes, repeatable, e.g. --excludeTables \"audit_*\" --excludeTables \"/_(log|bak)$/\"")
+	cmd.Flags().String("queryFilter", "", "The filter to apply to the tables")
+	cmd.Flags().Int("parrallel", 5, "The number of tables to copy in parallel")
+	cmd.Flags().Bool("ci", false, "Enables CI runner output instead of interactive mode")
+	cmd.Flags().String("ciTemplate", "", "Go text/template for the CI progress lines, with .Time, .Table, .RowsCopied, .RowTotal, .Approximate, .Total, .BytesCopied, .Current and .Average (.RowsPerSecond, .MBPerSecond) and {{env \"NAME\"}}, default "+strconv.Quote(monitor.DefaultProgressTemplate))
+	cmd.Flags().String("ciSummaryTemplate", "", "Go text/template for the CI summary, with .Time, .Tables, .Done, .Failed, .RowsCopied, .Failures (.Table, .Error) and {{env \"NAME\"}}")
+	cmd.Flags().Duration("ciInterval", 0, "Write the CI progress line of a table at most every interval, e.g. 30s, instead of whenever it copied rows. The first and last line of a table are always written")
+	cmd.Flags().Int("ciPercent", 0, "Write the CI progress line of a table when it progressed by this percentage of its rows, combined with --ciInterval whichever comes first")
+	cmd.Flags().String("output", string(monitor.OutputText), "Progress output: text (progress bars, or lines with --ci) or json (every start, count, progress, error and finish of a table as a JSON line with a timestamp, followed by a summary line), errors (only the failures of tables) or none")
+	cmd.Flags().Bool("no-ansi", false, "Write a status line of the run every 10 seconds instead of the progress bars, for terminals and log viewers without support for escape codes. Detected when stdout is not a terminal or TERM is dumb")
+	cmd.Flags().Bool("quiet", false, "Write no progress, only the failures of tables and errors, the same as --output errors")
+	cmd.Flags().String("outputFile", "", "Write the progress output to this file instead of stdout")
+	cmd.Flags().String("metricsAddr", "", "Serve Prometheus metrics of the tables and the run at /metrics on this address while copying, e.g. :9090")
+	cmd.Flags().String("notifyWebhook", "", "Post a message to this Slack or Teams incoming webhook URL when a table fails and when the run finishes")
+	cmd.Flags().String("logFile", "", "File receiving the diagnostics of the run apart from the progress: the truncates, deletes and foreign key changes, retries, failures and fatal errors, as JSON lines when it ends in .json")
+	cmd.Flags().String("logLevel", "info", "Lowest level written to the --logFile: debug, info, warn or error")
+	cmd.Flags().Bool("debug", false, "Log every SQL statement with its duration and rows, the reads and the begin and commit of the bulk copies, to the --logFile or else to stderr")
+	cmd.Flags().String("view", string(monitor.ViewAuto), "Interactive progress layout: full, compact or auto (compact for more than 20 tables). In a terminal, select a table with j/k or the arrow keys and cancel it with c, toggle the layout with v, scroll the errors after tab and cancel the run with q")
+	cmd.Flags().String("mode", string(copy.ModeTruncate), "How to treat existing target data: truncate, append, merge, delete (rows matching the query filter), incremental or sync (change tracking)")
+	cmd.Flags().String("schemaCheck", string(copy.SchemaCheckStrict), "How the target schema has to match the source: strict (same columns and types), compatible (extra nullable target columns and widening conversions like int to bigint or varchar(50) to varchar(100)) or none (copy the common columns)")
+	cmd.Flags().StringToString("tableMap", nil, "Copy tables into a table of another schema or name in the target, as source=target, e.g. --tableMap dbo.Orders=staging.Orders_copy. Overrides the target of the tables in --config")
+	cmd.Flags().StringSlice("excludeColumns", nil, "Columns to leave out of the copy, as column for every table or schema.table.column for one table, e.g. --excludeColumns row_version,dbo.Orders.AuditBlob. Computed and rowversion columns of the target are never copied")
+	cmd.Flags().String("watermarkColumn", "", "The monotonically increasing column (id or modified date) used by the incremental mode")
+	cmd.Flags().Bool("exactCounts", false, "Count rows with COUNT(*) instead of the table metadata when copying whole tables")
+	cmd.Flags().Bool("dependencyOrder", false, "Copy parent tables before the tables referencing them instead of dropping foreign keys, only applies to the truncate mode")
+	cmd.Flags().Bool("consistentSnapshot", false, "Read all tables in a single SNAPSHOT transaction so they are copied as of the same moment, tables are copied one at a time")
+	cmd.Flags().Bool("createTables", false, "Create the tables missing in the target from the source definition (columns, identity, primary key and indexes) before copying")
+	cmd.Flags().Bool("disableIndexes", false, "Disable the non-unique nonclustered indexes of the target tables during the load and rebuild them afterwards, which speeds up loading wide tables with many indexes")
+	cmd.Flags().String("readIsolation", string(mssql.ReadCommitted), "How the source reads interact with concurrent writes: committed (waits for the locks of writers), nolock (neither waits nor locks, but can read uncommitted, missing or duplicate rows), readpast (skips locked rows) or snapshot (a consistent view of every table that does not wait, the source database has to allow snapshot isolation)")
+	cmd.Flags().String("triggers", string(copy.TriggersKeep), "How to treat the triggers of the target tables: keep (bulk copies skip them, the insert strategies run them), disable (during the load) or fire (bulk copies run them too)")
+	cmd.Flags().Int("commitCount", mssql.DefaultCommitCount, "The number of rows per bulk copy transaction, smaller transactions suit small targets and larger ones speed up big targets. Tables with many columns commit more often")
+	cmd.Flags().Bool("adaptiveBatches", false, "Start at --commitCount rows per bulk copy transaction and grow or shrink it per table by the duration of the batches, at most 100000 rows with --retries or --maxErrors, shrinking it after transient errors. The progress shows the batch size of every table")
+	cmd.Flags().Bool("tablock", false, "Take a table lock during every bulk copy batch instead of row locks, which loads faster and allows minimal logging but blocks other sessions")
+	cmd.Flags().Bool("keepNulls", false, "Insert NULL values of bulk copies as is instead of the default values of their columns")
+	cmd.Flags().Bool("keepIdentity", false, "Keep the identity values of the source instead of having the target generate new ones with the bulk, insert and insert-select strategies, merges always keep them. In bulk copies every batch of a table with an identity column is loaded into a temporary table and inserted with IDENTITY_INSERT, which writes the rows twice and is fully logged. Its triggers are disabled during the load unless --triggers fire")
+	cmd.Flags().Bool("checkConstraints", false, "Check the check and foreign key constraints during bulk copies, which the server skips by default and marks the constraints as not trusted. Use --triggers fire to run the triggers")
+	cmd.Flags().Int("retries", 0, "The number of times a bulk copy batch failing with a transient error (deadlock, throttling, lost connection) is inserted again before the table fails, e.g. 3. The rows of the batch are kept in memory until it is committed. Truncates, deletes and foreign key changes of the target failing with a deadlock or lock timeout are retried as often")
+	cmd.Flags().Duration("retryDelay", time.Second, "The wait before the first retry of a failed batch or target operation, it doubles with every retry")
+	cmd.Flags().Duration("timeout", 0, "Stop the run when it takes longer, e.g. 8h. The unfinished tables fail and the verification gets as long again. 0 does not limit it")
+	cmd.Flags().Duration("tableTimeout", 0, "Fail the copy of a table that takes longer, e.g. 2h, while the other tables continue. 0 does not limit it")
+	cmd.Flags().Int("maxErrors", 0, "The number of rows per table the target may reject (conversion errors, constraint violations) before the table fails, the rejected rows are skipped. A batch failing on the values of a row is inserted again in halves to find them, other failures fail the table")
+	cmd.Flags().String("auditFile", "", "File to append a JSON line to before every TRUNCATE, DELETE and dropped or disabled foreign key on the target, with the statement and the DDL restoring the foreign key")
+	cmd.Flags().String("reportFile", "", "File receiving the report of the run as versioned JSON for CI pipelines, e.g. run-report.json, with the status, the settings and the rows, duration, retries, errors and foreign keys of every table")
+	cmd.Flags().String("junitFile", "", "File receiving the report of the run as JUnit XML with a test case per table, for the test views of Azure DevOps and GitHub Actions")
+	cmd.Flags().String("rejectFile", "", "File receiving the rejected rows as JSON lines with the table, the error and the row, requires --maxErrors")
+	cmd.Flags().String("recoveryFile", cli.DefaultRecoveryFile, "File receiving the statements restoring the foreign keys, indexes and triggers of a table before they are changed, which are removed once restored. What a failed or killed run could not restore is left in it, as a last resort next to the cleanup command. Empty disables it")
+	cmd.Flags().Int("partitions", 0, "Split the copy of every table into this many ranges of the first primary key column, copied concurrently by a reader and writer each, which speeds up very large tables. Merges, insert-select and samples are copied by a single reader, the config file can set the partitions and the column per table")
+	cmd.Flags().Int("pageSize", 0, "Read the source tables in pages of this many rows ordered by their primary key, every page a query of its own, instead of a single select. This avoids long running queries being killed, e.g. by Azure SQL. Tables without a primary key are read with a single select")
+	cmd.Flags().Int("maxRowsPerSecond", 0, "Limit the rows inserted per second by all tables together, so a copy does not saturate a shared source or target")
+	cmd.Flags().Float64("maxMBPerSecond", 0, "Limit the megabytes of row data inserted per second by all tables together, e.g. 5 or 0.5")
+	cmd.Flags().Float64("maxUtilization", 0, "Slow the copy down when the CPU, IO, log or memory utilization of the source or target Azure SQL database exceeds this percentage, e.g. 70, and speed it up again below it. The utilization is read from sys.dm_db_resource_stats every 15 seconds")
+	cmd.Flags().Int("bufferRows", copy.DefaultBufferRows, "Number of rows buffered between the reader and the writer of every table")
+	cmd.Flags().Float64("bufferMB", copy.DefaultBufferBytes/1024/1024, "Approximate megabytes of rows buffered between the reader and the writer of every table, the reader waits for the writer above it. 0 is unbounded")
+	cmd.Flags().Int("eventBuffer", cli.DefaultEventBuffer, "Number of progress events buffered for the monitor, copies of many small batches wait less on the display with a larger buffer")
+	cmd.Flags().Int("progressRows", copy.DefaultProgressRows, "Report the progress of a table every this many rows, or every --progressInterval when that comes first")
+	cmd.Flags().Duration("progressInterval", copy.DefaultProgressInterval, "Report the progress of a table at least this often while rows are copied")
+	cmd.Flags().Int("writers", 0, "Insert the rows of every table with this many concurrent bulk copies, each with its own connection and transaction, fed by a single reader. The target is truncated before the first writer starts and the foreign keys are restored when all committed. Bulk copies with --tablock into a table with a clustered index wait for each other, the config file can set the writers per table")
+	cmd.Flags().Int("rowsPerBatch", 0, "Hint the server at the number of rows of every bulk copy batch, usually --commitCount")
+	cmd.Flags().Bool("reseedIdentity", false, "Continue the identity of the target tables from the current identity value of the source tables after the copy")
+	cmd.Flags().Bool("dry-run", false, "Print what would be emptied, dropped and copied without changing the target")
+	cmd.Flags().String("references", string(cli.ReferencesAsk), "How to handle foreign keys from tables outside the copy set: ask, drop, include, disable or abort")
+	cmd.Flags().Bool("noLock", false, "Do not lock the target tables against concurrent copy runs")
+	cmd.Flags().Bool("allowSameDatabase", false, "Copy when the source and the target are the same database, detected by the guid of the database, or its server and database name, also through other host names, e.g. into other tables with --tableMap. A table is never copied into itself")
+	cmd.Flags().Bool("skipPermissionCheck", false, "Do not check the permissions the copy needs in the source and the target before it starts, see asqlcp doctor")
+	cmd.Flags().Bool("skipSpaceCheck", false, "Do not check that the copied data fits in the target database before the copy starts")
+	cmd.Flags().String("checkpointFile", "", "File recording the completed tables, rerunning with the same file skips them")
+	cmd.Flags().Bool("subset", false, "Apply the query filter to the tables that have its columns and copy the rows they reference from the other tables, so no foreign key dangles. Tables below them stay empty unless --subsetChildren is set, unrelated tables are copied completely")
+	cmd.Flags().Bool("subsetChildren", false, "Also copy the rows referencing the rows of the subset")
+	cmd.Flags().Int("sampleRows", 0, "Copy at most this many rows per table, for small development copies")
+	cmd.Flags().Float64("samplePercent", 0, "Copy a random sample of about this percentage of every table (TABLESAMPLE, which samples pages so small tables can end up empty)")
+	cmd.Flags().Bool("verify", false, "Compare the row count and checksum of every table between source and target after the copy, fails when they differ")
+	cmd.Flags().Bool("verifyCounts", false, "Compare the number of target rows with the rows copied from the source after each table, tables that differ fail. Requires the truncate or delete mode")
+	cmd.Flags().Bool("verifyChecksums", false, "Compare the SHA-256 hashes of the rows of every table between source and target after the copy and list the primary keys of the rows that differ, fails when they differ. Also applies to --verifyOnly")
+	cmd.Flags().Bool("validateForeignKeys", false, "Validate the foreign keys re-added WITH NOCHECK after the copy WITH CHECK, so the server trusts them again, fails when rows violate a foreign key")
+	cmd.Flags().Bool("verifyOnly", false, "Only compare the row count and checksum of every table between source and target, without copying")
+	cmd.Flags().String("config", "", `JSON file with per table settings, e.g. {"tables": {"dbo.Orders": {"strategy": "merge", "hints": ["RECOMPILE", "MAXDOP 4"]}}}. Strategies: bulk (default), merge, insert-select (source on the target server) or insert (fires triggers). Hints are added to the OPTION clause of the source select`)
+	cmd.Flags().Bool("resume", false, "Record the last committed primary key in the checkpoint file and continue partially copied tables from it instead of starting over")
 }
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+var schemaDiffCmd = &cobra.Command{
+	Use:   "schema-diff",
+	Short: "Compare the table definitions of the source and the target",
+	Long: `Compare all tables matching the filter between the source and the target database and report
+	missing tables, missing and extra columns, and differences in type, length, precision, scale,
+	nullability, identity, computed expression and collation. Exits with an error when a table differs.
+
+	Example:
+
+	asqlcp schema-diff --sourceHost source.database.windows.net --sourceDB sourceDB --targetHost target.database.windows.net --targetDB targetDB --schema dbo --tableFilter "%" --format json
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, _ := cmd.Flags().GetString("schema")
+		tableFilter, _ := cmd.Flags().GetString("tableFilter")
+		format, _ := cmd.Flags().GetString("format")
+
+		source, target, err := endpointsFromFlags(cmd, false)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if source.Host == "" || source.Database == "" || target.Host == "" || target.Database == "" || schema == "" {
+			fmt.Println(endpointsRequired + ", and --schema are required")
+			os.Exit(1)
+		}
+
+		if format != "text" && format != "json" {
+			fmt.Printf("unknown format %q\n", format)
+			os.Exit(1)
+		}
+
+		cli.SchemaDiff(cli.SchemaDiffOptions{
+			SourceHost:  source.Host,
+			SourceDB:    source.Database,
+			TargetHost:  target.Host,
+			TargetDB:    target.Database,
+			SourceAuth:  source.Auth,
+			TargetAuth:  target.Auth,
+			Schema:      schema,
+			TableFilter: tableFilter,
+			JSON:        format == "json",
+		})
+	},
+}
+
+func init() {
+	schemaDiffCmd.Flags().String("sourceHost", "", "The source database host")
+	schemaDiffCmd.Flags().String("sourceDB", "", "The source database name")
+	schemaDiffCmd.Flags().String("targetHost", "", "The target database host")
+	schemaDiffCmd.Flags().String("targetDB", "", "The target database name")
+	addProfileFlags(schemaDiffCmd)
+	schemaDiffCmd.Flags().String("schema", "", "The schema to compare")
+	schemaDiffCmd.Flags().String("tableFilter", "%", "The filter to apply to the tables")
+	schemaDiffCmd.Flags().String("format", "text", "Output format: text or json")
+
+	rootCmd.AddCommand(schemaDiffCmd)
+}
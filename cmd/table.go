@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jeff-99/mssqlcopy/pkg/cli"
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/spf13/cobra"
+)
+
+var tableCmd = &cobra.Command{
+	Use:   "table <sourceHost/sourceDB/schema.table> <targetHost/targetDB>",
+	Short: "Copy a single table",
+	Long: `Copy a single table from the source to the target database, without discovering the schema
+	and without the progress monitor. The target table has the same name as the source table unless the
+	target argument names another schema.table. With --from the source argument is only the schema.table,
+	with --to the target argument is only the optional schema.table.
+
+	Example:
+
+	asqlcp table source.database.windows.net/sourceDB/dbo.Orders target.database.windows.net/targetDB --where "created_at > '2024-01-01'"
+	asqlcp table source.database.windows.net/sourceDB/dbo.Orders target.database.windows.net/targetDB/staging.Orders_copy
+	asqlcp table --from prod --to staging dbo.Orders
+	`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		where, _ := cmd.Flags().GetString("where")
+		modeFlag, _ := cmd.Flags().GetString("mode")
+		createTable, _ := cmd.Flags().GetBool("createTable")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		source, target, err := tableLocations(cmd, args)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		mode, err := copy.ParseMode(modeFlag)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if mode != copy.ModeTruncate && mode != copy.ModeAppend && mode != copy.ModeMerge && mode != copy.ModeDelete {
+			fmt.Printf("mode %s is not supported for a single table, use the root command\n", mode)
+			os.Exit(1)
+		}
+
+		cli.CopyTable(cli.TableCopyOptions{
+			SourceHost:  source.Host,
+			SourceDB:    source.Database,
+			TargetHost:  target.Host,
+			TargetDB:    target.Database,
+			SourceAuth:  source.Auth,
+			TargetAuth:  target.Auth,
+			Table:       source.Table,
+			Target:      target.Table,
+			QueryFilter: where,
+			Mode:        mode,
+			CreateTable: createTable,
+			Timeout:     timeout,
+			Yes:         confirmed(cmd),
+		})
+	},
+}
+
+func init() {
+	tableCmd.Flags().String("where", "", "The filter selecting the rows to copy, like --queryFilter of the root command")
+	tableCmd.Flags().String("mode", string(copy.ModeTruncate), "How to treat existing target data: truncate, append, merge or delete (rows matching --where)")
+	tableCmd.Flags().Bool("createTable", false, "Create the table in the target from the source definition when it is missing")
+	tableCmd.Flags().Duration("timeout", 0, "Fail the copy when it takes longer, e.g. 2h, 0 does not limit it")
+	addConfirmFlags(tableCmd)
+	addProfileFlags(tableCmd)
+
+	rootCmd.AddCommand(tableCmd)
+}
+
+// tableLocation is a table argument of the table command with the credentials of its database
+type tableLocation struct {
+	cli.TableLocation
+	Auth mssql.Auth
+}
+
+// tableLocations parses the table arguments of the table command, the databases of which are replaced by the --from and --to profiles
+func tableLocations(cmd *cobra.Command, args []string) (tableLocation, tableLocation, error) {
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	profiles, _ := cmd.Flags().GetString("profiles")
+
+	var source, target tableLocation
+	var err error
+	if from != "" {
+		source.Table, err = cli.ParseTableName(args[0])
+	} else {
+		source.TableLocation, err = cli.ParseTableLocation(args[0], false)
+	}
+	if err != nil {
+		return source, target, err
+	}
+
+	switch {
+	case to == "" && len(args) < 2:
+		return source, target, fmt.Errorf("the target argument is required without --to")
+	case to == "":
+		target.TableLocation, err = cli.ParseTableLocation(args[1], true)
+	case len(args) == 2:
+		target.Table, err = cli.ParseTableName(args[1])
+	}
+	if err != nil {
+		return source, target, err
+	}
+
+	sourceEndpoint := cli.Endpoint{Host: source.Host, Database: source.Database}
+	targetEndpoint := cli.Endpoint{Host: target.Host, Database: target.Database}
+	err = cli.ApplyProfiles(profiles, from, to, &sourceEndpoint, &targetEndpoint, true)
+	if err != nil {
+		return source, target, err
+	}
+
+	source.Host, source.Database, source.Auth = sourceEndpoint.Host, sourceEndpoint.Database, sourceEndpoint.Auth
+	target.Host, target.Database, target.Auth = targetEndpoint.Host, targetEndpoint.Database, targetEndpoint.Auth
+
+	return source, target, nil
+}
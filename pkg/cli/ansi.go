@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ansiOutput reports whether the progress bars can be drawn, which requires stdout to be a terminal supporting escape codes.
+// Progress written to a file, a pipe or a dumb terminal is written as plain status lines instead.
+func ansiOutput(opts CopyOptions) bool {
+	if opts.NoANSI || opts.OutputFile != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return false
+	}
+
+	return enableVirtualTerminal(os.Stdout)
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package cli
+
+import "os"
+
+// enableVirtualTerminal reports whether terminal f interprets escape codes, which all terminals outside Windows do
+func enableVirtualTerminal(f *os.File) bool {
+	return true
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package cli
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableVirtualTerminal turns on the processing of escape codes by console f, which is off by default in cmd.exe and
+// unsupported before Windows 10. It reports whether the console interprets escape codes.
+func enableVirtualTerminal(f *os.File) bool {
+	console := windows.Handle(f.Fd())
+
+	var mode uint32
+	if err := windows.GetConsoleMode(console, &mode); err != nil {
+		return false
+	}
+
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+
+	return windows.SetConsoleMode(console, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// Cleanup restores the foreign keys, rebuilds the indexes, enables the triggers, turns system versioning on and drops the staging tables left behind by copy runs that crashed.
+// A leftover that fails does not stop the others, the run exits with ExitCleanup when any failed.
+func Cleanup(target Endpoint, dryRun bool) {
+	tDB, err := mssql.ConnectWith(target.Host, target.Database, target.Auth)
+	if err != nil {
+		fatal(ExitConnection, err)
+	}
+	defer tDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	artifacts, err := tDB.GetArtifacts(ctx)
+	if err != nil {
+		fatal(ExitError, err)
+	}
+
+	stagingTables, err := tDB.GetStagingTables(ctx)
+	if err != nil {
+		fatal(ExitError, err)
+	}
+
+	if len(artifacts) == 0 && len(stagingTables) == 0 {
+		fmt.Println("Nothing to clean up")
+		return
+	}
+
+	// the locks of a running copy are held until it ends, crashed runs lose them with their connection
+	tables := make([]mssql.TableRef, 0)
+	for _, staging := range stagingTables {
+		tables = appendTable(tables, mssql.StagedTable(staging))
+	}
+	for _, artifact := range artifacts {
+		for _, fk := range artifact.ForeignKeys {
+			tables = appendTable(tables, mssql.TableRef{Schema: fk.ReferencedSchema, Table: fk.ReferencedTable})
+		}
+		for _, index := range artifact.Indexes {
+			tables = appendTable(tables, mssql.TableRef{Schema: index.Schema, Table: index.Table})
+		}
+		for _, trigger := range artifact.Triggers {
+			tables = appendTable(tables, mssql.TableRef{Schema: trigger.Schema, Table: trigger.Table})
+		}
+		if artifact.Temporal != nil {
+			tables = appendTable(tables, artifact.Temporal.Table)
+			tables = appendTable(tables, artifact.Temporal.History)
+		}
+	}
+
+	lock, err := tDB.LockTables(ctx, tables)
+	if err != nil {
+		fatalf(ExitError, "a copy run is still active, %s", err)
+	}
+
+	failed := cleanup(ctx, tDB, artifacts, stagingTables, dryRun)
+	lock.Release()
+
+	if len(failed) > 0 {
+		tDB.Close()
+		fatalf(ExitCleanup, "%d leftovers could not be cleaned up, the others were:\n  %s", len(failed), strings.Join(failed, "\n  "))
+	}
+}
+
+// cleanup restores the artifacts and drops the staging tables, it returns the failures of the ones that could not be cleaned up
+func cleanup(ctx context.Context, tDB *mssql.MSSQLDB, artifacts []mssql.Artifact, stagingTables []mssql.TableRef, dryRun bool) []string {
+	failed := make([]string, 0)
+	for _, artifact := range artifacts {
+		switch artifact.Kind {
+		case mssql.ArtifactDroppedForeignKey:
+			fmt.Printf("ADD foreign key %s\n", artifact.Name)
+		case mssql.ArtifactDisabledForeignKey:
+			fmt.Printf("ENABLE foreign key %s\n", artifact.Name)
+		case mssql.ArtifactDisabledIndexes:
+			fmt.Printf("REBUILD indexes of %s\n", artifact.Name)
+		case mssql.ArtifactDisabledTriggers:
+			fmt.Printf("ENABLE triggers of %s\n", artifact.Name)
+		case mssql.ArtifactDisabledVersioning:
+			fmt.Printf("ENABLE system versioning of %s\n", artifact.Name)
+		default:
+			failed = append(failed, fmt.Sprintf("%s: unknown artifact %s", artifact.Name, artifact.Kind))
+			continue
+		}
+
+		if dryRun {
+			continue
+		}
+
+		err := restoreArtifact(ctx, tDB, artifact)
+		if err != nil {
+			log.Printf("FAILED %s: %s", artifact.Name, err)
+			failed = append(failed, fmt.Sprintf("%s: %s", artifact.Name, err))
+		}
+	}
+
+	for _, staging := range stagingTables {
+		fmt.Printf("DROP staging table %s\n", staging)
+
+		if dryRun {
+			continue
+		}
+
+		err := tDB.DropTable(ctx, staging)
+		if err != nil {
+			log.Printf("FAILED %s: %s", staging, err)
+			failed = append(failed, fmt.Sprintf("%s: %s", staging, err))
+		}
+	}
+
+	return failed
+}
+
+// restoreArtifact restores what the artifact recorded and forgets it once it is restored
+func restoreArtifact(ctx context.Context, tDB *mssql.MSSQLDB, artifact mssql.Artifact) error {
+	var err error
+	switch artifact.Kind {
+	case mssql.ArtifactDroppedForeignKey:
+		err = tDB.AddForeignKeys(ctx, artifact.ForeignKeys)
+	case mssql.ArtifactDisabledForeignKey:
+		err = tDB.EnableForeignKeys(ctx, artifact.ForeignKeys)
+	case mssql.ArtifactDisabledIndexes:
+		err = tDB.RebuildIndexes(ctx, artifact.Indexes)
+	case mssql.ArtifactDisabledTriggers:
+		err = tDB.EnableTriggers(ctx, artifact.Triggers)
+	case mssql.ArtifactDisabledVersioning:
+		err = tDB.EnableSystemVersioning(ctx, *artifact.Temporal)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch artifact.Kind {
+	case mssql.ArtifactDisabledIndexes:
+		err = tDB.ForgetIndexes(ctx, mssql.TableRef{Schema: artifact.Indexes[0].Schema, Table: artifact.Indexes[0].Table})
+	case mssql.ArtifactDisabledTriggers:
+		err = tDB.ForgetTriggers(ctx, mssql.TableRef{Schema: artifact.Triggers[0].Schema, Table: artifact.Triggers[0].Table})
+	case mssql.ArtifactDisabledVersioning:
+		err = tDB.ForgetSystemVersioning(ctx, *artifact.Temporal)
+	default:
+		err = tDB.ForgetForeignKeys(ctx, artifact.Kind, artifact.ForeignKeys)
+	}
+
+	return err
+}
+
+func appendTable(tables []mssql.TableRef, table mssql.TableRef) []mssql.TableRef {
+	if containsTable(tables, table) {
+		return tables
+	}
+
+	return append(tables, table)
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"golang.org/x/term"
+)
+
+// confirmDestructive shows the target and the tables the mode empties and has the user type the name of the target database,
+// so swapped source and target flags do not wipe the wrong database. yes skips the confirmation, without a terminal it is required.
+func confirmDestructive(host, database string, mode copy.Mode, tables []mssql.TableRef, yes, ci bool) error {
+	if yes {
+		return nil
+	}
+
+	action := ""
+	switch mode {
+	case copy.ModeTruncate:
+		action = "truncates"
+	case copy.ModeDelete:
+		action = "deletes the rows matching the query filter from"
+	default:
+		return nil
+	}
+
+	if ci || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("the copy %s %d tables of %s/%s, confirm it with --yes when running without a terminal", action, len(tables), host, database)
+	}
+
+	fmt.Printf("The copy %s these tables of %s/%s:\n", action, host, database)
+	for _, table := range tables {
+		fmt.Printf("  %s\n", table)
+	}
+	answer := input(fmt.Sprintf("Type the name of the target database (%s) to continue: ", database))
+	if !strings.EqualFold(strings.TrimSpace(answer), database) {
+		return fmt.Errorf("%q is not the target database, aborting, nothing has been changed", strings.TrimSpace(answer))
+	}
+
+	return nil
+}
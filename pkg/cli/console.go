@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// consoleMaxRows limits the rows printed per query
+const consoleMaxRows = 50
+
+// console runs read-only queries against the source or target database until an empty line or \q is entered
+func console(opts CopyOptions) {
+	sDB, err := mssql.ConnectWith(opts.SourceHost, opts.SourceDB, opts.SourceAuth)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer sDB.Close()
+
+	tDB, err := mssql.ConnectWith(opts.TargetHost, opts.TargetDB, opts.TargetAuth)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer tDB.Close()
+
+	fmt.Println("Read-only SQL console, \\s queries the source, \\t the target, an empty line or \\q continues with the copy")
+
+	db, name := sDB, "source"
+	for {
+		query := strings.TrimSpace(input(fmt.Sprintf("%s> ", name)))
+		switch query {
+		case "", "\\q":
+			return
+		case "\\s":
+			db, name = sDB, "source"
+			continue
+		case "\\t":
+			db, name = tDB, "target"
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		columns, rows, err := db.ReadOnlyQuery(ctx, query, consoleMaxRows)
+		cancel()
+		if err != nil {
+			fmt.Println("ERROR:", err)
+			continue
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		w.Flush()
+
+		if len(rows) == consoleMaxRows {
+			fmt.Printf("(first %d rows)\n", consoleMaxRows)
+		}
+	}
+}
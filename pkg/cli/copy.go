@@ -2,75 +2,734 @@ package cli
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/jeff-99/mssqlcopy/pkg/config"
 	"github.com/jeff-99/mssqlcopy/pkg/copy"
 	"github.com/jeff-99/mssqlcopy/pkg/monitor"
 	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"golang.org/x/term"
 )
 
-func chunkBy[T any](items []T, chunkSize int) (chunks [][]T) {
-	var _chunks = make([][]T, 0, (len(items)/chunkSize)+1)
-	for chunkSize < len(items) {
-		items, _chunks = items[chunkSize:], append(_chunks, items[0:chunkSize:chunkSize])
-	}
-	return append(_chunks, items)
+type CopyOptions struct {
+	SourceHost string
+	SourceDB   string
+	TargetHost string
+	TargetDB   string
+	// SourceAuth and TargetAuth are the credentials of the source and the target, SourceProfile and TargetProfile the names of
+	// the profiles they were taken from
+	SourceAuth    mssql.Auth
+	TargetAuth    mssql.Auth
+	SourceProfile string
+	TargetProfile string
+	ProfilesFile  string
+	// Schema is a comma separated list of the schemas to copy, the names may contain * wildcards and * copies all schemas
+	Schema      string
+	TableFilter string
+	QueryFilter string
+	Parrallel   int
+	CI          bool
+	View        monitor.View
+	// Output is the format of the progress, OutputFile receives it instead of stdout
+	Output     monitor.Output
+	OutputFile string
+	// NoANSI writes a periodic status line instead of the progress bars, which is also done when stdout is not a terminal supporting escape codes
+	NoANSI bool
+	// MetricsAddr serves Prometheus metrics at /metrics on this address
+	MetricsAddr string
+	// NotifyWebhook is a Slack or Teams incoming webhook receiving the failures of tables and the summary of the run
+	NotifyWebhook string
+	// LogFile receives the diagnostics of the run at LogLevel and above, see setupLogging
+	LogFile  string
+	LogLevel slog.Level
+	// Debug logs every statement with its duration and rows, to stderr without a LogFile
+	Debug       bool
+	Mode        copy.Mode
+	Watermark   string
+	ExactCounts bool
+	// DependencyOrder copies parents first instead of dropping and recreating foreign keys
+	DependencyOrder bool
+	// ConsistentSnapshot reads all tables in one snapshot transaction, which copies one table at a time
+	ConsistentSnapshot bool
+	// CreateTables creates the tables missing in the target from the source definition
+	CreateTables bool
+	// ReseedIdentity continues the identity of the target tables from the source after the copy
+	ReseedIdentity bool
+	// DryRun prints what would be done without changing the target
+	DryRun bool
+	// PlanFile receives the plan of a dry run, which Apply executes
+	PlanFile string `json:"-"`
+	// AllowSameDatabase copies between tables of one database when the source and the target resolve to it
+	AllowSameDatabase bool
+	// Yes confirms truncating or deleting from the target tables without asking, which is required without a terminal
+	Yes bool `json:"-"`
+	// References determines how foreign keys from tables outside the copy set are handled
+	References ReferencePolicy
+	// NoLock skips the application locks that prevent concurrent runs into the same target tables
+	NoLock bool
+	// SkipPermissionCheck skips the check of the permissions the copy needs before the target is changed
+	SkipPermissionCheck bool
+	// SkipSpaceCheck skips the check that the copied data fits in the target before the target is changed
+	SkipSpaceCheck bool
+	// CheckpointFile records the completed tables, so a failed run can be restarted
+	CheckpointFile string
+	// Subset copies the rows matching the query filter together with the rows they reference, instead of filtering every table
+	Subset bool
+	// SubsetChildren also copies the rows referencing the rows of a subset
+	SubsetChildren bool
+	// SampleRows and SamplePercent copy a subset of every table, for development copies of large databases
+	SampleRows    int
+	SamplePercent float64
+	// Verify compares row counts and checksums of source and target after the copy
+	Verify bool
+	// VerifyOnly compares source and target without copying
+	VerifyOnly bool
+	// VerifyCounts fails the tables of which the target has another number of rows than were copied from the source
+	VerifyCounts bool
+	// VerifyChecksums compares the hashes of the rows of source and target after the copy and lists the rows that differ
+	VerifyChecksums bool
+	// ConfigFile holds the per table settings
+	ConfigFile string
+	// TableMap copies tables into a table of another name in the target, schema.table in the source to schema.table in the target
+	TableMap map[string]string
+	// Resume continues partially copied tables from the last key recorded in the checkpoint file
+	Resume bool
+	// SchemaCheck determines how the target schema has to match the source
+	SchemaCheck copy.SchemaCheck
+	// CIProgressTemplate and CISummaryTemplate are Go text/templates replacing the CI progress lines and summary
+	CIProgressTemplate string
+	CISummaryTemplate  string
+	// CIInterval and CIPercent space out the CI progress lines of a table, see monitor.SetCIHeartbeat
+	CIInterval time.Duration
+	CIPercent  int
+	// ExcludeColumns are left out of the copy, as column for every table or schema.table.column for one table
+	ExcludeColumns []string
+	// TableRegex is a regular expression the names of the tables Schema and TableFilter select have to match
+	TableRegex string
+	// ExcludeTables are the patterns of the tables left out of the copy, globs or regular expressions between slashes
+	// matching the table name or schema.table
+	ExcludeTables []string
+	// Tables and the lines of TablesFile are the schema.table names of the tables to copy, instead of the tables Schema
+	// and TableFilter select
+	Tables     []string
+	TablesFile string
+	// IncludeSystemTables also selects the system tables the schemas and filters match: the tables shipped with the server,
+	// the history tables of temporal tables and sysdiagrams
+	IncludeSystemTables bool
+	// ValidateForeignKeys validates the untrusted foreign keys of the copied tables WITH CHECK after the copy
+	ValidateForeignKeys bool
+	// DisableIndexes disables the nonclustered indexes of the target tables during the load and rebuilds them afterwards
+	DisableIndexes bool
+	// Triggers determines how the triggers of the target tables are treated during the load
+	Triggers copy.TriggerMode
+	// CommitCount is the number of rows per bulk copy transaction
+	CommitCount int
+	// Tablock, KeepNulls, CheckConstraints, RowsPerBatch and KeepIdentity are passed to the bulk copies, see mssql.BulkOptions
+	Tablock          bool
+	KeepNulls        bool
+	CheckConstraints bool
+	RowsPerBatch     int
+	KeepIdentity     bool
+	// MaxErrors is the number of rows per table the target may reject before the table fails
+	MaxErrors int
+	// RejectFile receives the rejected rows as JSON lines
+	RejectFile string
+	// RecoveryFile receives the statements restoring the foreign keys, indexes and triggers of the tables until they are restored
+	RecoveryFile string
+	// AuditFile receives a record of every TRUNCATE, DELETE and dropped or disabled foreign key before it runs, see mssql.AuditJournal
+	AuditFile string
+	// ReportFile receives the report of the run as JSON, JUnitFile as a JUnit XML test suite with a test case per table
+	ReportFile string
+	JUnitFile  string
+	// Retries is the number of times a batch failing with a transient error is inserted again, the first after RetryDelay
+	Retries    int
+	RetryDelay time.Duration
+	// Timeout stops the run and TableTimeout fails a table when it takes longer, 0 does not limit them
+	Timeout      time.Duration
+	TableTimeout time.Duration
+	// Partitions splits the copy of every table into this many key ranges copied concurrently, the config file can override it per table
+	Partitions int
+	// Writers inserts the rows of every table with this many concurrent bulk copies, the config file can override it per table
+	Writers int
+	// ReadIsolation determines how the reads of the source rows interact with concurrent writes
+	ReadIsolation mssql.ReadIsolation
+	// PageSize reads the source tables in pages of this many rows ordered by their primary key
+	PageSize int
+	// MaxRowsPerSecond and MaxMBPerSecond limit the throughput of all tables together
+	MaxRowsPerSecond int
+	MaxMBPerSecond   float64
+	// MaxUtilization throttles the copy to keep the utilization of the source and target Azure SQL databases below this percentage
+	MaxUtilization float64
+	// AdaptiveBatches tunes the number of rows per bulk copy transaction of every table by the duration of its batches
+	AdaptiveBatches bool
+	// BufferRows and BufferMB bound the rows buffered between the reader and the writer of every table
+	BufferRows int
+	BufferMB   float64
+	// EventBuffer is the number of progress events buffered for the monitor
+	EventBuffer int
+	// ProgressRows and ProgressInterval set how often the progress of a table is reported, see copy.Options
+	ProgressRows     int
+	ProgressInterval time.Duration
+
+	// plan is the saved plan Apply executes, the tables are taken from it instead of selected
+	plan *planFile
 }
 
-func Copy(sourceHost, sourceDB, targetHost, targetDB, schema, filter, queryFilter string, parrallel int, ci bool) {
-	sDB, err := mssql.Connect(sourceHost, sourceDB)
+// DefaultEventBuffer is the number of progress events buffered for the monitor by default
+const DefaultEventBuffer = 1000
+
+// DefaultRecoveryFile is the recovery file in the working directory, see CopyOptions.RecoveryFile
+const DefaultRecoveryFile = "asqlcp-recovery.sql"
+
+func Copy(opts CopyOptions) {
+	if opts.LogFile != "" || opts.Debug {
+		closeLog := setupLogging(opts.LogFile, opts.LogLevel)
+		defer closeLog()
+	}
+
+	sDB, err := mssql.ConnectWith(opts.SourceHost, opts.SourceDB, opts.SourceAuth)
 	if err != nil {
-		log.Fatal(err)
+		fatal(ExitConnection, err)
 	}
 	defer sDB.Close()
 
-	tDB, err := mssql.Connect(targetHost, targetDB)
+	tDB, err := mssql.ConnectWith(opts.TargetHost, opts.TargetDB, opts.TargetAuth)
 	if err != nil {
-		log.Fatal(err)
+		fatal(ExitConnection, err)
 	}
 	defer tDB.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
+	sameDatabase := mssql.SameDatabase(sDB.ServerInfo(), tDB.ServerInfo())
+	err = checkSameDatabase(sDB.ServerInfo(), tDB.ServerInfo(), opts.AllowSameDatabase)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if opts.AuditFile != "" {
+		f, err := os.OpenFile(opts.AuditFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		tDB.SetAuditJournal(mssql.NewAuditJournal(f))
+	}
+
+	for _, warning := range mssql.CompatibilityWarnings(sDB.ServerInfo(), tDB.ServerInfo()) {
+		log.Printf("WARNING: %s", warning)
+	}
+
+	ctx, cancel := withTimeout(context.Background(), opts.Timeout)
 	defer cancel()
+	// an interrupt cancels the run like q does, a second one kills it
+	ctx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stopSignals)
+
+	var tableRefs []mssql.TableRef
+	if opts.plan != nil {
+		tableRefs = opts.plan.tables()
+	} else {
+		tableRefs, err = selectTables(ctx, sDB, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if len(tableRefs) == 0 {
+		log.Fatal("No tables")
+	}
+
+	stopTracing := startTracing(ctx)
+	defer stopTracing()
+	ctx, span := startRunSpan(ctx, opts, len(tableRefs))
+	defer span.End()
+
+	targets, err := tableTargets(opts.ConfigFile, opts.TableMap, tableRefs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if sameDatabase {
+		err = checkSelfCopies(tableRefs, targets)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	metadata, err := prefetch(ctx, sDB, tDB, tableRefs, targets, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, table := range tableRefs {
+		schema, err := sDB.GetSchemaDefinition(ctx, table)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if columns := mssql.UnknownTypeColumns(schema); len(columns) > 0 {
+			log.Printf("WARNING: %s has columns of a type unknown to asqlcp (%s), they are copied through their text representation with INSERT statements", table, strings.Join(columns, ", "))
+		}
+	}
+
+	var subset map[string]string
+	if opts.Subset {
+		subset, err = copy.PlanSubset(ctx, sDB, tableRefs, opts.QueryFilter, opts.SubsetChildren)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	excludeColumns, err := copy.ParseExcludeColumns(opts.ExcludeColumns)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if opts.VerifyOnly {
+		verifyOpts := copy.Options{QueryFilter: opts.QueryFilter, Subset: subset, SchemaCheck: opts.SchemaCheck, ExcludeColumns: excludeColumns, Targets: targets}
+		failed := verify(ctx, sDB, tDB, tableRefs, verifyOpts)
+		if opts.VerifyChecksums {
+			failed = max(failed, verifyChecksums(ctx, sDB, tDB, tableRefs, verifyOpts))
+		}
+		if failed > 0 {
+			fatalf(ExitVerification, "verification failed for %d tables", failed)
+		}
+		return
+	}
 
-	eventChan := make(chan monitor.Event, 1000)
+	disableForeignKeys := false
+	if opts.Mode == copy.ModeTruncate || opts.Mode == copy.ModeDelete {
+		tableRefs, disableForeignKeys, err = resolveReferences(ctx, tDB, metadata, tableRefs, targets, opts.References, opts.CI)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	settings, err := tableSettings(opts.ConfigFile, tableRefs, opts.Mode, opts.Partitions, opts.Writers)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	copyOpts := copy.Options{
+		QueryFilter:        opts.QueryFilter,
+		Mode:               opts.Mode,
+		WatermarkColumn:    opts.Watermark,
+		ExactCounts:        opts.ExactCounts,
+		DependencyOrder:    opts.DependencyOrder,
+		DisableForeignKeys: disableForeignKeys,
+		ReseedIdentity:     opts.ReseedIdentity,
+		CreateTables:       opts.CreateTables,
+		Resume:             opts.Resume,
+		Strategies:         settings.strategies,
+		Hints:              settings.hints,
+		Partitions:         settings.partitions,
+		Writers:            settings.writers,
+		PageSize:           opts.PageSize,
+		ReadIsolation:      opts.ReadIsolation,
+		SampleRows:         opts.SampleRows,
+		SamplePercent:      opts.SamplePercent,
+		Subset:             subset,
+		SchemaCheck:        opts.SchemaCheck,
+		VerifyCounts:       opts.VerifyCounts,
+		ExcludeColumns:     excludeColumns,
+		Targets:            targets,
+		DisableIndexes:     opts.DisableIndexes,
+		Triggers:           opts.Triggers,
+		Metadata:           metadata,
+		Retries:            opts.Retries,
+		RetryDelay:         opts.RetryDelay,
+		TableTimeout:       opts.TableTimeout,
+		AdaptiveBatches:    opts.AdaptiveBatches,
+		BufferRows:         opts.BufferRows,
+		BufferBytes:        int64(opts.BufferMB * 1024 * 1024),
+		ProgressRows:       opts.ProgressRows,
+		ProgressInterval:   opts.ProgressInterval,
+		RecoveryFile:       opts.RecoveryFile,
+		Bulk: mssql.BulkOptions{
+			CommitCount:      opts.CommitCount,
+			Tablock:          opts.Tablock,
+			KeepNulls:        opts.KeepNulls,
+			CheckConstraints: opts.CheckConstraints,
+			RowsPerBatch:     opts.RowsPerBatch,
+			KeepIdentity:     opts.KeepIdentity,
+		},
+	}
+
+	err = preflight(ctx, copy.NewEngine(sDB, tDB, copyOpts, nil), tableRefs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !opts.SkipPermissionCheck {
+		err = checkPermissions(ctx, copy.NewEngine(sDB, tDB, copyOpts, nil), tableRefs)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if !opts.SkipSpaceCheck {
+		err = checkSpace(ctx, copy.NewEngine(sDB, tDB, copyOpts, nil), tableRefs)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if opts.plan != nil {
+		checkDrift(opts.plan, copy.NewEngine(sDB, tDB, copyOpts, nil).Plan(ctx, tableRefs))
+	}
+
+	if opts.DryRun {
+		plans := copy.NewEngine(sDB, tDB, copyOpts, nil).Plan(ctx, tableRefs)
+		printPlan(opts, plans)
+		if opts.PlanFile != "" {
+			err = writePlanFile(opts.PlanFile, opts, disableForeignKeys, plans)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Plan saved to %s, run asqlcp apply %s to execute it\n", opts.PlanFile, opts.PlanFile)
+		}
+		return
+	}
+
+	err = confirmDestructive(opts.TargetHost, opts.TargetDB, opts.Mode, copyOpts.TargetTables(tableRefs), opts.Yes, opts.CI)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !opts.NoLock {
+		lock, err := tDB.LockTables(ctx, copyOpts.TargetTables(tableRefs))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer lock.Release()
+	}
+
+	if opts.CheckpointFile != "" {
+		copyOpts.Checkpoints, err = copy.LoadCheckpoints(opts.CheckpointFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if opts.MaxErrors > 0 {
+		// without a reject file the rejected rows are only counted
+		var w io.Writer
+		if opts.RejectFile != "" {
+			f, err := os.Create(opts.RejectFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		copyOpts.MaxErrors = opts.MaxErrors
+		copyOpts.Rejects = copy.NewRejects(w)
+	}
+
+	if opts.MaxRowsPerSecond > 0 || opts.MaxMBPerSecond > 0 {
+		copyOpts.Limiter = copy.NewLimiter(float64(opts.MaxRowsPerSecond), opts.MaxMBPerSecond*1024*1024)
+	}
+
+	if opts.MaxUtilization > 0 && copyOpts.Limiter == nil {
+		// unlimited until the throttle sets a rate
+		copyOpts.Limiter = copy.NewLimiter(0, 0)
+	}
+
+	readDB := sDB
+	if opts.ConsistentSnapshot {
+		readDB, err = sDB.Snapshot(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer readDB.Close()
+
+		// all reads share the connection of the snapshot transaction
+		opts.Parrallel = 1
+	}
+
+	eventBuffer := opts.EventBuffer
+	if eventBuffer <= 0 {
+		eventBuffer = DefaultEventBuffer
+	}
+	eventChan := make(chan monitor.Event, eventBuffer)
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 
-	monitor := monitor.NewMonitor(eventChan, ci, nil)
+	var output io.Writer
+	if opts.OutputFile != "" {
+		f, err := os.Create(opts.OutputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		output = f
+	}
+
+	mon := monitor.NewMonitor(eventChan, opts.CI, output)
+	mon.SetView(opts.View)
+	mon.SetTables(tableRefs)
+	mon.SetExpectedRows(expectedRows(metadata, tableRefs))
+	mon.SetOutput(opts.Output)
+	ansi := ansiOutput(opts)
+	if !ansi {
+		mon.DisableANSI()
+	}
+	templates, err := monitor.ParseCITemplates(opts.CIProgressTemplate, opts.CISummaryTemplate)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mon.SetCITemplates(templates)
+	mon.SetCIHeartbeat(opts.CIInterval, opts.CIPercent)
+	if opts.MetricsAddr != "" {
+		stopMetrics := serveMetrics(mon, opts.MetricsAddr)
+		defer stopMetrics()
+	}
+	report := monitor.NewReportSink()
+	mon.AddSink(report)
+	if opts.NotifyWebhook != "" {
+		mon.AddSink(monitor.NewWebhookSink(opts.NotifyWebhook, fmt.Sprintf("asqlcp %s to %s", opts.SourceDB, opts.TargetDB)))
+	}
+	if opts.LogFile != "" || opts.Debug {
+		mon.AddSink(monitor.NewLogSink(slog.Default()))
+	}
+
+	engine := copy.NewEngine(readDB, tDB, copyOpts, eventChan)
+	engine.SetConsumerDone(mon.Done())
+	if !opts.CI && ansi && (opts.Output == "" || opts.Output == monitor.OutputText) && term.IsTerminal(int(os.Stdin.Fd())) {
+		mon.UseTUI(os.Stdin, cancel, engine.CancelTable)
+	}
+
+	// the monitor is stopped once the engine returned, so the events of every table are rendered and reported
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	defer stopMonitor()
 	go func() {
 		defer wg.Done()
-		monitor.Run(ctx)
+		mon.Run(monitorCtx)
 	}()
 
-	tables, err := sDB.GetTablesFromFilter(ctx, schema, filter)
+	if opts.MaxUtilization > 0 {
+		throttle := copy.NewThrottle(copyOpts.Limiter, opts.MaxUtilization, map[string]*mssql.MSSQLDB{"source": sDB, "target": tDB})
+		throttle.OnChange(func(status string) {
+			mon.SetStatus("throttle", status)
+		})
+		go throttle.Run(ctx)
+	}
+
+	// failures of single tables are reported by the monitor, they decide the exit status
+	tableErrs, runErr := copy.SplitTableErrors(engine.Run(ctx, tableRefs, opts.Parrallel))
+	if runErr != nil {
+		log.Println(runErr)
+	}
+
+	// a completed run should not be skipped by the next one
+	if copyOpts.Checkpoints != nil && copyOpts.Checkpoints.AllDone(tableRefs) {
+		err = copyOpts.Checkpoints.Remove()
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	cancelled := errors.Is(ctx.Err(), context.Canceled)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("the run took longer than --timeout %s, the unfinished tables were stopped", opts.Timeout)
+	}
+	cancel()
+	stopMonitor()
+	wg.Wait()
+
+	summary := report.Report()
+	summary.ExitStatus = runExitStatus(tableErrs, runErr, cancelled)
+	printReport(summary, opts, output)
+
+	if copyOpts.Rejects != nil && copyOpts.Rejects.Count() > 0 {
+		fmt.Printf("\n%d rows were rejected by the target", copyOpts.Rejects.Count())
+		if opts.RejectFile != "" {
+			fmt.Printf(", see %s", opts.RejectFile)
+		}
+		fmt.Println()
+	}
+
+	var verificationErr error
+	if opts.ValidateForeignKeys && !cancelled {
+		// the copy context is cancelled once the engine returned
+		validateCtx, cancelValidate := withTimeout(context.Background(), opts.Timeout)
+		defer cancelValidate()
+		if failed := validateForeignKeys(validateCtx, tDB, copyOpts.TargetTables(tableRefs)); failed > 0 {
+			verificationErr = fmt.Errorf("validation failed for %d foreign keys", failed)
+		}
+	}
+
+	if opts.Verify && !cancelled {
+		// the copy context is cancelled once the engine returned
+		verifyCtx, cancelVerify := withTimeout(context.Background(), opts.Timeout)
+		defer cancelVerify()
+		if failed := verify(verifyCtx, readDB, tDB, tableRefs, copy.Options{QueryFilter: opts.QueryFilter, Subset: subset, SchemaCheck: opts.SchemaCheck, ExcludeColumns: excludeColumns, Targets: targets}); failed > 0 {
+			verificationErr = errors.Join(verificationErr, fmt.Errorf("verification failed for %d tables", failed))
+		}
+	}
+
+	if opts.VerifyChecksums && !cancelled {
+		verifyCtx, cancelVerify := withTimeout(context.Background(), opts.Timeout)
+		defer cancelVerify()
+		if failed := verifyChecksums(verifyCtx, readDB, tDB, tableRefs, copy.Options{QueryFilter: opts.QueryFilter, Subset: subset, SchemaCheck: opts.SchemaCheck, ExcludeColumns: excludeColumns, Targets: targets}); failed > 0 {
+			verificationErr = errors.Join(verificationErr, fmt.Errorf("checksum verification failed for %d tables", failed))
+		}
+	}
+
+	// failed tables take precedence, the verification of a partial copy fails as well
+	if summary.ExitStatus != 0 {
+		if runErr != nil {
+			fatalf(summary.ExitStatus, "the copy stopped, %s", runErr)
+		}
+		fatalf(summary.ExitStatus, "%d of %d tables were not copied", len(tableErrs), len(tableRefs))
+	}
+	if verificationErr != nil {
+		fatal(ExitVerification, verificationErr)
+	}
+}
+
+// verify prints the comparison of source and target and returns the number of tables that differ
+func verify(ctx context.Context, sDB, tDB *mssql.MSSQLDB, tables []mssql.TableRef, copyOpts copy.Options) int {
+	engine := copy.NewEngine(sDB, tDB, copyOpts, nil)
+
+	fmt.Println()
+	return printVerification(engine.Verify(ctx, tables))
+}
+
+// verifyChecksums prints the comparison of the row hashes of source and target and returns the number of tables that differ
+func verifyChecksums(ctx context.Context, sDB, tDB *mssql.MSSQLDB, tables []mssql.TableRef, copyOpts copy.Options) int {
+	engine := copy.NewEngine(sDB, tDB, copyOpts, nil)
+
+	fmt.Println()
+	return printChecksumVerification(engine.VerifyChecksums(ctx, tables))
+}
+
+// validateForeignKeys prints the validation of the foreign keys of the tables and returns the number of foreign keys the rows violate
+func validateForeignKeys(ctx context.Context, tDB *mssql.MSSQLDB, tables []mssql.TableRef) int {
+	validations, err := copy.NewEngine(nil, tDB, copy.Options{}, nil).ValidateForeignKeys(ctx, tables)
 	if err != nil {
-		log.Fatal(err)
+		fatal(ExitVerification, err)
 	}
 
-	if len(tables) == 0 {
-		log.Fatal("No tables")
+	fmt.Println()
+	return printForeignKeyValidation(validations)
+}
+
+// settings holds the per table settings by TableRef.String()
+type settings struct {
+	strategies map[string]copy.Strategy
+	hints      map[string][]string
+	partitions map[string]copy.Partitioning
+	writers    map[string]int
+}
+
+// tableSettings returns the load strategies, query hints, partitioning and writers configured for the tables in the config file,
+// the tables without partitions or writers in the config file get the default number. Merge mode loads every table with the merge
+// strategy, so another strategy in the config file is refused.
+func tableSettings(configFile string, tables []mssql.TableRef, mode copy.Mode, partitions, writers int) (settings, error) {
+	s := settings{
+		strategies: make(map[string]copy.Strategy),
+		hints:      make(map[string][]string),
+		partitions: make(map[string]copy.Partitioning),
+		writers:    make(map[string]int),
 	}
 
-	tasks := make([]*copy.CopyTask, len(tables))
+	var c *config.Config
+	if configFile != "" {
+		var err error
+		c, err = config.Load(configFile)
+		if err != nil {
+			return settings{}, err
+		}
+	}
+
+	for _, table := range tables {
+		tableConfig := c.Table(table)
+		strategy, err := copy.ParseStrategy(tableConfig.Strategy)
+		if err != nil {
+			return settings{}, fmt.Errorf("table %s: %w", table, err)
+		}
+		if mode == copy.ModeMerge && tableConfig.Strategy != "" && strategy != copy.StrategyMerge {
+			return settings{}, fmt.Errorf("table %s: the config file loads it with the %s strategy, which can not be used with --mode merge", table, strategy)
+		}
+		s.strategies[table.String()] = strategy
+
+		if len(tableConfig.Hints) > 0 {
+			s.hints[table.String()] = tableConfig.Hints
+		}
+
+		parts := partitions
+		if tableConfig.Partitions > 0 {
+			parts = tableConfig.Partitions
+		}
+		if parts > 1 {
+			s.partitions[table.String()] = copy.Partitioning{Parts: parts, Column: tableConfig.PartitionColumn}
+		}
 
-	for i, table := range tables {
-		task := copy.NewCopyTask(mssql.TableRef{Schema: schema, Table: table}, sDB, tDB, queryFilter, eventChan)
-		tasks[i] = task
+		tableWriters := writers
+		if tableConfig.Writers > 0 {
+			tableWriters = tableConfig.Writers
+		}
+		if tableWriters > 1 {
+			s.writers[table.String()] = tableWriters
+		}
 	}
 
-	for _, chunk := range chunkBy(tasks, parrallel) {
-		for _, task := range chunk {
-			go task.Run(ctx)
+	return s, nil
+}
+
+// preflight validates the select of every table against the source before the target is touched, the estimated rows of
+// the tables are logged and their total is printed
+func preflight(ctx context.Context, engine *copy.Engine, tables []mssql.TableRef) error {
+	failed := 0
+	var rows float64
+	for _, estimate := range engine.Preflight(ctx, tables) {
+		if estimate.Err != nil {
+			log.Printf("%s: %s", estimate.Table, estimate.Err)
+			failed++
+			continue
 		}
 
-		for _, task := range chunk {
-			task.Wait()
+		slog.Info("estimated rows", "table", estimate.Table.String(), "rows", int64(estimate.Rows))
+		rows += estimate.Rows
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("the select of %d tables failed to compile against the source", failed)
+	}
+
+	fmt.Printf("The selects of %d tables compile against the source, an estimated %d rows are read\n", len(tables), int64(rows))
+
+	return nil
+}
+
+// withTimeout returns a context that is cancelled after timeout, or only by its cancel function when timeout is 0
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// expectedRows returns the approximate rows of the tables by name, for the progress of the run before the tables are counted
+func expectedRows(metadata *copy.Metadata, tables []mssql.TableRef) map[string]int {
+	rows := make(map[string]int, len(tables))
+	for _, table := range tables {
+		if count, ok := metadata.Count(table); ok {
+			rows[table.String()] = count
 		}
 	}
 
-	cancel()
-	wg.Wait()
+	return rows
 }
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableSettings(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "asqlcp.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{"tables": {"dbo.Orders": {"strategy": "insert", "partitions": 4}, "dbo.Lines": {"strategy": "merge"}}}`), 0o644))
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "Orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "Lines"}
+	customers := mssql.TableRef{Schema: "dbo", Table: "Customers"}
+
+	s, err := tableSettings(configFile, []mssql.TableRef{orders, lines, customers}, copy.ModeTruncate, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]copy.Strategy{
+		orders.String():    copy.StrategyInsert,
+		lines.String():     copy.StrategyMerge,
+		customers.String(): copy.StrategyBulk,
+	}, s.strategies)
+	assert.Equal(t, map[string]copy.Partitioning{orders.String(): {Parts: 4}}, s.partitions)
+	assert.Equal(t, map[string]int{orders.String(): 2, lines.String(): 2, customers.String(): 2}, s.writers)
+
+	// merge mode merges the tables without a strategy and refuses the others
+	_, err = tableSettings(configFile, []mssql.TableRef{lines, customers}, copy.ModeMerge, 0, 0)
+	assert.NoError(t, err)
+	_, err = tableSettings(configFile, []mssql.TableRef{orders, lines}, copy.ModeMerge, 0, 0)
+	assert.EqualError(t, err, "table [dbo].[Orders]: the config file loads it with the insert strategy, which can not be used with --mode merge")
+}
+
+func TestRunExitStatus(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "Orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "Lines"}
+	mismatch := &copy.TableError{Table: orders, Errs: []error{fmt.Errorf("Schema check failed on table %s, %w", orders, mssql.ErrSchemaMismatch)}}
+	failed := &copy.TableError{Table: lines, Errs: []error{errors.New("Failed to insert rows")}}
+
+	assert.Equal(t, 0, runExitStatus(nil, nil, false))
+	assert.Equal(t, ExitSchemaMismatch, runExitStatus([]*copy.TableError{mismatch}, nil, false))
+	assert.Equal(t, ExitPartialCopy, runExitStatus([]*copy.TableError{mismatch, failed}, nil, false))
+	// the run stopped before the tables were copied
+	assert.Equal(t, ExitPartialCopy, runExitStatus(nil, errors.New("Failed to empty the tables"), false))
+	assert.Equal(t, ExitCancelled, runExitStatus([]*copy.TableError{failed}, nil, true))
+}
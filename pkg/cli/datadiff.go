@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// DataDiffOptions selects the tables and rows compared by DataDiff
+type DataDiffOptions struct {
+	SourceHost  string
+	SourceDB    string
+	TargetHost  string
+	TargetDB    string
+	Schema      string
+	TableFilter string
+	// TableRegex, ExcludeTables and IncludeSystemTables select the tables like the options of a copy, see listTables
+	TableRegex          string
+	ExcludeTables       []string
+	IncludeSystemTables bool
+	// ConfigFile and TableMap compare the tables with the target tables they are copied into, see tableTargets
+	ConfigFile string
+	TableMap   map[string]string
+	// QueryFilter selects the rows compared on both sides
+	QueryFilter string
+	// Limit is the number of differing rows listed per table, Samples the number of them printed with their values
+	Limit   int
+	Samples int
+	// JSON prints the report as JSON instead of text
+	JSON bool
+	// SourceAuth and TargetAuth are the credentials of the source and the target
+	SourceAuth mssql.Auth
+	TargetAuth mssql.Auth
+}
+
+// tableDataDiff is the JSON report of the rows of a table that differ
+type tableDataDiff struct {
+	Table            string    `json:"table"`
+	MissingInTarget  bool      `json:"missing_in_target,omitempty"`
+	MissingInSource  bool      `json:"missing_in_source,omitempty"`
+	SourceRows       int64     `json:"source_rows"`
+	TargetRows       int64     `json:"target_rows"`
+	Buckets          int       `json:"buckets"`
+	DifferentBuckets int       `json:"different_buckets"`
+	Columns          []string  `json:"columns,omitempty"`
+	Rows             []rowDiff `json:"rows,omitempty"`
+	Skipped          []string  `json:"skipped_columns,omitempty"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// rowDiff is a row that is missing, extra or changed in the target, with the values of both sides when it was sampled
+type rowDiff struct {
+	Key    string   `json:"key"`
+	Kind   string   `json:"kind"`
+	Source []string `json:"source,omitempty"`
+	Target []string `json:"target,omitempty"`
+}
+
+// Equal reports whether the rows of the table are the same in the source and the target
+func (d tableDataDiff) Equal() bool {
+	return !d.MissingInTarget && !d.MissingInSource && d.Error == "" && d.DifferentBuckets == 0
+}
+
+// DataDiff compares the rows of the tables matching the filter in the source and the target by their primary key,
+// and exits with ExitVerification when any of them differs
+func DataDiff(opts DataDiffOptions) {
+	sDB, err := mssql.ConnectWith(opts.SourceHost, opts.SourceDB, opts.SourceAuth)
+	if err != nil {
+		fatal(ExitConnection, err)
+	}
+	defer sDB.Close()
+
+	tDB, err := mssql.ConnectWith(opts.TargetHost, opts.TargetDB, opts.TargetAuth)
+	if err != nil {
+		fatal(ExitConnection, err)
+	}
+	defer tDB.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	diffs, err := dataDiff(ctx, sDB, tDB, opts)
+	if err != nil {
+		fatal(ExitError, err)
+	}
+
+	if opts.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diffs)
+		if err != nil {
+			fatal(ExitError, err)
+		}
+	} else {
+		printDataDiff(diffs)
+	}
+
+	differing := 0
+	for _, diff := range diffs {
+		if !diff.Equal() {
+			differing++
+		}
+	}
+	if differing > 0 {
+		fatalf(ExitVerification, "the rows of %d tables differ", differing)
+	}
+}
+
+// dataDiff compares the tables selected in the source with the tables they are copied into, the tables selected in the target
+// that none of them is copied into are reported as only in the target
+func dataDiff(ctx context.Context, sDB, tDB *mssql.MSSQLDB, opts DataDiffOptions) ([]tableDataDiff, error) {
+	sourceTables, err := listTables(ctx, sDB, opts.Schema, opts.TableFilter, opts.TableRegex, opts.ExcludeTables, opts.IncludeSystemTables)
+	if err != nil {
+		return nil, err
+	}
+
+	targetTables, err := listTables(ctx, tDB, opts.Schema, opts.TableFilter, opts.TableRegex, opts.ExcludeTables, opts.IncludeSystemTables)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := tableTargets(opts.ConfigFile, opts.TableMap, sourceTables)
+	if err != nil {
+		return nil, err
+	}
+	copyOpts := copy.Options{QueryFilter: opts.QueryFilter, Targets: targets}
+
+	inTarget := make(map[string]bool, len(targetTables))
+	for _, table := range targetTables {
+		inTarget[strings.ToLower(table.String())] = true
+	}
+
+	diffs := make([]tableDataDiff, 0, len(sourceTables))
+	compared := make(map[string]bool, len(sourceTables))
+	matched := make([]mssql.TableRef, 0, len(sourceTables))
+	for i, target := range copyOpts.TargetTables(sourceTables) {
+		table := sourceTables[i]
+		compared[strings.ToLower(target.String())] = true
+
+		exists := inTarget[strings.ToLower(target.String())]
+		if !exists && target != table {
+			// the target of a mapped table can be outside the tables selected in the target
+			exists, err = tDB.TableExists(ctx, target)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !exists {
+			diffs = append(diffs, tableDataDiff{Table: table.Schema + "." + table.Table, MissingInTarget: true})
+			continue
+		}
+		matched = append(matched, table)
+	}
+
+	for _, table := range targetTables {
+		if !compared[strings.ToLower(table.String())] {
+			diffs = append(diffs, tableDataDiff{Table: table.Schema + "." + table.Table, MissingInSource: true})
+		}
+	}
+
+	engine := copy.NewEngine(sDB, tDB, copyOpts, nil)
+	for _, v := range engine.DiffRows(ctx, matched, copy.DiffOptions{Limit: opts.Limit, Samples: opts.Samples}) {
+		diffs = append(diffs, newTableDataDiff(v))
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Table < diffs[j].Table
+	})
+
+	return diffs, nil
+}
+
+func newTableDataDiff(v copy.ChecksumVerification) tableDataDiff {
+	diff := tableDataDiff{
+		Table:            v.Table.Schema + "." + v.Table.Table,
+		SourceRows:       v.SourceRows,
+		TargetRows:       v.TargetRows,
+		Buckets:          v.Buckets,
+		DifferentBuckets: v.DifferentBuckets,
+		Columns:          v.Columns,
+		Skipped:          v.Skipped,
+	}
+	if v.Err != nil {
+		diff.Error = v.Err.Error()
+	}
+
+	for _, d := range v.Differences {
+		diff.Rows = append(diff.Rows, rowDiff{Key: d.Key, Kind: d.Kind, Source: d.Source, Target: d.Target})
+	}
+
+	return diff
+}
+
+// printDataDiff prints the rows that differ per table, the sampled rows with their values, followed by a summary
+func printDataDiff(diffs []tableDataDiff) {
+	differing := 0
+	for _, diff := range diffs {
+		switch {
+		case diff.MissingInTarget:
+			fmt.Printf("%s: missing in the target\n", diff.Table)
+		case diff.MissingInSource:
+			fmt.Printf("%s: only in the target\n", diff.Table)
+		case diff.Error != "":
+			fmt.Printf("%s: %s\n", diff.Table, diff.Error)
+		case diff.Equal():
+			fmt.Printf("%s: identical, %d rows\n", diff.Table, diff.SourceRows)
+		default:
+			fmt.Printf("%s: %d source rows, %d target rows, %d of %d key buckets differ\n", diff.Table, diff.SourceRows, diff.TargetRows, diff.DifferentBuckets, diff.Buckets)
+		}
+
+		if !diff.Equal() {
+			differing++
+		}
+
+		for _, row := range diff.Rows {
+			fmt.Printf("  %s in the target: %s\n", row.Kind, row.Key)
+			if len(row.Source) > 0 {
+				fmt.Printf("    source: %s\n", columnValues(diff.Columns, row.Source))
+			}
+			if len(row.Target) > 0 {
+				fmt.Printf("    target: %s\n", columnValues(diff.Columns, row.Target))
+			}
+		}
+
+		if len(diff.Skipped) > 0 {
+			fmt.Printf("  not compared: %s\n", strings.Join(diff.Skipped, ", "))
+		}
+	}
+
+	fmt.Printf("\n%d of %d tables differ\n", differing, len(diffs))
+}
+
+// columnValues formats the values of a row as column=value pairs
+func columnValues(columns, values []string) string {
+	pairs := make([]string, len(values))
+	for i, value := range values {
+		pairs[i] = columns[i] + "=" + value
+	}
+
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// DoctorOptions describes the copy Doctor checks the connections and permissions for
+type DoctorOptions struct {
+	SourceHost   string
+	SourceDB     string
+	TargetHost   string
+	TargetDB     string
+	Schema       string
+	TableFilter  string
+	Mode         copy.Mode
+	CreateTables bool
+	ConfigFile   string
+	// TableRegex, ExcludeTables and IncludeSystemTables select the tables like those of CopyOptions
+	TableRegex          string
+	ExcludeTables       []string
+	IncludeSystemTables bool
+	// SourceAuth and TargetAuth are the credentials of the source and the target
+	SourceAuth mssql.Auth
+	TargetAuth mssql.Auth
+}
+
+// Doctor connects to the source and the target and checks the permissions a copy with the options needs, printing the statement
+// granting every permission that is missing, and whether the copied data fits in the target. It exits with an error when a connection fails or a required permission is missing.
+func Doctor(opts DoctorOptions) {
+	ok := true
+
+	sDB, err := mssql.ConnectWith(opts.SourceHost, opts.SourceDB, opts.SourceAuth)
+	if err != nil {
+		fmt.Printf("FAIL connecting to source %s/%s: %s\n", opts.SourceHost, opts.SourceDB, err)
+		ok = false
+	} else {
+		defer sDB.Close()
+		printServer("source", opts.SourceHost, opts.SourceDB, sDB.ServerInfo())
+	}
+
+	tDB, err := mssql.ConnectWith(opts.TargetHost, opts.TargetDB, opts.TargetAuth)
+	if err != nil {
+		fmt.Printf("FAIL connecting to target %s/%s: %s\n", opts.TargetHost, opts.TargetDB, err)
+		ok = false
+	} else {
+		defer tDB.Close()
+		printServer("target", opts.TargetHost, opts.TargetDB, tDB.ServerInfo())
+	}
+
+	if !ok {
+		os.Exit(ExitConnection)
+	}
+
+	for _, warning := range mssql.CompatibilityWarnings(sDB.ServerInfo(), tDB.ServerInfo()) {
+		fmt.Printf("WARNING %s\n", warning)
+	}
+
+	if mssql.SameDatabase(sDB.ServerInfo(), tDB.ServerInfo()) {
+		fmt.Printf("WARNING the source and the target are the same database %s, a copy needs --allowSameDatabase\n", sDB.ServerInfo().Database)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	tableRefs, err := listTables(ctx, sDB, opts.Schema, opts.TableFilter, opts.TableRegex, opts.ExcludeTables, opts.IncludeSystemTables)
+	if err != nil {
+		fatal(ExitError, err)
+	}
+
+	settings, err := tableSettings(opts.ConfigFile, tableRefs, opts.Mode, 0, 0)
+	if err != nil {
+		fatal(ExitError, err)
+	}
+
+	targets, err := tableTargets(opts.ConfigFile, nil, tableRefs)
+	if err != nil {
+		fatal(ExitError, err)
+	}
+
+	engine := copy.NewEngine(sDB, tDB, copy.Options{Mode: opts.Mode, CreateTables: opts.CreateTables, Strategies: settings.strategies, Targets: targets}, nil)
+	permissions, err := engine.CheckPermissions(ctx, tableRefs)
+	if err != nil {
+		fatal(ExitError, err)
+	}
+
+	fits := true
+	estimate, err := engine.EstimateSpace(ctx, tableRefs)
+	if err != nil {
+		fmt.Printf("WARNING %s\n", err)
+	} else {
+		printSpace(estimate)
+		fits = estimate.Fits()
+	}
+
+	fmt.Printf("\nChecked %d permissions on %d tables\n", len(permissions.Source)+len(permissions.Target), len(tableRefs))
+	missing := printMissingPermissions("source", permissions.SourceUser, permissions.Source, true)
+	missing += printMissingPermissions("target", permissions.TargetUser, permissions.Target, true)
+	if missing > 0 {
+		fatalf(ExitError, "%d required permissions are missing", missing)
+	}
+	if !fits {
+		fatal(ExitError, "the copy does not fit in the target")
+	}
+
+	fmt.Println("All required permissions are granted")
+}
+
+func printServer(side, host, database string, info mssql.ServerInfo) {
+	fmt.Printf("OK   connected to %s %s/%s, %s, compatibility level %d\n", side, host, database, info.Edition, info.CompatibilityLevel)
+}
+
+// printMissingPermissions prints the permissions missing in the source or the target with the statements granting them to user,
+// the optional ones only when optional is set, and returns the number of required permissions that are missing
+func printMissingPermissions(side, user string, checks []mssql.PermissionCheck, optional bool) int {
+	required := 0
+	for _, check := range copy.Missing(checks, optional) {
+		status := "MISSING"
+		if check.Optional {
+			status = "OPTIONAL"
+		} else {
+			required++
+		}
+
+		fmt.Printf("%s %s on %s in the %s, %s\n", status, check.Permission, check.On(), side, check.Reason)
+		fmt.Printf("     %s\n", check.Grant(user))
+	}
+
+	return required
+}
+
+// checkPermissions fails the run before the target is changed when a permission it needs is missing
+func checkPermissions(ctx context.Context, engine *copy.Engine, tables []mssql.TableRef) error {
+	permissions, err := engine.CheckPermissions(ctx, tables)
+	if err != nil {
+		// the copy itself reports the permissions it lacks
+		log.Printf("WARNING: %s", err)
+		return nil
+	}
+
+	missing := printMissingPermissions("source", permissions.SourceUser, permissions.Source, false)
+	missing += printMissingPermissions("target", permissions.TargetUser, permissions.Target, false)
+	if missing > 0 {
+		return fmt.Errorf("%d required permissions are missing, grant them or run with --skipPermissionCheck", missing)
+	}
+
+	if optional := len(copy.Missing(permissions.Source, true)) + len(copy.Missing(permissions.Target, true)); optional > 0 {
+		log.Printf("WARNING: %d optional permissions are missing, asqlcp doctor lists them", optional)
+	}
+
+	return nil
+}
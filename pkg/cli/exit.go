@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// The exit codes of asqlcp, which tell scripts whether the target was changed
+const (
+	// ExitError is any other error, the target is not changed
+	ExitError = 1
+	// ExitConnection is a failure to connect or authenticate to the source or the target
+	ExitConnection = 2
+	// ExitSchemaMismatch is a run of which the tables only failed because their schema in the target does not match the source,
+	// the target is not changed
+	ExitSchemaMismatch = monitor.ExitSchemaMismatch
+	// ExitPartialCopy is a run of which tables failed or were not finished, they may have been emptied
+	ExitPartialCopy = monitor.ExitPartialCopy
+	// ExitVerification is a run of which all tables were copied, but the verification of the copy or of the foreign keys failed
+	ExitVerification = 5
+	// ExitCancelled is a run cancelled while copying, by q or an interrupt, the tables not finished may have been emptied
+	ExitCancelled = 6
+	// ExitCleanup is a cleanup of which leftovers of crashed runs could not be restored or dropped, the others were
+	ExitCleanup = 7
+)
+
+// runExitStatus returns the exit status of a copy from the failures returned by the engine, the tables failed and runErr
+// is the error that stopped the run before all tables were copied
+func runExitStatus(tableErrs []*copy.TableError, runErr error, cancelled bool) int {
+	switch {
+	case cancelled:
+		return ExitCancelled
+	case runErr != nil:
+		return ExitPartialCopy
+	case len(tableErrs) == 0:
+		return 0
+	}
+
+	for _, tableErr := range tableErrs {
+		if !errors.Is(tableErr, mssql.ErrSchemaMismatch) {
+			return ExitPartialCopy
+		}
+	}
+
+	return ExitSchemaMismatch
+}
+
+// fatal logs v like log.Fatal and exits with code
+func fatal(code int, v ...interface{}) {
+	log.Print(v...)
+	os.Exit(code)
+}
+
+// fatalf logs like log.Fatalf and exits with code
+func fatalf(code int, format string, v ...interface{}) {
+	fatal(code, fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+func ValidateFilter(queryFilter string, source Endpoint, schema, table string) {
+	where, err := mssql.FilterSQL(queryFilter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("WHERE", where)
+
+	if schema == "" || table == "" {
+		return
+	}
+
+	tableRef := mssql.TableRef{Schema: schema, Table: table}
+	query, err := mssql.SelectQuery(tableRef, queryFilter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println()
+	fmt.Println(query)
+
+	if source.Host == "" || source.Database == "" {
+		return
+	}
+
+	sDB, err := mssql.ConnectWith(source.Host, source.Database, source.Auth)
+	if err != nil {
+		fatal(ExitConnection, err)
+	}
+	defer sDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	plan, err := sDB.Explain(ctx, tableRef, queryFilter)
+	if err != nil {
+		log.Fatal(fmt.Errorf("filter does not compile against %s: %w", tableRef, err))
+	}
+
+	fmt.Println()
+	fmt.Println("Estimated plan:")
+	for _, line := range plan {
+		fmt.Println(line)
+	}
+}
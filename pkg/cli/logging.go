@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// setupLogging writes the diagnostics of the run to path at level and above: the statements emptying tables and changing
+// foreign keys, the start, retries and failures of tables and the end of the run, and at debug level every statement with
+// its duration and rows. A path ending in .json receives JSON lines. The messages of the log package, like warnings and
+// fatal errors, are logged as well, besides going to stderr where the progress can hide them. Without a path the diagnostics
+// are written to stderr. The returned function closes the file.
+func setupLogging(path string, level slog.Level) func() {
+	if path == "" {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+		// set after SetDefault, which routes the log package through the handler
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+		return func() {}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(f, handlerOpts)
+	if filepath.Ext(path) == ".json" {
+		handler = slog.NewJSONHandler(f, handlerOpts)
+	}
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+
+	// set after SetDefault, which routes the log package through the handler at info level
+	log.SetOutput(logWriter{logger: logger})
+	log.SetFlags(0)
+
+	return func() {
+		f.Close()
+	}
+}
+
+// logWriter writes the messages of the log package to stderr and logs them, at warn level when they start with WARNING:
+// and at error level otherwise, as those are mostly the errors ending the run
+type logWriter struct {
+	logger *slog.Logger
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	_, err := os.Stderr.WriteString(time.Now().Format("2006/01/02 15:04:05 ") + string(p))
+	if err != nil {
+		return 0, err
+	}
+
+	message := strings.TrimSpace(string(p))
+	level := slog.LevelError
+	if strings.HasPrefix(message, "WARNING: ") {
+		level = slog.LevelWarn
+		message = strings.TrimPrefix(message, "WARNING: ")
+	}
+	w.logger.Log(context.Background(), level, message)
+
+	return len(p), nil
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+)
+
+// serveMetrics serves the metrics of the run at /metrics on addr, the returned function stops the server
+func serveMetrics(mon *monitor.Monitor, addr string) func() {
+	sink := monitor.NewMetricsSink()
+	mon.AddSink(sink)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", sink)
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("WARNING: failed to serve metrics on %s, %s", addr, err)
+		}
+	}()
+
+	return func() {
+		server.Close()
+	}
+}
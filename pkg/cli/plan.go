@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+)
+
+func printPlan(opts CopyOptions, plans []copy.TablePlan) {
+	fmt.Printf("Dry run, nothing is changed on %s/%s\n\n", opts.TargetHost, opts.TargetDB)
+
+	for _, plan := range plans {
+		fmt.Println(plan.Table.String())
+
+		if plan.Err != nil {
+			fmt.Printf("  ERROR: %s\n\n", plan.Err)
+			continue
+		}
+
+		if !plan.SchemaMatches {
+			fmt.Println("  schema mismatch between source and target, the table would fail")
+		}
+
+		rows := fmt.Sprintf("%d", plan.Rows)
+		if plan.Approximate {
+			rows = "~" + rows
+		}
+
+		if plan.Create {
+			fmt.Println("  create:  the table is missing in the target and is created from the source definition")
+		}
+
+		fmt.Printf("  mode:    %s\n", plan.Mode)
+		if plan.Partitions > 0 {
+			fmt.Printf("  load:    %s in %d concurrent ranges\n", plan.Strategy, plan.Partitions)
+		} else if plan.Writers > 0 {
+			fmt.Printf("  load:    %s with %d concurrent writers\n", plan.Strategy, plan.Writers)
+		} else {
+			fmt.Printf("  load:    %s\n", plan.Strategy)
+		}
+		fmt.Printf("  empty:   %s\n", plan.EmptyAction)
+		if plan.Mode != copy.ModeSync {
+			fmt.Printf("  copy:    %s rows\n", rows)
+		}
+
+		for _, fk := range plan.ForeignKeys {
+			fmt.Printf("  foreign key %s from %s.%s: %s\n", fk.Name, fk.Schema, fk.Table, plan.ForeignKeyPlan)
+		}
+
+		for _, index := range plan.Indexes {
+			fmt.Printf("  index %s: disable and rebuild\n", index.Name)
+		}
+
+		for _, trigger := range plan.Triggers {
+			fmt.Printf("  trigger %s: disable and enable\n", trigger.Name)
+		}
+
+		fmt.Println()
+	}
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// planFileVersion is incremented when the plan file changes incompatibly
+const planFileVersion = 1
+
+// planFile is a copy resolved by asqlcp plan, with the options of the run and the plan of every table, which asqlcp apply executes
+type planFile struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	// DisableForeignKeys records whether the foreign keys from outside the copy set are disabled instead of dropped,
+	// so apply does not ask again
+	DisableForeignKeys bool             `json:"disable_foreign_keys"`
+	Options            CopyOptions      `json:"options"`
+	Tables             []copy.TablePlan `json:"tables"`
+}
+
+// tables returns the source tables of the plan in the order they were planned
+func (p *planFile) tables() []mssql.TableRef {
+	tables := make([]mssql.TableRef, len(p.Tables))
+	for i, plan := range p.Tables {
+		tables[i] = plan.Table
+	}
+
+	return tables
+}
+
+// writePlanFile saves the plans of the tables to path, it refuses plans of which a table would fail
+func writePlanFile(path string, opts CopyOptions, disableForeignKeys bool, plans []copy.TablePlan) error {
+	failing := make([]string, 0)
+	for _, plan := range plans {
+		if plan.Err != nil || !plan.SchemaMatches {
+			failing = append(failing, plan.Table.String())
+		}
+	}
+	if len(failing) > 0 {
+		return fmt.Errorf("the plan is not saved, the copy of %s would fail", strings.Join(failing, ", "))
+	}
+
+	opts.DryRun = false
+	opts.PlanFile = ""
+	data, err := json.MarshalIndent(planFile{
+		Version:            planFileVersion,
+		Created:            time.Now().UTC(),
+		DisableForeignKeys: disableForeignKeys,
+		Options:            opts,
+		Tables:             plans,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// loadPlanFile reads a plan saved by writePlanFile
+func loadPlanFile(path string) (*planFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan planFile
+	err = json.Unmarshal(data, &plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the plan %s, %w", path, err)
+	}
+
+	if plan.Version != planFileVersion {
+		return nil, fmt.Errorf("the plan %s has version %d, this asqlcp applies version %d", path, plan.Version, planFileVersion)
+	}
+	if len(plan.Tables) == 0 {
+		return nil, errors.New("the plan has no tables")
+	}
+
+	return &plan, nil
+}
+
+// Apply copies the tables of the plan at path with the options it was made with. The tables are planned again before the
+// target is changed and the run stops when the tables, their schemas, the actions or the foreign keys differ from the plan.
+func Apply(path string, yes bool) {
+	plan, err := loadPlanFile(path)
+	if err != nil {
+		fatal(ExitError, err)
+	}
+
+	opts := applyOptions(plan, yes)
+	fmt.Printf("Applying the plan of %s, %d tables from %s/%s to %s/%s\n", plan.Created.Local().Format(time.DateTime), len(plan.Tables),
+		opts.SourceHost, opts.SourceDB, opts.TargetHost, opts.TargetDB)
+	Copy(opts)
+}
+
+// applyOptions returns the options of the copy executing plan
+func applyOptions(plan *planFile, yes bool) CopyOptions {
+	opts := plan.Options
+	opts.plan = plan
+	opts.Yes = yes
+	// the choice made when planning, the referencing tables of include are already in the plan
+	if plan.DisableForeignKeys {
+		opts.References = ReferencesDisable
+	} else if opts.References == ReferencesAsk {
+		opts.References = ReferencesDrop
+	}
+
+	return opts
+}
+
+// checkDrift fails the run when the plans of the tables made now differ from the saved plan
+func checkDrift(saved *planFile, current []copy.TablePlan) {
+	drift := copy.PlanDrift(saved.Tables, current)
+	if len(drift) > 0 {
+		fatalf(ExitError, "the databases changed since the plan was made, nothing has been changed:\n  %s", strings.Join(drift, "\n  "))
+	}
+}
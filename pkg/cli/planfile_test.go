@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refresh.plan.json")
+	orders := mssql.TableRef{Schema: "dbo", Table: "Orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "Lines"}
+	plans := []copy.TablePlan{
+		{Table: orders, Target: orders, Mode: copy.ModeTruncate, Strategy: copy.StrategyBulk, SchemaMatches: true, SourceSchema: "a", TargetSchema: "a", Rows: 10,
+			ForeignKeys: []mssql.ForeingKeyConstraint{{Name: "fk_lines_orders", Schema: "dbo", Table: "Lines"}}},
+		{Table: lines, Target: lines, Mode: copy.ModeTruncate, Strategy: copy.StrategyBulk, SchemaMatches: true, SourceSchema: "b", Create: true},
+	}
+	opts := CopyOptions{SourceHost: "source", SourceDB: "sales", TargetHost: "target", TargetDB: "sales", Mode: copy.ModeTruncate, DryRun: true, PlanFile: path}
+
+	require.NoError(t, writePlanFile(path, opts, true, plans))
+
+	plan, err := loadPlanFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, plans, plan.Tables)
+	assert.Equal(t, []mssql.TableRef{orders, lines}, plan.tables())
+	assert.True(t, plan.DisableForeignKeys)
+	// the saved options copy instead of planning again
+	opts.DryRun = false
+	opts.PlanFile = ""
+	assert.Equal(t, opts, plan.Options)
+
+	// a plan of which a table would fail is not saved
+	failing := append([]copy.TablePlan{}, plans...)
+	failing[1].SchemaMatches = false
+	assert.EqualError(t, writePlanFile(path, opts, false, failing), "the plan is not saved, the copy of [dbo].[Lines] would fail")
+}
+
+func TestLoadPlanFileRejectsInvalidPlans(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	path := write("version.json", `{"version": 2, "tables": [{"table": {"schema": "dbo", "table": "Orders"}}]}`)
+	_, err := loadPlanFile(path)
+	assert.EqualError(t, err, "the plan "+path+" has version 2, this asqlcp applies version 1")
+
+	_, err = loadPlanFile(write("empty.json", `{"version": 1, "tables": []}`))
+	assert.EqualError(t, err, "the plan has no tables")
+
+	_, err = loadPlanFile(write("invalid.json", `{"version": `))
+	assert.ErrorContains(t, err, "failed to read the plan")
+
+	_, err = loadPlanFile(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestApplyOptions(t *testing.T) {
+	tests := []struct {
+		name               string
+		references         ReferencePolicy
+		disableForeignKeys bool
+		want               ReferencePolicy
+	}{
+		{name: "ask drops the foreign keys", references: ReferencesAsk, want: ReferencesDrop},
+		{name: "disabled when planning", references: ReferencesAsk, disableForeignKeys: true, want: ReferencesDisable},
+		{name: "the referencing tables of include are in the plan", references: ReferencesInclude, want: ReferencesInclude},
+		{name: "abort is kept", references: ReferencesAbort, want: ReferencesAbort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &planFile{DisableForeignKeys: tt.disableForeignKeys, Options: CopyOptions{References: tt.references}}
+
+			opts := applyOptions(plan, true)
+			assert.Equal(t, tt.want, opts.References)
+			assert.True(t, opts.Yes)
+			assert.Same(t, plan, opts.plan)
+		})
+	}
+}
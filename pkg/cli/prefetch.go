@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"context"
+	"log"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/schollz/progressbar/v3"
+)
+
+// prefetch loads the metadata of all tables before the copy starts, with a progress bar unless running in CI, quietly or without escape codes
+func prefetch(ctx context.Context, sDB, tDB *mssql.MSSQLDB, tables []mssql.TableRef, targets map[string]mssql.TableRef, opts CopyOptions) (*copy.Metadata, error) {
+	if opts.Output == monitor.OutputErrors {
+		return copy.Prefetch(ctx, sDB, tDB, tables, targets, opts.Parrallel, nil)
+	}
+
+	if opts.CI || !ansiOutput(opts) {
+		log.Printf("Loading the metadata of %d tables", len(tables))
+		return copy.Prefetch(ctx, sDB, tDB, tables, targets, opts.Parrallel, nil)
+	}
+
+	bar := progressbar.NewOptions(len(tables),
+		progressbar.OptionSetDescription("Loading metadata"),
+		progressbar.OptionShowCount(),
+		progressbar.OptionClearOnFinish(),
+	)
+	defer bar.Finish()
+
+	return copy.Prefetch(ctx, sDB, tDB, tables, targets, opts.Parrallel, func(table mssql.TableRef) {
+		bar.Add(1)
+	})
+}
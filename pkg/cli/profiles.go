@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jeff-99/mssqlcopy/pkg/config"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// Endpoint is a database a command connects to
+type Endpoint struct {
+	Host     string
+	Database string
+	Auth     mssql.Auth
+	// Profile is the name of the profile the endpoint was taken from, empty when it was given by flags
+	Profile string
+}
+
+// ApplyProfiles replaces source by the profile from and target by the profile to of the profiles file at path, ~/.asqlcp/profiles.yaml
+// when it is empty. A profile replaces the host and database flags of its side, so they cannot be combined, and a protected profile
+// cannot be the target of a command that writes to it, neither by its name nor by its host and database.
+func ApplyProfiles(path, from, to string, source, target *Endpoint, writesTarget bool) error {
+	if from == "" && to == "" && !writesTarget {
+		return nil
+	}
+
+	defaultPath := path == ""
+	if defaultPath {
+		var err error
+		path, err = config.DefaultProfilesPath()
+		if err != nil {
+			return err
+		}
+	}
+
+	profiles, err := config.LoadProfiles(path)
+	// without a profiles file no target is protected
+	if errors.Is(err, os.ErrNotExist) && defaultPath && from == "" && to == "" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if from != "" {
+		if source.Host != "" || source.Database != "" {
+			return fmt.Errorf("--from replaces --sourceHost and --sourceDB, leave them out")
+		}
+		*source, _, err = profileEndpoint(profiles, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	if to != "" {
+		if target.Host != "" || target.Database != "" {
+			return fmt.Errorf("--to replaces --targetHost and --targetDB, leave them out")
+		}
+		var protected bool
+		*target, protected, err = profileEndpoint(profiles, to)
+		if err != nil {
+			return err
+		}
+		if protected && writesTarget {
+			return fmt.Errorf("profile %s is protected, it can only be copied from", to)
+		}
+	} else if writesTarget {
+		if name, ok := profiles.ProtectedProfile(target.Host, target.Database); ok {
+			return fmt.Errorf("%s/%s is the database of profile %s, which is protected, it can only be copied from", target.Host, target.Database, name)
+		}
+	}
+
+	return nil
+}
+
+// profileEndpoint returns the endpoint of the profile and whether it is protected
+func profileEndpoint(profiles *config.Profiles, name string) (Endpoint, bool, error) {
+	profile, err := profiles.Profile(name)
+	if err != nil {
+		return Endpoint{}, false, err
+	}
+
+	auth, err := profile.Credentials()
+	if err != nil {
+		return Endpoint{}, false, err
+	}
+
+	return Endpoint{Host: profile.Host, Database: profile.Database, Auth: auth, Profile: name}, profile.Protected, nil
+}
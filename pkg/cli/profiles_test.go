@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+profiles:
+  prod:
+    host: prod.database.windows.net
+    database: sales
+    protected: true
+  staging:
+    host: staging.database.windows.net
+    database: sales
+`), 0o644))
+
+	source, target := Endpoint{}, Endpoint{}
+	require.NoError(t, ApplyProfiles(path, "prod", "staging", &source, &target, true))
+	assert.Equal(t, "prod", source.Profile)
+	assert.Equal(t, "staging.database.windows.net", target.Host)
+
+	target = Endpoint{}
+	assert.EqualError(t, ApplyProfiles(path, "", "prod", &Endpoint{}, &target, true), "profile prod is protected, it can only be copied from")
+
+	// a protected database given by its host and database is refused as well
+	target = Endpoint{Host: "tcp:prod.database.windows.net,1433", Database: "sales"}
+	assert.EqualError(t, ApplyProfiles(path, "", "", &Endpoint{}, &target, true),
+		"tcp:prod.database.windows.net,1433/sales is the database of profile prod, which is protected, it can only be copied from")
+	assert.NoError(t, ApplyProfiles(path, "", "", &Endpoint{}, &target, false))
+
+	source = Endpoint{Host: "prod.database.windows.net"}
+	assert.EqualError(t, ApplyProfiles(path, "prod", "", &source, &Endpoint{}, false), "--from replaces --sourceHost and --sourceDB, leave them out")
+
+	// without a profiles file nothing is protected, a missing file given by the flag fails
+	target = Endpoint{Host: "prod.database.windows.net", Database: "sales"}
+	t.Setenv("HOME", t.TempDir())
+	assert.NoError(t, ApplyProfiles("", "", "", &Endpoint{}, &target, true))
+	assert.Error(t, ApplyProfiles(filepath.Join(t.TempDir(), "missing.yaml"), "", "", &Endpoint{}, &target, true))
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"golang.org/x/term"
+)
+
+// ReferencePolicy determines what happens when tables outside the copy set reference tables that will be emptied
+type ReferencePolicy string
+
+const (
+	// ReferencesAsk presents the references and lets the user choose, without a terminal it behaves as ReferencesDrop
+	ReferencesAsk ReferencePolicy = "ask"
+	// ReferencesDrop drops the foreign keys and recreates them after the copy
+	ReferencesDrop ReferencePolicy = "drop"
+	// ReferencesInclude adds the referencing tables to the copy set
+	ReferencesInclude ReferencePolicy = "include"
+	// ReferencesDisable disables the foreign keys during the copy
+	ReferencesDisable ReferencePolicy = "disable"
+	// ReferencesAbort stops before anything is changed
+	ReferencesAbort ReferencePolicy = "abort"
+)
+
+func ParseReferencePolicy(policy string) (ReferencePolicy, error) {
+	switch ReferencePolicy(policy) {
+	case ReferencesAsk, ReferencesDrop, ReferencesInclude, ReferencesDisable, ReferencesAbort:
+		return ReferencePolicy(policy), nil
+	case "":
+		return ReferencesAsk, nil
+	}
+
+	return "", fmt.Errorf("unknown reference policy %q", policy)
+}
+
+// externalReferences returns the foreign keys from tables outside the copy set that reference a table in it,
+// the tables of the copy set are named in the target as targets maps them
+func externalReferences(ctx context.Context, db *mssql.MSSQLDB, metadata *copy.Metadata, tables []mssql.TableRef, targets map[string]mssql.TableRef) ([]mssql.ForeingKeyConstraint, error) {
+	targetTables := copy.Options{Targets: targets}.TargetTables(tables)
+	inScope := make(map[string]bool, len(tables))
+	for _, table := range targetTables {
+		inScope[table.String()] = true
+	}
+
+	external := make([]mssql.ForeingKeyConstraint, 0)
+	for i, table := range tables {
+		fks, ok := metadata.ReferencedForeignKeys(table)
+		if !ok {
+			var err error
+			fks, err = db.GetReferencedForeignKeys(ctx, targetTables[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, fk := range fks {
+			if !inScope[mssql.TableRef{Schema: fk.Schema, Table: fk.Table}.String()] {
+				external = append(external, fk)
+			}
+		}
+	}
+
+	return external, nil
+}
+
+func referenceReport(fks []mssql.ForeingKeyConstraint) string {
+	lines := make([]string, len(fks))
+	for i, fk := range fks {
+		lines[i] = fmt.Sprintf("  %s.%s -> %s.%s (%s)", fk.Schema, fk.Table, fk.ReferencedSchema, fk.ReferencedTable, fk.Name)
+	}
+	sort.Strings(lines)
+
+	return "Tables outside the copy set reference tables that will be emptied:\n" + strings.Join(lines, "\n")
+}
+
+// resolveReferences applies the reference policy to the copy set, returning the tables to copy
+// and whether foreign keys should be disabled instead of dropped
+func resolveReferences(ctx context.Context, db *mssql.MSSQLDB, metadata *copy.Metadata, tables []mssql.TableRef, targets map[string]mssql.TableRef, policy ReferencePolicy, ci bool) ([]mssql.TableRef, bool, error) {
+	external, err := externalReferences(ctx, db, metadata, tables, targets)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(external) == 0 {
+		return tables, false, nil
+	}
+
+	if policy == ReferencesAsk {
+		if ci || !term.IsTerminal(int(os.Stdin.Fd())) {
+			return tables, false, nil
+		}
+
+		fmt.Println(referenceReport(external))
+		answer := input("Include the referencing tables (i), disable their foreign keys (d), drop and recreate them (r) or abort (a)? ")
+		switch strings.TrimSpace(answer) {
+		case "i":
+			policy = ReferencesInclude
+		case "d":
+			policy = ReferencesDisable
+		case "r":
+			policy = ReferencesDrop
+		default:
+			policy = ReferencesAbort
+		}
+	}
+
+	switch policy {
+	case ReferencesInclude:
+		// the included tables can be referenced from outside the copy set themselves
+		for len(external) > 0 {
+			for _, fk := range external {
+				table := mssql.TableRef{Schema: fk.Schema, Table: fk.Table}
+				if !containsTable(tables, table) {
+					tables = append(tables, table)
+				}
+			}
+
+			external, err = externalReferences(ctx, db, metadata, tables, targets)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		return tables, false, nil
+	case ReferencesDisable:
+		return tables, true, nil
+	case ReferencesAbort:
+		return nil, false, fmt.Errorf("%s\naborting, nothing has been changed", referenceReport(external))
+	}
+
+	return tables, false, nil
+}
+
+func containsTable(tables []mssql.TableRef, table mssql.TableRef) bool {
+	for _, t := range tables {
+		if t.String() == table.String() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// reportSettings are the settings of a run in its JSON report, the secrets and the settings of the output are left out
+type reportSettings struct {
+	SourceHost         string              `json:"source_host"`
+	SourceDB           string              `json:"source_db"`
+	TargetHost         string              `json:"target_host"`
+	TargetDB           string              `json:"target_db"`
+	SourceProfile      string              `json:"source_profile,omitempty"`
+	TargetProfile      string              `json:"target_profile,omitempty"`
+	Schema             string              `json:"schema"`
+	TableFilter        string              `json:"table_filter,omitempty"`
+	TableRegex         string              `json:"table_regex,omitempty"`
+	ExcludeTables      []string            `json:"exclude_tables,omitempty"`
+	Tables             []string            `json:"tables,omitempty"`
+	TablesFile         string              `json:"tables_file,omitempty"`
+	QueryFilter        string              `json:"query_filter,omitempty"`
+	Mode               copy.Mode           `json:"mode"`
+	Parallel           int                 `json:"parallel"`
+	ConfigFile         string              `json:"config_file,omitempty"`
+	DependencyOrder    bool                `json:"dependency_order"`
+	ConsistentSnapshot bool                `json:"consistent_snapshot"`
+	ReadIsolation      mssql.ReadIsolation `json:"read_isolation,omitempty"`
+	Subset             bool                `json:"subset"`
+	SampleRows         int                 `json:"sample_rows,omitempty"`
+	SamplePercent      float64             `json:"sample_percent,omitempty"`
+	Resume             bool                `json:"resume"`
+	Verify             bool                `json:"verify"`
+	VerifyCounts       bool                `json:"verify_counts"`
+	VerifyChecksums    bool                `json:"verify_checksums"`
+	CommitCount        int                 `json:"commit_count,omitempty"`
+	Partitions         int                 `json:"partitions,omitempty"`
+	Writers            int                 `json:"writers,omitempty"`
+	Retries            int                 `json:"retries"`
+	Timeout            float64             `json:"timeout_seconds,omitempty"`
+	TableTimeout       float64             `json:"table_timeout_seconds,omitempty"`
+	MaxErrors          int                 `json:"max_errors"`
+	DryRun             bool                `json:"dry_run"`
+}
+
+func newReportSettings(opts CopyOptions) reportSettings {
+	return reportSettings{
+		SourceHost:         opts.SourceHost,
+		SourceDB:           opts.SourceDB,
+		TargetHost:         opts.TargetHost,
+		TargetDB:           opts.TargetDB,
+		SourceProfile:      opts.SourceProfile,
+		TargetProfile:      opts.TargetProfile,
+		Schema:             opts.Schema,
+		TableFilter:        opts.TableFilter,
+		TableRegex:         opts.TableRegex,
+		ExcludeTables:      opts.ExcludeTables,
+		Tables:             opts.Tables,
+		TablesFile:         opts.TablesFile,
+		QueryFilter:        opts.QueryFilter,
+		Mode:               opts.Mode,
+		Parallel:           opts.Parrallel,
+		ConfigFile:         opts.ConfigFile,
+		DependencyOrder:    opts.DependencyOrder,
+		ConsistentSnapshot: opts.ConsistentSnapshot,
+		ReadIsolation:      opts.ReadIsolation,
+		Subset:             opts.Subset,
+		SampleRows:         opts.SampleRows,
+		SamplePercent:      opts.SamplePercent,
+		Resume:             opts.Resume,
+		Verify:             opts.Verify,
+		VerifyCounts:       opts.VerifyCounts,
+		VerifyChecksums:    opts.VerifyChecksums,
+		CommitCount:        opts.CommitCount,
+		Partitions:         opts.Partitions,
+		Writers:            opts.Writers,
+		Retries:            opts.Retries,
+		Timeout:            opts.Timeout.Seconds(),
+		TableTimeout:       opts.TableTimeout.Seconds(),
+		MaxErrors:          opts.MaxErrors,
+		DryRun:             opts.DryRun,
+	}
+}
+
+// printReport prints the report of the run after the progress of the text output to w, stdout when nil,
+// and writes it as JSON to the report file and as JUnit XML to the JUnit file
+func printReport(report monitor.Report, opts CopyOptions, w io.Writer) {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	if opts.Output == "" || opts.Output == monitor.OutputText {
+		fmt.Fprintln(w)
+		err := report.WriteText(w)
+		if err != nil {
+			log.Printf("WARNING: failed to print the report, %s", err)
+		}
+	}
+
+	if opts.ReportFile != "" {
+		report.Settings = newReportSettings(opts)
+		writeReportFile(opts.ReportFile, report.WriteJSON)
+	}
+
+	if opts.JUnitFile != "" {
+		suite := fmt.Sprintf("asqlcp %s to %s", opts.SourceDB, opts.TargetDB)
+		writeReportFile(opts.JUnitFile, func(w io.Writer) error {
+			return report.WriteJUnit(w, suite)
+		})
+	}
+}
+
+// writeReportFile writes a report to path with write
+func writeReportFile(path string, write func(w io.Writer) error) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("WARNING: failed to write the report, %s", err)
+		return
+	}
+	defer f.Close()
+
+	err = write(f)
+	if err != nil {
+		log.Printf("WARNING: failed to write the report %s, %s", path, err)
+	}
+}
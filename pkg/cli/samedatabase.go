@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// checkSameDatabase fails when the source and the target connections resolve to the same database, as the copy would empty
+// the tables it reads, unless allow is set to copy between tables of one database
+func checkSameDatabase(source, target mssql.ServerInfo, allow bool) error {
+	if !mssql.SameDatabase(source, target) || allow {
+		return nil
+	}
+
+	if (source.DatabaseGUID == "" || target.DatabaseGUID == "") && (source.ServerName == "" || target.ServerName == "") {
+		return fmt.Errorf("the source and the target may be the same database %s, a server has no name to tell them apart, set --allowSameDatabase to copy anyway",
+			source.Database)
+	}
+
+	return fmt.Errorf("the source and the target are the same database %s on server %s, set --allowSameDatabase to copy into other tables of it with --tableMap",
+		source.Database, source.ServerName)
+}
+
+// checkSelfCopies fails when a table of a copy within one database would be copied into itself, which no flag allows
+func checkSelfCopies(tables []mssql.TableRef, targets map[string]mssql.TableRef) error {
+	targetTables := copy.Options{Targets: targets}.TargetTables(tables)
+	for i, table := range tables {
+		if strings.EqualFold(targetTables[i].String(), table.String()) {
+			return fmt.Errorf("the source and the target are the same database, table %s would be copied into itself", table)
+		}
+	}
+
+	return nil
+}
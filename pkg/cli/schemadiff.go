@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// SchemaDiffOptions selects the tables compared by SchemaDiff
+type SchemaDiffOptions struct {
+	SourceHost  string
+	SourceDB    string
+	TargetHost  string
+	TargetDB    string
+	Schema      string
+	TableFilter string
+	// JSON prints the report as JSON instead of text
+	JSON bool
+	// SourceAuth and TargetAuth are the credentials of the source and the target
+	SourceAuth mssql.Auth
+	TargetAuth mssql.Auth
+}
+
+// SchemaDiff compares the definition of the tables matching the filter in the source and the target,
+// and exits with an error when any of them differs
+func SchemaDiff(opts SchemaDiffOptions) {
+	sDB, err := mssql.ConnectWith(opts.SourceHost, opts.SourceDB, opts.SourceAuth)
+	if err != nil {
+		fatal(ExitConnection, err)
+	}
+	defer sDB.Close()
+
+	tDB, err := mssql.ConnectWith(opts.TargetHost, opts.TargetDB, opts.TargetAuth)
+	if err != nil {
+		fatal(ExitConnection, err)
+	}
+	defer tDB.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	diffs, err := schemaDiff(ctx, sDB, tDB, opts.Schema, opts.TableFilter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if opts.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diffs)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		printSchemaDiff(diffs)
+	}
+
+	for _, diff := range diffs {
+		if !diff.Equal() {
+			os.Exit(1)
+		}
+	}
+}
+
+func schemaDiff(ctx context.Context, sDB, tDB *mssql.MSSQLDB, schema, tableFilter string) ([]mssql.TableDiff, error) {
+	sourceTables, err := sDB.GetTablesFromFilter(ctx, schema, tableFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	targetTables, err := tDB.GetTablesFromFilter(ctx, schema, tableFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	inTarget := make(map[string]bool, len(targetTables))
+	for _, table := range targetTables {
+		inTarget[strings.ToLower(table)] = true
+	}
+
+	diffs := make([]mssql.TableDiff, 0, len(sourceTables))
+	inSource := make(map[string]bool, len(sourceTables))
+	for _, table := range sourceTables {
+		inSource[strings.ToLower(table)] = true
+		ref := mssql.TableRef{Schema: schema, Table: table}
+
+		if !inTarget[strings.ToLower(table)] {
+			diffs = append(diffs, mssql.TableDiff{Table: schema + "." + table, MissingInTarget: true})
+			continue
+		}
+
+		source, err := sDB.GetTableDefinition(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		target, err := tDB.GetTableDefinition(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		diffs = append(diffs, mssql.DiffTableDefinitions(source, target))
+	}
+
+	for _, table := range targetTables {
+		if !inSource[strings.ToLower(table)] {
+			diffs = append(diffs, mssql.TableDiff{Table: schema + "." + table, MissingInSource: true})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Table < diffs[j].Table
+	})
+
+	return diffs, nil
+}
+
+// printSchemaDiff prints the differences per table followed by a summary
+func printSchemaDiff(diffs []mssql.TableDiff) {
+	differing := 0
+	for _, diff := range diffs {
+		switch {
+		case diff.MissingInTarget:
+			fmt.Printf("%s: missing in the target\n", diff.Table)
+		case diff.MissingInSource:
+			fmt.Printf("%s: only in the target\n", diff.Table)
+		case diff.Equal():
+			fmt.Printf("%s: identical\n", diff.Table)
+		default:
+			fmt.Printf("%s:\n", diff.Table)
+		}
+
+		if !diff.Equal() {
+			differing++
+		}
+
+		for _, column := range diff.MissingColumns {
+			fmt.Printf("  column %s is missing in the target\n", column)
+		}
+		for _, column := range diff.ExtraColumns {
+			fmt.Printf("  column %s only exists in the target\n", column)
+		}
+		for _, d := range diff.Differences {
+			fmt.Printf("  column %s %s differs: %s in the source, %s in the target\n", d.Column, d.Attribute, orNone(d.Source), orNone(d.Target))
+		}
+	}
+
+	fmt.Printf("\n%d of %d tables differ\n", differing, len(diffs))
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+
+	return value
+}
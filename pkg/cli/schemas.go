@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// schemaNames returns the schemas of --schema, a comma separated list of names that may contain * wildcards
+func schemaNames(schema string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(schema, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// ListsTables reports whether the tables to copy are listed by Tables or TablesFile instead of selected by Schema and TableFilter
+func (o CopyOptions) ListsTables() bool {
+	return len(o.Tables) > 0 || o.TablesFile != ""
+}
+
+// selectTables returns the tables the options list or select
+func selectTables(ctx context.Context, db *mssql.MSSQLDB, opts CopyOptions) ([]mssql.TableRef, error) {
+	if !opts.ListsTables() {
+		return listTables(ctx, db, opts.Schema, opts.TableFilter, opts.TableRegex, opts.ExcludeTables, opts.IncludeSystemTables)
+	}
+
+	names := opts.Tables
+	if opts.TablesFile != "" {
+		lines, err := readTablesFile(opts.TablesFile)
+		if err != nil {
+			return nil, err
+		}
+		names = append(append([]string{}, names...), lines...)
+	}
+
+	listed, err := parseTableList(names)
+	if err != nil {
+		return nil, err
+	}
+
+	// a listed table is copied even when it is a system table
+	existing, err := db.GetTables(ctx, []string{"*"}, "%", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveTableList(listed, existing)
+}
+
+// readTablesFile returns the table names of a file with a table per line, empty lines and lines starting with # are skipped
+func readTablesFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --tablesFile, %w", err)
+	}
+
+	names := make([]string, 0)
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+
+	return names, nil
+}
+
+// parseTableList parses the schema.table names of the tables to copy, a table listed twice is refused
+func parseTableList(names []string) ([]mssql.TableRef, error) {
+	tables := make([]mssql.TableRef, 0, len(names))
+	listed := make(map[string]bool, len(names))
+	for _, name := range names {
+		table, err := ParseTableName(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		key := strings.ToLower(table.String())
+		if listed[key] {
+			return nil, fmt.Errorf("table %s is listed twice", table)
+		}
+		listed[key] = true
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// resolveTableList returns the listed tables with the names they have in the source, it fails on the tables the source does not have
+func resolveTableList(listed, existing []mssql.TableRef) ([]mssql.TableRef, error) {
+	names := make(map[string]mssql.TableRef, len(existing))
+	for _, table := range existing {
+		names[strings.ToLower(table.String())] = table
+	}
+
+	tables := make([]mssql.TableRef, 0, len(listed))
+	missing := make([]string, 0)
+	for _, table := range listed {
+		name, ok := names[strings.ToLower(table.String())]
+		if !ok {
+			missing = append(missing, table.String())
+			continue
+		}
+		tables = append(tables, name)
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the source has no table %s", strings.Join(missing, ", "))
+	}
+
+	return tables, nil
+}
+
+// listTables returns the tables of the schemas of --schema of which the name is LIKE tableFilter, matches tableRegex when it is set
+// and matches none of the patterns of --excludeTables, ordered by schema and name. System tables are only listed with includeSystem.
+func listTables(ctx context.Context, db *mssql.MSSQLDB, schema, tableFilter, tableRegex string, excludeTables []string, includeSystem bool) ([]mssql.TableRef, error) {
+	exclusions, err := mssql.ParseTableExclusions(excludeTables)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := db.GetTables(ctx, schemaNames(schema), tableFilter, includeSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableRegex != "" {
+		re, err := regexp.Compile(tableRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --tableRegex, %w", err)
+		}
+		tables = slices.DeleteFunc(tables, func(table mssql.TableRef) bool { return !re.MatchString(table.Table) })
+	}
+
+	tables, excluded := exclusions.Apply(tables)
+	if excluded > 0 {
+		log.Printf("%d tables matching --excludeTables are not copied", excluded)
+	}
+
+	return tables, nil
+}
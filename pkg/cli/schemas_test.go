@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaNames(t *testing.T) {
+	assert.Equal(t, []string{"dbo", "sales"}, schemaNames(" dbo, sales,,"))
+	assert.Empty(t, schemaNames(""))
+}
+
+func TestReadTablesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tables.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# the tables of the report\ndbo.Orders\n\n  dbo.Lines  \r\n"), 0o644))
+
+	names, err := readTablesFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dbo.Orders", "dbo.Lines"}, names)
+
+	_, err = readTablesFile(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.ErrorContains(t, err, "failed to read --tablesFile")
+}
+
+func TestParseTableList(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  []mssql.TableRef
+		err   string
+	}{
+		{
+			name:  "tables in the listed order",
+			names: []string{"dbo.Orders", " sales.Lines "},
+			want:  []mssql.TableRef{{Schema: "dbo", Table: "Orders"}, {Schema: "sales", Table: "Lines"}},
+		},
+		{
+			name:  "table without schema",
+			names: []string{"Orders"},
+			err:   `invalid table "Orders", use schema.table`,
+		},
+		{
+			name:  "table listed twice",
+			names: []string{"dbo.Orders", "dbo.Lines", "DBO.orders"},
+			err:   "table [DBO].[orders] is listed twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables, err := parseTableList(tt.names)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tables)
+		})
+	}
+}
+
+func TestResolveTableList(t *testing.T) {
+	existing := []mssql.TableRef{{Schema: "dbo", Table: "Orders"}, {Schema: "dbo", Table: "Lines"}, {Schema: "sales", Table: "Customers"}}
+
+	tests := []struct {
+		name   string
+		listed []mssql.TableRef
+		want   []mssql.TableRef
+		err    string
+	}{
+		{
+			name:   "names of the source in the listed order",
+			listed: []mssql.TableRef{{Schema: "SALES", Table: "customers"}, {Schema: "dbo", Table: "orders"}},
+			want:   []mssql.TableRef{{Schema: "sales", Table: "Customers"}, {Schema: "dbo", Table: "Orders"}},
+		},
+		{
+			name:   "tables missing in the source",
+			listed: []mssql.TableRef{{Schema: "dbo", Table: "Orders"}, {Schema: "dbo", Table: "Invoices"}, {Schema: "sales", Table: "Lines"}},
+			err:    "the source has no table [dbo].[Invoices], [sales].[Lines]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables, err := resolveTableList(tt.listed, existing)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tables)
+		})
+	}
+}
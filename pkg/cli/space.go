@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// checkSpace fails the run before the target is changed when the data of the tables does not fit in the target,
+// a log that may be too small only gets a warning as log backups and checkpoints free it while the copy runs
+func checkSpace(ctx context.Context, engine *copy.Engine, tables []mssql.TableRef) error {
+	estimate, err := engine.EstimateSpace(ctx, tables)
+	if err != nil {
+		log.Printf("WARNING: %s", err)
+		return nil
+	}
+
+	if !estimate.LogFits() {
+		free, _ := estimate.Target.LogFree()
+		log.Printf("WARNING: the largest table loads %s in a single transaction, the log of the target has %s left, set --commitCount to commit in parts",
+			formatBytes(estimate.Transaction), formatBytes(free))
+	}
+
+	if !estimate.Fits() {
+		free, _ := estimate.Target.DataFree()
+		return fmt.Errorf("the copy needs about %s in the target, which has %s left of its maximum size of %s, "+
+			"grow the target database or run with --skipSpaceCheck", formatBytes(estimate.Needed()), formatBytes(free), formatBytes(estimate.Target.DataMax))
+	}
+
+	return nil
+}
+
+// printSpace prints the space the copy needs and the space left in the target
+func printSpace(estimate copy.SpaceEstimate) {
+	status := "OK  "
+	if !estimate.Fits() {
+		status = "FAIL"
+	}
+
+	free, limited := estimate.Target.DataFree()
+	if limited {
+		fmt.Printf("%s copy needs about %s, target has %s left of %s\n", status, formatBytes(estimate.Needed()), formatBytes(free), formatBytes(estimate.Target.DataMax))
+	} else {
+		fmt.Printf("%s copy needs about %s, target grows until its disk is full\n", status, formatBytes(estimate.Needed()))
+	}
+
+	if len(estimate.Unestimated) > 0 {
+		fmt.Printf("     %d tables copy part of their rows and are not estimated\n", len(estimate.Unestimated))
+	}
+}
+
+// formatBytes returns bytes in the largest unit of which there is at least one
+func formatBytes(bytes int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+
+	size := float64(bytes)
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
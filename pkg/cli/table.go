@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// TableCopyOptions selects the single table copied by CopyTable
+type TableCopyOptions struct {
+	SourceHost  string
+	SourceDB    string
+	TargetHost  string
+	TargetDB    string
+	Table       mssql.TableRef
+	QueryFilter string
+	Mode        copy.Mode
+	// CreateTable creates the table in the target when it is missing
+	CreateTable bool
+	// Timeout fails the copy when it takes longer, 0 does not limit it
+	Timeout time.Duration
+	// Target is the table in the target database, the same name as Table when it is empty
+	Target mssql.TableRef
+	// Yes confirms truncating or deleting from the target table without asking
+	Yes bool
+	// SourceAuth and TargetAuth are the credentials of the source and the target
+	SourceAuth mssql.Auth
+	TargetAuth mssql.Auth
+}
+
+// TableLocation is a table argument of the table command, host/database/schema.table
+type TableLocation struct {
+	Host     string
+	Database string
+	// Table is empty when the argument only names the host and database
+	Table mssql.TableRef
+}
+
+// ParseTableLocation parses host/database/schema.table, the table is optional when optionalTable is set
+func ParseTableLocation(location string, optionalTable bool) (TableLocation, error) {
+	parts := strings.Split(location, "/")
+	if len(parts) == 2 && optionalTable && parts[0] != "" && parts[1] != "" {
+		return TableLocation{Host: parts[0], Database: parts[1]}, nil
+	}
+
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return TableLocation{}, fmt.Errorf("invalid table %q, use host/database/schema.table", location)
+	}
+
+	table, err := ParseTableName(parts[2])
+	if err != nil {
+		return TableLocation{}, fmt.Errorf("invalid table %q, use host/database/schema.table", location)
+	}
+
+	return TableLocation{Host: parts[0], Database: parts[1], Table: table}, nil
+}
+
+// ParseTableName parses schema.table
+func ParseTableName(name string) (mssql.TableRef, error) {
+	schema, table, ok := strings.Cut(name, ".")
+	if !ok || schema == "" || table == "" {
+		return mssql.TableRef{}, fmt.Errorf("invalid table %q, use schema.table", name)
+	}
+
+	return mssql.TableRef{Schema: schema, Table: table}, nil
+}
+
+// target returns the name of the table in the target database
+func (o TableCopyOptions) target() mssql.TableRef {
+	if o.Target.Table == "" {
+		return o.Table
+	}
+
+	return o.Target
+}
+
+// CopyTable copies one table without discovering the schema and without the progress monitor,
+// it prints a single line when the table is copied and exits with an error when it fails
+func CopyTable(opts TableCopyOptions) {
+	sDB, err := mssql.ConnectWith(opts.SourceHost, opts.SourceDB, opts.SourceAuth)
+	if err != nil {
+		fatal(ExitConnection, err)
+	}
+	defer sDB.Close()
+
+	tDB, err := mssql.ConnectWith(opts.TargetHost, opts.TargetDB, opts.TargetAuth)
+	if err != nil {
+		fatal(ExitConnection, err)
+	}
+	defer tDB.Close()
+
+	ctx, cancel := withTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	tables := []mssql.TableRef{opts.target()}
+	if mssql.SameDatabase(sDB.ServerInfo(), tDB.ServerInfo()) {
+		err = checkSelfCopies([]mssql.TableRef{opts.Table}, map[string]mssql.TableRef{opts.Table.String(): opts.target()})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = confirmDestructive(opts.TargetHost, opts.TargetDB, opts.Mode, tables, opts.Yes, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lock, err := tDB.LockTables(ctx, tables)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer lock.Release()
+
+	start := time.Now()
+	rows, err := copyTable(ctx, sDB, tDB, opts)
+	if err != nil {
+		log.Fatalf("%s FAILED: %s", opts.Table, err)
+	}
+
+	fmt.Printf("%s: %d rows copied in %s\n", opts.Table, rows, time.Since(start).Round(time.Millisecond))
+}
+
+// copyTable runs the copy of the table and returns the number of rows copied, reading the events instead of the monitor
+func copyTable(ctx context.Context, sDB, tDB *mssql.MSSQLDB, opts TableCopyOptions) (int, error) {
+	eventChan := make(chan monitor.Event, DefaultEventBuffer)
+	done := make(chan struct{})
+
+	rows := 0
+	go func() {
+		defer close(done)
+		for event := range eventChan {
+			if e, ok := event.(monitor.ProgressUpdateEvent); ok {
+				rows += e.RowsCopied
+			}
+		}
+	}()
+
+	engine := copy.NewEngine(sDB, tDB, copy.Options{
+		QueryFilter:  opts.QueryFilter,
+		Mode:         opts.Mode,
+		CreateTables: opts.CreateTable,
+		Targets:      map[string]mssql.TableRef{opts.Table.String(): opts.target()},
+	}, eventChan)
+	err := engine.Run(ctx, []mssql.TableRef{opts.Table}, 1)
+
+	close(eventChan)
+	<-done
+
+	// the failures of the table are returned by Run
+	return rows, err
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/config"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// tableTargets returns the table in the target per source table by TableRef.String(), from the targets in the config file and
+// tableMap, which takes precedence. tableMap maps schema.table in the source to schema.table in the target. Tables copied into
+// a table of the same name are left out, two tables copied into the same table are refused.
+func tableTargets(configFile string, tableMap map[string]string, tables []mssql.TableRef) (map[string]mssql.TableRef, error) {
+	var c *config.Config
+	if configFile != "" {
+		var err error
+		c, err = config.Load(configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	targets := make(map[string]mssql.TableRef)
+	mapped := make(map[string]bool, len(tableMap))
+	sources := make(map[string]mssql.TableRef, len(tables))
+	for _, table := range tables {
+		name := c.Table(table).Target
+		for source, target := range tableMap {
+			if config.MatchesTable(source, table) {
+				name = target
+				mapped[source] = true
+			}
+		}
+
+		target := table
+		if name != "" {
+			var err error
+			target, err = ParseTableName(name)
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %w", table, err)
+			}
+		}
+
+		// SQL Server compares names case insensitively by default
+		key := strings.ToLower(target.String())
+		if other, ok := sources[key]; ok {
+			return nil, fmt.Errorf("tables %s and %s are both copied into %s", other, table, target)
+		}
+		sources[key] = table
+
+		if target != table {
+			targets[table.String()] = target
+		}
+	}
+
+	for source := range tableMap {
+		if !mapped[source] {
+			return nil, fmt.Errorf("--tableMap names table %s, which is not copied", source)
+		}
+	}
+
+	return targets, nil
+}
+
+// tableMapArgs returns tableMap as the value of --tableMap, ordered by source table
+func tableMapArgs(tableMap map[string]string) string {
+	pairs := make([]string, 0, len(tableMap))
+	for source, target := range tableMap {
+		pairs = append(pairs, source+"="+target)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
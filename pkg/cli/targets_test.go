@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableTargets(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "asqlcp.json")
+	require.NoError(t, os.WriteFile(configFile, []byte(`{"tables": {"dbo.Orders": {"target": "staging.Orders"}}}`), 0o644))
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "Orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "Lines"}
+	stagingOrders := mssql.TableRef{Schema: "staging", Table: "Orders"}
+
+	tests := []struct {
+		name       string
+		configFile string
+		tableMap   map[string]string
+		tables     []mssql.TableRef
+		want       map[string]mssql.TableRef
+		err        string
+	}{
+		{
+			name:   "tables of the same name are left out",
+			tables: []mssql.TableRef{orders, lines},
+			want:   map[string]mssql.TableRef{},
+		},
+		{
+			name:       "target from the config file",
+			configFile: configFile,
+			tables:     []mssql.TableRef{orders, lines},
+			want:       map[string]mssql.TableRef{orders.String(): stagingOrders},
+		},
+		{
+			name:       "tableMap takes precedence over the config file",
+			configFile: configFile,
+			tableMap:   map[string]string{"dbo.Orders": "archive.Orders"},
+			tables:     []mssql.TableRef{orders},
+			want:       map[string]mssql.TableRef{orders.String(): {Schema: "archive", Table: "Orders"}},
+		},
+		{
+			name:     "tableMap matches the source table case insensitively",
+			tableMap: map[string]string{"DBO.orders": "staging.Orders", "[dbo].[Lines]": "staging.Lines"},
+			tables:   []mssql.TableRef{orders, lines},
+			want: map[string]mssql.TableRef{
+				orders.String(): stagingOrders,
+				lines.String():  {Schema: "staging", Table: "Lines"},
+			},
+		},
+		{
+			name:     "two tables copied into the same table",
+			tableMap: map[string]string{"dbo.Lines": "dbo.Orders"},
+			tables:   []mssql.TableRef{orders, lines},
+			err:      "tables [dbo].[Orders] and [dbo].[Lines] are both copied into [dbo].[Orders]",
+		},
+		{
+			name:     "two tables copied into tables differing only in case",
+			tableMap: map[string]string{"dbo.Orders": "staging.orders", "dbo.Lines": "STAGING.Orders"},
+			tables:   []mssql.TableRef{orders, lines},
+			err:      "tables [dbo].[Orders] and [dbo].[Lines] are both copied into [STAGING].[Orders]",
+		},
+		{
+			name:     "tableMap entry of a table that is not copied",
+			tableMap: map[string]string{"dbo.Customers": "staging.Customers"},
+			tables:   []mssql.TableRef{orders},
+			err:      "--tableMap names table dbo.Customers, which is not copied",
+		},
+		{
+			name:     "invalid target",
+			tableMap: map[string]string{"dbo.Orders": "Orders"},
+			tables:   []mssql.TableRef{orders},
+			err:      `table [dbo].[Orders]: invalid table "Orders", use schema.table`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := tableTargets(tt.configFile, tt.tableMap, tt.tables)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, targets)
+		})
+	}
+}
+
+func TestTableMapArgs(t *testing.T) {
+	assert.Equal(t, "dbo.Lines=staging.Lines,dbo.Orders=staging.Orders", tableMapArgs(map[string]string{
+		"dbo.Orders": "staging.Orders",
+		"dbo.Lines":  "staging.Lines",
+	}))
+	assert.Equal(t, "", tableMapArgs(nil))
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the span of a run, the spans of the tables and their database operations are its children
+var tracer = otel.Tracer("github.com/jeff-99/mssqlcopy/pkg/cli")
+
+// startTracing exports the spans of the run over OTLP/HTTP when an OTLP endpoint is set with OTEL_EXPORTER_OTLP_ENDPOINT
+// or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, the other OTEL_ variables configure the exporter and the resource as usual.
+// The returned function flushes the spans.
+func startTracing(ctx context.Context) func() {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func() {}
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		log.Printf("WARNING: failed to export traces, %s", err)
+		return func() {}
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName("asqlcp")),
+		// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		log.Printf("WARNING: failed to detect the trace resource, %s", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		// the run context can be cancelled already
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err := provider.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Printf("WARNING: failed to export traces, %s", err)
+		}
+	}
+}
+
+// startRunSpan starts the span of a copy run between the databases of opts
+func startRunSpan(ctx context.Context, opts CopyOptions, tables int) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "copy", trace.WithAttributes(
+		attribute.String("asqlcp.source", opts.SourceHost+"/"+opts.SourceDB),
+		attribute.String("asqlcp.target", opts.TargetHost+"/"+opts.TargetDB),
+		attribute.String("asqlcp.mode", string(opts.Mode)),
+		attribute.Int("asqlcp.tables", tables),
+	))
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+)
+
+// printVerification prints a pass or fail line per table and returns the number of failed tables
+func printVerification(verifications []copy.TableVerification) int {
+	failed := 0
+	for _, v := range verifications {
+		status := "PASS"
+		if !v.Passed() {
+			status = "FAIL"
+			failed++
+		}
+
+		switch {
+		case v.Err != nil:
+			fmt.Printf("%s %s: %s\n", status, v.Table, v.Err)
+		case v.SourceRows != v.TargetRows:
+			fmt.Printf("%s %s: %d source rows, %d target rows\n", status, v.Table, v.SourceRows, v.TargetRows)
+		case v.SourceChecksum != v.TargetChecksum:
+			fmt.Printf("%s %s: %d rows, checksum %d in the source and %d in the target\n", status, v.Table, v.SourceRows, v.SourceChecksum, v.TargetChecksum)
+		default:
+			fmt.Printf("%s %s: %d rows, checksum %d\n", status, v.Table, v.SourceRows, v.SourceChecksum)
+		}
+
+		if len(v.Skipped) > 0 {
+			fmt.Printf("     not checksummed: %s\n", strings.Join(v.Skipped, ", "))
+		}
+	}
+
+	fmt.Printf("\n%d of %d tables verified\n", len(verifications)-failed, len(verifications))
+
+	return failed
+}
+
+// printChecksumVerification prints a pass or fail line per table followed by the rows that differ, and returns the number of failed tables
+func printChecksumVerification(verifications []copy.ChecksumVerification) int {
+	failed := 0
+	for _, v := range verifications {
+		status := "PASS"
+		if !v.Passed() {
+			status = "FAIL"
+			failed++
+		}
+
+		switch {
+		case v.Err != nil:
+			fmt.Printf("%s %s: %s\n", status, v.Table, v.Err)
+		case v.DifferentBuckets > 0:
+			fmt.Printf("%s %s: %d source rows, %d target rows, %d of %d key buckets differ\n", status, v.Table, v.SourceRows, v.TargetRows, v.DifferentBuckets, v.Buckets)
+		default:
+			fmt.Printf("%s %s: %d rows hashed\n", status, v.Table, v.SourceRows)
+		}
+
+		for _, difference := range v.Differences {
+			fmt.Printf("     %s in the target: %s\n", difference.Kind, difference.Key)
+		}
+
+		if len(v.Skipped) > 0 {
+			fmt.Printf("     not hashed: %s\n", strings.Join(v.Skipped, ", "))
+		}
+	}
+
+	fmt.Printf("\n%d of %d tables verified by checksum\n", len(verifications)-failed, len(verifications))
+
+	return failed
+}
+
+// printForeignKeyValidation prints a pass or fail line per foreign key and returns the number of foreign keys that failed validation
+func printForeignKeyValidation(validations []copy.ForeignKeyValidation) int {
+	failed := 0
+	for _, v := range validations {
+		if v.Err != nil {
+			failed++
+			fmt.Printf("FAIL %s on %s referencing %s: %s\n", v.Name, v.Table, v.Parent, v.Err)
+			continue
+		}
+
+		fmt.Printf("PASS %s on %s referencing %s\n", v.Name, v.Table, v.Parent)
+	}
+
+	fmt.Printf("\n%d of %d foreign keys validated\n", len(validations)-failed, len(validations))
+
+	return failed
+}
@@ -4,24 +4,28 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jeff-99/mssqlcopy/pkg/azure"
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
 )
 
-func Wizard(sourceHost, sourceDB, targetHost, targetDB, schema, filter, queryFilter string, parrallel int, ci bool) {
+func Wizard(opts CopyOptions) {
 	var dbs []azure.DatabaseRef
-	if sourceHost == "" || sourceDB == "" || targetHost == "" || targetDB == "" {
+	if opts.SourceHost == "" || opts.SourceDB == "" || opts.TargetHost == "" || opts.TargetDB == "" {
 		fmt.Println("Scanning for databases... (this may take a while)")
 
 		azureClient, err := azure.NewAzureClient()
 		if err != nil {
 			log.Fatal(err)
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
+		ctx, cancel := withTimeout(context.Background(), opts.Timeout)
 		defer cancel()
 
 		dbs, err = azureClient.ListDatabases(ctx)
@@ -40,7 +44,7 @@ func Wizard(sourceHost, sourceDB, targetHost, targetDB, schema, filter, queryFil
 	}
 
 	var sourceDBRef azure.DatabaseRef
-	if sourceHost == "" || sourceDB == "" {
+	if opts.SourceHost == "" || opts.SourceDB == "" {
 		sourceDBIndexStr := input("Select a source database, by entering it's number: ")
 		sourceDBIndex, err := strconv.Atoi(strings.Trim(sourceDBIndexStr, "\n"))
 		if err != nil {
@@ -48,11 +52,11 @@ func Wizard(sourceHost, sourceDB, targetHost, targetDB, schema, filter, queryFil
 		}
 		sourceDBRef = dbs[sourceDBIndex]
 	} else {
-		sourceDBRef = azure.NewDatabaseRef(sourceHost, sourceDB)
+		sourceDBRef = azure.NewDatabaseRef(opts.SourceHost, opts.SourceDB)
 	}
 
 	var targetDBRef azure.DatabaseRef
-	if targetHost == "" || targetDB == "" {
+	if opts.TargetHost == "" || opts.TargetDB == "" {
 
 		targetDBIndexStr := input("Select a target database, by entering it's number: ")
 		targetDBIndex, err := strconv.Atoi(strings.Trim(targetDBIndexStr, "\n"))
@@ -61,19 +65,369 @@ func Wizard(sourceHost, sourceDB, targetHost, targetDB, schema, filter, queryFil
 		}
 		targetDBRef = dbs[targetDBIndex]
 	} else {
-		targetDBRef = azure.NewDatabaseRef(targetHost, targetDB)
+		targetDBRef = azure.NewDatabaseRef(opts.TargetHost, opts.TargetDB)
 	}
 
-	if schema == "" {
-		schema = input("Enter the schema to copy: ")
+	if opts.Schema == "" && !opts.ListsTables() {
+		opts.Schema = input("Enter the schemas to copy, separated by commas, * copies all schemas: ")
 	}
 
-	if filter == "" {
-		filter = input("Enter the filter to apply to the tables (wildcard: %): ")
+	if opts.TableFilter == "" && !opts.ListsTables() {
+		opts.TableFilter = input("Enter the filter to apply to the tables (wildcard: %): ")
 	}
 
-	fmt.Println("COMMAND: azsqlcp --sourceHost", sourceDBRef.ServerName(), "--sourceDB", sourceDBRef.DatabaseName(), "--targetHost", targetDBRef.ServerName(), "--targetDB", targetDBRef.DatabaseName(), "--schema", schema, "--tableFilter", fmt.Sprintf("\"%s\"", filter), "--parrallel ", parrallel)
+	opts.SourceHost, opts.SourceDB = sourceDBRef.ServerName(), sourceDBRef.DatabaseName()
+	opts.TargetHost, opts.TargetDB = targetDBRef.ServerName(), targetDBRef.DatabaseName()
 
-	Copy(sourceDBRef.ServerName(), sourceDBRef.DatabaseName(), targetDBRef.ServerName(), targetDBRef.DatabaseName(), schema, filter, queryFilter, 10, ci)
+	if strings.EqualFold(strings.TrimSpace(input("Run read-only queries against the source or target before copying? [y/N]: ")), "y") {
+		console(opts)
+	}
+
+	fmt.Println("COMMAND:", commandLine(opts))
+	if opts.NotifyWebhook != "" {
+		fmt.Printf("Set %s to the webhook URL before running the command\n", webhookEnv)
+	}
+
+	Copy(opts)
+
+}
+
+// webhookEnv is the environment variable the command printed by the wizard reads the webhook URL from, the URL carries
+// the secret of the webhook and is not printed
+const webhookEnv = "ASQLCP_NOTIFY_WEBHOOK"
+
+// schemaArg returns the value of --schema, quoted when the shell would expand its wildcards
+func schemaArg(schema string) string {
+	if strings.Contains(schema, "*") {
+		return strconv.Quote(schema)
+	}
+
+	return schema
+}
+
+// commandLine returns the non-interactive command equivalent to the options selected in the wizard
+func commandLine(opts CopyOptions) string {
+	args := []string{"azsqlcp"}
+
+	if opts.SourceProfile != "" {
+		args = append(args, "--from", opts.SourceProfile)
+	} else {
+		args = append(args, "--sourceHost", opts.SourceHost, "--sourceDB", opts.SourceDB)
+	}
+	if opts.TargetProfile != "" {
+		args = append(args, "--to", opts.TargetProfile)
+	} else {
+		args = append(args, "--targetHost", opts.TargetHost, "--targetDB", opts.TargetDB)
+	}
+	if opts.ProfilesFile != "" && (opts.SourceProfile != "" || opts.TargetProfile != "") {
+		args = append(args, "--profiles", opts.ProfilesFile)
+	}
+
+	if len(opts.Tables) > 0 {
+		args = append(args, "--tables", strings.Join(opts.Tables, ","))
+	}
+	if opts.TablesFile != "" {
+		args = append(args, "--tablesFile", opts.TablesFile)
+	}
+	if !opts.ListsTables() {
+		args = append(args, "--schema", schemaArg(opts.Schema), "--tableFilter", fmt.Sprintf("\"%s\"", opts.TableFilter))
+	}
+	if opts.TableRegex != "" {
+		args = append(args, "--tableRegex", strconv.Quote(opts.TableRegex))
+	}
+
+	if opts.IncludeSystemTables {
+		args = append(args, "--includeSystemTables")
+	}
+	args = append(args, "--parrallel", strconv.Itoa(opts.Parrallel), "--mode", string(opts.Mode))
+
+	if opts.CI {
+		args = append(args, "--ci")
+		if opts.CIProgressTemplate != "" {
+			args = append(args, "--ciTemplate", strconv.Quote(opts.CIProgressTemplate))
+		}
+		if opts.CISummaryTemplate != "" {
+			args = append(args, "--ciSummaryTemplate", strconv.Quote(opts.CISummaryTemplate))
+		}
+		if opts.CIInterval > 0 {
+			args = append(args, "--ciInterval", opts.CIInterval.String())
+		}
+		if opts.CIPercent > 0 {
+			args = append(args, "--ciPercent", strconv.Itoa(opts.CIPercent))
+		}
+	} else if opts.View != monitor.ViewAuto {
+		args = append(args, "--view", string(opts.View))
+	}
+
+	if opts.Output == monitor.OutputErrors {
+		args = append(args, "--quiet")
+	} else if opts.Output != "" && opts.Output != monitor.OutputText {
+		args = append(args, "--output", string(opts.Output))
+	}
+
+	if opts.OutputFile != "" {
+		args = append(args, "--outputFile", opts.OutputFile)
+	}
+
+	if opts.NoANSI {
+		args = append(args, "--no-ansi")
+	}
+
+	if opts.MetricsAddr != "" {
+		args = append(args, "--metricsAddr", opts.MetricsAddr)
+	}
+
+	if opts.NotifyWebhook != "" {
+		args = append(args, "--notifyWebhook", fmt.Sprintf("\"$%s\"", webhookEnv))
+	}
+
+	if opts.LogFile != "" {
+		args = append(args, "--logFile", opts.LogFile)
+	}
+
+	if opts.Debug {
+		args = append(args, "--debug")
+	} else if opts.LogLevel != slog.LevelInfo {
+		args = append(args, "--logLevel", strings.ToLower(opts.LogLevel.String()))
+	}
+
+	if opts.SchemaCheck != "" && opts.SchemaCheck != copy.SchemaCheckStrict {
+		args = append(args, "--schemaCheck", string(opts.SchemaCheck))
+	}
+
+	for _, pattern := range opts.ExcludeTables {
+		args = append(args, "--excludeTables", strconv.Quote(pattern))
+	}
+	if len(opts.TableMap) > 0 {
+		args = append(args, "--tableMap", tableMapArgs(opts.TableMap))
+	}
+	if len(opts.ExcludeColumns) > 0 {
+		args = append(args, "--excludeColumns", strings.Join(opts.ExcludeColumns, ","))
+	}
+
+	if opts.QueryFilter != "" {
+		args = append(args, "--queryFilter", fmt.Sprintf("\"%s\"", opts.QueryFilter))
+	}
+
+	if opts.Watermark != "" {
+		args = append(args, "--watermarkColumn", opts.Watermark)
+	}
+
+	if opts.ExactCounts {
+		args = append(args, "--exactCounts")
+	}
+
+	if opts.DependencyOrder {
+		args = append(args, "--dependencyOrder")
+	}
+
+	if opts.ConsistentSnapshot {
+		args = append(args, "--consistentSnapshot")
+	}
+
+	if opts.CreateTables {
+		args = append(args, "--createTables")
+	}
+
+	if opts.ReseedIdentity {
+		args = append(args, "--reseedIdentity")
+	}
+
+	if opts.DisableIndexes {
+		args = append(args, "--disableIndexes")
+	}
+
+	if opts.ReadIsolation != "" && opts.ReadIsolation != mssql.ReadCommitted {
+		args = append(args, "--readIsolation", string(opts.ReadIsolation))
+	}
+
+	if opts.Triggers != "" && opts.Triggers != copy.TriggersKeep {
+		args = append(args, "--triggers", string(opts.Triggers))
+	}
+
+	if opts.CommitCount != 0 && opts.CommitCount != mssql.DefaultCommitCount {
+		args = append(args, "--commitCount", strconv.Itoa(opts.CommitCount))
+	}
+
+	if opts.Tablock {
+		args = append(args, "--tablock")
+	}
+
+	if opts.KeepNulls {
+		args = append(args, "--keepNulls")
+	}
+
+	if opts.KeepIdentity {
+		args = append(args, "--keepIdentity")
+	}
+
+	if opts.CheckConstraints {
+		args = append(args, "--checkConstraints")
+	}
+
+	if opts.RowsPerBatch > 0 {
+		args = append(args, "--rowsPerBatch", strconv.Itoa(opts.RowsPerBatch))
+	}
+
+	if opts.Retries > 0 {
+		args = append(args, "--retries", strconv.Itoa(opts.Retries))
+	}
+
+	if opts.RetryDelay > 0 && opts.RetryDelay != time.Second {
+		args = append(args, "--retryDelay", opts.RetryDelay.String())
+	}
+
+	if opts.Timeout > 0 {
+		args = append(args, "--timeout", opts.Timeout.String())
+	}
+
+	if opts.TableTimeout > 0 {
+		args = append(args, "--tableTimeout", opts.TableTimeout.String())
+	}
+
+	if opts.MaxRowsPerSecond > 0 {
+		args = append(args, "--maxRowsPerSecond", strconv.Itoa(opts.MaxRowsPerSecond))
+	}
+
+	if opts.MaxMBPerSecond > 0 {
+		args = append(args, "--maxMBPerSecond", strconv.FormatFloat(opts.MaxMBPerSecond, 'f', -1, 64))
+	}
+
+	if opts.AdaptiveBatches {
+		args = append(args, "--adaptiveBatches")
+	}
+
+	if opts.BufferRows > 0 && opts.BufferRows != copy.DefaultBufferRows {
+		args = append(args, "--bufferRows", strconv.Itoa(opts.BufferRows))
+	}
+
+	if opts.BufferMB > 0 && opts.BufferMB != copy.DefaultBufferBytes/1024/1024 {
+		args = append(args, "--bufferMB", strconv.FormatFloat(opts.BufferMB, 'f', -1, 64))
+	}
+
+	if opts.EventBuffer > 0 && opts.EventBuffer != DefaultEventBuffer {
+		args = append(args, "--eventBuffer", strconv.Itoa(opts.EventBuffer))
+	}
+
+	if opts.ProgressRows > 0 && opts.ProgressRows != copy.DefaultProgressRows {
+		args = append(args, "--progressRows", strconv.Itoa(opts.ProgressRows))
+	}
+
+	if opts.ProgressInterval > 0 && opts.ProgressInterval != copy.DefaultProgressInterval {
+		args = append(args, "--progressInterval", opts.ProgressInterval.String())
+	}
+
+	if opts.MaxUtilization > 0 {
+		args = append(args, "--maxUtilization", strconv.FormatFloat(opts.MaxUtilization, 'f', -1, 64))
+	}
+
+	if opts.PageSize > 0 {
+		args = append(args, "--pageSize", strconv.Itoa(opts.PageSize))
+	}
+
+	if opts.Partitions > 0 {
+		args = append(args, "--partitions", strconv.Itoa(opts.Partitions))
+	}
+
+	if opts.Writers > 1 {
+		args = append(args, "--writers", strconv.Itoa(opts.Writers))
+	}
+
+	if opts.MaxErrors > 0 {
+		args = append(args, "--maxErrors", strconv.Itoa(opts.MaxErrors))
+	}
+
+	if opts.RejectFile != "" {
+		args = append(args, "--rejectFile", opts.RejectFile)
+	}
+
+	if opts.RecoveryFile != "" && opts.RecoveryFile != DefaultRecoveryFile {
+		args = append(args, "--recoveryFile", opts.RecoveryFile)
+	}
+
+	if opts.AuditFile != "" {
+		args = append(args, "--auditFile", opts.AuditFile)
+	}
+
+	if opts.ReportFile != "" {
+		args = append(args, "--reportFile", opts.ReportFile)
+	}
+
+	if opts.JUnitFile != "" {
+		args = append(args, "--junitFile", opts.JUnitFile)
+	}
+
+	if opts.DryRun {
+		args = append(args, "--dry-run")
+	}
+
+	if opts.References != ReferencesAsk {
+		args = append(args, "--references", string(opts.References))
+	}
+
+	if opts.NoLock {
+		args = append(args, "--noLock")
+	}
+
+	if opts.AllowSameDatabase {
+		args = append(args, "--allowSameDatabase")
+	}
+
+	if opts.SkipPermissionCheck {
+		args = append(args, "--skipPermissionCheck")
+	}
+
+	if opts.SkipSpaceCheck {
+		args = append(args, "--skipSpaceCheck")
+	}
+
+	if opts.CheckpointFile != "" {
+		args = append(args, "--checkpointFile", opts.CheckpointFile)
+	}
+
+	if opts.Subset {
+		args = append(args, "--subset")
+	}
+
+	if opts.SubsetChildren {
+		args = append(args, "--subsetChildren")
+	}
+
+	if opts.SampleRows > 0 {
+		args = append(args, "--sampleRows", strconv.Itoa(opts.SampleRows))
+	}
+
+	if opts.SamplePercent > 0 {
+		args = append(args, "--samplePercent", strconv.FormatFloat(opts.SamplePercent, 'f', -1, 64))
+	}
+
+	if opts.Verify {
+		args = append(args, "--verify")
+	}
+
+	if opts.VerifyCounts {
+		args = append(args, "--verifyCounts")
+	}
+
+	if opts.VerifyChecksums {
+		args = append(args, "--verifyChecksums")
+	}
+
+	if opts.ValidateForeignKeys {
+		args = append(args, "--validateForeignKeys")
+	}
+
+	if opts.VerifyOnly {
+		args = append(args, "--verifyOnly")
+	}
+
+	if opts.ConfigFile != "" {
+		args = append(args, "--config", opts.ConfigFile)
+	}
+
+	if opts.Resume {
+		args = append(args, "--resume")
+	}
 
+	return strings.Join(args, " ")
 }
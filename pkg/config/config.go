@@ -0,0 +1,82 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// TableConfig holds the settings of a single table
+type TableConfig struct {
+	// Strategy is the load strategy of the table: bulk, merge, insert-select or insert
+	Strategy string `json:"strategy,omitempty"`
+	// Hints are query hints added to the OPTION clause of the source select, like "RECOMPILE" or "MAXDOP 4"
+	Hints []string `json:"hints,omitempty"`
+	// Partitions splits the copy of the table into this many ranges copied concurrently, overriding --partitions
+	Partitions int `json:"partitions,omitempty"`
+	// PartitionColumn is the NOT NULL column the ranges are taken from, the first primary key column by default
+	PartitionColumn string `json:"partitionColumn,omitempty"`
+	// Writers inserts the rows of the table with this many concurrent bulk copies, overriding --writers
+	Writers int `json:"writers,omitempty"`
+	// Target is the schema.table in the target the table is copied into, the table of the same name by default
+	Target string `json:"target,omitempty"`
+}
+
+// Config is the content of the --config file, tables are keyed by schema.table
+//
+//	{
+//	  "tables": {
+//	    "dbo.Orders": {"strategy": "merge"},
+//	    "dbo.AuditedAccounts": {"strategy": "insert"},
+//	    "dbo.Events": {"hints": ["RECOMPILE", "MAXDOP 4"]},
+//	    "dbo.Measurements": {"partitions": 8, "partitionColumn": "MeasuredAt"},
+//	    "dbo.Transactions": {"writers": 4},
+//	    "dbo.Customers": {"target": "staging.Customers_copy"}
+//	  }
+//	}
+type Config struct {
+	Tables map[string]TableConfig `json:"tables"`
+}
+
+// Load reads the config file at path
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &Config{}
+	decoder := json.NewDecoder(f)
+	// a misspelled setting should not be silently ignored
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(c)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s, %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Table returns the settings of table, table names are matched case insensitively like SQL Server does by default
+func (c *Config) Table(table mssql.TableRef) TableConfig {
+	if c == nil {
+		return TableConfig{}
+	}
+
+	for key, tableConfig := range c.Tables {
+		if MatchesTable(key, table) {
+			return tableConfig
+		}
+	}
+
+	return TableConfig{}
+}
+
+// MatchesTable reports whether key names table as schema.table or [schema].[table], case insensitively
+func MatchesTable(key string, table mssql.TableRef) bool {
+	return strings.EqualFold(key, table.Schema+"."+table.Table) || strings.EqualFold(key, table.String())
+}
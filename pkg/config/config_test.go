@@ -0,0 +1,62 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/config"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asqlcp.json")
+	err := os.WriteFile(path, []byte(`{"tables": {"dbo.Orders": {"strategy": "merge", "hints": ["RECOMPILE"]}, "[sales].[Lines]": {"strategy": "insert", "partitions": 4, "partitionColumn": "LineId"}, "dbo.Transactions": {"writers": 4}}}`), 0o644)
+	assert.NoError(t, err)
+
+	c, err := config.Load(path)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "merge", c.Table(mssql.TableRef{Schema: "dbo", Table: "orders"}).Strategy)
+	assert.Equal(t, "insert", c.Table(mssql.TableRef{Schema: "sales", Table: "Lines"}).Strategy)
+	assert.Equal(t, "", c.Table(mssql.TableRef{Schema: "dbo", Table: "Lines"}).Strategy)
+	assert.Equal(t, []string{"RECOMPILE"}, c.Table(mssql.TableRef{Schema: "dbo", Table: "Orders"}).Hints)
+	assert.Equal(t, 4, c.Table(mssql.TableRef{Schema: "sales", Table: "Lines"}).Partitions)
+	assert.Equal(t, "LineId", c.Table(mssql.TableRef{Schema: "sales", Table: "Lines"}).PartitionColumn)
+	assert.Equal(t, 4, c.Table(mssql.TableRef{Schema: "dbo", Table: "Transactions"}).Writers)
+
+	var missing *config.Config
+	assert.Equal(t, config.TableConfig{}, missing.Table(mssql.TableRef{Schema: "dbo", Table: "Orders"}))
+}
+
+func TestTableTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asqlcp.json")
+	err := os.WriteFile(path, []byte(`{"tables": {"dbo.Orders": {"target": "staging.Orders_copy"}}}`), 0o644)
+	assert.NoError(t, err)
+
+	c, err := config.Load(path)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "staging.Orders_copy", c.Table(mssql.TableRef{Schema: "dbo", Table: "Orders"}).Target)
+	assert.Equal(t, "", c.Table(mssql.TableRef{Schema: "staging", Table: "Orders"}).Target)
+}
+
+func TestMatchesTable(t *testing.T) {
+	table := mssql.TableRef{Schema: "dbo", Table: "Orders"}
+
+	assert.True(t, config.MatchesTable("dbo.Orders", table))
+	assert.True(t, config.MatchesTable("DBO.orders", table))
+	assert.True(t, config.MatchesTable("[dbo].[Orders]", table))
+	assert.False(t, config.MatchesTable("dbo.Lines", table))
+	assert.False(t, config.MatchesTable("Orders", table))
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asqlcp.json")
+	err := os.WriteFile(path, []byte(`{"tables": {"dbo.Orders": {"stratgy": "merge"}}}`), 0o644)
+	assert.NoError(t, err)
+
+	_, err = config.Load(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is a named database with the credentials to connect to it
+type Profile struct {
+	Host     string `yaml:"host"`
+	Database string `yaml:"database"`
+	// Auth is the auth method: default, azcli, managedIdentity, servicePrincipal or password
+	Auth string `yaml:"auth,omitempty"`
+	// User is the client id of a managed identity or service principal, or the SQL login
+	User string `yaml:"user,omitempty"`
+	// PasswordEnv is the environment variable holding the client secret or password
+	PasswordEnv string `yaml:"passwordEnv,omitempty"`
+	// Protected profiles are never copied into, they can only be the source of a run
+	Protected bool `yaml:"protected,omitempty"`
+}
+
+// Credentials returns the credentials of the profile
+func (p Profile) Credentials() (mssql.Auth, error) {
+	method, err := mssql.ParseAuthMethod(p.Auth)
+	if err != nil {
+		return mssql.Auth{}, err
+	}
+
+	return mssql.Auth{Method: method, User: p.User, PasswordEnv: p.PasswordEnv}, nil
+}
+
+// Profiles is the content of the profiles file
+//
+//	profiles:
+//	  prod:
+//	    host: prod.database.windows.net
+//	    database: sales
+//	    auth: azcli
+//	    protected: true
+//	  staging:
+//	    host: staging.database.windows.net
+//	    database: sales
+//	    auth: servicePrincipal
+//	    user: 00000000-0000-0000-0000-000000000000@contoso.onmicrosoft.com
+//	    passwordEnv: STAGING_CLIENT_SECRET
+type Profiles struct {
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// DefaultProfilesPath returns ~/.asqlcp/profiles.yaml
+func DefaultProfilesPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".asqlcp", "profiles.yaml"), nil
+}
+
+// LoadProfiles reads the profiles file at path
+func LoadProfiles(path string) (*Profiles, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Profiles{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	// a misspelled setting should not be silently ignored
+	decoder.KnownFields(true)
+	err = decoder.Decode(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profiles file %s, %w", path, err)
+	}
+
+	for name, profile := range p.Profiles {
+		if profile.Host == "" || profile.Database == "" {
+			return nil, fmt.Errorf("invalid profiles file %s, profile %s needs a host and a database", path, name)
+		}
+		if _, err := profile.Credentials(); err != nil {
+			return nil, fmt.Errorf("invalid profiles file %s, profile %s: %w", path, name, err)
+		}
+	}
+
+	return p, nil
+}
+
+// ProtectedProfile returns the name of the protected profile of the database on host, so a target given by its host and database
+// is refused like the profile itself. Hosts match regardless of case, a tcp: prefix and a port.
+func (p *Profiles) ProtectedProfile(host, database string) (string, bool) {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		profile := p.Profiles[name]
+		if profile.Protected && normalizeHost(profile.Host) == normalizeHost(host) && strings.EqualFold(profile.Database, database) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// normalizeHost strips the protocol, the port and the case of a host, tcp:server.database.windows.net,1433 becomes server.database.windows.net
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimPrefix(host, "tcp:")
+	host, _, _ = strings.Cut(host, ",")
+
+	return strings.TrimSuffix(host, ".")
+}
+
+// Profile returns the profile by name
+func (p *Profiles) Profile(name string) (Profile, error) {
+	profile, ok := p.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(p.Profiles))
+		for name := range p.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("no profile %q, the profiles are %s", name, strings.Join(names, ", "))
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,83 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/config"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.yaml")
+	err := os.WriteFile(path, []byte(`
+profiles:
+  prod:
+    host: prod.database.windows.net
+    database: sales
+    protected: true
+  staging:
+    host: staging.database.windows.net
+    database: sales
+    auth: password
+    user: loader
+    passwordEnv: STAGING_PASSWORD
+`), 0o644)
+	require.NoError(t, err)
+
+	p, err := config.LoadProfiles(path)
+	require.NoError(t, err)
+
+	prod, err := p.Profile("prod")
+	require.NoError(t, err)
+	assert.Equal(t, "prod.database.windows.net", prod.Host)
+	assert.True(t, prod.Protected)
+	auth, err := prod.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, mssql.Auth{Method: mssql.AuthDefault}, auth)
+
+	staging, err := p.Profile("staging")
+	require.NoError(t, err)
+	auth, err = staging.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, mssql.Auth{Method: mssql.AuthPassword, User: "loader", PasswordEnv: "STAGING_PASSWORD"}, auth)
+
+	_, err = p.Profile("test")
+	assert.EqualError(t, err, `no profile "test", the profiles are prod, staging`)
+}
+
+func TestLoadProfilesRejectsInvalidProfiles(t *testing.T) {
+	for _, content := range []string{
+		"profiles:\n  prod:\n    hostname: prod.database.windows.net\n    database: sales\n",
+		"profiles:\n  prod:\n    database: sales\n",
+		"profiles:\n  prod:\n    host: prod.database.windows.net\n    database: sales\n    auth: kerberos\n",
+	} {
+		path := filepath.Join(t.TempDir(), "profiles.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+		_, err := config.LoadProfiles(path)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestProtectedProfile(t *testing.T) {
+	p := &config.Profiles{Profiles: map[string]config.Profile{
+		"prod":    {Host: "prod.database.windows.net", Database: "sales", Protected: true},
+		"staging": {Host: "staging.database.windows.net", Database: "sales"},
+	}}
+
+	name, ok := p.ProtectedProfile("prod.database.windows.net", "sales")
+	assert.True(t, ok)
+	assert.Equal(t, "prod", name)
+
+	_, ok = p.ProtectedProfile("tcp:PROD.database.windows.net,1433", "Sales")
+	assert.True(t, ok)
+
+	_, ok = p.ProtectedProfile("prod.database.windows.net", "sales_copy")
+	assert.False(t, ok)
+	_, ok = p.ProtectedProfile("staging.database.windows.net", "sales")
+	assert.False(t, ok)
+}
@@ -0,0 +1,120 @@
+package copy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+const (
+	// targetCommitLatency is the duration of a commit the adaptive batch size aims for, long commits hold locks
+	// and log space for long and are more likely to be throttled, short ones spend more time on round trips
+	targetCommitLatency = 5 * time.Second
+	// minBatchSize and maxBatchSize bound the adaptive batch size
+	minBatchSize = 1_000
+	maxBatchSize = 500_000
+	// maxPendingBatchSize bounds the adaptive batch size of writers keeping the rows of their batch in memory to insert them again
+	maxPendingBatchSize = 100_000
+)
+
+// batchTuner adapts the number of rows per bulk copy transaction of a table, it is shared by the writers of a partitioned table
+type batchTuner struct {
+	lock sync.Mutex
+	size int
+	// maxSize bounds the growth of the size, maxBatchSize when unset
+	maxSize int
+}
+
+// newBatchTuner returns the tuner of a table copied with opts, the batches of writers keeping their pending rows grow to maxPendingBatchSize
+func newBatchTuner(opts Options) *batchTuner {
+	if opts.keepsPending() {
+		return &batchTuner{maxSize: maxPendingBatchSize}
+	}
+
+	return &batchTuner{maxSize: maxBatchSize}
+}
+
+// start returns the batch size, the first writer sets the initial size
+func (t *batchTuner) start(size int) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.size == 0 {
+		t.size = size
+	}
+
+	return t.size
+}
+
+// commitTook returns the batch size after a batch of rows took the given duration from its first row to its commit, and why it changed.
+// Commits taking more than twice the target latency halve the size, commits of full batches taking less than half of it grow it by half.
+func (t *batchTuner) commitTook(rows int, took time.Duration) (int, string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	switch {
+	case took > 2*targetCommitLatency && t.size > minBatchSize:
+		t.size = max(t.size/2, minBatchSize)
+		return t.size, fmt.Sprintf("a commit of %d rows took %s", rows, took.Round(time.Millisecond))
+	case took < targetCommitLatency/2 && rows >= t.size && t.size < t.limit():
+		t.size = min(t.size*3/2, t.limit())
+		return t.size, fmt.Sprintf("a commit of %d rows took %s", rows, took.Round(time.Millisecond))
+	}
+
+	return t.size, ""
+}
+
+// limit returns the size the batches grow to
+func (t *batchTuner) limit() int {
+	if t.maxSize > 0 {
+		return t.maxSize
+	}
+
+	return maxBatchSize
+}
+
+// failed returns the batch size after a batch failed with a transient error, which halves it
+func (t *batchTuner) failed(err error) (int, string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.size <= minBatchSize {
+		return t.size, ""
+	}
+
+	t.size = max(t.size/2, minBatchSize)
+	return t.size, fmt.Sprintf("a batch failed with %s", err)
+}
+
+// tuneBatches adapts the batch size of bulk to the commit latency, the changes are reported to the monitor
+func (ct *CopyTask) tuneBatches(bulk *mssql.BulkInsert) {
+	bulk.SetCommitCount(ct.tuner.start(bulk.CommitCount()))
+	bulk.OnBatch(func(rows int, took time.Duration) {
+		size, reason := ct.tuner.commitTook(rows, took)
+		ct.resizeBatches(bulk, size, reason)
+	})
+}
+
+// shrinkBatches halves the batch size of writer after a transient error
+func (ct *CopyTask) shrinkBatches(writer rowWriter, err error) {
+	bulk, ok := writer.(*mssql.BulkInsert)
+	if !ok || ct.tuner == nil {
+		return
+	}
+
+	size, reason := ct.tuner.failed(err)
+	ct.resizeBatches(bulk, size, reason)
+}
+
+// resizeBatches applies the batch size to bulk, which can have been changed by another writer of the table, and reports the changes
+func (ct *CopyTask) resizeBatches(bulk *mssql.BulkInsert, size int, reason string) {
+	bulk.SetCommitCount(size)
+	if reason == "" {
+		return
+	}
+
+	ct.eventChan <- monitor.BatchSizeEvent{Table: ct.table, BatchSize: size, Reason: reason}
+}
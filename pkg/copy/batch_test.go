@@ -0,0 +1,60 @@
+package copy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchTuner(t *testing.T) {
+	tuner := &batchTuner{}
+	assert.Equal(t, 50_000, tuner.start(50_000))
+	// the other writers of a partitioned table start at the same size
+	assert.Equal(t, 50_000, tuner.start(10_000))
+
+	size, reason := tuner.commitTook(50_000, 3*time.Second)
+	assert.Equal(t, 50_000, size)
+	assert.Empty(t, reason)
+
+	size, reason = tuner.commitTook(50_000, 12*time.Second)
+	assert.Equal(t, 25_000, size)
+	assert.Equal(t, "a commit of 50000 rows took 12s", reason)
+
+	size, _ = tuner.commitTook(25_000, time.Second)
+	assert.Equal(t, 37_500, size)
+
+	// the last batch of a table is not full
+	size, reason = tuner.commitTook(100, time.Millisecond)
+	assert.Equal(t, 37_500, size)
+	assert.Empty(t, reason)
+
+	size, reason = tuner.failed(errors.New("deadlock"))
+	assert.Equal(t, 18_750, size)
+	assert.Equal(t, "a batch failed with deadlock", reason)
+
+	tuner = &batchTuner{size: minBatchSize}
+	size, reason = tuner.failed(errors.New("deadlock"))
+	assert.Equal(t, minBatchSize, size)
+	assert.Empty(t, reason)
+
+	tuner = &batchTuner{size: maxBatchSize}
+	size, reason = tuner.commitTook(maxBatchSize, time.Second)
+	assert.Equal(t, maxBatchSize, size)
+	assert.Empty(t, reason)
+
+	// the writers keeping the rows of their batch grow to fewer rows
+	tuner = newBatchTuner(Options{Retries: 3})
+	tuner.start(80_000)
+	size, _ = tuner.commitTook(80_000, time.Second)
+	assert.Equal(t, maxPendingBatchSize, size)
+	size, reason = tuner.commitTook(maxPendingBatchSize, time.Second)
+	assert.Equal(t, maxPendingBatchSize, size)
+	assert.Empty(t, reason)
+
+	tuner = newBatchTuner(Options{})
+	tuner.start(80_000)
+	size, _ = tuner.commitTook(80_000, time.Second)
+	assert.Equal(t, 120_000, size)
+}
@@ -0,0 +1,96 @@
+package copy
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// rowBatchSize is the number of rows read from the source and passed to the writer at a time
+	rowBatchSize = 250
+	// DefaultBufferRows is the number of rows buffered between the reader and the writer of a table,
+	// about as many rows as a single batch of the bulk copy protocol holds
+	DefaultBufferRows = 1000
+	// DefaultBufferBytes bounds the approximate size of the rows buffered between the reader and the writer of a table
+	DefaultBufferBytes = 64 << 20
+)
+
+// rowBatch is a batch of rows passed from the reader to the writer of a table, with their approximate size in bytes
+type rowBatch struct {
+	rows [][]interface{}
+	size int64
+}
+
+func newRowBatch(rows [][]interface{}) rowBatch {
+	batch := rowBatch{rows: rows}
+	for _, row := range rows {
+		batch.size += rowSize(row)
+	}
+
+	return batch
+}
+
+// bufferBatches returns the number of batches buffered between a reader and a writer
+func (o Options) bufferBatches() int {
+	rows := o.BufferRows
+	if rows <= 0 {
+		rows = DefaultBufferRows
+	}
+
+	return max(rows/rowBatchSize, 1)
+}
+
+// byteBudget bounds the approximate size of the batches buffered between a reader and a writer, a nil budget is unbounded
+type byteBudget struct {
+	lock  sync.Mutex
+	limit int64
+	used  int64
+	// released is closed when bytes are released
+	released chan struct{}
+}
+
+func newByteBudget(limit int64) *byteBudget {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &byteBudget{limit: limit, released: make(chan struct{})}
+}
+
+// acquire waits until n bytes fit the budget, a batch larger than the budget is let through once nothing else is buffered
+func (b *byteBudget) acquire(ctx context.Context, n int64) error {
+	if b == nil {
+		return nil
+	}
+
+	for {
+		b.lock.Lock()
+		if b.used == 0 || b.used+n <= b.limit {
+			b.used += n
+			b.lock.Unlock()
+			return nil
+		}
+		released := b.released
+		b.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// release returns the bytes of a batch written to the target
+func (b *byteBudget) release(n int64) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.used -= n
+	close(b.released)
+	b.released = make(chan struct{})
+}
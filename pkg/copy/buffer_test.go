@@ -0,0 +1,56 @@
+package copy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteBudget(t *testing.T) {
+	ctx := context.Background()
+	budget := newByteBudget(100)
+
+	assert.NoError(t, budget.acquire(ctx, 60))
+	assert.NoError(t, budget.acquire(ctx, 40))
+
+	// the budget is used up until the writer releases a batch
+	acquired := make(chan error)
+	go func() {
+		acquired <- budget.acquire(ctx, 50)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired more than the budget")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	budget.release(60)
+	select {
+	case err := <-acquired:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("not acquired after the release")
+	}
+
+	// a batch larger than the budget is let through when nothing is buffered
+	budget.release(40)
+	budget.release(50)
+	assert.NoError(t, budget.acquire(ctx, 500))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, budget.acquire(cancelled, 1), context.Canceled)
+
+	var unbounded *byteBudget
+	assert.NoError(t, unbounded.acquire(ctx, 1<<40))
+	unbounded.release(1 << 40)
+}
+
+func TestBufferBatches(t *testing.T) {
+	assert.Equal(t, 4, Options{}.bufferBatches())
+	assert.Equal(t, 1, Options{BufferRows: 10}.bufferBatches())
+	assert.Equal(t, 40, Options{BufferRows: 10_000}.bufferBatches())
+}
@@ -0,0 +1,57 @@
+package copy
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// tableCancels cancels the copies of single tables while the others continue, see Engine.CancelTable
+type tableCancels struct {
+	lock    sync.Mutex
+	running map[string]context.CancelFunc
+	// cancelled are the tables cancelled before they started
+	cancelled map[string]bool
+}
+
+func newTableCancels() *tableCancels {
+	return &tableCancels{running: make(map[string]context.CancelFunc), cancelled: make(map[string]bool)}
+}
+
+// start returns the context of the copy of table, which is done already when the table was cancelled before it started.
+// stop releases the context when the copy returned.
+func (c *tableCancels) start(ctx context.Context, table mssql.TableRef) (context.Context, func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	tableCtx, cancel := context.WithCancel(ctx)
+	if c.cancelled[table.String()] {
+		cancel()
+	}
+	c.running[table.String()] = cancel
+
+	return tableCtx, func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		delete(c.running, table.String())
+		cancel()
+	}
+}
+
+func (c *tableCancels) cancel(table string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.cancelled[table] = true
+	if cancel, ok := c.running[table]; ok {
+		cancel()
+	}
+}
+
+// CancelTable stops the copy of the table named table, as formatted by mssql.TableRef.String, which fails with the cancellation.
+// A table that has not started yet fails as soon as it starts. It is safe to call from any goroutine.
+func (e *Engine) CancelTable(table string) {
+	e.cancels.cancel(table)
+}
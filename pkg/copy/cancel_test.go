@@ -0,0 +1,31 @@
+package copy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancelTable(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	notes := mssql.TableRef{Schema: "dbo", Table: "notes"}
+	e := NewEngine(nil, nil, Options{}, nil)
+
+	ordersCtx, stopOrders := e.cancels.start(context.Background(), orders)
+	defer stopOrders()
+	linesCtx, stopLines := e.cancels.start(context.Background(), lines)
+	defer stopLines()
+
+	e.CancelTable(orders.String())
+	assert.ErrorIs(t, ordersCtx.Err(), context.Canceled)
+	assert.NoError(t, linesCtx.Err())
+
+	// cancelled before it started
+	e.CancelTable(notes.String())
+	notesCtx, stopNotes := e.cancels.start(context.Background(), notes)
+	defer stopNotes()
+	assert.ErrorIs(t, notesCtx.Err(), context.Canceled)
+}
@@ -0,0 +1,210 @@
+package copy
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// TableCheckpoint is the persisted progress of a single table
+type TableCheckpoint struct {
+	Done bool `json:"done"`
+	// Ranges holds the completed key ranges of a table that is copied in parts
+	Ranges map[string]bool `json:"ranges,omitempty"`
+	// Partitions holds the conditions of the ranges a table copied in parts was split into, the keys of Ranges index them
+	Partitions [][]mssql.Condition `json:"partitions,omitempty"`
+	// LastKey holds the primary key values of the last committed row of a table that is not done yet
+	LastKey []string `json:"last_key,omitempty"`
+}
+
+// Checkpoints persists which tables (or key ranges of a table) have been copied completely,
+// so a rerun after a failure only copies what is left.
+type Checkpoints struct {
+	path string
+	lock *sync.Mutex
+
+	Tables map[string]*TableCheckpoint `json:"tables"`
+}
+
+// LoadCheckpoints reads the checkpoint file at path, a missing file results in empty checkpoints
+func LoadCheckpoints(path string) (*Checkpoints, error) {
+	c := &Checkpoints{
+		path:   path,
+		lock:   &sync.Mutex{},
+		Tables: make(map[string]*TableCheckpoint),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Tables == nil {
+		c.Tables = make(map[string]*TableCheckpoint)
+	}
+
+	return c, nil
+}
+
+func (c *Checkpoints) table(table mssql.TableRef) *TableCheckpoint {
+	if _, ok := c.Tables[table.String()]; !ok {
+		c.Tables[table.String()] = &TableCheckpoint{Ranges: make(map[string]bool)}
+	}
+
+	if c.Tables[table.String()].Ranges == nil {
+		c.Tables[table.String()].Ranges = make(map[string]bool)
+	}
+
+	return c.Tables[table.String()]
+}
+
+func (c *Checkpoints) IsTableDone(table mssql.TableRef) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.table(table).Done
+}
+
+func (c *Checkpoints) MarkTableDone(table mssql.TableRef) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.table(table).Done = true
+	c.table(table).LastKey = nil
+	c.table(table).Ranges = nil
+	c.table(table).Partitions = nil
+	return c.save()
+}
+
+// ForgetProgress forgets the committed rows and ranges of a table that is copied from the start
+func (c *Checkpoints) ForgetProgress(table mssql.TableRef) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	progress := c.table(table)
+	if progress.LastKey == nil && len(progress.Ranges) == 0 && progress.Partitions == nil {
+		return nil
+	}
+
+	progress.LastKey = nil
+	progress.Ranges = nil
+	progress.Partitions = nil
+	return c.save()
+}
+
+// LastKey returns the key of the last committed row of table, nil when no rows were committed
+func (c *Checkpoints) LastKey(table mssql.TableRef) []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.table(table).LastKey
+}
+
+// SetLastKey records the key of the last committed row of table, a nil key forgets the progress of the table
+func (c *Checkpoints) SetLastKey(table mssql.TableRef, key []string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.table(table).LastKey = key
+	return c.save()
+}
+
+// Partitions returns the ranges a previous run split table into, nil when it was not copied in parts
+func (c *Checkpoints) Partitions(table mssql.TableRef) [][]mssql.Condition {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.table(table).Partitions
+}
+
+// SetPartitions records the ranges table is split into, the ranges recorded as done before are forgotten
+func (c *Checkpoints) SetPartitions(table mssql.TableRef, partitions [][]mssql.Condition) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.table(table).Partitions = partitions
+	c.table(table).Ranges = make(map[string]bool)
+	return c.save()
+}
+
+func (c *Checkpoints) IsRangeDone(table mssql.TableRef, rangeKey string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.table(table).Ranges[rangeKey]
+}
+
+func (c *Checkpoints) MarkRangeDone(table mssql.TableRef, rangeKey string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.table(table).Ranges[rangeKey] = true
+	return c.save()
+}
+
+// AllDone reports whether every table has been copied completely
+func (c *Checkpoints) AllDone(tables []mssql.TableRef) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, table := range tables {
+		if !c.table(table).Done {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Remove deletes the checkpoint file, so the next run starts from scratch
+func (c *Checkpoints) Remove() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err := os.Remove(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (c *Checkpoints) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so a crash never leaves a truncated checkpoint file behind
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,82 @@
+package copy_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointsPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+
+	checkpoints, err := copy.LoadCheckpoints(path)
+	assert.NoError(t, err)
+	assert.False(t, checkpoints.IsTableDone(orders))
+
+	assert.NoError(t, checkpoints.MarkTableDone(orders))
+	assert.NoError(t, checkpoints.MarkRangeDone(lines, "0"))
+
+	reloaded, err := copy.LoadCheckpoints(path)
+	assert.NoError(t, err)
+	assert.True(t, reloaded.IsTableDone(orders))
+	assert.True(t, reloaded.IsRangeDone(lines, "0"))
+	assert.False(t, reloaded.IsRangeDone(lines, "1"))
+	assert.False(t, reloaded.AllDone([]mssql.TableRef{orders, lines}))
+
+	assert.NoError(t, reloaded.Remove())
+	empty, err := copy.LoadCheckpoints(path)
+	assert.NoError(t, err)
+	assert.False(t, empty.IsTableDone(orders))
+}
+
+func TestCheckpointsLastKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+
+	checkpoints, err := copy.LoadCheckpoints(path)
+	assert.NoError(t, err)
+	assert.Nil(t, checkpoints.LastKey(orders))
+
+	assert.NoError(t, checkpoints.SetLastKey(orders, []string{"42"}))
+	reloaded, err := copy.LoadCheckpoints(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"42"}, reloaded.LastKey(orders))
+
+	assert.NoError(t, reloaded.MarkTableDone(orders))
+	assert.Nil(t, reloaded.LastKey(orders))
+}
+
+func TestCheckpointsPartitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	ranges := mssql.PartitionConditions("id", []string{"100"})
+
+	checkpoints, err := copy.LoadCheckpoints(path)
+	assert.NoError(t, err)
+	assert.NoError(t, checkpoints.SetPartitions(orders, ranges))
+	assert.NoError(t, checkpoints.MarkRangeDone(orders, "1"))
+
+	reloaded, err := copy.LoadCheckpoints(path)
+	assert.NoError(t, err)
+	assert.Equal(t, ranges, reloaded.Partitions(orders))
+	assert.False(t, reloaded.IsRangeDone(orders, "0"))
+	assert.True(t, reloaded.IsRangeDone(orders, "1"))
+
+	// the ranges are split again when the table is copied from the start
+	assert.NoError(t, reloaded.SetPartitions(orders, ranges))
+	assert.False(t, reloaded.IsRangeDone(orders, "1"))
+
+	assert.NoError(t, reloaded.MarkRangeDone(orders, "0"))
+	assert.NoError(t, reloaded.ForgetProgress(orders))
+	assert.Nil(t, reloaded.Partitions(orders))
+	assert.False(t, reloaded.IsRangeDone(orders, "0"))
+
+	assert.NoError(t, reloaded.SetPartitions(orders, ranges))
+	assert.NoError(t, reloaded.MarkTableDone(orders))
+	assert.Nil(t, reloaded.Partitions(orders))
+}
@@ -0,0 +1,287 @@
+package copy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+const (
+	// checksumBucketRows is the number of rows per bucket the rows of a table are hashed in, the rows of a bucket that differs are compared one by one
+	checksumBucketRows = 10000
+	// maxChecksumBuckets bounds the groups of the checksum query of large tables
+	maxChecksumBuckets = 4096
+	// maxRowDifferences is the number of differing rows listed per table
+	maxRowDifferences = 20
+)
+
+const (
+	// RowMissing, RowExtra and RowChanged are the kinds of a RowDifference: a source row missing in the target,
+	// a target row not in the source and a row with other values in the target
+	RowMissing = "missing"
+	RowExtra   = "extra"
+	RowChanged = "changed"
+)
+
+// RowDifference is a row that differs between the source and the target, identified by the values of its primary key
+type RowDifference struct {
+	Key  string
+	Kind string
+	// Source and Target are the values of the columns of a sampled row, in the order of ChecksumVerification.Columns.
+	// They are empty for the side without the row.
+	Source []string
+	Target []string
+
+	bucket int
+}
+
+// ChecksumVerification is the comparison of the hashes of the rows of a table in the source and the target
+type ChecksumVerification struct {
+	Table      mssql.TableRef
+	SourceRows int64
+	TargetRows int64
+	// Buckets is the number of buckets of primary keys the rows were hashed in, DifferentBuckets the number that differ
+	Buckets          int
+	DifferentBuckets int
+	// Differences are the first rows that differ, they are only listed for tables with a primary key
+	Differences []RowDifference
+	// Columns are the hashed columns, Skipped the columns left out of the hashes because their type can not be compared
+	Columns []string
+	Skipped []string
+	Err     error
+}
+
+// Passed reports whether the hashes of all rows are equal in the source and the target
+func (v ChecksumVerification) Passed() bool {
+	return v.Err == nil && v.DifferentBuckets == 0
+}
+
+// DiffOptions limits the rows listed by DiffRows
+type DiffOptions struct {
+	// Limit is the number of differing rows listed per table
+	Limit int
+	// Samples is the number of the listed rows of which the values are read from the source and the target
+	Samples int
+}
+
+// VerifyChecksums compares the SHA-256 hashes of the rows of every table between source and target. Unlike Verify it finds
+// changed rows that keep their BINARY_CHECKSUM and lists the primary keys of the rows that differ.
+func (e *Engine) VerifyChecksums(ctx context.Context, tables []mssql.TableRef) []ChecksumVerification {
+	return e.DiffRows(ctx, tables, DiffOptions{Limit: maxRowDifferences})
+}
+
+// DiffRows compares the SHA-256 hashes of the rows of every table between source and target, and lists the rows that are missing,
+// extra or changed in the target by their primary key. The rows are hashed in buckets of their key, only the rows of buckets
+// that differ are compared one by one.
+func (e *Engine) DiffRows(ctx context.Context, tables []mssql.TableRef, opts DiffOptions) []ChecksumVerification {
+	verifications := make([]ChecksumVerification, len(tables))
+	for i, table := range tables {
+		verifications[i] = e.diffRows(ctx, table, opts)
+	}
+
+	return verifications
+}
+
+func (e *Engine) diffRows(ctx context.Context, table mssql.TableRef, opts DiffOptions) ChecksumVerification {
+	verification := ChecksumVerification{Table: table}
+
+	columns, skipped, err := e.comparableColumns(ctx, table)
+	if err != nil {
+		verification.Err = err
+		return verification
+	}
+	verification.Columns = columns
+	verification.Skipped = skipped
+
+	key, err := e.sourceDB.GetPrimaryKey(ctx, table)
+	if err != nil {
+		verification.Err = fmt.Errorf("failed to get the primary key from the source, %w", err)
+		return verification
+	}
+
+	readOpts := e.opts.filter(table)
+	rows, err := e.sourceDB.GetCount(ctx, table, readOpts)
+	if err != nil {
+		verification.Err = fmt.Errorf("failed to count the source rows, %w", err)
+		return verification
+	}
+	verification.Buckets = checksumBuckets(rows, key)
+
+	source, err := e.sourceDB.GetBucketChecksums(ctx, table, key, columns, verification.Buckets, readOpts)
+	if err != nil {
+		verification.Err = fmt.Errorf("failed to hash the source rows, %w", err)
+		return verification
+	}
+
+	target, err := e.targetDB.GetBucketChecksums(ctx, e.opts.targetTable(table), key, columns, verification.Buckets, readOpts)
+	if err != nil {
+		verification.Err = fmt.Errorf("failed to hash the target rows, %w", err)
+		return verification
+	}
+
+	for _, checksum := range source {
+		verification.SourceRows += checksum.Rows
+	}
+	for _, checksum := range target {
+		verification.TargetRows += checksum.Rows
+	}
+
+	different := differentBuckets(source, target)
+	verification.DifferentBuckets = len(different)
+	if len(key) == 0 || opts.Limit <= 0 {
+		return verification
+	}
+
+	schema, err := e.sourceDB.GetSchemaDefinition(ctx, table)
+	if err != nil {
+		verification.Err = fmt.Errorf("failed to get the schema from the source, %w", err)
+		return verification
+	}
+	keyOf := func(values []interface{}) string {
+		return strings.Join(displayValues(schema, key, values), ", ")
+	}
+
+	for _, bucket := range different {
+		if len(verification.Differences) >= opts.Limit {
+			break
+		}
+
+		sourceHashes, err := e.sourceDB.GetRowHashes(ctx, table, key, columns, verification.Buckets, bucket, readOpts)
+		if err != nil {
+			verification.Err = fmt.Errorf("failed to hash the source rows, %w", err)
+			return verification
+		}
+
+		targetHashes, err := e.targetDB.GetRowHashes(ctx, e.opts.targetTable(table), key, columns, verification.Buckets, bucket, readOpts)
+		if err != nil {
+			verification.Err = fmt.Errorf("failed to hash the target rows, %w", err)
+			return verification
+		}
+
+		verification.Differences = append(verification.Differences, diffRowHashes(sourceHashes, targetHashes, keyOf, bucket)...)
+	}
+	if len(verification.Differences) > opts.Limit {
+		verification.Differences = verification.Differences[:opts.Limit]
+	}
+
+	samples := verification.Differences[:min(opts.Samples, len(verification.Differences))]
+	err = e.sampleDifferences(ctx, table, key, columns, verification.Buckets, readOpts, samples, keyOf, schema)
+	if err != nil {
+		verification.Err = err
+	}
+
+	return verification
+}
+
+// sampleDifferences reads the values of the rows that differ from the source and the target, a bucket at a time
+func (e *Engine) sampleDifferences(ctx context.Context, table mssql.TableRef, key, columns []string, buckets int, readOpts mssql.ReadOptions,
+	differences []RowDifference, keyOf func([]interface{}) string, schema mssql.SchemaDefinition) error {
+	byBucket := make(map[int]map[string]*RowDifference)
+	for i := range differences {
+		d := &differences[i]
+		if byBucket[d.bucket] == nil {
+			byBucket[d.bucket] = make(map[string]*RowDifference)
+		}
+		byBucket[d.bucket][d.Key] = d
+	}
+
+	for bucket, wanted := range byBucket {
+		sourceRows, err := e.sourceDB.GetBucketRows(ctx, table, key, columns, buckets, bucket, readOpts)
+		if err != nil {
+			return fmt.Errorf("failed to read the source rows, %w", err)
+		}
+		for _, row := range sourceRows {
+			if d, ok := wanted[keyOf(row[:len(key)])]; ok {
+				d.Source = displayValues(schema, columns, row[len(key):])
+			}
+		}
+
+		targetRows, err := e.targetDB.GetBucketRows(ctx, e.opts.targetTable(table), key, columns, buckets, bucket, readOpts)
+		if err != nil {
+			return fmt.Errorf("failed to read the target rows, %w", err)
+		}
+		for _, row := range targetRows {
+			if d, ok := wanted[keyOf(row[:len(key)])]; ok {
+				d.Target = displayValues(schema, columns, row[len(key):])
+			}
+		}
+	}
+
+	return nil
+}
+
+// checksumBuckets returns the number of buckets the rows of a table are hashed in, a table without a primary key is hashed as a whole
+func checksumBuckets(rows int, key []string) int {
+	if len(key) == 0 {
+		return 1
+	}
+
+	return min(max(rows/checksumBucketRows, 1), maxChecksumBuckets)
+}
+
+// differentBuckets returns the buckets of which the rows differ between the source and the target, in order
+func differentBuckets(source, target map[int]mssql.BucketChecksum) []int {
+	different := make([]int, 0)
+	for bucket, checksum := range source {
+		if target[bucket] != checksum {
+			different = append(different, bucket)
+		}
+	}
+	for bucket := range target {
+		if _, ok := source[bucket]; !ok {
+			different = append(different, bucket)
+		}
+	}
+	sort.Ints(different)
+
+	return different
+}
+
+// diffRowHashes returns the rows of a bucket that are missing, extra or changed in the target, ordered by key
+func diffRowHashes(source, target []mssql.RowHash, keyOf func([]interface{}) string, bucket int) []RowDifference {
+	targetHashes := make(map[string][]byte, len(target))
+	for _, row := range target {
+		targetHashes[keyOf(row.Key)] = row.Hash
+	}
+
+	differences := make([]RowDifference, 0)
+	inSource := make(map[string]bool, len(source))
+	for _, row := range source {
+		key := keyOf(row.Key)
+		inSource[key] = true
+
+		targetHash, ok := targetHashes[key]
+		switch {
+		case !ok:
+			differences = append(differences, RowDifference{Key: key, Kind: RowMissing, bucket: bucket})
+		case !bytes.Equal(row.Hash, targetHash):
+			differences = append(differences, RowDifference{Key: key, Kind: RowChanged, bucket: bucket})
+		}
+	}
+	for key := range targetHashes {
+		if !inSource[key] {
+			differences = append(differences, RowDifference{Key: key, Kind: RowExtra, bucket: bucket})
+		}
+	}
+	sort.Slice(differences, func(i, j int) bool { return differences[i].Key < differences[j].Key })
+
+	return differences
+}
+
+// displayValues formats the values of the columns as text, NULL as NULL
+func displayValues(schema mssql.SchemaDefinition, columns []string, values []interface{}) []string {
+	display := make([]string, len(columns))
+	for i, column := range columns {
+		if values[i] == nil {
+			display[i] = "NULL"
+			continue
+		}
+		display[i] = csvField(schema[column], plainValue(schema[column], values[i]))
+	}
+
+	return display
+}
@@ -0,0 +1,53 @@
+package copy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksumBuckets(t *testing.T) {
+	assert.Equal(t, 1, checksumBuckets(50000, nil))
+	assert.Equal(t, 1, checksumBuckets(500, []string{"id"}))
+	assert.Equal(t, 5, checksumBuckets(50000, []string{"id"}))
+	assert.Equal(t, maxChecksumBuckets, checksumBuckets(1000000000, []string{"id"}))
+}
+
+func TestDifferentBuckets(t *testing.T) {
+	source := map[int]mssql.BucketChecksum{0: {Rows: 10, Sum: 4, Xor: 7}, 1: {Rows: 12, Sum: 9, Xor: 1}, 3: {Rows: 1, Sum: 2, Xor: 2}}
+	target := map[int]mssql.BucketChecksum{0: {Rows: 10, Sum: 4, Xor: 7}, 1: {Rows: 12, Sum: 9, Xor: 3}, 2: {Rows: 1, Sum: 5, Xor: 5}}
+
+	assert.Equal(t, []int{1, 2, 3}, differentBuckets(source, target))
+	assert.Empty(t, differentBuckets(source, source))
+}
+
+func TestDiffRowHashes(t *testing.T) {
+	schema := mssql.SchemaDefinition{"order_id": {Type: "int"}, "line": {Type: "int"}}
+	keyOf := func(values []interface{}) string {
+		return strings.Join(displayValues(schema, []string{"order_id", "line"}, values), ", ")
+	}
+	source := []mssql.RowHash{{Key: []interface{}{int64(1), int64(1)}, Hash: []byte{1}}, {Key: []interface{}{int64(1), int64(2)}, Hash: []byte{2}}, {Key: []interface{}{int64(2), int64(1)}, Hash: []byte{3}}}
+	target := []mssql.RowHash{{Key: []interface{}{int64(1), int64(1)}, Hash: []byte{1}}, {Key: []interface{}{int64(1), int64(2)}, Hash: []byte{9}}, {Key: []interface{}{int64(3), int64(1)}, Hash: []byte{4}}}
+
+	assert.Equal(t, []RowDifference{
+		{Key: "1, 2", Kind: RowChanged, bucket: 7},
+		{Key: "2, 1", Kind: RowMissing, bucket: 7},
+		{Key: "3, 1", Kind: RowExtra, bucket: 7},
+	}, diffRowHashes(source, target, keyOf, 7))
+}
+
+func TestDisplayValues(t *testing.T) {
+	schema := mssql.SchemaDefinition{
+		"id":      {Type: "uniqueidentifier"},
+		"amount":  {Type: "decimal"},
+		"created": {Type: "date"},
+		"note":    {Type: "nvarchar"},
+	}
+	id := []byte{0x67, 0x45, 0x23, 0x01, 0xab, 0x89, 0xef, 0xcd, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+
+	values := displayValues(schema, []string{"id", "amount", "created", "note"}, []interface{}{id, []byte("12.50"), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil})
+	assert.Equal(t, []string{"01234567-89AB-CDEF-0123-456789ABCDEF", "12.50", "2024-03-01", "NULL"}, values)
+}
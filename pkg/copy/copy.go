@@ -3,54 +3,276 @@ package copy
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jeff-99/mssqlcopy/pkg/monitor"
 	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Mode determines what happens with the existing data in the target table
+type Mode string
+
+const (
+	// ModeTruncate empties the target table before the source rows are inserted
+	ModeTruncate Mode = "truncate"
+	// ModeAppend inserts the source rows on top of the existing target rows
+	ModeAppend Mode = "append"
+	// ModeMerge updates target rows with a matching primary key and inserts the others
+	ModeMerge Mode = "merge"
+	// ModeDelete deletes the target rows matching the query filter before the source rows are inserted
+	ModeDelete Mode = "delete"
+	// ModeIncremental only inserts the source rows with a watermark value above the highest one in the target
+	ModeIncremental Mode = "incremental"
+	// ModeSync applies the changes recorded by SQL Server Change Tracking since the previous sync
+	ModeSync Mode = "sync"
+)
+
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(mode) {
+	case ModeTruncate, ModeAppend, ModeMerge, ModeDelete, ModeIncremental, ModeSync:
+		return Mode(mode), nil
+	case "":
+		return ModeTruncate, nil
+	}
+
+	return "", fmt.Errorf("unknown copy mode %q", mode)
+}
+
+type Options struct {
+	QueryFilter string
+	Mode        Mode
+	// WatermarkColumn is a monotonically increasing column used by ModeIncremental
+	WatermarkColumn string
+	Transformers    []RowTransformer
+	// DependencyOrder copies parents before the tables referencing them, so truncating does not require dropping foreign keys
+	DependencyOrder bool
+	// DisableForeignKeys disables the foreign keys referencing a table and empties it with DELETE, instead of dropping them and truncating
+	DisableForeignKeys bool
+	// CreateTables creates the tables missing in the target from their definition in the source before copying
+	CreateTables bool
+	// ReseedIdentity sets the identity of the target table to the current identity value of the source table after the copy
+	ReseedIdentity bool
+	// ExactCounts uses COUNT(*) for progress reporting instead of the table metadata when copying whole tables
+	ExactCounts bool
+	// Checkpoints, when set, skips the tables completed by a previous run and records the ones completed by this run
+	Checkpoints *Checkpoints
+	// SampleRows copies at most this many rows per table
+	SampleRows int
+	// SamplePercent copies a random sample of about this percentage of every table
+	SamplePercent float64
+	// Subset holds the predicate per table of a referentially consistent subset, which replaces the query filter, see PlanSubset
+	Subset map[string]string
+	// Strategies selects the load strategy per table by TableRef.String(), tables without one are bulk copied
+	Strategies map[string]Strategy
+	// ExcludeColumns holds the columns left out of the copy per table by TableRef.String(),
+	// the columns under the empty key are left out of every table
+	ExcludeColumns map[string][]string
+	// Hints holds the query hints of the source select per table by TableRef.String()
+	Hints map[string][]string
+	// Resume records the key of the last committed row in the checkpoints and continues partially copied tables after it,
+	// this requires a primary key and transformers that leave the key columns untouched
+	Resume bool
+	// SchemaCheck determines how the target schema has to match the source, the default is strict
+	SchemaCheck SchemaCheck
+	// VerifyCounts fails a table in the truncate or delete mode when the target has another number of rows than were copied, see verifyCount
+	VerifyCounts bool
+	// Metadata holds the row counts and foreign keys loaded up front by Prefetch, tables missing from it are looked up by their task
+	Metadata *Metadata
+	// DisableIndexes disables the non-unique nonclustered indexes of the target tables during the load and rebuilds them afterwards
+	DisableIndexes bool
+	// Triggers determines how the triggers of the target tables are treated, the default keeps them
+	Triggers TriggerMode
+	// Bulk tunes the bulk copies into the target tables, FireTriggers is set by the fire trigger mode. KeepIdentity applies to the
+	// insert and insert-select strategies as well, with it the triggers of bulk copied tables with an identity column are disabled
+	// during the load in the keep trigger mode, see loadDisablesTriggers
+	Bulk mssql.BulkOptions
+	// MaxErrors is the number of rows per table the target may reject before the table fails, the rejected rows are skipped
+	MaxErrors int
+	// Rejects records the rejected rows when MaxErrors is set
+	Rejects *Rejects
+	// RecoveryFile receives the statements restoring the foreign keys, indexes and triggers of a table before they are changed,
+	// they are removed again once restored, see writeRecovery
+	RecoveryFile string
+	// Retries is the number of times a batch failing with a transient error, like a deadlock or throttling, is inserted again,
+	// and the number of times an operation on the target failing with a deadlock or lock timeout runs again
+	Retries int
+	// RetryDelay is the wait before the first retry, it doubles with every retry
+	RetryDelay time.Duration
+	// TableTimeout fails the copy of a table that takes longer, 0 does not limit it
+	TableTimeout time.Duration
+	// Targets holds the table in the target per source table by TableRef.String(), tables without one have the same name in the target
+	Targets map[string]mssql.TableRef
+	// Partitions splits the copy of tables by TableRef.String() into ranges copied concurrently
+	Partitions map[string]Partitioning
+	// ReadIsolation determines how the reads of the source rows interact with concurrent writes, see mssql.ReadIsolation
+	ReadIsolation mssql.ReadIsolation
+	// PageSize reads the source tables in pages of this many rows ordered by their primary key instead of a single select,
+	// tables without a usable primary key are read with a single select
+	PageSize int
+	// Writers inserts the rows of tables by TableRef.String() with this many concurrent bulk copies, each with its own connection
+	// and transaction
+	Writers map[string]int
+	// Limiter limits the rows and bytes per second inserted by all tables
+	Limiter *Limiter
+	// BufferRows is the number of rows buffered between the reader and the writer of a table, DefaultBufferRows by default
+	BufferRows int
+	// BufferBytes bounds the approximate size of the rows buffered between the reader and the writer of a table,
+	// so wide rows do not fill the memory when the target is slower than the source. Zero is unbounded.
+	BufferBytes int64
+	// AdaptiveBatches grows and shrinks the number of rows per bulk copy transaction of every table by the duration of its batches
+	// and halves it after transient errors, starting from Bulk.CommitCount
+	AdaptiveBatches bool
+	// ProgressRows and ProgressInterval set how often the progress of a table is reported to the monitor, every this many rows
+	// or this long, whichever comes first. DefaultProgressRows and DefaultProgressInterval by default.
+	ProgressRows     int
+	ProgressInterval time.Duration
+}
+
+// targetTable returns the table in the target the source table is copied into
+func (o Options) targetTable(table mssql.TableRef) mssql.TableRef {
+	if target, ok := o.Targets[table.String()]; ok {
+		return target
+	}
+
+	return table
+}
+
+// TargetTables returns the tables in the target the source tables are copied into, in the same order
+func (o Options) TargetTables(tables []mssql.TableRef) []mssql.TableRef {
+	targets := make([]mssql.TableRef, len(tables))
+	for i, table := range tables {
+		targets[i] = o.targetTable(table)
+	}
+
+	return targets
+}
+
+// filter returns the read options selecting the rows of table, the subset predicate of the table replaces the query filter
+func (o Options) filter(table mssql.TableRef) mssql.ReadOptions {
+	if predicate, ok := o.Subset[table.String()]; ok {
+		return mssql.ReadOptions{Predicate: predicate, Hints: o.Hints[table.String()], Isolation: o.ReadIsolation}
+	}
+
+	return mssql.ReadOptions{QueryFilter: o.QueryFilter, Hints: o.Hints[table.String()], Isolation: o.ReadIsolation}
+}
+
 type CopyTask struct {
 	table mssql.TableRef
+	// target is the table loaded in the target, the same as table unless Targets maps it to another one
+	target mssql.TableRef
 
 	wg       *sync.WaitGroup
 	sourceDB *mssql.MSSQLDB
 	targetDB *mssql.MSSQLDB
 
-	queryFilter string
+	opts Options
 
 	eventChan chan<- monitor.Event
 
 	isRunning bool
-	errs      []error
+	// errs are the failures of the table, appended by fail from the readers and writers of the table
+	errs    []error
+	errLock sync.Mutex
+
+	// change tracking versions used by ModeSync
+	lastSyncVersion int64
+	syncVersion     int64
+	recordSync      bool
+
+	strategy Strategy
+
+	// resumeKey is the primary key used to record the progress of the table, resumeAfter the key of the last committed row
+	resumeKey   []string
+	resumeAfter []string
+	// resumeRanges records the ranges of a partitioned table in the checkpoints, so a resumed run skips the ranges that committed
+	resumeRanges bool
+
+	// pageKey is the primary key the source table is read in pages by
+	pageKey []string
+
+	// tuner adapts the batch size of the bulk copies when AdaptiveBatches is set
+	tuner *batchTuner
+
+	// progress coalesces the rows copied into progress events
+	progress *progress
+
+	// span traces the copy of the table from Run until Wait returns
+	span trace.Span
+
+	// rejected counts the rows the target rejected, guarded by rejectLock as the ranges of a partitioned table are loaded concurrently
+	rejected   int
+	rejectLock sync.Mutex
+	// skipped counts the rows skipped by the transformers
+	skipped atomic.Int64
+	// sourceRows is the exact number of source rows to copy when count took it, verifyCount counts them otherwise
+	sourceRows    int
+	sourceCounted bool
+
+	// disabledIndexes are rebuilt and disabledTriggers enabled when the table is loaded
+	disabledIndexes  []mssql.Index
+	disabledTriggers []mssql.Trigger
+	// disablesTriggers is set when the triggers of the target are disabled during the load, see loadDisablesTriggers
+	disablesTriggers bool
+	// recovery holds the sections of the recovery file written for the table, by what they restore
+	recovery map[string]string
+	// droppedForeignKeys are the foreign keys dropped or disabled by prepareTarget until they are restored, see restoreOnFailure
+	droppedForeignKeys []mssql.ForeingKeyConstraint
 }
 
-func NewCopyTask(table mssql.TableRef, sourceDB *mssql.MSSQLDB, targetDB *mssql.MSSQLDB, queryFilter string, eventChan chan<- monitor.Event) *CopyTask {
+func NewCopyTask(table mssql.TableRef, sourceDB *mssql.MSSQLDB, targetDB *mssql.MSSQLDB, opts Options, eventChan chan<- monitor.Event) *CopyTask {
 	wg := sync.WaitGroup{}
 	wg.Add(2)
 
+	strategy := opts.Strategies[table.String()]
+	switch {
+	case strategy == StrategyMerge:
+		opts.Mode = ModeMerge
+	case opts.Mode == ModeMerge:
+		// the cli refuses other strategies of the config file in merge mode, the tables without one default to bulk
+		strategy = StrategyMerge
+	case strategy == "":
+		strategy = StrategyBulk
+	}
+
+	var tuner *batchTuner
+	if opts.AdaptiveBatches {
+		tuner = newBatchTuner(opts)
+	}
+
 	return &CopyTask{
-		table: table,
+		table:  table,
+		target: opts.targetTable(table),
 
 		sourceDB: sourceDB,
 		targetDB: targetDB,
 
-		queryFilter: queryFilter,
+		opts:     opts,
+		strategy: strategy,
+		tuner:    tuner,
+		progress: newProgress(table, opts, eventChan),
 
 		eventChan: eventChan,
 
 		isRunning: false,
 		wg:        &wg,
-
-		errs: make([]error, 0),
 	}
 
 }
 
+// Wait waits for the copy of the table and returns a *TableError with its failures, or nil when the table was copied
 func (ct *CopyTask) Wait() error {
 	ct.wg.Wait()
+	ct.endSpan()
+
+	ct.errLock.Lock()
+	defer ct.errLock.Unlock()
 
 	if len(ct.errs) > 0 {
-		return fmt.Errorf("Errors encountered: %v", ct.errs)
+		return &TableError{Table: ct.table, Errs: slices.Clone(ct.errs)}
 	}
 
 	return nil
@@ -58,169 +280,320 @@ func (ct *CopyTask) Wait() error {
 
 
 func (ct *CopyTask) Run(ctx context.Context) error {
-	dataChan := make(chan []interface{}, 1000)
+	ctx = ct.startSpan(ctx)
+	dataChan := make(chan rowBatch, ct.opts.bufferBatches())
+	budget := newByteBudget(ct.opts.BufferBytes)
 
 	ct.eventChan <- monitor.CopyTaskStartedEvent{Table: ct.table}
 
-	targetSchema, err := ct.targetDB.GetSchemaDefinition(ctx, ct.table)
+	if ct.opts.Checkpoints != nil && ct.opts.Checkpoints.IsTableDone(ct.table) {
+		ct.eventChan <- monitor.CopyTaskFinishedEvent{Table: ct.table}
+		ct.wg.Done()
+		ct.wg.Done()
+		return nil
+	}
+
+	targetSchema, err := ct.targetDB.GetSchemaDefinition(ctx, ct.target)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to get schema for table %s from the targetDB, %w", ct.table, err))
+		ct.wg.Done()
+		ct.wg.Done()
+		return err
+	}
+
+	if len(targetSchema) == 0 {
+		err = fmt.Errorf("table %s does not exist in the target", ct.table)
+		ct.fail(err)
+		ct.wg.Done()
+		ct.wg.Done()
+		return err
+	}
+
+	sourceSchema, err := ct.sourceDB.GetSchemaDefinition(ctx, ct.table)
 	if err != nil {
-		ct.eventChan <- monitor.ErrorEvent{
-			Table: ct.table,
-			Err:   fmt.Errorf("Failed to get schema for table %s from the targetDB", ct.table),
+		ct.fail(fmt.Errorf("Failed to get schema for table %s from the sourceDB, %w", ct.table, err))
+		ct.wg.Done()
+		ct.wg.Done()
+		return err
+	}
+
+	targetColumns, err := ct.opts.copyColumns(ct.table, sourceSchema, targetSchema)
+	if err != nil {
+		err = fmt.Errorf("Schema check failed on table %s, %w", ct.table, err)
+		ct.fail(err)
+		ct.wg.Done()
+		ct.wg.Done()
+		return err
+	}
+
+	ct.disablesTriggers = ct.loadDisablesTriggers(targetSchema, targetColumns)
+
+	if ct.opts.Mode == ModeSync {
+		fullCopy, err := ct.prepareSync(ctx)
+		if err != nil {
+			ct.fail(fmt.Errorf("Failed to determine the change tracking version of table %s, %w", ct.table, err))
+			ct.wg.Done()
+			ct.wg.Done()
+			return err
+		}
+
+		if !fullCopy {
+			go ct.applyChanges(ctx, targetColumns)
+			return nil
+		}
+
+		// the whole table is copied, after which the recorded version is the starting point of the next sync
+		ct.opts.Mode = ModeTruncate
+		ct.recordSync = true
+	}
+
+	if ct.opts.Checkpoints != nil {
+		err = ct.prepareResume(ctx, targetSchema)
+		if err != nil {
+			ct.fail(fmt.Errorf("Failed to prepare the checkpoint of table %s, %w", ct.table, err))
+			ct.wg.Done()
+			ct.wg.Done()
+			return err
 		}
+	}
+
+	if ct.strategy == StrategyInsertSelect {
+		go ct.insertSelect(ctx, targetColumns)
+		return nil
+	}
+
+	err = ct.preparePaging(ctx, sourceSchema, targetColumns)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to get the primary key to page source table %s by, %w", ct.table, err))
 		ct.wg.Done()
 		ct.wg.Done()
 		return err
 	}
 
-	targetColumns := make([]string, 0, len(targetSchema))
-	for column := range targetSchema {
-		targetColumns = append(targetColumns, column)
+	if ct.partitioned() {
+		go ct.copyPartitioned(ctx, targetColumns, targetSchema)
+		return nil
 	}
 
+	if ct.fannedOut() {
+		go ct.copyFannedOut(ctx, targetColumns, targetSchema)
+		return nil
+	}
+
+	// the writer stops the reader when it fails, so the reader does not wait for it forever with the rows of the source open
+	readCtx, stopReading := context.WithCancel(ctx)
+
 	go func() {
 		defer close(dataChan)
 		defer ct.wg.Done()
-		sourceSchema, err := ct.sourceDB.GetSchemaDefinition(ctx, ct.table)
+		readOpts, err := ct.readOptions(ctx)
 		if err != nil {
-			_ = append(ct.errs, err)
-			ct.eventChan <- monitor.ErrorEvent{
-				Table: ct.table,
-				Err:   fmt.Errorf("Failed to get schema for table %s from the sourceDB", ct.table),
-			}
-			return
-		}
-
-		if !compareSchemas(sourceSchema, targetSchema) {
-			_ = append(ct.errs, err)
-			ct.eventChan <- monitor.ErrorEvent{
-				Table: ct.table,
-				Err:   fmt.Errorf("Schema mismatch detected between Source and Target DBs on table %s", ct.table),
-			}
-
+			ct.fail(fmt.Errorf("Failed to get the watermark for table %s from the targetDB, %w", ct.table, err))
 			return
 		}
 
-		numberOfRows, err := ct.sourceDB.GetCount(ctx, ct.table, ct.queryFilter)
+		numberOfRows, approximate, err := ct.count(ctx, readOpts)
 		if err != nil {
-			_ = append(ct.errs, err)
-			ct.eventChan <- monitor.ErrorEvent{
-				Table: ct.table,
-				Err:   fmt.Errorf("Failed to get count for table %s from the sourceDB", ct.table),
-			}
+			ct.fail(fmt.Errorf("Failed to get count for table %s from the sourceDB, %w", ct.table, err))
 			return
 		}
-		ct.eventChan <- monitor.CountUpdateEvent{TotalRows: numberOfRows, Table: ct.table}
+		ct.eventChan <- monitor.CountUpdateEvent{TotalRows: numberOfRows, Table: ct.table, Approximate: approximate}
 
-		rows, err := ct.sourceDB.SelectFrom(ctx, ct.table, targetColumns, ct.queryFilter)
+		rows, err := ct.sourceDB.SelectFrom(ctx, ct.table, targetColumns, readOpts)
 		if err != nil {
-			_ = append(ct.errs, err)
-			ct.eventChan <- monitor.ErrorEvent{
-				Table: ct.table,
-				Err:   fmt.Errorf("Failed to select data from source table %s, %s", ct.table, err),
-			}
+			ct.fail(fmt.Errorf("Failed to select data from source table %s, %w", ct.table, err))
 			return
 		}
+		// unread rows hold the connection, which the other tables of a consistent snapshot share
+		defer rows.Close()
 
 		for {
-			values, err := rows.Next()
+			batch, err := rows.NextBatch(rowBatchSize)
 			if err != nil {
-				_ = append(ct.errs, err)
-				ct.eventChan <- monitor.ErrorEvent{
-					Table: ct.table,
-					Err:   fmt.Errorf("Failed to get the Next row from the source table %s", ct.table),
-				}
+				ct.fail(fmt.Errorf("Failed to get the Next row from the source table %s, %w", ct.table, err))
+				return
 			}
 
-			if len(values) == 0 {
+			if len(batch) == 0 {
 				break
 			}
 
-			dataChan <- values
-		}
-	}()
-
-	go func() {
-		defer ct.wg.Done()
+			transformed := batch[:0]
+			for _, values := range batch {
+				values, skip, err := ct.transform(targetColumns, values)
+				if err != nil {
+					ct.fail(fmt.Errorf("Failed to transform a row from the source table %s, %w", ct.table, err))
+					return
+				}
 
-		bulkInsert, err := ct.targetDB.BulkInsert(ctx, ct.table, targetColumns)
+				if skip {
+					ct.skipped.Add(1)
+					continue
+				}
+				transformed = append(transformed, values)
+			}
 
-		i := 0
-		var fks []mssql.ForeingKeyConstraint
-		for row := range dataChan {
-			if i == 0 {
-				// only drop and recreate foreign keys if we are inserting data
-				fks, err = ct.targetDB.GetReferencedForeignKeys(ctx, ct.table)
+			if len(transformed) > 0 {
+				batch := newRowBatch(transformed)
+				// waits for the writer when the buffered rows are too large
+				err = budget.acquire(readCtx, batch.size)
 				if err != nil {
-					_ = append(ct.errs, err)
-					ct.eventChan <- monitor.ErrorEvent{
-						Table: ct.table,
-						Err:   fmt.Errorf("Failed to get foreign keys for table %s from the targetDB", ct.table),
+					// otherwise the writer failed
+					if ctx.Err() != nil {
+						ct.fail(err)
 					}
 					return
 				}
 
-				err = ct.targetDB.DropReferencedForeignKeys(ctx, ct.table)
-				if err != nil {
-					_ = append(ct.errs, err)
-					ct.eventChan <- monitor.ErrorEvent{
-						Table: ct.table,
-						Err:   fmt.Errorf("Failed to drop foreign keys for table %s from the targetDB", ct.table),
-					}
+				select {
+				case dataChan <- batch:
+				case <-readCtx.Done():
 					return
 				}
+			}
+		}
+	}()
 
-				err = ct.targetDB.EmptyTable(ctx, ct.table)
-				if err != nil {
-					_ = append(ct.errs, err)
-					ct.eventChan <- monitor.ErrorEvent{
-						Table: ct.table,
-						Err:   fmt.Errorf("Failed to empty target table %s", ct.table),
-					}
+	go func() {
+		defer ct.wg.Done()
+		defer ct.restoreOnFailure()
+		defer stopReading()
+
+		insertTable := ct.target
+		var primaryKey []string
+		var err error
+		if ct.opts.Mode == ModeMerge {
+			primaryKey, err = ct.targetDB.GetPrimaryKey(ctx, ct.target)
+			if err != nil || len(primaryKey) == 0 {
+				ct.fail(fmt.Errorf("Failed to get a primary key to merge on for target table %s", ct.table))
+				return
+			}
 
+			insertTable, err = ct.targetDB.CreateStagingTable(ctx, ct.target)
+			if err != nil {
+				ct.fail(fmt.Errorf("Failed to create a staging table for target table %s, %w", ct.table, err))
+				return
+			}
+			defer ct.targetDB.DropTable(context.Background(), insertTable)
+		}
+
+		writer, err := ct.rowWriter(ctx, insertTable, targetColumns, targetSchema)
+		if err != nil {
+			ct.fail(fmt.Errorf("Failed to prepare the inserts into target table %s, %w", ct.table, err))
+			return
+		}
+
+		// the target of a resumed table is not prepared again, the foreign keys dropped by the run it continues are restored when it is loaded
+		if len(ct.resumeAfter) > 0 {
+			err = ct.resumeForeignKeys(ctx)
+			if err != nil {
+				ct.fail(err)
+				return
+			}
+		}
+
+		if ct.disablesTriggers {
+			err = ct.disableTriggers(ctx)
+			if err != nil {
+				ct.fail(err)
+				return
+			}
+		}
+
+		// merges load a staging table, the merge itself uses the indexes of the target
+		if ct.opts.DisableIndexes && insertTable == ct.target {
+			err = ct.disableIndexes(ctx)
+			if err != nil {
+				ct.fail(err)
+				return
+			}
+		}
+
+		var lastKey []string
+		if len(ct.resumeKey) > 0 {
+			writer.OnCommit(func() error {
+				return ct.opts.Checkpoints.SetLastKey(ct.table, lastKey)
+			})
+		}
+
+		i := 0
+		for batch := range dataChan {
+			// a resumed table already holds the rows up to the last checkpoint
+			if i == 0 && len(ct.resumeAfter) == 0 && (ct.opts.Mode == ModeTruncate || ct.opts.Mode == ModeDelete) {
+				// only drop and recreate foreign keys if we are inserting data
+				err = ct.prepareTarget(ctx)
+				if err != nil {
+					ct.fail(err)
 					return
 				}
 			}
 
-			i++
+			i += len(batch.rows)
 
-			err := bulkInsert.Insert(ctx, row)
+			err = ct.throttle(ctx, len(batch.rows), batch.size)
 			if err != nil {
-				bulkInsert.Rollback(ctx)
-				_ = append(ct.errs, err)
-				ct.eventChan <- monitor.ErrorEvent{
-					Table: ct.table,
-					Err:   fmt.Errorf("Failed to insert row into the target table %s, %s", ct.table, err),
-				}
+				writer.Rollback(ctx)
+				ct.fail(err)
 				return
 			}
-			ct.eventChan <- monitor.ProgressUpdateEvent{RowsCopied: 1, Table: ct.table}
+
+			err := ct.insertBatch(ctx, writer, targetColumns, batch.rows, &lastKey)
+			if err != nil {
+				err = ct.retryPending(ctx, writer, targetColumns, err, false, &lastKey)
+			}
+			if err != nil && ct.opts.MaxErrors > 0 {
+				err = ct.rejectPending(ctx, writer, targetColumns, err, &lastKey)
+			}
+			if err != nil {
+				writer.Rollback(ctx)
+				ct.fail(err)
+				return
+			}
+			ct.progress.add(len(batch.rows), batch.size)
+			budget.release(batch.size)
 
 		}
+		ct.progress.flush()
 
-		err = bulkInsert.Commit(ctx)
+		err = writer.Commit(ctx)
 		if err != nil {
-			_ = append(ct.errs, err)
-			ct.eventChan <- monitor.ErrorEvent{
-				Table: ct.table,
-				Err:   fmt.Errorf("Failed to commit the transaction into target table %s, %s", ct.table, err),
-			}
+			err = ct.retryPending(ctx, writer, targetColumns, err, true, &lastKey)
+		}
+		if err != nil && ct.opts.MaxErrors > 0 {
+			err = ct.rejectPending(ctx, writer, targetColumns, err, &lastKey)
+		}
+		if err != nil {
+			ct.fail(fmt.Errorf("Failed to commit the transaction into target table %s, %w", ct.table, err))
 			return
 		}
 
-		if len(fks) > 0 {
-
-			err = ct.targetDB.AddForeignKeys(ctx, fks)
+		if ct.opts.Mode == ModeMerge {
+			err = ct.targetDB.Merge(ctx, insertTable, ct.target, targetColumns, primaryKey)
 			if err != nil {
-				_ = append(ct.errs, err)
-				ct.eventChan <- monitor.ErrorEvent{
-					Table: ct.table,
-					Err:   fmt.Errorf("Failed to add foreign keys into target table %s, %s", ct.table, err),
-				}
+				ct.fail(fmt.Errorf("Failed to merge staged rows into target table %s, %w", ct.table, err))
 				return
 			}
 		}
 
+		err = ct.finishTarget(ctx)
+		if err != nil {
+			ct.fail(err)
+			return
+		}
+
+		// checked after the foreign keys are restored, the accepted rows are committed either way
+		err = ct.checkAccepted(writer)
+		if err != nil {
+			ct.fail(err)
+			return
+		}
+
+		err = ct.verifyCount(ctx)
+		if err != nil {
+			ct.fail(err)
+			return
+		}
+
 		ct.eventChan <- monitor.CopyTaskFinishedEvent{Table: ct.table}
 
 	}()
@@ -228,16 +601,364 @@ func (ct *CopyTask) Run(ctx context.Context) error {
 	return nil
 }
 
-func compareSchemas(sourceSchema, targetSchema map[string]string) bool {
-	if len(sourceSchema) != len(targetSchema) {
-		return false
+// insertBatch writes the rows of batch. Resumed tables insert them one at a time, so lastKey holds the key of the last committed row
+// when the writer commits.
+func (ct *CopyTask) insertBatch(ctx context.Context, writer rowWriter, columns []string, batch [][]interface{}, lastKey *[]string) error {
+	if len(ct.resumeKey) == 0 {
+		err := writer.InsertBatch(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("Failed to insert rows into the target table %s, %w", ct.table, err)
+		}
+		return nil
 	}
 
-	for column, dataType := range sourceSchema {
-		if targetSchema[column] != dataType {
-			return false
+	for _, row := range batch {
+		key, err := rowKey(columns, ct.resumeKey, row)
+		if err != nil {
+			return fmt.Errorf("Failed to read the key of a row of table %s, %s", ct.table, err)
+		}
+		*lastKey = key
+
+		err = writer.Insert(ctx, row)
+		if err != nil {
+			return fmt.Errorf("Failed to insert row into the target table %s, %w", ct.table, err)
+		}
+	}
+
+	return nil
+}
+
+// prepareTarget drops or disables the foreign keys referencing the table and empties it according to the mode,
+// finishTarget restores the foreign keys, or restoreOnFailure when the load fails
+func (ct *CopyTask) prepareTarget(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "prepare target")
+	defer span.End()
+
+	fks, err := ct.targetDB.GetReferencedForeignKeys(ctx, ct.target)
+	if err != nil {
+		return fmt.Errorf("Failed to get foreign keys for table %s from the targetDB", ct.table)
+	}
+
+	// recorded first, so the cleanup command can restore them when the run crashes
+	err = ct.targetDB.RecordForeignKeys(ctx, ct.foreignKeyArtifact(), fks)
+	if err != nil {
+		return fmt.Errorf("Failed to record the foreign keys of table %s in the targetDB, %s", ct.table, err)
+	}
+
+	// and written to the recovery file, for when the target is out of reach as well
+	err = ct.writeRecovery(recoveryForeignKeys, mssql.RestoreForeignKeyStatements(fks, ct.opts.DisableForeignKeys))
+	if err != nil {
+		return fmt.Errorf("Failed to write the foreign keys of table %s to the recovery file, %s", ct.table, err)
+	}
+
+	if ct.opts.DisableForeignKeys {
+		err = ct.retryLocked(ctx, "disable foreign keys", func() error { return ct.targetDB.DisableForeignKeys(ctx, fks) })
+	} else {
+		// the foreign keys dropped before a conflict are not dropped again
+		err = ct.retryLocked(ctx, "drop foreign keys", func() error { return ct.targetDB.DropReferencedForeignKeys(ctx, ct.target) })
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to drop foreign keys for table %s from the targetDB", ct.table)
+	}
+	ct.droppedForeignKeys = fks
+	if len(fks) > 0 {
+		ct.eventChan <- monitor.ForeignKeysEvent{Table: ct.table, Dropped: mssql.CountForeignKeys(fks)}
+	}
+
+	switch {
+	case ct.opts.Mode == ModeDelete:
+		err = ct.retryLocked(ctx, "delete", func() error { return ct.targetDB.DeleteWhere(ctx, ct.target, ct.opts.QueryFilter) })
+	case ct.opts.DisableForeignKeys:
+		// disabled foreign keys still prevent TRUNCATE
+		err = ct.retryLocked(ctx, "delete", func() error { return ct.targetDB.DeleteAll(ctx, ct.target) })
+	default:
+		err = ct.retryLocked(ctx, "truncate", func() error { return ct.targetDB.EmptyTable(ctx, ct.target) })
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to empty target table %s", ct.table)
+	}
+
+	return nil
+}
+
+// disableIndexes disables the nonclustered indexes of the target table before it is loaded, finishTarget rebuilds them
+func (ct *CopyTask) disableIndexes(ctx context.Context) error {
+	enabled, err := ct.targetDB.GetNonclusteredIndexes(ctx, ct.target)
+	if err != nil {
+		return fmt.Errorf("Failed to get the indexes of target table %s, %s", ct.table, err)
+	}
+
+	// the indexes a run that did not finish left disabled are rebuilt with the others, and stay in the record that replaces its record
+	recorded, err := ct.targetDB.GetRecordedIndexes(ctx, ct.target)
+	if err != nil {
+		return fmt.Errorf("Failed to get the recorded indexes of table %s from the targetDB, %s", ct.table, err)
+	}
+	indexes := append(enabled, recorded...)
+
+	// recorded first, so the cleanup command can rebuild them when the run crashes
+	err = ct.targetDB.RecordIndexes(ctx, ct.target, indexes)
+	if err != nil {
+		return fmt.Errorf("Failed to record the indexes of table %s in the targetDB, %s", ct.table, err)
+	}
+
+	err = ct.writeRecovery(recoveryIndexes, mssql.RebuildIndexStatements(indexes))
+	if err != nil {
+		return fmt.Errorf("Failed to write the indexes of table %s to the recovery file, %s", ct.table, err)
+	}
+
+	// set before they are disabled, rebuilding the indexes a failure left enabled does no harm
+	ct.disabledIndexes = indexes
+	err = ct.targetDB.DisableIndexes(ctx, enabled)
+	if err != nil {
+		return fmt.Errorf("Failed to disable the indexes of target table %s, %s", ct.table, err)
+	}
+
+	return nil
+}
+
+// finishTarget restores the indexes, triggers and foreign keys and records the state of the loaded table. The rows are committed,
+// so it finishes when the table timeout or a cancellation ends ctx meanwhile.
+func (ct *CopyTask) finishTarget(ctx context.Context) error {
+	ctx, cancel := restoreContext(ctx)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "finish target")
+	defer span.End()
+
+	err := ct.restoreIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = ct.restoreTriggers(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = ct.restoreForeignKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ct.opts.ReseedIdentity {
+		err := ct.reseedIdentity(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to reseed the identity of target table %s, %s", ct.table, err)
+		}
+	}
+
+	if ct.recordSync {
+		err := ct.targetDB.SetSyncVersion(ctx, ct.target, ct.syncVersion)
+		if err != nil {
+			return fmt.Errorf("Failed to record the sync version of target table %s, %s", ct.table, err)
+		}
+	}
+
+	if ct.opts.Checkpoints != nil {
+		err := ct.opts.Checkpoints.MarkTableDone(ct.table)
+		if err != nil {
+			return fmt.Errorf("Failed to write the checkpoint for table %s, %s", ct.table, err)
+		}
+	}
+
+	return nil
+}
+
+func (ct *CopyTask) readOptions(ctx context.Context) (mssql.ReadOptions, error) {
+	readOpts := ct.opts.filter(ct.table)
+	readOpts.Limit = ct.opts.SampleRows
+	readOpts.SamplePercent = ct.opts.SamplePercent
+
+	if len(ct.resumeKey) > 0 {
+		readOpts.OrderBy = ct.resumeKey
+		readOpts.After = ct.resumeAfter
+	}
+
+	if len(ct.pageKey) > 0 {
+		readOpts.OrderBy = ct.pageKey
+		readOpts.PageSize = ct.opts.PageSize
+	}
+
+	if ct.opts.Mode == ModeIncremental {
+		watermark, ok, err := ct.targetDB.GetMaxValue(ctx, ct.target, ct.opts.WatermarkColumn)
+		if err != nil {
+			return mssql.ReadOptions{}, err
+		}
+
+		// an empty target gets all source rows
+		if ok {
+			readOpts.Conditions = append(readOpts.Conditions, mssql.Condition{Column: ct.opts.WatermarkColumn, Operator: ">", Value: watermark})
+		}
+	}
+
+	return readOpts, nil
+}
+
+func (ct *CopyTask) foreignKeyArtifact() mssql.ArtifactKind {
+	if ct.opts.DisableForeignKeys {
+		return mssql.ArtifactDisabledForeignKey
+	}
+
+	return mssql.ArtifactDroppedForeignKey
+}
+
+// prepareResume determines the key used to record the progress of the table and where a previous run left off,
+// without Resume the progress of a previous run is forgotten as the table is copied from the start
+func (ct *CopyTask) prepareResume(ctx context.Context, schema mssql.SchemaDefinition) error {
+	if !ct.opts.Resume {
+		return ct.opts.Checkpoints.ForgetProgress(ct.table)
+	}
+
+	// merged rows are only final after the staging table is merged, insert-select copies in a single statement
+	if ct.opts.Mode != ModeTruncate && ct.opts.Mode != ModeDelete && ct.opts.Mode != ModeAppend || ct.strategy == StrategyInsertSelect {
+		return nil
+	}
+
+	// the ranges of a partitioned table commit independently, so the finished ranges are recorded instead of a last key
+	if ct.partitioned() {
+		ct.resumeRanges = true
+		return ct.opts.Checkpoints.SetLastKey(ct.table, nil)
+	}
+
+	// the writers of a fanned out table commit independently, so the table is copied from the start
+	if ct.fannedOut() {
+		return ct.opts.Checkpoints.ForgetProgress(ct.table)
+	}
+
+	primaryKey, err := ct.sourceDB.GetPrimaryKey(ctx, ct.table)
+	if err != nil {
+		return err
+	}
+
+	// tables without a usable key are copied from the start
+	if !mssql.KeysetSupported(schema, primaryKey) {
+		return ct.opts.Checkpoints.ForgetProgress(ct.table)
+	}
+
+	ct.resumeKey = primaryKey
+	ct.resumeAfter = ct.opts.Checkpoints.LastKey(ct.table)
+
+	return nil
+}
+
+// preparePaging determines the key the source table is read in pages by, tables of which the primary key is missing, not copied
+// or of a type that can not be continued after are read with a single select
+func (ct *CopyTask) preparePaging(ctx context.Context, schema mssql.SchemaDefinition, columns []string) error {
+	if ct.opts.PageSize <= 0 || ct.opts.SamplePercent > 0 {
+		return nil
+	}
+
+	if len(ct.resumeKey) > 0 {
+		ct.pageKey = ct.resumeKey
+		return nil
+	}
+
+	primaryKey, err := ct.sourceDB.GetPrimaryKey(ctx, ct.table)
+	if err != nil {
+		return err
+	}
+
+	if !mssql.KeysetSupported(schema, primaryKey) {
+		return nil
+	}
+
+	for _, keyColumn := range primaryKey {
+		if !slices.Contains(columns, keyColumn) {
+			return nil
+		}
+	}
+
+	ct.pageKey = primaryKey
+
+	return nil
+}
+
+// rowKey returns the values of the key columns of row
+func rowKey(columns []string, key []string, row []interface{}) ([]string, error) {
+	values := make([]string, len(key))
+	for i, keyColumn := range key {
+		found := false
+		for j, column := range columns {
+			if column != keyColumn {
+				continue
+			}
+
+			value, err := mssql.KeyValue(row[j])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+			found = true
+			break
+		}
+
+		if !found {
+			return nil, fmt.Errorf("key column %s is not copied", keyColumn)
+		}
+	}
+
+	return values, nil
+}
+
+// count returns the number of rows that will be copied, for a whole table the metadata count is used unless exact counts are requested
+func (ct *CopyTask) count(ctx context.Context, readOpts mssql.ReadOptions) (int, bool, error) {
+	if readOpts.Unfiltered() && !ct.opts.ExactCounts {
+		if count, ok := ct.opts.Metadata.Count(ct.table); ok {
+			return count, true, nil
+		}
+
+		count, err := ct.sourceDB.GetApproximateCount(ctx, ct.table)
+		// reading partition stats requires VIEW DATABASE STATE, fall back to an exact count without it
+		if err == nil {
+			return count, true, nil
+		}
+	}
+
+	count, err := ct.sourceDB.GetCount(ctx, ct.table, readOpts)
+	// the rows of a resumed or incremental copy are only part of the table
+	if err == nil && readOpts.Limit == 0 && readOpts.SamplePercent == 0 && len(readOpts.After) == 0 && len(readOpts.Conditions) == 0 {
+		ct.sourceRows, ct.sourceCounted = count, true
+	}
+	// TABLESAMPLE picks other pages for every query
+	return count, readOpts.SamplePercent > 0, err
+}
+
+// reseedIdentity continues the identity of the target table from the current identity value of the source table
+func (ct *CopyTask) reseedIdentity(ctx context.Context) error {
+	sourceIdentity, err := ct.sourceDB.GetIdentityColumn(ctx, ct.table)
+	if err != nil {
+		return err
+	}
+
+	if sourceIdentity == nil || sourceIdentity.Current == "" {
+		return nil
+	}
+
+	targetIdentity, err := ct.targetDB.GetIdentityColumn(ctx, ct.target)
+	if err != nil {
+		return err
+	}
+
+	if targetIdentity == nil {
+		return nil
+	}
+
+	return ct.targetDB.ReseedIdentity(ctx, ct.target, sourceIdentity.Current)
+}
+
+func (ct *CopyTask) transform(columns []string, row []interface{}) ([]interface{}, bool, error) {
+	return ct.opts.transform(ct.table, columns, row)
+}
+
+// transform applies the transformers to a row of table in order, the first to skip the row or fail ends the pipeline
+func (o Options) transform(table mssql.TableRef, columns []string, row []interface{}) ([]interface{}, bool, error) {
+	for _, transformer := range o.Transformers {
+		var skip bool
+		var err error
+		row, skip, err = transformer.Transform(table, columns, row)
+		if err != nil || skip {
+			return nil, skip, err
 		}
 	}
 
-	return true
+	return row, false, nil
 }
\ No newline at end of file
@@ -0,0 +1,230 @@
+package copy
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	mssqlDriver "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWriter commits every commitCount rows and fails commits of batches holding a row with a nil value
+type fakeWriter struct {
+	commitCount int
+	pending     [][]interface{}
+	committed   [][]interface{}
+	batches     []int
+	onCommit    func() error
+	// failures are returned by the next commits
+	failures []error
+}
+
+func (w *fakeWriter) Insert(ctx context.Context, row []interface{}) error {
+	w.pending = append(w.pending, row)
+	if len(w.pending)%w.commitCount == 0 {
+		return w.Commit(ctx)
+	}
+	return nil
+}
+
+func (w *fakeWriter) InsertBatch(ctx context.Context, rows [][]interface{}) error {
+	w.batches = append(w.batches, len(rows))
+	w.pending = append(w.pending, rows...)
+	return nil
+}
+
+func (w *fakeWriter) Commit(ctx context.Context) error {
+	if len(w.failures) > 0 {
+		err := w.failures[0]
+		w.failures = w.failures[1:]
+		return err
+	}
+
+	for _, row := range w.pending {
+		if row[1] == nil {
+			return mssqlDriver.Error{Number: 515, Message: "Cannot insert the value NULL into column total"}
+		}
+	}
+
+	w.committed = append(w.committed, w.pending...)
+	w.pending = nil
+	if w.onCommit != nil {
+		return w.onCommit()
+	}
+	return nil
+}
+
+func (w *fakeWriter) Rollback(ctx context.Context) error {
+	w.pending = nil
+	return nil
+}
+
+func (w *fakeWriter) OnCommit(fn func() error) { w.onCommit = fn }
+
+func (w *fakeWriter) Pending() [][]interface{} { return w.pending }
+
+func TestInsertBatch(t *testing.T) {
+	columns := []string{"id", "total"}
+	batch := [][]interface{}{{int64(1), "10"}, {int64(2), "20"}, {int64(3), "30"}}
+
+	ct := &CopyTask{table: mssql.TableRef{Schema: "dbo", Table: "orders"}}
+	writer := &fakeWriter{commitCount: 2}
+	var lastKey []string
+	assert.NoError(t, ct.insertBatch(context.Background(), writer, columns, batch, &lastKey))
+	assert.Equal(t, []int{3}, writer.batches)
+	assert.Nil(t, lastKey)
+
+	// resumed tables record the key of the last committed row, not the last row of the batch
+	ct.resumeKey = []string{"id"}
+	writer = &fakeWriter{commitCount: 2}
+	committed := make([][]string, 0)
+	writer.OnCommit(func() error {
+		committed = append(committed, lastKey)
+		return nil
+	})
+	assert.NoError(t, ct.insertBatch(context.Background(), writer, columns, batch, &lastKey))
+	assert.Equal(t, [][]string{{"2"}}, committed)
+	assert.Equal(t, []string{"3"}, lastKey)
+}
+
+func TestRejectPending(t *testing.T) {
+	columns := []string{"id", "total"}
+	batch := [][]interface{}{{int64(1), "10"}, {int64(2), nil}, {int64(3), "30"}, {int64(4), "40"}, {int64(5), nil}}
+	var lastKey []string
+
+	var buf bytes.Buffer
+	rejects := NewRejects(&buf)
+	ct := &CopyTask{table: mssql.TableRef{Schema: "dbo", Table: "orders"}, opts: Options{MaxErrors: 2, Rejects: rejects}}
+	writer := &fakeWriter{commitCount: 100}
+	assert.NoError(t, ct.insertBatch(context.Background(), writer, columns, batch, &lastKey))
+	err := writer.Commit(context.Background())
+	assert.Error(t, err)
+
+	assert.NoError(t, ct.rejectPending(context.Background(), writer, columns, err, &lastKey))
+	assert.Equal(t, [][]interface{}{{int64(1), "10"}, {int64(3), "30"}, {int64(4), "40"}}, writer.committed)
+	assert.Equal(t, 2, rejects.Count())
+	assert.Equal(t, `{"table":"[dbo].[orders]","error":"mssql: Cannot insert the value NULL into column total","row":{"id":2,"total":null}}
+{"table":"[dbo].[orders]","error":"mssql: Cannot insert the value NULL into column total","row":{"id":5,"total":null}}
+`, buf.String())
+
+	// one more rejected row than allowed fails the table
+	ct = &CopyTask{table: mssql.TableRef{Schema: "dbo", Table: "orders"}, opts: Options{MaxErrors: 1}}
+	writer = &fakeWriter{commitCount: 100}
+	assert.NoError(t, ct.insertBatch(context.Background(), writer, columns, batch, &lastKey))
+	assert.Error(t, ct.rejectPending(context.Background(), writer, columns, err, &lastKey))
+
+	// failures unrelated to the rows are returned without rejecting any
+	ct = &CopyTask{table: mssql.TableRef{Schema: "dbo", Table: "orders"}, opts: Options{MaxErrors: 10, Rejects: rejects}}
+	writer = &fakeWriter{commitCount: 100}
+	assert.NoError(t, ct.insertBatch(context.Background(), writer, columns, batch, &lastKey))
+	throttled := mssqlDriver.Error{Number: 40501}
+	assert.Equal(t, throttled, ct.rejectPending(context.Background(), writer, columns, throttled, &lastKey))
+	assert.Empty(t, writer.committed)
+	assert.Equal(t, 2, rejects.Count())
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, ct.rejectPending(cancelled, writer, columns, err, &lastKey), context.Canceled)
+}
+
+func TestRetryPending(t *testing.T) {
+	columns := []string{"id", "total"}
+	batch := [][]interface{}{{int64(1), "10"}, {int64(2), "20"}}
+	deadlock := mssqlDriver.Error{Number: 1205}
+	var lastKey []string
+
+	events := make(chan monitor.Event, 10)
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	ct := &CopyTask{table: orders, opts: Options{Retries: 2, RetryDelay: time.Millisecond}, eventChan: events}
+	writer := &fakeWriter{commitCount: 100, failures: []error{deadlock, deadlock}}
+	assert.NoError(t, ct.insertBatch(context.Background(), writer, columns, batch, &lastKey))
+	err := writer.Commit(context.Background())
+	assert.NoError(t, ct.retryPending(context.Background(), writer, columns, err, true, &lastKey))
+	assert.Equal(t, batch, writer.committed)
+	// the retries are reported
+	assert.Equal(t, monitor.RetryEvent{Table: orders, Attempt: 1, Err: deadlock}, <-events)
+	assert.Equal(t, monitor.RetryEvent{Table: orders, Attempt: 2, Err: deadlock}, <-events)
+
+	// the retries are used up
+	writer = &fakeWriter{commitCount: 100, failures: []error{deadlock, deadlock, deadlock}}
+	assert.NoError(t, ct.insertBatch(context.Background(), writer, columns, batch, &lastKey))
+	err = writer.Commit(context.Background())
+	assert.Equal(t, deadlock, ct.retryPending(context.Background(), writer, columns, err, true, &lastKey))
+
+	// other errors are not retried
+	writer = &fakeWriter{commitCount: 100, failures: []error{errors.New("Violation of PRIMARY KEY constraint")}}
+	assert.NoError(t, ct.insertBatch(context.Background(), writer, columns, batch, &lastKey))
+	err = writer.Commit(context.Background())
+	assert.Equal(t, err, ct.retryPending(context.Background(), writer, columns, err, true, &lastKey))
+	assert.Empty(t, writer.committed)
+
+	// the rows of a commit the server did not answer may have landed
+	unknown := fmt.Errorf("%w, %w", mssql.ErrCommitUnknown, driver.ErrBadConn)
+	writer = &fakeWriter{commitCount: 100, failures: []error{unknown}}
+	assert.NoError(t, ct.insertBatch(context.Background(), writer, columns, batch, &lastKey))
+	err = writer.Commit(context.Background())
+	assert.Equal(t, unknown, ct.retryPending(context.Background(), writer, columns, err, true, &lastKey))
+	assert.Empty(t, writer.committed)
+}
+
+func TestPartitioned(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	partitions := map[string]Partitioning{orders.String(): {Parts: 4}}
+
+	assert.True(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeTruncate, Partitions: partitions}, nil).partitioned())
+	assert.True(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeIncremental, Partitions: partitions}, nil).partitioned())
+	assert.False(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeTruncate}, nil).partitioned())
+	assert.False(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeTruncate, Partitions: map[string]Partitioning{orders.String(): {Parts: 1}}}, nil).partitioned())
+
+	// merges, insert-select and samples are copied by a single reader
+	assert.False(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeMerge, Partitions: partitions}, nil).partitioned())
+	assert.False(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeTruncate, Partitions: partitions, Strategies: map[string]Strategy{orders.String(): StrategyInsertSelect}}, nil).partitioned())
+	assert.False(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeTruncate, Partitions: partitions, SampleRows: 100}, nil).partitioned())
+}
+
+func TestFannedOut(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	writers := map[string]int{orders.String(): 4}
+
+	assert.True(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeTruncate, Writers: writers}, nil).fannedOut())
+	assert.True(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeAppend, Writers: writers, SampleRows: 100}, nil).fannedOut())
+	assert.False(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeTruncate}, nil).fannedOut())
+
+	// merges load a single staging table, insert-select copies in a single statement and partitions have a writer per range
+	assert.False(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeMerge, Writers: writers}, nil).fannedOut())
+	assert.False(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeTruncate, Writers: writers, Strategies: map[string]Strategy{orders.String(): StrategyInsertSelect}}, nil).fannedOut())
+	assert.False(t, NewCopyTask(orders, nil, nil, Options{Mode: ModeTruncate, Writers: writers, Partitions: map[string]Partitioning{orders.String(): {Parts: 2}}}, nil).fannedOut())
+}
+
+func TestTargetTables(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "Orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "Lines"}
+	copied := mssql.TableRef{Schema: "staging", Table: "Orders_copy"}
+	opts := Options{Targets: map[string]mssql.TableRef{orders.String(): copied}}
+
+	assert.Equal(t, []mssql.TableRef{copied, lines}, opts.TargetTables([]mssql.TableRef{orders, lines}))
+	assert.Equal(t, copied, NewCopyTask(orders, nil, nil, opts, nil).target)
+	assert.Equal(t, lines, NewCopyTask(lines, nil, nil, opts, nil).target)
+}
+
+func TestRenamedDefinition(t *testing.T) {
+	definition := mssql.TableDefinition{
+		Table:      mssql.TableRef{Schema: "dbo", Table: "Orders"},
+		PrimaryKey: &mssql.PrimaryKeyDefinition{Name: "PK_Orders", Columns: []string{"id"}},
+	}
+	target := mssql.TableRef{Schema: "staging", Table: "Orders_copy"}
+
+	renamed := renamedDefinition(definition, target)
+	assert.Equal(t, target, renamed.Table)
+	assert.Equal(t, "", renamed.PrimaryKey.Name)
+	assert.Equal(t, []string{"id"}, renamed.PrimaryKey.Columns)
+	// the definition of the source is left as it is
+	assert.Equal(t, "PK_Orders", definition.PrimaryKey.Name)
+}
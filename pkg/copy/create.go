@@ -0,0 +1,53 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// createMissingTables creates the tables that do not exist in the target from their definition in the source,
+// under the name Targets maps them to
+func (e *Engine) createMissingTables(ctx context.Context, tables []mssql.TableRef) error {
+	for _, table := range tables {
+		target := e.opts.targetTable(table)
+		exists, err := e.targetDB.TableExists(ctx, target)
+		if err != nil {
+			return fmt.Errorf("failed to check whether table %s exists in the target, %w", table, err)
+		}
+
+		if exists {
+			continue
+		}
+
+		definition, err := e.sourceDB.GetTableDefinition(ctx, table)
+		if err != nil {
+			return fmt.Errorf("failed to read the definition of table %s from the source, %w", table, err)
+		}
+
+		if target != table {
+			definition = renamedDefinition(definition, target)
+		}
+
+		err = e.targetDB.CreateTable(ctx, definition)
+		if err != nil {
+			return fmt.Errorf("failed to create table %s in the target, %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// renamedDefinition returns the definition of the table named target. The name of the primary key is left to the server,
+// as the constraint of the source table may exist in the target schema as well.
+func renamedDefinition(definition mssql.TableDefinition, target mssql.TableRef) mssql.TableDefinition {
+	definition.Table = target
+	if definition.PrimaryKey != nil {
+		primaryKey := *definition.PrimaryKey
+		primaryKey.Name = ""
+		definition.PrimaryKey = &primaryKey
+	}
+
+	return definition
+}
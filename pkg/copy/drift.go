@@ -0,0 +1,77 @@
+package copy
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// PlanDrift compares a saved plan with a plan of the same copy made now and returns the differences that change what the copy does.
+// Row counts are expected to change between planning and applying and are not compared.
+func PlanDrift(saved, current []TablePlan) []string {
+	currentPlans := make(map[string]TablePlan, len(current))
+	for _, plan := range current {
+		currentPlans[plan.Table.String()] = plan
+	}
+
+	drift := make([]string, 0)
+	seen := make(map[string]bool, len(saved))
+	for _, plan := range saved {
+		table := plan.Table.String()
+		seen[table] = true
+
+		now, ok := currentPlans[table]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("%s: not in the copy anymore", table))
+			continue
+		}
+
+		if now.Err != nil {
+			drift = append(drift, fmt.Sprintf("%s: %s", table, now.Err))
+			continue
+		}
+
+		if now.Target.String() != plan.Target.String() {
+			drift = append(drift, fmt.Sprintf("%s: copied into %s instead of %s", table, now.Target, plan.Target))
+		}
+		if now.Create != plan.Create {
+			if plan.Create {
+				drift = append(drift, fmt.Sprintf("%s: was missing in the target and exists now", table))
+			} else {
+				drift = append(drift, fmt.Sprintf("%s: existed in the target and is missing now", table))
+			}
+		}
+		if now.Mode != plan.Mode || now.Strategy != plan.Strategy {
+			drift = append(drift, fmt.Sprintf("%s: loaded as %s with %s instead of %s with %s", table, now.Mode, now.Strategy, plan.Mode, plan.Strategy))
+		}
+		if now.SourceSchema != plan.SourceSchema {
+			drift = append(drift, fmt.Sprintf("%s: the schema of the source changed", table))
+		}
+		if now.TargetSchema != plan.TargetSchema && now.Create == plan.Create {
+			drift = append(drift, fmt.Sprintf("%s: the schema of the target changed", table))
+		}
+		if !slices.Equal(foreignKeyNames(now.ForeignKeys), foreignKeyNames(plan.ForeignKeys)) {
+			drift = append(drift, fmt.Sprintf("%s: the foreign keys referencing the table changed", table))
+		}
+	}
+
+	for _, plan := range current {
+		if !seen[plan.Table.String()] {
+			drift = append(drift, fmt.Sprintf("%s: added to the copy", plan.Table))
+		}
+	}
+
+	return drift
+}
+
+func foreignKeyNames(fks []mssql.ForeingKeyConstraint) []string {
+	names := make([]string, len(fks))
+	for i, fk := range fks {
+		names[i] = fk.Schema + "." + fk.Table + "." + fk.Name
+	}
+	sort.Strings(names)
+
+	return names
+}
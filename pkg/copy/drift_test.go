@@ -0,0 +1,39 @@
+package copy
+
+import (
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanDrift(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	saved := []TablePlan{
+		{Table: orders, Target: orders, Mode: ModeTruncate, Strategy: StrategyBulk, SourceSchema: "a", TargetSchema: "a", Rows: 10,
+			ForeignKeys: []mssql.ForeingKeyConstraint{{Name: "fk_lines_orders", Schema: "dbo", Table: "lines"}}},
+		{Table: lines, Target: lines, Mode: ModeTruncate, Strategy: StrategyBulk, SourceSchema: "b", Create: true},
+	}
+
+	// the rows are expected to change
+	current := []TablePlan{saved[0], saved[1]}
+	current[0].Rows = 20
+	assert.Empty(t, PlanDrift(saved, current))
+
+	current[0].TargetSchema = "c"
+	current[0].ForeignKeys = nil
+	current[1].Create = false
+	current[1].TargetSchema = "b"
+	assert.Equal(t, []string{
+		"[dbo].[orders]: the schema of the target changed",
+		"[dbo].[orders]: the foreign keys referencing the table changed",
+		"[dbo].[lines]: was missing in the target and exists now",
+	}, PlanDrift(saved, current))
+
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+	assert.Equal(t, []string{
+		"[dbo].[lines]: not in the copy anymore",
+		"[dbo].[customers]: added to the copy",
+	}, PlanDrift(saved, []TablePlan{saved[0], {Table: customers, Target: customers}}))
+}
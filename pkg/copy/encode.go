@@ -0,0 +1,121 @@
+package copy
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	mssqlDriver "github.com/microsoft/go-mssqldb"
+)
+
+// NewCSVEncoder returns an encoder writing the rows as CSV with a header row of the column names. Every table starts with its own header,
+// so extract one table per writer for plain CSV files. NULL is written as an empty field, binary values as 0x followed by hex digits.
+func NewCSVEncoder(w io.Writer) RowEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []mssql.ColumnDefinition
+}
+
+func (e *csvEncoder) Begin(table mssql.TableRef, columns []mssql.ColumnDefinition) error {
+	e.columns = columns
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+
+	return e.w.Write(header)
+}
+
+func (e *csvEncoder) Encode(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = csvField(e.columns[i], plainValue(e.columns[i], value))
+	}
+
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func csvField(column mssql.ColumnDefinition, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return "0x" + strings.ToUpper(hex.EncodeToString(v))
+	case time.Time:
+		switch column.Type {
+		case "date":
+			return v.Format(time.DateOnly)
+		case "time":
+			return v.Format("15:04:05.9999999")
+		}
+		return v.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// NewJSONLEncoder returns an encoder writing every row as a JSON object of column names to values on its own line.
+// Decimal and money values are strings to keep their precision, binary values are base64 encoded.
+func NewJSONLEncoder(w io.Writer) RowEncoder {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return &jsonlEncoder{encoder: encoder}
+}
+
+type jsonlEncoder struct {
+	encoder *json.Encoder
+	columns []mssql.ColumnDefinition
+}
+
+func (e *jsonlEncoder) Begin(table mssql.TableRef, columns []mssql.ColumnDefinition) error {
+	e.columns = columns
+	return nil
+}
+
+func (e *jsonlEncoder) Encode(row []interface{}) error {
+	object := make(map[string]interface{}, len(row))
+	for i, value := range row {
+		object[e.columns[i].Name] = plainValue(e.columns[i], value)
+	}
+
+	return e.encoder.Encode(object)
+}
+
+func (e *jsonlEncoder) End() error {
+	return nil
+}
+
+// plainValue converts the values the driver reads as bytes but are not binary data to strings
+func plainValue(column mssql.ColumnDefinition, value interface{}) interface{} {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+
+	switch column.Type {
+	case "decimal", "numeric", "money", "smallmoney":
+		return string(b)
+	case "uniqueidentifier":
+		var id mssqlDriver.UniqueIdentifier
+		if err := id.Scan(b); err == nil {
+			return id.String()
+		}
+	}
+
+	return b
+}
@@ -0,0 +1,37 @@
+package copy_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/copy"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoders(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	columns := []mssql.ColumnDefinition{
+		{Name: "id", Type: "int"},
+		{Name: "amount", Type: "decimal", Precision: 10, Scale: 2},
+		{Name: "ordered", Type: "date"},
+		{Name: "note", Type: "nvarchar", Nullable: true},
+		{Name: "hash", Type: "varbinary", MaxLength: 4},
+	}
+	row := []interface{}{int64(1), []byte("12.50"), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil, []byte{0xca, 0xfe}}
+
+	var csvOut bytes.Buffer
+	encoder := copy.NewCSVEncoder(&csvOut)
+	assert.NoError(t, encoder.Begin(orders, columns))
+	assert.NoError(t, encoder.Encode(row))
+	assert.NoError(t, encoder.End())
+	assert.Equal(t, "id,amount,ordered,note,hash\n1,12.50,2024-03-01,,0xCAFE\n", csvOut.String())
+
+	var jsonOut bytes.Buffer
+	encoder = copy.NewJSONLEncoder(&jsonOut)
+	assert.NoError(t, encoder.Begin(orders, columns))
+	assert.NoError(t, encoder.Encode(row))
+	assert.NoError(t, encoder.End())
+	assert.Equal(t, `{"amount":"12.50","hash":"yv4=","id":1,"note":null,"ordered":"2024-03-01T00:00:00Z"}`+"\n", jsonOut.String())
+}
@@ -0,0 +1,176 @@
+package copy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// RowTransformer is applied to every row read from the source before it is inserted into the target.
+// It returns the row to insert, whether the row should be skipped, or an error which fails the table.
+type RowTransformer interface {
+	Transform(table mssql.TableRef, columns []string, row []interface{}) ([]interface{}, bool, error)
+}
+
+// RowTransformerFunc allows a plain function to be used as a RowTransformer
+type RowTransformerFunc func(table mssql.TableRef, columns []string, row []interface{}) ([]interface{}, bool, error)
+
+func (f RowTransformerFunc) Transform(table mssql.TableRef, columns []string, row []interface{}) ([]interface{}, bool, error) {
+	return f(table, columns, row)
+}
+
+// Engine copies a set of tables from the source to the target database
+type Engine struct {
+	sourceDB *mssql.MSSQLDB
+	targetDB *mssql.MSSQLDB
+	opts     Options
+
+	eventChan chan<- monitor.Event
+	// cancels stops the copies of single tables
+	cancels *tableCancels
+	// consumerDone is closed when the consumer of the events stops reading them
+	consumerDone <-chan struct{}
+}
+
+func NewEngine(sourceDB *mssql.MSSQLDB, targetDB *mssql.MSSQLDB, opts Options, eventChan chan<- monitor.Event) *Engine {
+	return &Engine{
+		sourceDB:  sourceDB,
+		targetDB:  targetDB,
+		opts:      opts,
+		eventChan: eventChan,
+		cancels:   newTableCancels(),
+	}
+}
+
+// Use appends transformers to the pipeline, they are applied in the order they were added
+func (e *Engine) Use(transformers ...RowTransformer) {
+	e.opts.Transformers = append(e.opts.Transformers, transformers...)
+}
+
+// Run copies the tables, with at most parrallel tables in flight at the same time
+func (e *Engine) Run(ctx context.Context, tables []mssql.TableRef, parrallel int) error {
+	if e.eventChan != nil {
+		out := e.eventChan
+		var stop func()
+		e.eventChan, stop = relayEvents(out, e.consumerDone)
+		defer func() {
+			stop()
+			e.eventChan = out
+		}()
+	}
+
+	if e.opts.CreateTables {
+		err := e.createMissingTables(ctx, tables)
+		if err != nil {
+			return err
+		}
+	}
+
+	tables, temporal, err := e.prepareTemporalTables(ctx, tables)
+	if err != nil {
+		return err
+	}
+
+	err = e.runTables(ctx, tables, parrallel)
+
+	return errors.Join(err, e.restoreTemporalTables(ctx, temporal))
+}
+
+func (e *Engine) runTables(ctx context.Context, tables []mssql.TableRef, parrallel int) error {
+	if e.opts.DependencyOrder && e.opts.Mode == ModeTruncate {
+		return e.runInDependencyOrder(ctx, tables, parrallel)
+	}
+
+	tables = e.largestFirst(ctx, tables, parrallel)
+	tasks := make([]*CopyTask, len(tables))
+
+	for i, table := range tables {
+		tasks[i] = NewCopyTask(table, e.sourceDB, e.targetDB, e.opts, e.eventChan)
+	}
+
+	return e.runTasks(ctx, tasks, parrallel)
+}
+
+// runInDependencyOrder empties the tables children first and then copies them parents first,
+// so only tables referenced from outside the copy set or part of a cycle need their foreign keys dropped
+func (e *Engine) runInDependencyOrder(ctx context.Context, tables []mssql.TableRef, parrallel int) error {
+	plan, err := e.planCopyOrder(ctx, tables)
+	if err != nil {
+		return err
+	}
+
+	err = e.emptyInOrder(ctx, plan)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	for _, level := range plan.levels {
+		level = e.largestFirst(ctx, level, parrallel)
+		tasks := make([]*CopyTask, len(level))
+		for i, table := range level {
+			opts := e.opts
+			if !plan.fallback[table.String()] {
+				// already emptied
+				opts.Mode = ModeAppend
+			}
+			tasks[i] = NewCopyTask(table, e.sourceDB, e.targetDB, opts, e.eventChan)
+		}
+
+		if err := e.runTasks(ctx, tasks, parrallel); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// runTasks runs the tasks with at most parrallel tables in flight, the next task starts as soon as a table is copied
+func (e *Engine) runTasks(ctx context.Context, tasks []*CopyTask, parrallel int) error {
+	errs := make([]error, len(tasks))
+	inParallel(len(tasks), parrallel, func(i int) {
+		taskCtx, stop := e.cancels.start(ctx, tasks[i].table)
+		defer stop()
+
+		if e.opts.TableTimeout > 0 {
+			var cancel context.CancelFunc
+			taskCtx, cancel = context.WithTimeoutCause(taskCtx, e.opts.TableTimeout, fmt.Errorf("%w of %s", ErrTableTimeout, e.opts.TableTimeout))
+			defer cancel()
+		}
+
+		go tasks[i].Run(taskCtx)
+		tasks[i].wg.Wait()
+		// the failures caused by the timeout only tell the context deadline was exceeded
+		if cause := context.Cause(taskCtx); errors.Is(cause, ErrTableTimeout) && tasks[i].failed() {
+			tasks[i].fail(fmt.Errorf("Failed to copy table %s, %w", tasks[i].table, cause))
+		}
+		errs[i] = tasks[i].Wait()
+	})
+
+	return errors.Join(errs...)
+}
+
+// inParallel calls fn for the indexes up to n in order with a pool of parrallel workers, and returns when all calls returned
+func inParallel(n, parrallel int, fn func(i int)) {
+	next := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < max(min(parrallel, n), 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
@@ -0,0 +1,104 @@
+package copy
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInParallel(t *testing.T) {
+	lock := sync.Mutex{}
+	running, maxRunning := 0, 0
+	others := make(chan int, 6)
+
+	inParallel(7, 3, func(i int) {
+		lock.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		lock.Unlock()
+
+		// the first call only returns after the others, which the two remaining workers get to
+		if i == 0 {
+			for range 6 {
+				select {
+				case <-others:
+				case <-time.After(time.Second):
+					t.Error("the other calls waited for the first one")
+					return
+				}
+			}
+		} else {
+			time.Sleep(5 * time.Millisecond)
+			others <- i
+		}
+
+		lock.Lock()
+		running--
+		lock.Unlock()
+	})
+
+	assert.Equal(t, 3, maxRunning)
+
+	// nothing to do
+	inParallel(0, 3, func(i int) { t.Fail() })
+}
+
+func TestRunTablesMonitorsEveryTable(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+	tables := []mssql.TableRef{orders, lines, customers}
+
+	// the checkpointed tables finish without touching the databases
+	checkpoints, err := LoadCheckpoints(filepath.Join(t.TempDir(), "checkpoints.json"))
+	require.NoError(t, err)
+	for _, table := range tables {
+		require.NoError(t, checkpoints.MarkTableDone(table))
+	}
+
+	eventChan := make(chan monitor.Event, 10)
+	mon := monitor.NewMonitor(eventChan, true, io.Discard)
+	mon.SetTables(tables)
+	report := monitor.NewReportSink()
+	mon.AddSink(report)
+	go mon.Run(context.Background())
+
+	// relayed like Run does, one at a time the next table starts after the previous one finished
+	e := NewEngine(nil, nil, Options{Checkpoints: checkpoints}, eventChan)
+	var stop func()
+	e.eventChan, stop = relayEvents(eventChan, mon.Done())
+	assert.NoError(t, e.runTables(context.Background(), tables, 1))
+	stop()
+
+	select {
+	case <-mon.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the monitor did not finish after the last table")
+	}
+	assert.Equal(t, 3, report.Report().Copied)
+}
+
+func TestLargestFirst(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+	notes := mssql.TableRef{Schema: "dbo", Table: "notes"}
+	tables := []mssql.TableRef{customers, notes, orders, lines}
+
+	metadata := &Metadata{sizes: map[string]int64{orders.String(): 80 << 20, lines.String(): 2 << 30, customers.String(): 8192}}
+	e := NewEngine(nil, nil, Options{Metadata: metadata}, nil)
+
+	assert.Equal(t, []mssql.TableRef{lines, orders, customers, notes}, e.largestFirst(context.Background(), tables, 4))
+	assert.Equal(t, []mssql.TableRef{customers, notes, orders, lines}, tables)
+
+	// copied one at a time the order is kept
+	assert.Equal(t, tables, e.largestFirst(context.Background(), tables, 1))
+}
@@ -0,0 +1,77 @@
+package copy
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// ErrTableTimeout is the failure of a table of which the copy took longer than the TableTimeout
+var ErrTableTimeout = errors.New("the copy of the table took longer than the table timeout")
+
+// TableError is the failure of the copy of a table, with the errors it encountered in the order they occurred
+type TableError struct {
+	Table mssql.TableRef
+	Errs  []error
+}
+
+func (e *TableError) Error() string {
+	messages := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the errors of the table, so errors.Is and errors.As match any of them
+func (e *TableError) Unwrap() []error {
+	return e.Errs
+}
+
+// failed reports whether the table failed so far
+func (ct *CopyTask) failed() bool {
+	ct.errLock.Lock()
+	defer ct.errLock.Unlock()
+
+	return len(ct.errs) > 0
+}
+
+// SplitTableErrors separates the failures of single tables from the other errors returned by Engine.Run
+func SplitTableErrors(err error) ([]*TableError, error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	if tableErr, ok := err.(*TableError); ok {
+		return []*TableError{tableErr}, nil
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil, err
+	}
+
+	tables := make([]*TableError, 0)
+	others := make([]error, 0)
+	for _, err := range joined.Unwrap() {
+		t, other := SplitTableErrors(err)
+		tables = append(tables, t...)
+		if other != nil {
+			others = append(others, other)
+		}
+	}
+
+	return tables, errors.Join(others...)
+}
+
+// fail records err as a failure of the table and reports it to the monitor, it is called concurrently by the readers and writers of the table
+func (ct *CopyTask) fail(err error) {
+	ct.errLock.Lock()
+	ct.errs = append(ct.errs, err)
+	ct.errLock.Unlock()
+
+	ct.eventChan <- monitor.ErrorEvent{Table: ct.table, Err: err}
+}
@@ -0,0 +1,62 @@
+package copy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitCollectsErrors(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	eventChan := make(chan monitor.Event, 100)
+	ct := NewCopyTask(orders, nil, nil, Options{}, eventChan)
+
+	// the reader and the writer fail concurrently
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer ct.wg.Done()
+			ct.fail(fmt.Errorf("failure %d, %w", i, context.Canceled))
+		}(i)
+	}
+	wg.Wait()
+
+	err := ct.Wait()
+	var tableErr *TableError
+	assert.ErrorAs(t, err, &tableErr)
+	assert.Equal(t, orders, tableErr.Table)
+	assert.Len(t, tableErr.Errs, 2)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, eventChan, 2)
+}
+
+func TestWaitWithoutErrors(t *testing.T) {
+	ct := NewCopyTask(mssql.TableRef{Schema: "dbo", Table: "orders"}, nil, nil, Options{}, nil)
+	ct.wg.Done()
+	ct.wg.Done()
+
+	assert.NoError(t, ct.Wait())
+}
+
+func TestSplitTableErrors(t *testing.T) {
+	orders := &TableError{Table: mssql.TableRef{Schema: "dbo", Table: "orders"}, Errs: []error{errors.New("deadlock")}}
+	lines := &TableError{Table: mssql.TableRef{Schema: "dbo", Table: "lines"}, Errs: []error{errors.New("timeout")}}
+	temporal := errors.New("Failed to turn system versioning on")
+
+	tables, err := SplitTableErrors(errors.Join(errors.Join(orders, nil), lines, temporal))
+	assert.Equal(t, []*TableError{orders, lines}, tables)
+	assert.ErrorIs(t, err, temporal)
+	assert.NotErrorIs(t, err, orders)
+
+	tables, err = SplitTableErrors(errors.Join(orders, lines))
+	assert.Len(t, tables, 2)
+	assert.NoError(t, err)
+}
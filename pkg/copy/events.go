@@ -0,0 +1,70 @@
+package copy
+
+import (
+	"log"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+)
+
+// consumerStallTimeout is how long an event waits for the consumer before the consumer is considered dead
+const consumerStallTimeout = 1 * time.Minute
+
+// SetConsumerDone registers a channel that is closed when the consumer of the events stops reading them,
+// so the copy continues without progress reporting instead of blocking on the full event channel
+func (e *Engine) SetConsumerDone(done <-chan struct{}) {
+	e.consumerDone = done
+}
+
+// relayEvents forwards the events sent to the returned channel to out, in order. Once the consumer is done or does not
+// read an event for consumerStallTimeout, the events are dropped instead. stop waits for the events sent so far.
+func relayEvents(out chan<- monitor.Event, consumerDone <-chan struct{}) (chan<- monitor.Event, func()) {
+	in := make(chan monitor.Event, cap(out))
+	stopping := make(chan struct{})
+	stopped := make(chan struct{})
+
+	forward := func(event monitor.Event, dead bool) bool {
+		if dead {
+			return true
+		}
+
+		select {
+		case out <- event:
+			return false
+		case <-consumerDone:
+			log.Println("the progress monitor stopped, copying continues without progress reporting")
+		case <-time.After(consumerStallTimeout):
+			log.Printf("the progress monitor did not read any event for %s, copying continues without progress reporting", consumerStallTimeout)
+		}
+
+		return true
+	}
+
+	go func() {
+		defer close(stopped)
+
+		dead := false
+		for {
+			select {
+			case event := <-in:
+				dead = forward(event, dead)
+			case <-stopping:
+				// tasks only report before they are done, so everything they sent is buffered by now
+				for {
+					select {
+					case event := <-in:
+						dead = forward(event, dead)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	// the channel is never closed, a late sender fills the buffer instead of panicking
+	return in, func() {
+		close(stopping)
+		<-stopped
+	}
+}
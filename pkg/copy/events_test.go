@@ -0,0 +1,41 @@
+package copy
+
+import (
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayEventsDropsAfterConsumerDone(t *testing.T) {
+	out := make(chan monitor.Event)
+	consumerDone := make(chan struct{})
+	close(consumerDone)
+
+	in, stop := relayEvents(out, consumerDone)
+
+	table := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	// nobody reads out, the sends must not block
+	for i := 0; i < 10; i++ {
+		in <- monitor.ProgressUpdateEvent{Table: table, RowsCopied: 1}
+	}
+	stop()
+
+	assert.Len(t, out, 0)
+}
+
+func TestRelayEventsForwardsInOrder(t *testing.T) {
+	out := make(chan monitor.Event, 10)
+	in, stop := relayEvents(out, nil)
+
+	table := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	in <- monitor.CopyTaskStartedEvent{Table: table}
+	in <- monitor.ProgressUpdateEvent{Table: table, RowsCopied: 3}
+	in <- monitor.CopyTaskFinishedEvent{Table: table}
+	stop()
+
+	assert.Equal(t, monitor.CopyTaskStartedEvent{Table: table}, <-out)
+	assert.Equal(t, monitor.ProgressUpdateEvent{Table: table, RowsCopied: 3}, <-out)
+	assert.Equal(t, monitor.CopyTaskFinishedEvent{Table: table}, <-out)
+}
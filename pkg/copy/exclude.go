@@ -0,0 +1,46 @@
+package copy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// ParseExcludeColumns groups the columns to exclude by table for Options.ExcludeColumns,
+// a column is either excluded from every table or from one table as schema.table.column
+func ParseExcludeColumns(columns []string) (map[string][]string, error) {
+	excluded := make(map[string][]string)
+	for _, column := range columns {
+		parts := strings.Split(strings.TrimSpace(column), ".")
+		switch {
+		case len(parts) == 1 && parts[0] != "":
+			excluded[""] = append(excluded[""], parts[0])
+		case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+			table := mssql.TableRef{Schema: parts[0], Table: parts[1]}.String()
+			excluded[table] = append(excluded[table], parts[2])
+		default:
+			return nil, fmt.Errorf("invalid column %q to exclude, use column or schema.table.column", column)
+		}
+	}
+
+	return excluded, nil
+}
+
+// withoutExcluded returns the schema without the columns excluded from table, column names are matched case insensitively
+func (o Options) withoutExcluded(table mssql.TableRef, schema mssql.SchemaDefinition) mssql.SchemaDefinition {
+	excluded := slices.Concat(o.ExcludeColumns[""], o.ExcludeColumns[table.String()])
+	if len(excluded) == 0 {
+		return schema
+	}
+
+	remaining := make(mssql.SchemaDefinition, len(schema))
+	for name, column := range schema {
+		if !slices.ContainsFunc(excluded, func(e string) bool { return strings.EqualFold(e, name) }) {
+			remaining[name] = column
+		}
+	}
+
+	return remaining
+}
@@ -0,0 +1,37 @@
+package copy
+
+import (
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExcludeColumns(t *testing.T) {
+	excluded, err := ParseExcludeColumns([]string{"row_version", "dbo.Orders.AuditBlob", "dbo.Orders.Notes"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"": {"row_version"}, "[dbo].[Orders]": {"AuditBlob", "Notes"}}, excluded)
+
+	_, err = ParseExcludeColumns([]string{"Orders.AuditBlob"})
+	assert.Error(t, err)
+}
+
+func TestCopyColumnsWithoutExcluded(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "Orders"}
+	source := mssql.SchemaDefinition{
+		"id":        {Name: "id", Type: "int"},
+		"AuditBlob": {Name: "AuditBlob", Type: "varbinary", MaxLength: -1},
+		"total":     {Name: "total", Type: "int", Computed: "([a]+[b])"},
+	}
+	target := mssql.SchemaDefinition{
+		"id":    {Name: "id", Type: "int"},
+		"total": {Name: "total", Type: "int", Computed: "([a]+[b])"},
+	}
+
+	_, err := Options{}.copyColumns(orders, source, target)
+	assert.Error(t, err)
+
+	columns, err := Options{ExcludeColumns: map[string][]string{"[dbo].[Orders]": {"auditblob"}}}.copyColumns(orders, source, target)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id"}, columns)
+}
@@ -0,0 +1,99 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// RowEncoder writes the rows of extracted tables, see NewCSVEncoder and NewJSONLEncoder for encoders writing to an io.Writer
+type RowEncoder interface {
+	// Begin starts the rows of table, the values of the rows are in the order of columns
+	Begin(table mssql.TableRef, columns []mssql.ColumnDefinition) error
+	Encode(row []interface{}) error
+	// End finishes the rows of the table started by Begin
+	End() error
+}
+
+// Extract reads the tables from the source one after the other and passes their rows to the encoder instead of writing them to a target database.
+// The query filter, subset, sampling, hints, excluded columns and transformers of the options apply like they do to a copy.
+func (e *Engine) Extract(ctx context.Context, tables []mssql.TableRef, encoder RowEncoder) error {
+	for _, table := range tables {
+		err := e.extractTable(ctx, table, encoder)
+		if err != nil {
+			return fmt.Errorf("failed to extract table %s, %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) extractTable(ctx context.Context, table mssql.TableRef, encoder RowEncoder) error {
+	definition, err := e.sourceDB.GetTableDefinition(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	schema := make(mssql.SchemaDefinition, len(definition.Columns))
+	for _, column := range definition.Columns {
+		schema[column.Name] = column
+	}
+	schema = e.opts.withoutExcluded(table, schema)
+
+	// the columns keep the order of the table
+	columns := make([]mssql.ColumnDefinition, 0, len(schema))
+	names := make([]string, 0, len(schema))
+	for _, column := range definition.Columns {
+		if _, ok := schema[column.Name]; ok {
+			columns = append(columns, column)
+			names = append(names, column.Name)
+		}
+	}
+
+	if len(columns) == 0 {
+		return fmt.Errorf("no columns left to extract")
+	}
+
+	readOpts := e.opts.filter(table)
+	readOpts.Limit = e.opts.SampleRows
+	readOpts.SamplePercent = e.opts.SamplePercent
+
+	rows, err := e.sourceDB.SelectFrom(ctx, table, names, readOpts)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	err = encoder.Begin(table, columns)
+	if err != nil {
+		return err
+	}
+
+	for {
+		values, err := rows.Next()
+		if err != nil {
+			return err
+		}
+
+		if len(values) == 0 {
+			break
+		}
+
+		values, skip, err := e.opts.transform(table, names, values)
+		if err != nil {
+			return err
+		}
+
+		if skip {
+			continue
+		}
+
+		err = encoder.Encode(values)
+		if err != nil {
+			return err
+		}
+	}
+
+	return encoder.End()
+}
@@ -0,0 +1,117 @@
+package copy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter limits the rows and bytes per second inserted by all tables of a run, so a copy does not saturate a shared source or target
+type Limiter struct {
+	lock           sync.Mutex
+	rowsPerSecond  float64
+	bytesPerSecond float64
+	// adaptiveRowsPerSecond is the rate set by a Throttle, the stricter of both row limits applies
+	adaptiveRowsPerSecond float64
+	// next is the time at which the rows inserted so far are within the limits
+	next time.Time
+	// rows counts the rows inserted
+	rows int64
+}
+
+// NewLimiter returns a limiter of at most rowsPerSecond rows and bytesPerSecond bytes per second, a limit of 0 is unlimited
+func NewLimiter(rowsPerSecond, bytesPerSecond float64) *Limiter {
+	return &Limiter{rowsPerSecond: rowsPerSecond, bytesPerSecond: bytesPerSecond}
+}
+
+// Wait blocks until rows of size bytes can be inserted within the limits. The rows are accounted for right away,
+// so the callers sharing the limiter are spaced out by the time their rows take at the limited rate.
+func (l *Limiter) Wait(ctx context.Context, rows int, bytes int64) error {
+	if l == nil {
+		return nil
+	}
+
+	delay := l.reserve(time.Now(), rows, bytes)
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// reserve accounts for the rows and returns how long to wait before inserting them
+func (l *Limiter) reserve(now time.Time, rows int, bytes int64) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.rows += int64(rows)
+
+	rowsPerSecond := l.rowsPerSecond
+	if l.adaptiveRowsPerSecond > 0 && (rowsPerSecond == 0 || l.adaptiveRowsPerSecond < rowsPerSecond) {
+		rowsPerSecond = l.adaptiveRowsPerSecond
+	}
+
+	var duration time.Duration
+	if rowsPerSecond > 0 {
+		duration = max(duration, time.Duration(float64(rows)/rowsPerSecond*float64(time.Second)))
+	}
+	if l.bytesPerSecond > 0 {
+		duration = max(duration, time.Duration(float64(bytes)/l.bytesPerSecond*float64(time.Second)))
+	}
+
+	// the time not used while nothing was inserted is not saved up for later
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(duration)
+
+	return start.Sub(now)
+}
+
+// adapt sets the rows per second of a Throttle, 0 lifts it
+func (l *Limiter) adapt(rowsPerSecond float64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.adaptiveRowsPerSecond = rowsPerSecond
+}
+
+// adapted returns the rows per second set by a Throttle and the number of rows inserted so far
+func (l *Limiter) adapted() (float64, int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.adaptiveRowsPerSecond, l.rows
+}
+
+// throttle waits until rows of size bytes can be inserted within the limits of the run
+func (ct *CopyTask) throttle(ctx context.Context, rows int, bytes int64) error {
+	return ct.opts.Limiter.Wait(ctx, rows, bytes)
+}
+
+// rowSize estimates the number of bytes of a row sent to the target
+func rowSize(row []interface{}) int64 {
+	var size int64
+	for _, value := range row {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			size += int64(len(v))
+		case []byte:
+			size += int64(len(v))
+		case bool:
+			size++
+		default:
+			// numbers, dates and times
+			size += 8
+		}
+	}
+
+	return size
+}
@@ -0,0 +1,33 @@
+package copy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+
+	limiter := NewLimiter(100, 0)
+	assert.Equal(t, time.Duration(0), limiter.reserve(now, 50, 1000))
+	// the next rows wait for the 50 rows before them
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve(now, 50, 1000))
+	assert.Equal(t, time.Second, limiter.reserve(now, 10, 1000))
+	// unused time is not saved up
+	assert.Equal(t, time.Duration(0), limiter.reserve(now.Add(time.Minute), 10, 1000))
+
+	// the strictest limit applies
+	limiter = NewLimiter(100, 1000)
+	assert.Equal(t, time.Duration(0), limiter.reserve(now, 10, 2000))
+	assert.Equal(t, 2*time.Second, limiter.reserve(now, 10, 2000))
+
+	var unlimited *Limiter
+	assert.NoError(t, unlimited.Wait(context.Background(), 1000, 1000))
+}
+
+func TestRowSize(t *testing.T) {
+	assert.Equal(t, int64(8+5+3+1), rowSize([]interface{}{int64(1), "hello", []byte{1, 2, 3}, true, nil}))
+}
@@ -0,0 +1,183 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// dependencyPlan is the order in which tables are emptied and copied so foreign keys never have to be dropped
+type dependencyPlan struct {
+	// levels holds the tables in copy order, parents come in an earlier level than the tables referencing them
+	levels [][]mssql.TableRef
+	// fallback holds the tables that are referenced from outside the copy set or are part of a cycle and the tables they
+	// reference, those keep dropping and recreating the foreign keys referencing them
+	fallback map[string]bool
+	// referenced holds the tables referenced by other tables in the copy set, which can not be truncated
+	referenced map[string]bool
+}
+
+// planDependencies orders tables topologically, children maps a table to the tables referencing it through a foreign key
+func planDependencies(tables []mssql.TableRef, children map[string][]mssql.TableRef) dependencyPlan {
+	inScope := make(map[string]mssql.TableRef, len(tables))
+	for _, table := range tables {
+		inScope[table.String()] = table
+	}
+
+	plan := dependencyPlan{
+		fallback:   make(map[string]bool),
+		referenced: make(map[string]bool),
+	}
+
+	parentCount := make(map[string]int, len(tables))
+	for _, table := range tables {
+		for _, child := range children[table.String()] {
+			if child.String() == table.String() {
+				// a self reference does not affect the order
+				plan.referenced[table.String()] = true
+				continue
+			}
+
+			if _, ok := inScope[child.String()]; !ok {
+				plan.fallback[table.String()] = true
+				continue
+			}
+
+			plan.referenced[table.String()] = true
+			parentCount[child.String()]++
+		}
+	}
+
+	level := make([]mssql.TableRef, 0)
+	for _, table := range tables {
+		if parentCount[table.String()] == 0 {
+			level = append(level, table)
+		}
+	}
+
+	placed := make(map[string]bool, len(tables))
+	for len(level) > 0 {
+		sort.Slice(level, func(i, j int) bool { return level[i].String() < level[j].String() })
+		plan.levels = append(plan.levels, level)
+
+		next := make([]mssql.TableRef, 0)
+		for _, table := range level {
+			placed[table.String()] = true
+			for _, child := range children[table.String()] {
+				if _, ok := inScope[child.String()]; !ok || child.String() == table.String() {
+					continue
+				}
+
+				parentCount[child.String()]--
+				if parentCount[child.String()] == 0 {
+					next = append(next, child)
+				}
+			}
+		}
+		level = next
+	}
+
+	// whatever could not be placed is part of a cycle and is copied last, the old way
+	cyclic := make([]mssql.TableRef, 0)
+	for _, table := range tables {
+		if !placed[table.String()] {
+			plan.fallback[table.String()] = true
+			cyclic = append(cyclic, table)
+		}
+	}
+	if len(cyclic) > 0 {
+		plan.levels = append(plan.levels, cyclic)
+	}
+
+	// the parents of a fallback table can not be emptied before it, as it is only emptied when it is copied
+	parents := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		for _, child := range children[table.String()] {
+			if _, ok := inScope[child.String()]; ok && child.String() != table.String() {
+				parents[child.String()] = append(parents[child.String()], table.String())
+			}
+		}
+	}
+	pending := make([]string, 0, len(plan.fallback))
+	for table := range plan.fallback {
+		pending = append(pending, table)
+	}
+	for len(pending) > 0 {
+		table := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		for _, parent := range parents[table] {
+			if !plan.fallback[parent] {
+				plan.fallback[parent] = true
+				pending = append(pending, parent)
+			}
+		}
+	}
+
+	return plan
+}
+
+// planCopyOrder reads the foreign keys referencing each table from the target, unless they were prefetched
+func (e *Engine) planCopyOrder(ctx context.Context, tables []mssql.TableRef) (dependencyPlan, error) {
+	children := make(map[string][]mssql.TableRef, len(tables))
+	// the tables by their name in the target
+	sources := make(map[string]mssql.TableRef, len(tables))
+	for _, table := range tables {
+		sources[e.opts.targetTable(table).String()] = table
+	}
+	for _, table := range tables {
+		fks, ok := e.opts.Metadata.ReferencedForeignKeys(table)
+		if !ok {
+			var err error
+			fks, err = e.targetDB.GetReferencedForeignKeys(ctx, e.opts.targetTable(table))
+			if err != nil {
+				return dependencyPlan{}, fmt.Errorf("failed to get the foreign keys referencing %s, %w", table, err)
+			}
+		}
+
+		for _, fk := range fks {
+			// the referencing tables are named as in the target
+			child := mssql.TableRef{Schema: fk.Schema, Table: fk.Table}
+			if source, ok := sources[child.String()]; ok {
+				child = source
+			}
+			children[table.String()] = append(children[table.String()], child)
+		}
+	}
+
+	return planDependencies(tables, children), nil
+}
+
+// emptyInOrder empties the tables that do not need foreign keys dropped, children before their parents.
+// Tables referenced by other tables are deleted from, as TRUNCATE is never allowed on them.
+func (e *Engine) emptyInOrder(ctx context.Context, plan dependencyPlan) error {
+	for i := len(plan.levels) - 1; i >= 0; i-- {
+		for _, table := range plan.levels[i] {
+			if plan.fallback[table.String()] || e.resumes(table) {
+				continue
+			}
+
+			var err error
+			if plan.referenced[table.String()] {
+				err = e.targetDB.DeleteAll(ctx, e.opts.targetTable(table))
+			} else {
+				err = e.targetDB.EmptyTable(ctx, e.opts.targetTable(table))
+			}
+			if err != nil {
+				return fmt.Errorf("failed to empty target table %s, %w", table, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resumes reports whether the target table holds rows of a previous run that are kept
+func (e *Engine) resumes(table mssql.TableRef) bool {
+	if e.opts.Checkpoints == nil {
+		return false
+	}
+
+	return e.opts.Checkpoints.IsTableDone(table) || (e.opts.Resume && e.opts.Checkpoints.LastKey(table) != nil)
+}
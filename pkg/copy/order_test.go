@@ -0,0 +1,92 @@
+package copy
+
+import (
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanDependencies(t *testing.T) {
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	audit := mssql.TableRef{Schema: "dbo", Table: "audit"}
+
+	plan := planDependencies([]mssql.TableRef{lines, orders, customers}, map[string][]mssql.TableRef{
+		customers.String(): {orders},
+		orders.String():    {lines, audit},
+	})
+
+	assert.Equal(t, [][]mssql.TableRef{{customers}, {orders}, {lines}}, plan.levels)
+	// customers can not be emptied while orders keeps its rows until it is copied
+	assert.Equal(t, map[string]bool{customers.String(): true, orders.String(): true}, plan.fallback)
+	assert.Equal(t, map[string]bool{customers.String(): true, orders.String(): true}, plan.referenced)
+}
+
+func TestPlanDependenciesFallbackParents(t *testing.T) {
+	regions := mssql.TableRef{Schema: "dbo", Table: "regions"}
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	products := mssql.TableRef{Schema: "dbo", Table: "products"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	audit := mssql.TableRef{Schema: "dbo", Table: "audit"}
+
+	// regions -> customers -> orders -> lines <- products, audit outside the copy set references customers
+	plan := planDependencies([]mssql.TableRef{lines, orders, customers, regions, products}, map[string][]mssql.TableRef{
+		regions.String():   {customers},
+		customers.String(): {orders, audit},
+		orders.String():    {lines},
+		products.String():  {lines},
+	})
+
+	assert.Equal(t, map[string]bool{regions.String(): true, customers.String(): true}, plan.fallback)
+}
+
+func TestPlanDependenciesCycle(t *testing.T) {
+	a := mssql.TableRef{Schema: "dbo", Table: "a"}
+	b := mssql.TableRef{Schema: "dbo", Table: "b"}
+	c := mssql.TableRef{Schema: "dbo", Table: "c"}
+
+	plan := planDependencies([]mssql.TableRef{a, b, c}, map[string][]mssql.TableRef{
+		a.String(): {b},
+		b.String(): {a},
+	})
+
+	assert.Equal(t, [][]mssql.TableRef{{c}, {a, b}}, plan.levels)
+	assert.Equal(t, map[string]bool{a.String(): true, b.String(): true}, plan.fallback)
+}
+
+func TestOrderPlans(t *testing.T) {
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	audit := mssql.TableRef{Schema: "dbo", Table: "audit"}
+
+	// audit outside the copy set references customers
+	order := planDependencies([]mssql.TableRef{lines, orders, customers}, map[string][]mssql.TableRef{
+		customers.String(): {orders, audit},
+		orders.String():    {lines},
+	})
+
+	plan := func(table mssql.TableRef, children ...mssql.TableRef) TablePlan {
+		fks := make([]mssql.ForeingKeyConstraint, len(children))
+		for i, child := range children {
+			fks[i] = mssql.ForeingKeyConstraint{Name: "fk_" + child.Table, Schema: child.Schema, Table: child.Table}
+		}
+		return TablePlan{Table: table, Target: table, Mode: ModeTruncate, EmptyAction: "TRUNCATE", ForeignKeys: fks, ForeignKeyPlan: "drop and recreate WITH NOCHECK"}
+	}
+
+	plans := orderPlans(map[string]TablePlan{
+		lines.String():     plan(lines),
+		orders.String():    plan(orders, lines),
+		customers.String(): plan(customers, orders, audit),
+	}, order)
+
+	assert.Equal(t, []TablePlan{
+		// the fallback keeps dropping the foreign keys referencing it
+		plan(customers, orders, audit),
+		{Table: orders, Target: orders, Mode: ModeTruncate, EmptyAction: "DELETE all rows before the copy, after the tables referencing it"},
+		{Table: lines, Target: lines, Mode: ModeTruncate, EmptyAction: "TRUNCATE before the copy, after the tables referencing it"},
+	}, plans)
+}
@@ -0,0 +1,399 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// Partitioning splits the copy of a table into ranges of a column, which are copied concurrently by their own reader and writer
+type Partitioning struct {
+	// Parts is the number of ranges
+	Parts int
+	// Column is the NOT NULL column the ranges are taken from, the first column of the primary key of the source table by default
+	Column string
+}
+
+// partitioned reports whether the table is copied in ranges. Merges load a single staging table, insert-select copies in a single statement
+// and samples are taken over the whole table, so those are copied by a single reader.
+func (ct *CopyTask) partitioned() bool {
+	if ct.opts.Partitions[ct.table.String()].Parts <= 1 {
+		return false
+	}
+
+	if ct.opts.SampleRows > 0 || ct.opts.SamplePercent > 0 {
+		return false
+	}
+
+	switch ct.opts.Mode {
+	case ModeTruncate, ModeAppend, ModeDelete, ModeIncremental:
+	default:
+		return false
+	}
+
+	return ct.strategy == StrategyBulk || ct.strategy == StrategyInsert
+}
+
+// partitionRanges returns the conditions selecting the ranges of the rows selected by readOpts, a single range without conditions
+// when the table has no column to partition on
+func (ct *CopyTask) partitionRanges(ctx context.Context, readOpts mssql.ReadOptions) ([][]mssql.Condition, error) {
+	partitioning := ct.opts.Partitions[ct.table.String()]
+
+	column := partitioning.Column
+	if column == "" {
+		primaryKey, err := ct.sourceDB.GetPrimaryKey(ctx, ct.table)
+		if err != nil {
+			return nil, err
+		}
+		if len(primaryKey) == 0 {
+			return [][]mssql.Condition{{}}, nil
+		}
+		column = primaryKey[0]
+	}
+
+	boundaries, err := ct.sourceDB.PartitionBoundaries(ctx, ct.table, column, partitioning.Parts, readOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return mssql.PartitionConditions(column, boundaries), nil
+}
+
+// copyPartitioned prepares the target once, copies the ranges of the table concurrently and restores the target when all ranges are loaded.
+// The ranges commit on their own, a failing range stops the others.
+func (ct *CopyTask) copyPartitioned(ctx context.Context, columns []string, schema mssql.SchemaDefinition) {
+	defer ct.wg.Done()
+	defer ct.wg.Done()
+	defer ct.restoreOnFailure()
+
+	readOpts, err := ct.readOptions(ctx)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to get the watermark for table %s from the targetDB, %w", ct.table, err))
+		return
+	}
+
+	numberOfRows, approximate, err := ct.count(ctx, readOpts)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to get count for table %s from the sourceDB, %w", ct.table, err))
+		return
+	}
+	ct.eventChan <- monitor.CountUpdateEvent{TotalRows: numberOfRows, Table: ct.table, Approximate: approximate}
+
+	// a resumed table is split into the ranges of the run it continues, the target already holds the rows of its finished ranges
+	ranges := ct.resumedRanges(columns)
+	resumed := len(ranges) > 0
+	if !resumed {
+		ranges, err = ct.partitionRanges(ctx, readOpts)
+		if err != nil {
+			ct.fail(fmt.Errorf("Failed to partition source table %s, %w", ct.table, err))
+			return
+		}
+	}
+
+	err = ct.startLoad(ctx, !resumed)
+	if err != nil {
+		ct.fail(err)
+		return
+	}
+
+	// recorded after the target is emptied, so a crash before it does not skip ranges of the rows that were removed
+	if ct.resumeRanges && !resumed {
+		err = ct.opts.Checkpoints.SetPartitions(ct.table, ranges)
+		if err != nil {
+			ct.fail(fmt.Errorf("Failed to record the ranges of table %s in the checkpoints, %w", ct.table, err))
+			return
+		}
+	}
+
+	rangeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writers := make([]rowWriter, len(ranges))
+	var failure error
+	var once sync.Once
+	wg := sync.WaitGroup{}
+	for i, conditions := range ranges {
+		rangeKey := strconv.Itoa(i)
+		if resumed && ct.opts.Checkpoints.IsRangeDone(ct.table, rangeKey) {
+			continue
+		}
+
+		rangeOpts := readOpts
+		rangeOpts.Conditions = append(append([]mssql.Condition{}, readOpts.Conditions...), conditions...)
+
+		wg.Add(1)
+		go func(i int, conditions []mssql.Condition) {
+			defer wg.Done()
+
+			var writer rowWriter
+			err := ct.restartRange(rangeCtx, conditions, resumed)
+			if err == nil {
+				writer, err = ct.copyRange(rangeCtx, columns, schema, rangeOpts)
+			}
+			if err == nil && ct.resumeRanges {
+				err = ct.opts.Checkpoints.MarkRangeDone(ct.table, rangeKey)
+			}
+			if err != nil {
+				// the errors of the other ranges are caused by the cancellation
+				once.Do(func() {
+					failure = err
+					cancel()
+				})
+				return
+			}
+			writers[i] = writer
+		}(i, conditions)
+	}
+	wg.Wait()
+	ct.progress.flush()
+
+	if failure != nil {
+		ct.fail(failure)
+		return
+	}
+
+	err = ct.finishLoad(ctx, writers)
+	if err != nil {
+		ct.fail(err)
+		return
+	}
+
+	ct.eventChan <- monitor.CopyTaskFinishedEvent{Table: ct.table}
+}
+
+// resumedRanges returns the ranges recorded by the run a resumed table continues, nil when the table is copied from the start
+func (ct *CopyTask) resumedRanges(columns []string) [][]mssql.Condition {
+	if !ct.resumeRanges {
+		return nil
+	}
+
+	ranges := ct.opts.Checkpoints.Partitions(ct.table)
+	for _, conditions := range ranges {
+		// the rows of an unfinished range are deleted by the column of the range, which has to be copied
+		for _, condition := range conditions {
+			if !slices.ContainsFunc(columns, func(column string) bool { return strings.EqualFold(column, condition.Column) }) {
+				return nil
+			}
+		}
+	}
+
+	return ranges
+}
+
+// restartRange deletes the rows an unfinished range of a resumed table committed before the run it continues failed,
+// appended rows can not be told apart from the rows already in the target and are copied again
+func (ct *CopyTask) restartRange(ctx context.Context, conditions []mssql.Condition, resumed bool) error {
+	if !resumed {
+		return nil
+	}
+
+	var err error
+	switch {
+	case ct.opts.Mode == ModeDelete:
+		err = ct.retryLocked(ctx, "delete", func() error {
+			return ct.targetDB.DeleteRange(ctx, ct.target, mssql.ReadOptions{QueryFilter: ct.opts.QueryFilter, Conditions: conditions})
+		})
+	case ct.opts.Mode == ModeTruncate && len(conditions) == 0:
+		err = ct.retryLocked(ctx, "delete", func() error { return ct.targetDB.DeleteAll(ctx, ct.target) })
+	case ct.opts.Mode == ModeTruncate:
+		err = ct.retryLocked(ctx, "delete", func() error {
+			return ct.targetDB.DeleteRange(ctx, ct.target, mssql.ReadOptions{Conditions: conditions})
+		})
+	default:
+		log.Printf("WARNING: the rows of an unfinished range of table %s that committed before the previous run failed are appended again", ct.table)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to delete the rows of an unfinished range from target table %s, %s", ct.table, err)
+	}
+
+	return nil
+}
+
+// startLoad prepares the target of the writers of a partitioned or fanned out table before any of them inserts. The target of
+// a resumed table is not emptied when empty is unset, it takes over the foreign keys of the run it continues.
+func (ct *CopyTask) startLoad(ctx context.Context, empty bool) error {
+	if ct.opts.Mode == ModeTruncate || ct.opts.Mode == ModeDelete {
+		prepare := ct.prepareTarget
+		if !empty {
+			prepare = ct.resumeForeignKeys
+		}
+		err := prepare(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.disablesTriggers {
+		err := ct.disableTriggers(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ct.opts.DisableIndexes {
+		err := ct.disableIndexes(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finishLoad restores the target when all writers committed and checks the rows they rejected and the rows in the target
+func (ct *CopyTask) finishLoad(ctx context.Context, writers []rowWriter) error {
+	err := ct.finishTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	// checked after the foreign keys are restored, the accepted rows are committed either way
+	for _, writer := range writers {
+		// the ranges finished by a previous run have no writer
+		if writer == nil {
+			continue
+		}
+		err = ct.checkAccepted(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ct.verifyCount(ctx)
+}
+
+// copyRange streams the rows selected by readOpts into the target table with a reader and a writer of its own,
+// it returns the writer of the committed rows
+func (ct *CopyTask) copyRange(ctx context.Context, columns []string, schema mssql.SchemaDefinition, readOpts mssql.ReadOptions) (rowWriter, error) {
+	writer, err := ct.rowWriter(ctx, ct.target, columns, schema)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to prepare the inserts into target table %s, %s", ct.table, err)
+	}
+
+	rows, err := ct.sourceDB.SelectFrom(ctx, ct.table, columns, readOpts)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to select data from source table %s, %s", ct.table, err)
+	}
+	defer rows.Close()
+
+	readCtx, stopReading := context.WithCancel(ctx)
+	defer stopReading()
+
+	dataChan := make(chan rowBatch, ct.opts.bufferBatches())
+	budget := newByteBudget(ct.opts.BufferBytes)
+	var readErr error
+	go func() {
+		defer close(dataChan)
+		readErr = ct.readRange(readCtx, rows, columns, dataChan, budget)
+	}()
+
+	var lastKey []string
+	err = ct.insertBatches(ctx, writer, columns, dataChan, budget, &lastKey)
+	if err != nil {
+		stopReading()
+		// the reader stops at its next batch
+		for range dataChan {
+		}
+		return nil, err
+	}
+
+	if readErr != nil {
+		writer.Rollback(ctx)
+		return nil, readErr
+	}
+
+	err = ct.commitRows(ctx, writer, columns, &lastKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to commit the transaction into target table %s, %s", ct.table, err)
+	}
+
+	return writer, nil
+}
+
+// insertBatches inserts the batches of dataChan until it is closed, the writer is rolled back when a batch fails
+func (ct *CopyTask) insertBatches(ctx context.Context, writer rowWriter, columns []string, dataChan <-chan rowBatch, budget *byteBudget, lastKey *[]string) error {
+	for batch := range dataChan {
+		err := ct.throttle(ctx, len(batch.rows), batch.size)
+		if err == nil {
+			err = ct.insertBatch(ctx, writer, columns, batch.rows, lastKey)
+		}
+		if err != nil && ctx.Err() == nil {
+			err = ct.retryPending(ctx, writer, columns, err, false, lastKey)
+		}
+		if err != nil && ctx.Err() == nil && ct.opts.MaxErrors > 0 {
+			err = ct.rejectPending(ctx, writer, columns, err, lastKey)
+		}
+		if err != nil {
+			writer.Rollback(ctx)
+			return err
+		}
+		ct.progress.add(len(batch.rows), batch.size)
+		budget.release(batch.size)
+	}
+
+	return nil
+}
+
+// commitRows commits the pending rows of writer, retrying or rejecting them when the commit fails
+func (ct *CopyTask) commitRows(ctx context.Context, writer rowWriter, columns []string, lastKey *[]string) error {
+	err := writer.Commit(ctx)
+	if err != nil {
+		err = ct.retryPending(ctx, writer, columns, err, true, lastKey)
+	}
+	if err != nil && ct.opts.MaxErrors > 0 {
+		err = ct.rejectPending(ctx, writer, columns, err, lastKey)
+	}
+
+	return err
+}
+
+// readRange sends the transformed rows of a range to dataChan in batches, within the budget of buffered bytes
+func (ct *CopyTask) readRange(ctx context.Context, rows *mssql.RowIterator, columns []string, dataChan chan<- rowBatch, budget *byteBudget) error {
+	for {
+		batch, err := rows.NextBatch(rowBatchSize)
+		if err != nil {
+			return fmt.Errorf("Failed to get the Next row from the source table %s, %s", ct.table, err)
+		}
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		transformed := batch[:0]
+		for _, values := range batch {
+			values, skip, err := ct.transform(columns, values)
+			if err != nil {
+				return fmt.Errorf("Failed to transform a row from the source table %s, %s", ct.table, err)
+			}
+
+			if skip {
+				ct.skipped.Add(1)
+				continue
+			}
+			transformed = append(transformed, values)
+		}
+
+		if len(transformed) == 0 {
+			continue
+		}
+
+		buffered := newRowBatch(transformed)
+		err = budget.acquire(ctx, buffered.size)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case dataChan <- buffered:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
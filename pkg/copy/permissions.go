@@ -0,0 +1,134 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// Permissions are the permissions the copy needs in the source and the target, checked for the users the copy connects as
+type Permissions struct {
+	SourceUser string
+	TargetUser string
+	Source     []mssql.PermissionCheck
+	Target     []mssql.PermissionCheck
+}
+
+// Missing returns the permissions of checks that are not granted, the optional ones only when optional is set.
+// Permissions on tables that do not exist are left out.
+func Missing(checks []mssql.PermissionCheck, optional bool) []mssql.PermissionCheck {
+	missing := make([]mssql.PermissionCheck, 0)
+	for _, check := range checks {
+		if check.Granted || check.Unknown || (check.Optional && !optional) {
+			continue
+		}
+		missing = append(missing, check)
+	}
+
+	return missing
+}
+
+// CheckPermissions checks the permissions the copy of the tables needs with the options of the engine, before anything is changed
+func (e *Engine) CheckPermissions(ctx context.Context, tables []mssql.TableRef) (Permissions, error) {
+	permissions := Permissions{}
+	permissions.Source, permissions.Target = e.requiredPermissions(tables)
+
+	var err error
+	permissions.SourceUser, err = e.sourceDB.CurrentUser(ctx)
+	if err != nil {
+		return Permissions{}, fmt.Errorf("failed to get the user of the source, %w", err)
+	}
+
+	err = e.sourceDB.CheckPermissions(ctx, permissions.Source)
+	if err != nil {
+		return Permissions{}, fmt.Errorf("failed to check the permissions in the source, %w", err)
+	}
+
+	permissions.TargetUser, err = e.targetDB.CurrentUser(ctx)
+	if err != nil {
+		return Permissions{}, fmt.Errorf("failed to get the user of the target, %w", err)
+	}
+
+	err = e.targetDB.CheckPermissions(ctx, permissions.Target)
+	if err != nil {
+		return Permissions{}, fmt.Errorf("failed to check the permissions in the target, %w", err)
+	}
+
+	return permissions, nil
+}
+
+// requiredPermissions returns the permissions the copy of the tables needs in the source and the target
+func (e *Engine) requiredPermissions(tables []mssql.TableRef) ([]mssql.PermissionCheck, []mssql.PermissionCheck) {
+	source := []mssql.PermissionCheck{
+		{Class: mssql.PermissionDatabase, Permission: "VIEW DATABASE STATE", Reason: "to take the row counts from the partition statistics instead of counting the rows", Optional: true},
+	}
+	target := make([]mssql.PermissionCheck, 0)
+
+	staging := false
+	schemas := make([]string, 0)
+	for _, table := range tables {
+		source = append(source,
+			mssql.PermissionCheck{Class: mssql.PermissionObject, Securable: table.String(), Permission: "SELECT", Reason: "to read the rows"},
+			mssql.PermissionCheck{Class: mssql.PermissionObject, Securable: table.String(), Permission: "VIEW DEFINITION", Reason: "to read the definition of the table", Optional: !e.opts.CreateTables},
+		)
+
+		targetTable := e.opts.targetTable(table)
+		check := func(permission, reason string) {
+			target = append(target, mssql.PermissionCheck{Class: mssql.PermissionObject, Securable: targetTable.String(), Permission: permission, Reason: reason})
+		}
+
+		strategy := e.opts.Strategies[table.String()]
+		merge := strategy == StrategyMerge || e.opts.Mode == ModeMerge || e.opts.Mode == ModeSync
+		staging = staging || merge
+		if !slices.Contains(schemas, targetTable.Schema) {
+			schemas = append(schemas, targetTable.Schema)
+		}
+
+		check("INSERT", "to load the rows")
+		if reason := e.alterReason(strategy, merge); reason != "" {
+			check("ALTER", reason)
+		}
+		if e.opts.Mode == ModeDelete || e.opts.Mode == ModeSync {
+			check("DELETE", "to delete the rows that are replaced or were deleted in the source")
+		}
+		if merge {
+			check("UPDATE", "to update the rows matching a source row")
+		}
+		if merge || e.opts.Mode == ModeIncremental || e.opts.VerifyCounts {
+			check("SELECT", "to read the watermark, match the rows or count them")
+		}
+	}
+
+	if e.opts.CreateTables || staging {
+		reason := "to create the staging tables of merges"
+		if e.opts.CreateTables {
+			reason = "to create the tables missing in the target"
+		}
+		target = append(target, mssql.PermissionCheck{Class: mssql.PermissionDatabase, Permission: "CREATE TABLE", Reason: reason})
+		for _, schema := range schemas {
+			target = append(target, mssql.PermissionCheck{Class: mssql.PermissionSchema, Securable: schema, Permission: "ALTER", Reason: reason})
+		}
+	}
+
+	return source, target
+}
+
+// alterReason returns what the load of a table needs ALTER on the target table for, or nothing when it does not
+func (e *Engine) alterReason(strategy Strategy, merge bool) string {
+	switch {
+	case e.opts.Mode == ModeTruncate:
+		return "to truncate the table"
+	case !merge && (strategy == "" || strategy == StrategyBulk):
+		return "to bulk load the rows with their identity values and without checking the constraints"
+	case e.opts.Triggers == TriggersDisable:
+		return "to disable the triggers"
+	case e.opts.DisableIndexes:
+		return "to disable the indexes"
+	case e.opts.ReseedIdentity:
+		return "to reseed the identity"
+	}
+
+	return ""
+}
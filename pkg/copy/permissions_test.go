@@ -0,0 +1,50 @@
+package copy
+
+import (
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredPermissions(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "sales", Table: "lines"}
+
+	e := NewEngine(nil, nil, Options{Mode: ModeTruncate}, nil)
+	source, target := e.requiredPermissions([]mssql.TableRef{orders})
+	assert.Equal(t, []mssql.PermissionCheck{
+		{Class: mssql.PermissionDatabase, Permission: "VIEW DATABASE STATE", Reason: "to take the row counts from the partition statistics instead of counting the rows", Optional: true},
+		{Class: mssql.PermissionObject, Securable: "[dbo].[orders]", Permission: "SELECT", Reason: "to read the rows"},
+		{Class: mssql.PermissionObject, Securable: "[dbo].[orders]", Permission: "VIEW DEFINITION", Reason: "to read the definition of the table", Optional: true},
+	}, source)
+	assert.Equal(t, []mssql.PermissionCheck{
+		{Class: mssql.PermissionObject, Securable: "[dbo].[orders]", Permission: "INSERT", Reason: "to load the rows"},
+		{Class: mssql.PermissionObject, Securable: "[dbo].[orders]", Permission: "ALTER", Reason: "to truncate the table"},
+	}, target)
+
+	// merges create staging tables, insert statements do not need ALTER
+	e = NewEngine(nil, nil, Options{Mode: ModeAppend, Strategies: map[string]Strategy{orders.String(): StrategyMerge, lines.String(): StrategyInsert}}, nil)
+	_, target = e.requiredPermissions([]mssql.TableRef{orders, lines})
+	permissions := make([]string, len(target))
+	for i, check := range target {
+		permissions[i] = check.Permission + " " + check.On()
+	}
+	assert.Equal(t, []string{
+		"INSERT [dbo].[orders]", "UPDATE [dbo].[orders]", "SELECT [dbo].[orders]",
+		"INSERT [sales].[lines]",
+		"CREATE TABLE the database", "ALTER schema dbo", "ALTER schema sales",
+	}, permissions)
+}
+
+func TestMissingPermissions(t *testing.T) {
+	checks := []mssql.PermissionCheck{
+		{Permission: "SELECT", Granted: true},
+		{Permission: "INSERT"},
+		{Permission: "VIEW DATABASE STATE", Optional: true},
+		{Permission: "ALTER", Unknown: true},
+	}
+
+	assert.Equal(t, []mssql.PermissionCheck{{Permission: "INSERT"}}, Missing(checks, false))
+	assert.Len(t, Missing(checks, true), 2)
+}
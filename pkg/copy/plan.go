@@ -0,0 +1,212 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// TablePlan describes what copying a table would do to the target
+type TablePlan struct {
+	Table mssql.TableRef `json:"table"`
+	// Target is the table in the target the table is copied into
+	Target   mssql.TableRef `json:"target"`
+	Mode     Mode           `json:"mode"`
+	Strategy Strategy       `json:"strategy"`
+	// Create is set when the table is missing in the target and would be created
+	Create        bool `json:"create"`
+	SchemaMatches bool `json:"schema_matches"`
+	// SourceSchema and TargetSchema are the fingerprints of the columns of the table, TargetSchema is empty when it is created
+	SourceSchema   string                       `json:"source_schema"`
+	TargetSchema   string                       `json:"target_schema,omitempty"`
+	Rows           int                          `json:"rows"`
+	Approximate    bool                         `json:"rows_approximate"`
+	EmptyAction    string                       `json:"empty_action"`
+	ForeignKeys    []mssql.ForeingKeyConstraint `json:"foreign_keys,omitempty"`
+	ForeignKeyPlan string                       `json:"foreign_key_plan,omitempty"`
+	// Indexes are disabled during the load and rebuilt afterwards
+	Indexes []mssql.Index `json:"indexes,omitempty"`
+	// Triggers are disabled during the load and enabled afterwards
+	Triggers []mssql.Trigger `json:"triggers,omitempty"`
+	// Partitions is the number of ranges copied concurrently, 0 when the table is copied by a single reader
+	Partitions int `json:"partitions,omitempty"`
+	// Writers is the number of concurrent bulk copies inserting the rows, 0 when the table is inserted by a single writer
+	Writers int   `json:"writers,omitempty"`
+	Err     error `json:"-"`
+}
+
+// Plan resolves what a run would do for every table without modifying the target. Tables copied in dependency order are
+// planned in the order they are copied.
+func (e *Engine) Plan(ctx context.Context, tables []mssql.TableRef) []TablePlan {
+	if e.opts.DependencyOrder && e.opts.Mode == ModeTruncate {
+		order, err := e.planCopyOrder(ctx, tables)
+		if err != nil {
+			plans := make([]TablePlan, len(tables))
+			for i, table := range tables {
+				plans[i] = TablePlan{Table: table, Target: e.opts.targetTable(table), Mode: e.opts.Mode, Err: err}
+			}
+			return plans
+		}
+
+		plans := make(map[string]TablePlan, len(tables))
+		for _, table := range tables {
+			plans[table.String()] = e.planTable(ctx, table)
+		}
+		return orderPlans(plans, order)
+	}
+
+	plans := make([]TablePlan, len(tables))
+	for i, table := range tables {
+		plans[i] = e.planTable(ctx, table)
+	}
+
+	return plans
+}
+
+// orderPlans returns the plans by table in the order of runInDependencyOrder. The tables it empties before the copy keep
+// the foreign keys referencing them, those referenced by other tables of the copy are deleted from as they can not be truncated.
+func orderPlans(plans map[string]TablePlan, order dependencyPlan) []TablePlan {
+	ordered := make([]TablePlan, 0, len(plans))
+	for _, level := range order.levels {
+		for _, table := range level {
+			plan := plans[table.String()]
+			if plan.Err == nil && !plan.Create && !order.fallback[table.String()] {
+				plan.ForeignKeys = nil
+				plan.ForeignKeyPlan = ""
+				plan.EmptyAction = "TRUNCATE before the copy, after the tables referencing it"
+				if order.referenced[table.String()] {
+					plan.EmptyAction = "DELETE all rows before the copy, after the tables referencing it"
+				}
+			}
+			ordered = append(ordered, plan)
+		}
+	}
+
+	return ordered
+}
+
+func (e *Engine) planTable(ctx context.Context, table mssql.TableRef) TablePlan {
+	task := NewCopyTask(table, e.sourceDB, e.targetDB, e.opts, nil)
+	plan := TablePlan{Table: table, Target: task.target, Mode: task.opts.Mode, Strategy: task.strategy}
+	if task.partitioned() {
+		plan.Partitions = e.opts.Partitions[table.String()].Parts
+	}
+	if task.fannedOut() {
+		plan.Writers = e.opts.Writers[table.String()]
+	}
+
+	exists, err := e.targetDB.TableExists(ctx, e.opts.targetTable(table))
+	if err != nil {
+		plan.Err = fmt.Errorf("failed to check whether the table exists in the target, %w", err)
+		return plan
+	}
+
+	if !exists {
+		if !e.opts.CreateTables {
+			plan.Err = fmt.Errorf("the table does not exist in the target")
+			return plan
+		}
+
+		sourceSchema, err := e.sourceDB.GetSchemaDefinition(ctx, table)
+		if err != nil {
+			plan.Err = fmt.Errorf("failed to get the schema from the source, %w", err)
+			return plan
+		}
+
+		plan.Create = true
+		plan.SchemaMatches = true
+		plan.SourceSchema = sourceSchema.Fingerprint()
+		plan.EmptyAction = "none, the table is created from the source definition"
+		plan.Rows, plan.Approximate, err = task.count(ctx, e.opts.filter(table))
+		if err != nil {
+			plan.Err = fmt.Errorf("failed to count the source rows, %w", err)
+		}
+		return plan
+	}
+
+	targetSchema, err := e.targetDB.GetSchemaDefinition(ctx, e.opts.targetTable(table))
+	if err != nil {
+		plan.Err = fmt.Errorf("failed to get the schema from the target, %w", err)
+		return plan
+	}
+
+	sourceSchema, err := e.sourceDB.GetSchemaDefinition(ctx, table)
+	if err != nil {
+		plan.Err = fmt.Errorf("failed to get the schema from the source, %w", err)
+		return plan
+	}
+
+	_, err = e.opts.copyColumns(table, sourceSchema, targetSchema)
+	plan.SchemaMatches = err == nil
+	plan.SourceSchema = sourceSchema.Fingerprint()
+	plan.TargetSchema = targetSchema.Fingerprint()
+
+	if plan.Mode == ModeSync {
+		// reading the sync state would create the state table, so the number of changes is not known up front
+		plan.EmptyAction = "apply the changes since the last sync"
+		return plan
+	}
+
+	readOpts, err := task.readOptions(ctx)
+	if err != nil {
+		plan.Err = fmt.Errorf("failed to get the watermark from the target, %w", err)
+		return plan
+	}
+
+	plan.Rows, plan.Approximate, err = task.count(ctx, readOpts)
+	if err != nil {
+		plan.Err = fmt.Errorf("failed to count the source rows, %w", err)
+		return plan
+	}
+
+	switch plan.Mode {
+	case ModeTruncate:
+		plan.EmptyAction = "TRUNCATE"
+		if e.opts.DisableForeignKeys {
+			plan.EmptyAction = "DELETE all rows"
+		}
+	case ModeDelete:
+		where, err := mssql.FilterSQL(e.opts.QueryFilter)
+		if err != nil {
+			plan.Err = err
+			return plan
+		}
+		plan.EmptyAction = "DELETE WHERE " + where
+	case ModeMerge:
+		plan.EmptyAction = "none, rows are merged on the primary key"
+	default:
+		plan.EmptyAction = "none"
+	}
+
+	if plan.Mode == ModeTruncate || plan.Mode == ModeDelete {
+		plan.ForeignKeys, err = e.targetDB.GetReferencedForeignKeys(ctx, e.opts.targetTable(table))
+		if err != nil {
+			plan.Err = fmt.Errorf("failed to get the foreign keys referencing the table, %w", err)
+			return plan
+		}
+
+		plan.ForeignKeyPlan = "drop and recreate WITH NOCHECK"
+		if e.opts.DisableForeignKeys {
+			plan.ForeignKeyPlan = "disable and enable"
+		}
+	}
+
+	if e.opts.Triggers == TriggersDisable {
+		plan.Triggers, err = e.targetDB.GetTriggers(ctx, e.opts.targetTable(table))
+		if err != nil {
+			plan.Err = fmt.Errorf("failed to get the triggers of the table, %w", err)
+			return plan
+		}
+	}
+
+	if e.opts.DisableIndexes && plan.Mode != ModeMerge {
+		plan.Indexes, err = e.targetDB.GetNonclusteredIndexes(ctx, e.opts.targetTable(table))
+		if err != nil {
+			plan.Err = fmt.Errorf("failed to get the indexes of the table, %w", err)
+			return plan
+		}
+	}
+
+	return plan
+}
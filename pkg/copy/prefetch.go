@@ -0,0 +1,144 @@
+package copy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// Metadata holds the row counts, data sizes and foreign keys loaded by Prefetch, a nil Metadata has nothing loaded
+type Metadata struct {
+	lock sync.Mutex
+
+	counts                map[string]int
+	sizes                 map[string]int64
+	referencedForeignKeys map[string][]mssql.ForeingKeyConstraint
+}
+
+// Count returns the approximate source row count of table
+func (m *Metadata) Count(table mssql.TableRef) (int, bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	count, ok := m.counts[table.String()]
+	return count, ok
+}
+
+// Size returns the number of bytes used by the rows of table in the source
+func (m *Metadata) Size(table mssql.TableRef) (int64, bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	size, ok := m.sizes[table.String()]
+	return size, ok
+}
+
+// ReferencedForeignKeys returns the foreign keys referencing table in the target
+func (m *Metadata) ReferencedForeignKeys(table mssql.TableRef) ([]mssql.ForeingKeyConstraint, bool) {
+	if m == nil {
+		return nil, false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	fks, ok := m.referencedForeignKeys[table.String()]
+	return fks, ok
+}
+
+// Prefetch loads the metadata of all tables with at most parrallel tables at the same time, before any table is copied.
+// Schema definitions and primary keys end up in the caches of the databases, the row counts and foreign keys
+// in the returned Metadata. Tables missing in the target get an empty target schema until they are created.
+// targets holds the table in the target per source table like Options.Targets. progress is called after each table.
+func Prefetch(ctx context.Context, sourceDB *mssql.MSSQLDB, targetDB *mssql.MSSQLDB, tables []mssql.TableRef, targets map[string]mssql.TableRef, parrallel int, progress func(table mssql.TableRef)) (*Metadata, error) {
+	metadata := &Metadata{
+		counts:                make(map[string]int, len(tables)),
+		sizes:                 make(map[string]int64, len(tables)),
+		referencedForeignKeys: make(map[string][]mssql.ForeingKeyConstraint, len(tables)),
+	}
+
+	if parrallel < 1 {
+		parrallel = 1
+	}
+
+	slots := make(chan struct{}, parrallel)
+	errs := make([]error, len(tables))
+	wg := sync.WaitGroup{}
+	for i, table := range tables {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := metadata.load(ctx, sourceDB, targetDB, table, Options{Targets: targets}.targetTable(table))
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to load the metadata of %s, %w", table, err)
+			}
+
+			if progress != nil {
+				progress(table)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return metadata, errors.Join(errs...)
+}
+
+// load loads the metadata of table, which is copied into target
+func (m *Metadata) load(ctx context.Context, sourceDB *mssql.MSSQLDB, targetDB *mssql.MSSQLDB, table, target mssql.TableRef) error {
+	sides := []struct {
+		db    *mssql.MSSQLDB
+		table mssql.TableRef
+	}{{sourceDB, table}, {targetDB, target}}
+	for _, side := range sides {
+		if _, err := side.db.GetSchemaDefinition(ctx, side.table); err != nil {
+			return err
+		}
+
+		if _, err := side.db.GetPrimaryKey(ctx, side.table); err != nil {
+			return err
+		}
+	}
+
+	// the foreign keys referencing target, by the name of the source table
+	fks, err := targetDB.GetReferencedForeignKeys(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	m.referencedForeignKeys[table.String()] = fks
+	m.lock.Unlock()
+
+	// reading partition stats requires VIEW DATABASE STATE, the tasks count the rows themselves without it
+	count, err := sourceDB.GetApproximateCount(ctx, table)
+	if err != nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	m.counts[table.String()] = count
+	m.lock.Unlock()
+
+	size, err := sourceDB.GetDataSize(ctx, table)
+	if err == nil {
+		m.lock.Lock()
+		m.sizes[table.String()] = size
+		m.lock.Unlock()
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package copy
+
+import (
+	"context"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// TableEstimate is the estimated number of rows the copy of a table reads, Err is set when its select does not compile
+type TableEstimate struct {
+	Table mssql.TableRef
+	Rows  float64
+	Err   error
+}
+
+// Preflight compiles the select of every table against the source without reading any rows,
+// so errors in the query filter are found before anything is changed in the target
+func (e *Engine) Preflight(ctx context.Context, tables []mssql.TableRef) []TableEstimate {
+	estimates := make([]TableEstimate, len(tables))
+	for i, table := range tables {
+		estimates[i].Table = table
+		estimates[i].Rows, estimates[i].Err = e.sourceDB.EstimateRows(ctx, table, e.opts.filter(table))
+	}
+
+	return estimates
+}
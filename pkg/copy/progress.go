@@ -0,0 +1,76 @@
+package copy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+const (
+	// DefaultProgressRows and DefaultProgressInterval are the granularity of the progress of a table by default
+	DefaultProgressRows     = 10_000
+	DefaultProgressInterval = 100 * time.Millisecond
+)
+
+// progress coalesces the rows copied of a table into a ProgressUpdateEvent every ProgressRows rows or ProgressInterval,
+// whichever comes first. It is shared by the writers of a partitioned table.
+type progress struct {
+	lock      sync.Mutex
+	eventChan chan<- monitor.Event
+	table     mssql.TableRef
+	every     int
+	interval  time.Duration
+	// rows and bytes are copied but not reported yet, last is the time of the last report
+	rows  int
+	bytes int64
+	last  time.Time
+}
+
+func newProgress(table mssql.TableRef, opts Options, eventChan chan<- monitor.Event) *progress {
+	every := opts.ProgressRows
+	if every <= 0 {
+		every = DefaultProgressRows
+	}
+	interval := opts.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	return &progress{eventChan: eventChan, table: table, every: every, interval: interval, last: time.Now()}
+}
+
+// add records copied rows of the given approximate size, they are reported once enough rows are copied or enough time has passed
+func (p *progress) add(rows int, bytes int64) {
+	rows, bytes = p.take(rows, bytes, time.Now(), false)
+	if rows > 0 {
+		p.eventChan <- monitor.ProgressUpdateEvent{RowsCopied: rows, BytesCopied: bytes, Table: p.table}
+	}
+}
+
+// flush reports the rows not reported yet, before the table is finished
+func (p *progress) flush() {
+	rows, bytes := p.take(0, 0, time.Now(), true)
+	if rows > 0 {
+		p.eventChan <- monitor.ProgressUpdateEvent{RowsCopied: rows, BytesCopied: bytes, Table: p.table}
+	}
+}
+
+// take adds rows and returns the rows and bytes to report, the event is sent without holding the lock
+func (p *progress) take(rows int, bytes int64, now time.Time, all bool) (int, int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.rows += rows
+	p.bytes += bytes
+	if !all && p.rows < p.every && now.Sub(p.last) < p.interval {
+		return 0, 0
+	}
+
+	rows, bytes = p.rows, p.bytes
+	p.rows, p.bytes = 0, 0
+	p.last = now
+
+	return rows, bytes
+}
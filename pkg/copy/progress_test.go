@@ -0,0 +1,37 @@
+package copy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	table := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	events := make(chan monitor.Event, 10)
+	p := newProgress(table, Options{ProgressRows: 100, ProgressInterval: time.Hour}, events)
+
+	p.add(60, 600)
+	assert.Empty(t, events)
+	p.add(60, 600)
+	assert.Equal(t, monitor.ProgressUpdateEvent{RowsCopied: 120, BytesCopied: 1200, Table: table}, <-events)
+
+	p.add(10, 100)
+	assert.Empty(t, events)
+	p.flush()
+	assert.Equal(t, monitor.ProgressUpdateEvent{RowsCopied: 10, BytesCopied: 100, Table: table}, <-events)
+
+	// nothing left to report
+	p.flush()
+	assert.Empty(t, events)
+
+	// a slow table is reported every interval
+	rows, _ := p.take(1, 10, p.last.Add(time.Minute), false)
+	assert.Equal(t, 0, rows)
+	rows, bytes := p.take(1, 10, p.last.Add(time.Hour), false)
+	assert.Equal(t, 2, rows)
+	assert.Equal(t, int64(20), bytes)
+}
@@ -0,0 +1,136 @@
+package copy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// Rejects collects the rows the target rejected, shared by the tasks of a run
+type Rejects struct {
+	lock    *sync.Mutex
+	encoder *json.Encoder
+	count   int
+}
+
+// rejectedRow is a line of the reject file
+type rejectedRow struct {
+	Table string                 `json:"table"`
+	Error string                 `json:"error"`
+	Row   map[string]interface{} `json:"row"`
+}
+
+// NewRejects returns the rejects written to w as JSON lines of the table, the error and the row by column name,
+// without a writer the rejected rows are only counted
+func NewRejects(w io.Writer) *Rejects {
+	r := &Rejects{lock: &sync.Mutex{}}
+	if w != nil {
+		r.encoder = json.NewEncoder(w)
+		r.encoder.SetEscapeHTML(false)
+	}
+
+	return r
+}
+
+// Add records a rejected row of table
+func (r *Rejects) Add(table mssql.TableRef, columns []string, row []interface{}, rowErr error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.count++
+	if r.encoder == nil {
+		return nil
+	}
+
+	values := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		values[column] = row[i]
+	}
+
+	return r.encoder.Encode(rejectedRow{Table: table.String(), Error: rowErr.Error(), Row: values})
+}
+
+// Count returns the number of rows rejected
+func (r *Rejects) Count() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.count
+}
+
+// rejectPending inserts the rows of the batch of writer that failed with batchErr again in halves, so the rows that fail on their own
+// are rejected and the others are committed. The table fails when more than MaxErrors of its rows are rejected. Only failures caused by
+// the values of a row are split up, the others like cancellations, lost connections and throttling are returned as they are.
+func (ct *CopyTask) rejectPending(ctx context.Context, writer rowWriter, columns []string, batchErr error, lastKey *[]string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if !mssql.IsRowError(batchErr) {
+		return batchErr
+	}
+
+	pending := writer.Pending()
+	writer.Rollback(ctx)
+
+	return ct.insertHalves(ctx, writer, columns, pending, lastKey)
+}
+
+func (ct *CopyTask) insertHalves(ctx context.Context, writer rowWriter, columns []string, rows [][]interface{}, lastKey *[]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	err := ct.insertBatch(ctx, writer, columns, rows, lastKey)
+	if err == nil {
+		err = writer.Commit(ctx)
+	}
+	if err == nil {
+		return nil
+	}
+	writer.Rollback(ctx)
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if !mssql.IsRowError(err) {
+		return err
+	}
+
+	if len(rows) == 1 {
+		return ct.reject(columns, rows[0], err)
+	}
+
+	half := len(rows) / 2
+	err = ct.insertHalves(ctx, writer, columns, rows[:half], lastKey)
+	if err != nil {
+		return err
+	}
+
+	return ct.insertHalves(ctx, writer, columns, rows[half:], lastKey)
+}
+
+func (ct *CopyTask) reject(columns []string, row []interface{}, rowErr error) error {
+	ct.rejectLock.Lock()
+	ct.rejected++
+	rejected := ct.rejected
+	ct.rejectLock.Unlock()
+
+	if rejected > ct.opts.MaxErrors {
+		return fmt.Errorf("More than %d rows of table %s were rejected, the last one with %s", ct.opts.MaxErrors, ct.table, rowErr)
+	}
+
+	if ct.opts.Rejects == nil {
+		return nil
+	}
+
+	err := ct.opts.Rejects.Add(ct.table, columns, row, rowErr)
+	if err != nil {
+		return fmt.Errorf("Failed to write a rejected row of table %s, %s", ct.table, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package copy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// restoreTimeout bounds the restore of the target of a failed table, which runs when the copy context may be cancelled already
+const restoreTimeout = 5 * time.Minute
+
+// restoreContext returns a context restoring the target that is not cancelled with ctx, once ctx is done the restore is bounded by restoreTimeout
+func restoreContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	restoreCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() { time.AfterFunc(restoreTimeout, cancel) })
+
+	return restoreCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// recoveryLock serializes the writes of the tasks to the recovery file
+var recoveryLock sync.Mutex
+
+// the changes to the target of which the restoring statements are written to the recovery file
+const (
+	recoveryForeignKeys = "foreign keys referencing"
+	recoveryIndexes     = "indexes of"
+	recoveryTriggers    = "triggers of"
+)
+
+// restoreForeignKeys adds or enables the foreign keys dropped or disabled by prepareTarget and forgets their records
+func (ct *CopyTask) restoreForeignKeys(ctx context.Context) error {
+	fks := ct.droppedForeignKeys
+	if len(fks) == 0 {
+		return nil
+	}
+
+	var err error
+	if ct.opts.DisableForeignKeys {
+		err = ct.retryLocked(ctx, "enable foreign keys", func() error { return ct.targetDB.EnableForeignKeys(ctx, fks) })
+	} else {
+		pending := fks
+		err = ct.retryLocked(ctx, "add foreign keys", func() error {
+			err := ct.targetDB.AddForeignKeys(ctx, pending)
+			if mssql.IsLockConflict(err) {
+				// the foreign keys added before the conflict are not added again
+				existing, existingErr := ct.targetDB.GetReferencedForeignKeys(ctx, ct.target)
+				if existingErr == nil {
+					pending = missingForeignKeys(pending, existing)
+				}
+			}
+			return err
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to add foreign keys into target table %s, %s", ct.table, err)
+	}
+	ct.droppedForeignKeys = nil
+	ct.eventChan <- monitor.ForeignKeysEvent{Table: ct.table, Restored: mssql.CountForeignKeys(fks)}
+	ct.forgetRecovery(recoveryForeignKeys)
+
+	err = ct.targetDB.ForgetForeignKeys(ctx, ct.foreignKeyArtifact(), fks)
+	if err != nil {
+		return fmt.Errorf("Failed to remove the foreign key records of table %s from the targetDB, %s", ct.table, err)
+	}
+
+	return nil
+}
+
+// resumeForeignKeys takes over the foreign keys the run a resumed table continues dropped or disabled before it failed, the target
+// is not prepared again so finishTarget restores them instead
+func (ct *CopyTask) resumeForeignKeys(ctx context.Context) error {
+	if ct.opts.Mode != ModeTruncate && ct.opts.Mode != ModeDelete {
+		return nil
+	}
+
+	recorded, err := ct.targetDB.GetRecordedForeignKeys(ctx, ct.foreignKeyArtifact(), ct.target)
+	if err != nil {
+		return fmt.Errorf("Failed to get the recorded foreign keys of table %s from the targetDB, %s", ct.table, err)
+	}
+
+	if !ct.opts.DisableForeignKeys {
+		existing, err := ct.targetDB.GetReferencedForeignKeys(ctx, ct.target)
+		if err != nil {
+			return fmt.Errorf("Failed to get foreign keys for table %s from the targetDB, %s", ct.table, err)
+		}
+
+		// the foreign keys restored by hand or by the cleanup command are only forgotten
+		missing := missingForeignKeys(recorded, existing)
+		err = ct.targetDB.ForgetForeignKeys(ctx, ct.foreignKeyArtifact(), missingForeignKeys(recorded, missing))
+		if err != nil {
+			return fmt.Errorf("Failed to remove the foreign key records of table %s from the targetDB, %s", ct.table, err)
+		}
+		recorded = missing
+	}
+
+	ct.droppedForeignKeys = recorded
+	return nil
+}
+
+// restoreIndexes rebuilds the indexes disabled by disableIndexes and forgets their records
+func (ct *CopyTask) restoreIndexes(ctx context.Context) error {
+	if len(ct.disabledIndexes) == 0 {
+		return nil
+	}
+
+	err := ct.targetDB.RebuildIndexes(ctx, ct.disabledIndexes)
+	if err != nil {
+		return fmt.Errorf("Failed to rebuild the indexes of target table %s, %s", ct.table, err)
+	}
+	ct.disabledIndexes = nil
+	ct.forgetRecovery(recoveryIndexes)
+
+	err = ct.targetDB.ForgetIndexes(ctx, ct.target)
+	if err != nil {
+		return fmt.Errorf("Failed to remove the index records of table %s from the targetDB, %s", ct.table, err)
+	}
+
+	return nil
+}
+
+// restoreTriggers enables the triggers disabled by disableTriggers and forgets their records
+func (ct *CopyTask) restoreTriggers(ctx context.Context) error {
+	if len(ct.disabledTriggers) == 0 {
+		return nil
+	}
+
+	err := ct.targetDB.EnableTriggers(ctx, ct.disabledTriggers)
+	if err != nil {
+		return fmt.Errorf("Failed to enable the triggers of target table %s, %s", ct.table, err)
+	}
+	ct.disabledTriggers = nil
+	ct.forgetRecovery(recoveryTriggers)
+
+	err = ct.targetDB.ForgetTriggers(ctx, ct.target)
+	if err != nil {
+		return fmt.Errorf("Failed to remove the trigger records of table %s from the targetDB, %s", ct.table, err)
+	}
+
+	return nil
+}
+
+// missingForeignKeys returns the per column constraints of fks of which the foreign key is not in existing
+func missingForeignKeys(fks, existing []mssql.ForeingKeyConstraint) []mssql.ForeingKeyConstraint {
+	names := make(map[string]bool, len(existing))
+	for _, fk := range existing {
+		names[mssql.TableRef{Schema: fk.Schema, Table: fk.Name}.String()] = true
+	}
+
+	missing := make([]mssql.ForeingKeyConstraint, 0, len(fks))
+	for _, fk := range fks {
+		if !names[mssql.TableRef{Schema: fk.Schema, Table: fk.Name}.String()] {
+			missing = append(missing, fk)
+		}
+	}
+
+	return missing
+}
+
+// restoreOnFailure rebuilds the disabled indexes, enables the disabled triggers and restores the foreign keys dropped or disabled
+// by prepareTarget when the load of the table failed, was cancelled or panicked before finishTarget restored them. The statements
+// restoring what can not be restored are left in the recovery file, as the records in the target may be out of reach as well.
+// It is deferred by the goroutines loading the target, a panic continues once the target is restored.
+func (ct *CopyTask) restoreOnFailure() {
+	r := recover()
+	defer func() {
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	if len(ct.droppedForeignKeys) == 0 && len(ct.disabledIndexes) == 0 && len(ct.disabledTriggers) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+
+	// the records of what could not be restored are left for the cleanup command
+	restores := []struct {
+		what    string
+		restore func(context.Context) error
+		pending func() bool
+	}{
+		{recoveryIndexes, ct.restoreIndexes, func() bool { return len(ct.disabledIndexes) > 0 }},
+		{recoveryTriggers, ct.restoreTriggers, func() bool { return len(ct.disabledTriggers) > 0 }},
+		{recoveryForeignKeys, ct.restoreForeignKeys, func() bool { return len(ct.droppedForeignKeys) > 0 }},
+	}
+	for _, r := range restores {
+		if !r.pending() {
+			continue
+		}
+
+		err := r.restore(ctx)
+		if err == nil {
+			log.Printf("WARNING: restored the %s %s after its copy failed", r.what, ct.table)
+			continue
+		}
+		log.Printf("WARNING: the %s %s could not be restored after its copy failed, %s", r.what, ct.table, err)
+
+		// restored, only their records are left for the cleanup command
+		if !r.pending() {
+			continue
+		}
+		if _, ok := ct.recovery[r.what]; ok {
+			log.Printf("WARNING: the statements restoring the %s %s are in %s", r.what, ct.table, ct.opts.RecoveryFile)
+		}
+	}
+}
+
+// removeRecoverySections removes the sections of which the header starts with header from script
+func removeRecoverySections(script, header string) (string, bool) {
+	lines := strings.SplitAfter(script, "\n")
+	kept := make([]string, 0, len(lines))
+	removed, skipping := false, false
+	for _, line := range lines {
+		// the statements of a section never start with a comment
+		if strings.HasPrefix(line, "-- ") {
+			skipping = strings.HasPrefix(line, header)
+			removed = removed || skipping
+		}
+		if !skipping {
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, ""), removed
+}
+
+// writeRecovery appends a section with the statements restoring what is about to be changed in the target to the recovery file,
+// before the target is changed. The section is removed by forgetRecovery once it is restored, so the file only holds what a failed
+// or crashed run left behind.
+func (ct *CopyTask) writeRecovery(what string, statements []string) error {
+	if ct.opts.RecoveryFile == "" || len(statements) == 0 {
+		return nil
+	}
+
+	section := fmt.Sprintf("-- %s %s, %s\n%s;\n", what, ct.table, time.Now().Format(time.RFC3339Nano), strings.Join(statements, ";\n"))
+
+	recoveryLock.Lock()
+	defer recoveryLock.Unlock()
+
+	f, err := os.OpenFile(ct.opts.RecoveryFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteString(section)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	if ct.recovery == nil {
+		ct.recovery = make(map[string]string)
+	}
+	ct.recovery[what] = section
+
+	return nil
+}
+
+// forgetRecovery removes the sections of the recovery file restoring what of the table once it is restored, including those of
+// the run a resumed table continues. The file is removed when it is empty.
+func (ct *CopyTask) forgetRecovery(what string) {
+	if ct.opts.RecoveryFile == "" {
+		return
+	}
+	delete(ct.recovery, what)
+
+	recoveryLock.Lock()
+	defer recoveryLock.Unlock()
+
+	data, err := os.ReadFile(ct.opts.RecoveryFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		log.Printf("WARNING: failed to remove the restored %s %s from the recovery file, %s", what, ct.table, err)
+		return
+	}
+
+	remaining, removed := removeRecoverySections(string(data), fmt.Sprintf("-- %s %s, ", what, ct.table))
+	if !removed {
+		return
+	}
+	if strings.TrimSpace(remaining) == "" {
+		err = os.Remove(ct.opts.RecoveryFile)
+	} else {
+		err = os.WriteFile(ct.opts.RecoveryFile, []byte(remaining), 0o644)
+	}
+	if err != nil {
+		log.Printf("WARNING: failed to remove the restored %s %s from the recovery file, %s", what, ct.table, err)
+	}
+}
@@ -0,0 +1,107 @@
+package copy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	mssqlDriver "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recovery.sql")
+	ct := NewCopyTask(mssql.TableRef{Schema: "dbo", Table: "orders"}, nil, nil, Options{RecoveryFile: path}, nil)
+
+	require.NoError(t, ct.writeRecovery(recoveryForeignKeys, []string{"ALTER TABLE [dbo].[lines] CHECK CONSTRAINT [FK_lines_orders]"}))
+	require.NoError(t, ct.writeRecovery(recoveryTriggers, []string{"ENABLE TRIGGER [dbo].[audit] ON [dbo].[orders]"}))
+
+	script, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Regexp(t, `^-- foreign keys referencing \[dbo\]\.\[orders\], \S+
+ALTER TABLE \[dbo\]\.\[lines\] CHECK CONSTRAINT \[FK_lines_orders\];
+-- triggers of \[dbo\]\.\[orders\], \S+
+ENABLE TRIGGER \[dbo\]\.\[audit\] ON \[dbo\]\.\[orders\];
+$`, string(script))
+
+	// the restored foreign keys are removed, the file goes once nothing is left to restore
+	ct.forgetRecovery(recoveryForeignKeys)
+	script, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Regexp(t, `^-- triggers of \[dbo\]\.\[orders\], \S+
+ENABLE TRIGGER \[dbo\]\.\[audit\] ON \[dbo\]\.\[orders\];
+$`, string(script))
+
+	ct.forgetRecovery(recoveryTriggers)
+	assert.NoFileExists(t, path)
+
+	// the sections of the run a resumed table continues are removed as well
+	previous := "-- foreign keys referencing [dbo].[orders], 2026-01-02T03:04:05Z\nALTER TABLE [dbo].[lines] ADD CONSTRAINT [FK_lines_orders];\n" +
+		"-- foreign keys referencing [dbo].[customers], 2026-01-02T03:04:05Z\nALTER TABLE [dbo].[orders] ADD CONSTRAINT [FK_orders_customers];\n"
+	require.NoError(t, os.WriteFile(path, []byte(previous), 0o644))
+	ct.forgetRecovery(recoveryForeignKeys)
+	script, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "-- foreign keys referencing [dbo].[customers], 2026-01-02T03:04:05Z\nALTER TABLE [dbo].[orders] ADD CONSTRAINT [FK_orders_customers];\n", string(script))
+
+	ct.opts.RecoveryFile = ""
+	assert.NoError(t, ct.writeRecovery(recoveryIndexes, []string{"ALTER INDEX [ix] ON [dbo].[orders] REBUILD"}))
+	ct.forgetRecovery(recoveryIndexes)
+}
+
+func TestMissingForeignKeys(t *testing.T) {
+	fks := []mssql.ForeingKeyConstraint{
+		{Name: "FK_lines_orders", Schema: "dbo", Table: "lines", Column: "order_id"},
+		{Name: "FK_notes_orders", Schema: "dbo", Table: "notes", Column: "order_id"},
+		{Name: "FK_notes_orders", Schema: "dbo", Table: "notes", Column: "order_line"},
+	}
+
+	missing := missingForeignKeys(fks, fks[:1])
+	assert.Equal(t, fks[1:], missing)
+	assert.Empty(t, missingForeignKeys(fks, fks))
+}
+
+func TestRetryLocked(t *testing.T) {
+	events := make(chan monitor.Event, 10)
+	ct := NewCopyTask(mssql.TableRef{Schema: "dbo", Table: "orders"}, nil, nil, Options{Retries: 2, RetryDelay: time.Millisecond}, events)
+
+	runs := 0
+	err := ct.retryLocked(context.Background(), "truncate", func() error {
+		runs++
+		if runs < 2 {
+			return mssqlDriver.Error{Number: 1205}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, monitor.RetryEvent{Table: ct.table, Operation: "truncate", Attempt: 1, Err: mssqlDriver.Error{Number: 1205}}, <-events)
+
+	// other failures are not retried
+	runs = 0
+	err = ct.retryLocked(context.Background(), "truncate", func() error {
+		runs++
+		return errors.New("permission denied")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, runs)
+}
+
+func TestRestoreContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	restoreCtx, stop := restoreContext(ctx)
+	defer stop()
+
+	// the restore continues for restoreTimeout after ctx is cancelled
+	cancel()
+	assert.NoError(t, restoreCtx.Err())
+
+	stop()
+	assert.ErrorIs(t, restoreCtx.Err(), context.Canceled)
+}
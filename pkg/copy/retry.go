@@ -0,0 +1,75 @@
+package copy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// defaultRetryDelay is the wait before the first retry of a failed batch, it doubles with every retry
+const defaultRetryDelay = time.Second
+
+// retryPending inserts the rows given to writer since its last commit again when they failed with a transient error, like a deadlock
+// or throttling, up to Retries times with an exponential backoff. commit commits them as well, for failures of the final commit.
+// Commits the server did not answer are not retried, as their rows may have landed. It returns the last error when the rows still fail.
+func (ct *CopyTask) retryPending(ctx context.Context, writer rowWriter, columns []string, cause error, commit bool, lastKey *[]string) error {
+	delay := ct.opts.retryDelay()
+
+	err := cause
+	for attempt := 0; attempt < ct.opts.Retries && mssql.IsTransient(err) && !errors.Is(err, mssql.ErrCommitUnknown); attempt++ {
+		pending := writer.Pending()
+		writer.Rollback(ctx)
+		ct.shrinkBatches(writer, err)
+		ct.eventChan <- monitor.RetryEvent{Table: ct.table, Attempt: attempt + 1, Err: err}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay << attempt):
+		}
+
+		err = ct.insertBatch(ctx, writer, columns, pending, lastKey)
+		if err == nil && commit {
+			err = writer.Commit(ctx)
+		}
+	}
+
+	return err
+}
+
+// retryLocked runs the operation on the target again when it was chosen as deadlock victim or timed out waiting for a lock held by
+// another workload, up to Retries times with an exponential backoff. The operation has to be safe to run again after it was rolled back.
+func (ct *CopyTask) retryLocked(ctx context.Context, operation string, run func() error) error {
+	delay := ct.opts.retryDelay()
+
+	err := run()
+	for attempt := 0; attempt < ct.opts.Retries && mssql.IsLockConflict(err); attempt++ {
+		ct.eventChan <- monitor.RetryEvent{Table: ct.table, Operation: operation, Attempt: attempt + 1, Err: err}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay << attempt):
+		}
+
+		err = run()
+	}
+
+	return err
+}
+
+func (o Options) retryDelay() time.Duration {
+	if o.RetryDelay == 0 {
+		return defaultRetryDelay
+	}
+
+	return o.RetryDelay
+}
+
+// keepsPending reports whether the bulk copy writers keep the rows of their batch, to insert them again when it fails
+func (o Options) keepsPending() bool {
+	return o.Retries > 0 || o.MaxErrors > 0
+}
@@ -0,0 +1,50 @@
+package copy
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// largestFirst orders the tables copied in parallel by the size of their data in the source, largest first, so the big tables
+// start first and do not end up running on their own at the end of the run. Tables of unknown size go last in their original order.
+func (e *Engine) largestFirst(ctx context.Context, tables []mssql.TableRef, parrallel int) []mssql.TableRef {
+	if parrallel <= 1 || len(tables) <= 1 {
+		return tables
+	}
+
+	sizes := make(map[mssql.TableRef]int64, len(tables))
+	for _, table := range tables {
+		sizes[table] = e.tableSize(ctx, table)
+	}
+
+	sorted := slices.Clone(tables)
+	slices.SortStableFunc(sorted, func(a, b mssql.TableRef) int {
+		return cmp.Compare(sizes[b], sizes[a])
+	})
+
+	return sorted
+}
+
+// tableSize returns the size of the data of table in the source, the metadata loaded by Prefetch is used when there is any
+func (e *Engine) tableSize(ctx context.Context, table mssql.TableRef) int64 {
+	size, _ := e.sourceSize(ctx, table)
+	return size
+}
+
+// sourceSize returns the size of the data of table in the source, false when it is unknown
+func (e *Engine) sourceSize(ctx context.Context, table mssql.TableRef) (int64, bool) {
+	if e.opts.Metadata != nil {
+		return e.opts.Metadata.Size(table)
+	}
+
+	// reading partition stats requires VIEW DATABASE STATE
+	size, err := e.sourceDB.GetDataSize(ctx, table)
+	if err != nil {
+		return 0, false
+	}
+
+	return size, true
+}
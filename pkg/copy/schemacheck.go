@@ -0,0 +1,63 @@
+package copy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// SchemaCheck determines how the schema of a table in the target has to match the source before it is copied
+type SchemaCheck string
+
+const (
+	// SchemaCheckStrict requires the same columns with the same type, length, precision, scale, nullability, identity and computed expression
+	SchemaCheckStrict SchemaCheck = "strict"
+	// SchemaCheckCompatible allows extra nullable target columns and target types the source types convert to without losing data
+	SchemaCheckCompatible SchemaCheck = "compatible"
+	// SchemaCheckNone copies the columns the tables have in common and leaves failures to the server
+	SchemaCheckNone SchemaCheck = "none"
+)
+
+func ParseSchemaCheck(check string) (SchemaCheck, error) {
+	switch SchemaCheck(check) {
+	case SchemaCheckStrict, SchemaCheckCompatible, SchemaCheckNone:
+		return SchemaCheck(check), nil
+	case "":
+		return SchemaCheckStrict, nil
+	}
+
+	return "", fmt.Errorf("unknown schema check %q", check)
+}
+
+// copyColumns checks the schemas of table according to the schema check and returns the columns to copy.
+// Excluded columns are left out of the check, columns the target computes itself are never copied.
+func (o Options) copyColumns(table mssql.TableRef, sourceSchema, targetSchema mssql.SchemaDefinition) ([]string, error) {
+	sourceSchema = o.withoutExcluded(table, sourceSchema)
+	targetSchema = o.withoutExcluded(table, targetSchema)
+
+	switch o.SchemaCheck {
+	case SchemaCheckCompatible:
+		if problems := sourceSchema.CompatibleWith(targetSchema); len(problems) > 0 {
+			return nil, fmt.Errorf("%w, the target schema is not compatible: %s", mssql.ErrSchemaMismatch, strings.Join(problems, ", "))
+		}
+	case SchemaCheckNone:
+	default:
+		if !sourceSchema.Matches(targetSchema) {
+			return nil, mssql.ErrSchemaMismatch
+		}
+	}
+
+	columns := make([]string, 0, len(sourceSchema))
+	for column := range sourceSchema {
+		if targetColumn, ok := targetSchema[column]; ok && targetColumn.Insertable() {
+			columns = append(columns, column)
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns left to copy")
+	}
+
+	return columns, nil
+}
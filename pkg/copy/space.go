@@ -0,0 +1,83 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// SpaceEstimate is the space the copy of the tables needs in the target, estimated from the data sizes in the source.
+// Compression and fill factors differ between the databases, so it is an estimate in both directions.
+type SpaceEstimate struct {
+	// Bytes is the size of the data of the tables that are copied in full
+	Bytes int64
+	// Freed is the size of the data truncated in the target before those tables are copied
+	Freed int64
+	// Transaction is the size of the largest table loaded in a single transaction, which the log has to hold until it commits
+	Transaction int64
+	// Unestimated are the tables of which only a part of the rows is copied or of which the size is unknown, they are not counted
+	Unestimated []mssql.TableRef
+	Target      mssql.DatabaseSpace
+}
+
+// Needed returns the number of bytes the data of the target grows by the copy
+func (s SpaceEstimate) Needed() int64 {
+	return max(s.Bytes-s.Freed, 0)
+}
+
+// Fits reports whether the data of the target can grow by the bytes needed
+func (s SpaceEstimate) Fits() bool {
+	free, limited := s.Target.DataFree()
+	return !limited || s.Needed() <= free
+}
+
+// LogFits reports whether the log of the target can hold the largest transaction of the copy
+func (s SpaceEstimate) LogFits() bool {
+	free, limited := s.Target.LogFree()
+	return !limited || s.Transaction <= free
+}
+
+// EstimateSpace estimates the space the copy of the tables needs in the target and returns it with the space the target has left.
+// Only truncate, delete and append copies of all rows of a table are estimated, the others add an unknown part of the rows.
+func (e *Engine) EstimateSpace(ctx context.Context, tables []mssql.TableRef) (SpaceEstimate, error) {
+	target, err := e.targetDB.GetDatabaseSpace(ctx)
+	if err != nil {
+		return SpaceEstimate{}, fmt.Errorf("failed to get the space of the target, %w", err)
+	}
+
+	estimate := SpaceEstimate{Target: target, Unestimated: make([]mssql.TableRef, 0)}
+	for _, table := range tables {
+		size, ok := e.sourceSize(ctx, table)
+		if !ok || !e.copiesAllRows(table) {
+			estimate.Unestimated = append(estimate.Unestimated, table)
+			continue
+		}
+		estimate.Bytes += size
+
+		if e.opts.Bulk.CommitCount == 0 {
+			estimate.Transaction = max(estimate.Transaction, size)
+		}
+
+		if e.opts.Mode == ModeTruncate {
+			// a table that does not exist yet has no size
+			truncated, err := e.targetDB.GetDataSize(ctx, e.opts.targetTable(table))
+			if err == nil {
+				estimate.Freed += truncated
+			}
+		}
+	}
+
+	return estimate, nil
+}
+
+// copiesAllRows reports whether the copy of table adds all rows of the source to the target
+func (e *Engine) copiesAllRows(table mssql.TableRef) bool {
+	switch e.opts.Mode {
+	case ModeTruncate, ModeDelete, ModeAppend:
+	default:
+		return false
+	}
+
+	return e.opts.SampleRows == 0 && e.opts.SamplePercent == 0 && e.opts.filter(table).Unfiltered()
+}
@@ -0,0 +1,31 @@
+package copy
+
+import (
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpaceEstimateFits(t *testing.T) {
+	estimate := SpaceEstimate{Bytes: 130, Freed: 60, Transaction: 60, Target: mssql.DatabaseSpace{DataUsed: 900, DataMax: 1000, LogUsed: 10, LogMax: 50}}
+	assert.Equal(t, int64(70), estimate.Needed())
+	assert.True(t, estimate.Fits())
+	assert.False(t, estimate.LogFits())
+
+	estimate.Freed = 0
+	assert.False(t, estimate.Fits())
+
+	// without a maximum size the target grows until its disk is full
+	estimate.Target.DataMax = 0
+	assert.True(t, estimate.Fits())
+}
+
+func TestCopiesAllRows(t *testing.T) {
+	table := mssql.TableRef{Schema: "dbo", Table: "test"}
+
+	assert.True(t, (&Engine{opts: Options{Mode: ModeTruncate}}).copiesAllRows(table))
+	assert.False(t, (&Engine{opts: Options{Mode: ModeMerge}}).copiesAllRows(table))
+	assert.False(t, (&Engine{opts: Options{Mode: ModeAppend, QueryFilter: "id > 10"}}).copiesAllRows(table))
+	assert.False(t, (&Engine{opts: Options{Mode: ModeDelete, SamplePercent: 10}}).copiesAllRows(table))
+}
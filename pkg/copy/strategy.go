@@ -0,0 +1,159 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// Strategy determines how rows are loaded into a target table
+type Strategy string
+
+const (
+	// StrategyBulk loads the rows with the bulk copy protocol
+	StrategyBulk Strategy = "bulk"
+	// StrategyMerge bulk copies the rows into a staging table and merges them on the primary key, like ModeMerge
+	StrategyMerge Strategy = "merge"
+	// StrategyInsertSelect copies the rows on the target server with INSERT ... SELECT, the source database has to be on the same server
+	StrategyInsertSelect Strategy = "insert-select"
+	// StrategyInsert loads the rows with batched INSERT statements, which fire the triggers of the table
+	StrategyInsert Strategy = "insert"
+)
+
+func ParseStrategy(strategy string) (Strategy, error) {
+	switch Strategy(strategy) {
+	case StrategyBulk, StrategyMerge, StrategyInsertSelect, StrategyInsert:
+		return Strategy(strategy), nil
+	case "":
+		return StrategyBulk, nil
+	}
+
+	return "", fmt.Errorf("unknown copy strategy %q", strategy)
+}
+
+// rowWriter is implemented by the inserters of the strategies that stream rows into the target
+type rowWriter interface {
+	Insert(ctx context.Context, row []interface{}) error
+	InsertBatch(ctx context.Context, rows [][]interface{}) error
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+	OnCommit(fn func() error)
+	// Pending returns the rows given since the last commit
+	Pending() [][]interface{}
+}
+
+// rowCounter is implemented by the row writers that know how many of the rows sent the server accepted
+type rowCounter interface {
+	RowsSent() int64
+	RowsAccepted() int64
+}
+
+// checkAccepted reports the rows the server did not accept from writer
+func (ct *CopyTask) checkAccepted(writer rowWriter) error {
+	counter, ok := writer.(rowCounter)
+	if !ok || counter.RowsAccepted() == counter.RowsSent() {
+		return nil
+	}
+
+	return fmt.Errorf("The server accepted %d of the %d rows sent to target table %s", counter.RowsAccepted(), counter.RowsSent(), ct.table)
+}
+
+func (ct *CopyTask) rowWriter(ctx context.Context, table mssql.TableRef, columns []string, schema mssql.SchemaDefinition) (rowWriter, error) {
+	// bulk copy needs driver support for every column type, INSERT statements let the server convert the values of the other types
+	if ct.strategy == StrategyInsert || len(mssql.BulkUnsupportedColumns(schema, columns)) > 0 {
+		return ct.targetDB.BatchInsert(ctx, table, columns, ct.opts.Bulk.KeepIdentity)
+	}
+
+	opts := ct.opts.Bulk
+	if ct.opts.Triggers == TriggersFire {
+		opts.FireTriggers = true
+	}
+
+	bulk, err := ct.targetDB.BulkInsert(ctx, table, columns, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the rows of a failed batch are inserted again to retry them or to find the rejected ones
+	if ct.opts.keepsPending() {
+		bulk.KeepPending()
+	}
+
+	if ct.tuner != nil {
+		ct.tuneBatches(bulk)
+	}
+
+	return bulk, nil
+}
+
+// insertSelect copies the table on the target server without streaming the rows through the client
+func (ct *CopyTask) insertSelect(ctx context.Context, columns []string) {
+	defer ct.wg.Done()
+	defer ct.wg.Done()
+	defer ct.restoreOnFailure()
+
+	if len(ct.opts.Transformers) > 0 {
+		ct.fail(fmt.Errorf("Row transformers can not be applied to table %s, which is copied with insert-select", ct.table))
+		return
+	}
+
+	readOpts, err := ct.readOptions(ctx)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to get the watermark for table %s from the targetDB, %w", ct.table, err))
+		return
+	}
+
+	numberOfRows, approximate, err := ct.count(ctx, readOpts)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to get count for table %s from the sourceDB, %w", ct.table, err))
+		return
+	}
+	ct.eventChan <- monitor.CountUpdateEvent{TotalRows: numberOfRows, Table: ct.table, Approximate: approximate}
+
+	if ct.opts.Mode == ModeTruncate || ct.opts.Mode == ModeDelete {
+		err = ct.prepareTarget(ctx)
+		if err != nil {
+			ct.fail(err)
+			return
+		}
+	}
+
+	if ct.disablesTriggers {
+		err = ct.disableTriggers(ctx)
+		if err != nil {
+			ct.fail(err)
+			return
+		}
+	}
+
+	if ct.opts.DisableIndexes {
+		err = ct.disableIndexes(ctx)
+		if err != nil {
+			ct.fail(err)
+			return
+		}
+	}
+
+	rowsCopied, err := ct.targetDB.InsertSelect(ctx, ct.sourceDB, ct.table, ct.target, columns, readOpts, ct.opts.Bulk.KeepIdentity)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to insert the rows of source table %s, %w", ct.table, err))
+		return
+	}
+	ct.eventChan <- monitor.ProgressUpdateEvent{RowsCopied: int(rowsCopied), Table: ct.table}
+
+	err = ct.finishTarget(ctx)
+	if err != nil {
+		ct.fail(err)
+		return
+	}
+
+	err = ct.verifyCount(ctx)
+	if err != nil {
+		ct.fail(err)
+		return
+	}
+
+	ct.eventChan <- monitor.CopyTaskFinishedEvent{Table: ct.table}
+}
@@ -0,0 +1,40 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// PlanSubset returns the predicate per table that selects a referentially consistent subset of the tables, starting from the
+// rows matching the query filter in the tables that have its columns. See mssql.Subset for which rows of the other tables are included.
+func PlanSubset(ctx context.Context, sourceDB *mssql.MSSQLDB, tables []mssql.TableRef, queryFilter string, includeChildren bool) (map[string]string, error) {
+	schemas := make(map[string]mssql.SchemaDefinition, len(tables))
+	foreignKeys := make([]mssql.ForeingKeyConstraint, 0)
+	for _, table := range tables {
+		schema, err := sourceDB.GetSchemaDefinition(ctx, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the schema of %s, %w", table, err)
+		}
+		schemas[table.String()] = schema
+
+		fks, err := sourceDB.GetForeignKeys(ctx, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the foreign keys of %s, %w", table, err)
+		}
+		foreignKeys = append(foreignKeys, fks...)
+	}
+
+	subset, err := mssql.NewSubset(queryFilter, schemas, mssql.References(foreignKeys), includeChildren)
+	if err != nil {
+		return nil, err
+	}
+
+	predicates := make(map[string]string, len(tables))
+	for _, table := range tables {
+		predicates[table.String()] = subset.Predicate(table)
+	}
+
+	return predicates, nil
+}
@@ -0,0 +1,143 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+)
+
+// prepareSync determines the change tracking versions to sync between and reports whether the whole table needs to be copied,
+// which is the case for the first sync of a table or when the changes since the last sync are no longer retained.
+func (ct *CopyTask) prepareSync(ctx context.Context) (bool, error) {
+	// read the version before any data, changes made during the copy are picked up by the next sync
+	currentVersion, err := ct.sourceDB.ChangeTrackingVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+	ct.syncVersion = currentVersion
+
+	minValidVersion, err := ct.sourceDB.ChangeTrackingMinValidVersion(ctx, ct.table)
+	if err != nil {
+		return false, err
+	}
+
+	lastVersion, ok, err := ct.targetDB.GetSyncVersion(ctx, ct.target)
+	if err != nil {
+		return false, err
+	}
+	ct.lastSyncVersion = lastVersion
+
+	return !ok || lastVersion < minValidVersion, nil
+}
+
+// applyChanges deletes the rows deleted from the source since the last sync and merges the inserted and updated rows into the target
+func (ct *CopyTask) applyChanges(ctx context.Context, columns []string) {
+	defer ct.wg.Done()
+	defer ct.wg.Done()
+
+	keys, err := ct.sourceDB.GetPrimaryKey(ctx, ct.table)
+	if err != nil || len(keys) == 0 {
+		ct.fail(fmt.Errorf("Failed to get the primary key of source table %s", ct.table))
+		return
+	}
+
+	numberOfChanges, err := ct.sourceDB.CountChanges(ctx, ct.table, ct.lastSyncVersion)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to count the changes of source table %s, %w", ct.table, err))
+		return
+	}
+	ct.eventChan <- monitor.CountUpdateEvent{TotalRows: numberOfChanges, Table: ct.table}
+
+	staging, err := ct.targetDB.CreateStagingTable(ctx, ct.target)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to create a staging table for target table %s, %w", ct.table, err))
+		return
+	}
+	defer ct.targetDB.DropTable(context.Background(), staging)
+
+	changes, err := ct.sourceDB.SelectChanges(ctx, ct.table, columns, keys, ct.lastSyncVersion)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to select changes from source table %s, %w", ct.table, err))
+		return
+	}
+	defer changes.Close()
+
+	bulkInsert, err := ct.targetDB.BulkInsert(ctx, staging, columns, ct.opts.Bulk)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to start a bulk insert into staging table %s, %w", staging, err))
+		return
+	}
+
+	for {
+		change, err := changes.Next()
+		if err != nil {
+			ct.fail(fmt.Errorf("Failed to get the Next change from the source table %s, %w", ct.table, err))
+			return
+		}
+
+		if len(change) == 0 {
+			break
+		}
+
+		operation := fmt.Sprint(change[0])
+		keyValues := change[1 : 1+len(keys)]
+		values := change[1+len(keys):]
+
+		if operation == "D" {
+			err = ct.targetDB.DeleteByKey(ctx, ct.target, keys, keyValues)
+			if err != nil {
+				ct.fail(fmt.Errorf("Failed to delete a row from target table %s, %w", ct.table, err))
+				return
+			}
+		} else {
+			values, skip, err := ct.transform(columns, values)
+			if err != nil {
+				ct.fail(fmt.Errorf("Failed to transform a row from the source table %s, %w", ct.table, err))
+				return
+			}
+
+			if !skip {
+				err = ct.throttle(ctx, 1, rowSize(values))
+				if err == nil {
+					err = bulkInsert.Insert(ctx, values)
+				}
+				if err != nil {
+					bulkInsert.Rollback(ctx)
+					ct.fail(fmt.Errorf("Failed to stage a changed row for target table %s, %w", ct.table, err))
+					return
+				}
+			}
+		}
+
+		ct.progress.add(1, rowSize(values))
+	}
+	ct.progress.flush()
+
+	err = bulkInsert.Commit(ctx)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to commit the changed rows into staging table %s, %w", staging, err))
+		return
+	}
+
+	// merging an incomplete staging table would leave changes out
+	err = ct.checkAccepted(bulkInsert)
+	if err != nil {
+		ct.fail(err)
+		return
+	}
+
+	err = ct.targetDB.Merge(ctx, staging, ct.target, columns, keys)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to merge changed rows into target table %s, %w", ct.table, err))
+		return
+	}
+
+	err = ct.targetDB.SetSyncVersion(ctx, ct.target, ct.syncVersion)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to record the sync version of target table %s, %w", ct.table, err))
+		return
+	}
+
+	ct.eventChan <- monitor.CopyTaskFinishedEvent{Table: ct.table}
+}
@@ -0,0 +1,100 @@
+package copy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// prepareTemporalTables turns system versioning off for the target temporal tables of which the current or the history table is copied,
+// as TRUNCATE and inserts into the period columns fail while it is on. The history tables of copied temporal tables are added
+// to the tables when the source keeps the same history. It returns the tables to copy and the tables restoreTemporalTables has to turn on again.
+func (e *Engine) prepareTemporalTables(ctx context.Context, tables []mssql.TableRef) ([]mssql.TableRef, []mssql.TemporalTable, error) {
+	targetTemporal, err := e.targetDB.GetTemporalTables(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get the temporal tables of the targetDB, %s", err)
+	}
+	if len(targetTemporal) == 0 {
+		return tables, nil, nil
+	}
+
+	sourceTemporal, err := e.sourceDB.GetTemporalTables(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get the temporal tables of the sourceDB, %s", err)
+	}
+
+	// the temporal tables are matched by their name in the target, the history tables added are unmapped
+	targets, temporal := temporalTablesOf(e.opts.TargetTables(tables), targetTemporal, sourceTemporal)
+	tables = append(tables, targets[len(tables):]...)
+
+	disabled := make([]mssql.TemporalTable, 0, len(temporal))
+	for _, table := range temporal {
+		// recorded first, so the cleanup command can turn versioning on when the run crashes
+		err := e.targetDB.RecordSystemVersioning(ctx, table)
+		if err == nil {
+			err = e.targetDB.DisableSystemVersioning(ctx, table)
+		}
+		if err != nil {
+			return nil, nil, errors.Join(
+				fmt.Errorf("Failed to turn system versioning off for target table %s, %s", table.Table, err),
+				e.restoreTemporalTables(ctx, disabled),
+			)
+		}
+		disabled = append(disabled, table)
+	}
+
+	return tables, disabled, nil
+}
+
+// restoreTemporalTables turns system versioning on again for the tables prepareTemporalTables turned it off for, also when the run
+// was cancelled
+func (e *Engine) restoreTemporalTables(ctx context.Context, temporal []mssql.TemporalTable) error {
+	ctx, cancel := restoreContext(ctx)
+	defer cancel()
+
+	errs := make([]error, 0)
+	for _, table := range temporal {
+		err := e.targetDB.EnableSystemVersioning(ctx, table)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to turn system versioning on for target table %s, %s", table.Table, err))
+			continue
+		}
+
+		err = e.targetDB.ForgetSystemVersioning(ctx, table)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to forget the system versioning of table %s in the targetDB, %s", table.Table, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// temporalTablesOf returns the tables with the missing history tables added and the target temporal tables involved in the copy
+func temporalTablesOf(tables []mssql.TableRef, targetTemporal, sourceTemporal []mssql.TemporalTable) ([]mssql.TableRef, []mssql.TemporalTable) {
+	copied := make(map[mssql.TableRef]bool, len(tables))
+	for _, table := range tables {
+		copied[table] = true
+	}
+
+	sourceHistory := make(map[mssql.TableRef]mssql.TableRef, len(sourceTemporal))
+	for _, table := range sourceTemporal {
+		sourceHistory[table.Table] = table.History
+	}
+
+	temporal := make([]mssql.TemporalTable, 0)
+	for _, table := range targetTemporal {
+		if !copied[table.Table] && !copied[table.History] {
+			continue
+		}
+		temporal = append(temporal, table)
+
+		if copied[table.Table] && !copied[table.History] && sourceHistory[table.Table] == table.History {
+			tables = append(tables, table.History)
+			copied[table.History] = true
+		}
+	}
+
+	return tables, temporal
+}
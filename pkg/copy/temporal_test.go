@@ -0,0 +1,32 @@
+package copy
+
+import (
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemporalTablesOf(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	ordersHistory := mssql.TableRef{Schema: "dbo", Table: "orders_history"}
+	prices := mssql.TableRef{Schema: "dbo", Table: "prices"}
+	pricesHistory := mssql.TableRef{Schema: "dbo", Table: "prices_history"}
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+
+	target := []mssql.TemporalTable{
+		{Table: orders, History: ordersHistory, PeriodStart: "valid_from", PeriodEnd: "valid_to"},
+		{Table: prices, History: pricesHistory, PeriodStart: "valid_from", PeriodEnd: "valid_to"},
+		{Table: mssql.TableRef{Schema: "dbo", Table: "products"}, History: mssql.TableRef{Schema: "dbo", Table: "products_history"}},
+	}
+	// the source keeps no history of prices
+	source := target[:1]
+
+	tables, temporal := temporalTablesOf([]mssql.TableRef{orders, prices, customers}, target, source)
+	assert.Equal(t, []mssql.TableRef{orders, prices, customers, ordersHistory}, tables)
+	assert.Equal(t, target[:2], temporal)
+
+	tables, temporal = temporalTablesOf([]mssql.TableRef{customers, ordersHistory}, target, source)
+	assert.Equal(t, []mssql.TableRef{customers, ordersHistory}, tables)
+	assert.Equal(t, target[:1], temporal)
+}
@@ -0,0 +1,132 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// throttleInterval is how often the utilization is checked, sys.dm_db_resource_stats is updated every 15 seconds
+const throttleInterval = 15 * time.Second
+
+// Throttle adapts the rows per second of a Limiter to keep the utilization of the databases under a threshold,
+// it slows the copy down when the busiest database exceeds the threshold and speeds it up again when it is well below
+type Throttle struct {
+	limiter   *Limiter
+	databases map[string]*mssql.MSSQLDB
+	threshold float64
+	interval  time.Duration
+	report    func(status string)
+}
+
+// NewThrottle returns a throttle of limiter keeping the utilization of the databases by name below threshold percent
+func NewThrottle(limiter *Limiter, threshold float64, databases map[string]*mssql.MSSQLDB) *Throttle {
+	return &Throttle{
+		limiter:   limiter,
+		databases: databases,
+		threshold: threshold,
+		interval:  throttleInterval,
+	}
+}
+
+// OnChange registers a function receiving a description of every change of the rate
+func (t *Throttle) OnChange(report func(status string)) {
+	t.report = report
+}
+
+// Run adapts the rate until ctx is done, or until none of the databases reports its utilization
+func (t *Throttle) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	_, lastRows := t.limiter.adapted()
+	lastTime := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			name, utilization, ok := t.utilization(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if !ok {
+				t.limiter.adapt(0)
+				t.notify("no database reports its utilization (sys.dm_db_resource_stats), the copy is not throttled")
+				return
+			}
+
+			rate, rows := t.limiter.adapted()
+			observed := float64(rows-lastRows) / now.Sub(lastTime).Seconds()
+			lastRows, lastTime = rows, now
+
+			next := nextRate(rate, observed, utilization, t.threshold)
+			if next == rate {
+				continue
+			}
+
+			t.limiter.adapt(next)
+			if next == 0 {
+				t.notify(fmt.Sprintf("not throttled, the %s database is at %.0f%% of its limits", name, utilization))
+			} else {
+				t.notify(fmt.Sprintf("throttled to %.0f rows/s, the %s database is at %.0f%% of its limits", next, name, utilization))
+			}
+		}
+	}
+}
+
+// utilization returns the busiest of the databases, the databases failing to report their utilization are no longer checked
+func (t *Throttle) utilization(ctx context.Context) (string, float64, bool) {
+	busiest, highest, ok := "", 0.0, false
+	for name, db := range t.databases {
+		usage, err := db.GetResourceUsage(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				delete(t.databases, name)
+			}
+			continue
+		}
+
+		if !ok || usage.Max() > highest {
+			busiest, highest, ok = name, usage.Max(), true
+		}
+	}
+
+	return busiest, highest, ok
+}
+
+func (t *Throttle) notify(status string) {
+	if t.report != nil {
+		t.report(status)
+	}
+}
+
+// nextRate returns the rows per second for the next interval, 0 is unlimited. Above the threshold the observed rate is cut
+// in proportion to the overshoot. Well below it a limited rate grows by a quarter, and the limit is lifted when the copy
+// no longer reaches it.
+func nextRate(rate, observed, utilization, threshold float64) float64 {
+	if utilization > threshold {
+		// without rows inserted the load comes from elsewhere
+		if observed <= 0 {
+			return rate
+		}
+
+		next := observed * threshold / utilization * 0.9
+		if rate > 0 {
+			next = min(next, rate)
+		}
+		return max(next, 1)
+	}
+
+	if rate == 0 || utilization > threshold*0.8 {
+		return rate
+	}
+
+	if observed < rate/2 {
+		return 0
+	}
+
+	return rate * 1.25
+}
@@ -0,0 +1,23 @@
+package copy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextRate(t *testing.T) {
+	// above the threshold the observed rate is cut by the overshoot
+	assert.InDelta(t, 720.0, nextRate(0, 1000, 100, 80), 0.001)
+	assert.Equal(t, 1.0, nextRate(10, 1, 100, 50))
+	// without rows inserted the load comes from elsewhere
+	assert.Equal(t, 500.0, nextRate(500, 0, 95, 80))
+
+	// between the threshold and 80% of it the rate is kept
+	assert.Equal(t, 500.0, nextRate(500, 500, 70, 80))
+	assert.Equal(t, 0.0, nextRate(0, 500, 70, 80))
+
+	// well below it the rate grows, and is lifted when the copy does not reach it
+	assert.Equal(t, 625.0, nextRate(500, 500, 40, 80))
+	assert.Equal(t, 0.0, nextRate(500, 100, 40, 80))
+}
@@ -0,0 +1,30 @@
+package copy
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the spans of the tables, it does nothing unless a tracer provider is configured
+var tracer = otel.Tracer("github.com/jeff-99/mssqlcopy/pkg/copy")
+
+// startSpan starts the span of the copy of the table, which is the parent of the spans of its database operations. Wait ends it.
+func (ct *CopyTask) startSpan(ctx context.Context) context.Context {
+	ctx, ct.span = tracer.Start(ctx, "copy table", trace.WithAttributes(
+		attribute.String("asqlcp.table", ct.table.String()),
+		attribute.String("asqlcp.mode", string(ct.opts.Mode)),
+		attribute.String("asqlcp.strategy", string(ct.strategy)),
+	))
+
+	return ctx
+}
+
+// endSpan ends the span of the table once its copy is done
+func (ct *CopyTask) endSpan() {
+	if ct.span != nil {
+		ct.span.End()
+	}
+}
@@ -0,0 +1,85 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// TriggerMode determines how the triggers of the target tables are treated during the load
+type TriggerMode string
+
+const (
+	// TriggersKeep leaves the triggers as they are, bulk copies skip them and the INSERT based strategies run them
+	TriggersKeep TriggerMode = "keep"
+	// TriggersDisable disables the triggers during the load and enables them afterwards
+	TriggersDisable TriggerMode = "disable"
+	// TriggersFire runs the triggers for bulk copies as well
+	TriggersFire TriggerMode = "fire"
+)
+
+func ParseTriggerMode(mode string) (TriggerMode, error) {
+	switch TriggerMode(mode) {
+	case TriggersKeep, TriggersDisable, TriggersFire:
+		return TriggerMode(mode), nil
+	case "":
+		return TriggersKeep, nil
+	}
+
+	return "", fmt.Errorf("unknown trigger mode %q", mode)
+}
+
+// loadDisablesTriggers reports whether the triggers of the target table are disabled during the load. Besides the disable mode
+// they are disabled when bulk copies keep the values of an identity column in the keep mode, which insert the batches with
+// INSERT ... SELECT that would run the triggers bulk copies skip otherwise.
+func (ct *CopyTask) loadDisablesTriggers(schema mssql.SchemaDefinition, columns []string) bool {
+	if ct.opts.Triggers == TriggersDisable {
+		return true
+	}
+
+	if ct.opts.Triggers == TriggersFire || !ct.opts.Bulk.KeepIdentity || ct.strategy != StrategyBulk || ct.opts.Mode == ModeMerge {
+		return false
+	}
+
+	// the tables with columns bulk copies do not support are loaded with INSERT statements, which run the triggers
+	if len(mssql.BulkUnsupportedColumns(schema, columns)) > 0 {
+		return false
+	}
+
+	for _, column := range columns {
+		if schema[column].Identity {
+			return true
+		}
+	}
+
+	return false
+}
+
+// disableTriggers disables the triggers of the target table before it is loaded, finishTarget enables them again
+func (ct *CopyTask) disableTriggers(ctx context.Context) error {
+	triggers, err := ct.targetDB.GetTriggers(ctx, ct.target)
+	if err != nil {
+		return fmt.Errorf("Failed to get the triggers of target table %s, %s", ct.table, err)
+	}
+
+	// recorded first, so the cleanup command can enable them when the run crashes
+	err = ct.targetDB.RecordTriggers(ctx, ct.target, triggers)
+	if err != nil {
+		return fmt.Errorf("Failed to record the triggers of table %s in the targetDB, %s", ct.table, err)
+	}
+
+	err = ct.writeRecovery(recoveryTriggers, mssql.EnableTriggerStatements(triggers))
+	if err != nil {
+		return fmt.Errorf("Failed to write the triggers of table %s to the recovery file, %s", ct.table, err)
+	}
+
+	// set before they are disabled, enabling the triggers a failure left enabled does no harm
+	ct.disabledTriggers = triggers
+	err = ct.targetDB.DisableTriggers(ctx, triggers)
+	if err != nil {
+		return fmt.Errorf("Failed to disable the triggers of target table %s, %s", ct.table, err)
+	}
+
+	return nil
+}
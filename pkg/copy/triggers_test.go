@@ -0,0 +1,31 @@
+package copy
+
+import (
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadDisablesTriggers(t *testing.T) {
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	schema := mssql.SchemaDefinition{
+		"id":    {Name: "id", Type: "int", Identity: true},
+		"total": {Name: "total", Type: "decimal"},
+	}
+	columns := []string{"id", "total"}
+
+	task := func(triggers TriggerMode, keepIdentity bool, strategy Strategy) *CopyTask {
+		ct := NewCopyTask(orders, nil, nil, Options{Triggers: triggers, Bulk: mssql.BulkOptions{KeepIdentity: keepIdentity}}, nil)
+		ct.strategy = strategy
+		return ct
+	}
+
+	assert.True(t, task(TriggersDisable, false, StrategyInsert).loadDisablesTriggers(schema, columns))
+	assert.False(t, task(TriggersKeep, false, StrategyBulk).loadDisablesTriggers(schema, columns))
+	// the batches keeping the identity values are inserted with INSERT ... SELECT, which would run the triggers
+	assert.True(t, task(TriggersKeep, true, StrategyBulk).loadDisablesTriggers(schema, columns))
+	assert.False(t, task(TriggersKeep, true, StrategyBulk).loadDisablesTriggers(schema, []string{"total"}))
+	assert.False(t, task(TriggersFire, true, StrategyBulk).loadDisablesTriggers(schema, columns))
+	assert.False(t, task(TriggersKeep, true, StrategyInsert).loadDisablesTriggers(schema, columns))
+}
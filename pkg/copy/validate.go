@@ -0,0 +1,60 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// ForeignKeyValidation is the result of validating the rows of the target against a foreign key
+type ForeignKeyValidation struct {
+	Name   string
+	Table  mssql.TableRef
+	Parent mssql.TableRef
+	Err    error
+}
+
+// ValidateForeignKeys validates the untrusted foreign keys of and referencing the tables in the target WITH CHECK,
+// so the server trusts them again. Foreign keys re-added WITH NOCHECK after a copy are untrusted, disabled ones are skipped.
+func (e *Engine) ValidateForeignKeys(ctx context.Context, tables []mssql.TableRef) ([]ForeignKeyValidation, error) {
+	foreignKeys := make(map[string]mssql.ForeingKeyConstraint)
+	for _, table := range tables {
+		own, err := e.targetDB.GetForeignKeys(ctx, e.opts.targetTable(table))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the foreign keys of table %s, %w", table, err)
+		}
+
+		referencing, err := e.targetDB.GetReferencedForeignKeys(ctx, e.opts.targetTable(table))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the foreign keys referencing table %s, %w", table, err)
+		}
+
+		// composite foreign keys have a constraint per column, they are validated once
+		for _, fk := range append(own, referencing...) {
+			if fk.NotTrusted && fk.NoCheck != "true" {
+				foreignKeys[mssql.TableRef{Schema: fk.Schema, Table: fk.Name}.String()] = fk
+			}
+		}
+	}
+
+	names := make([]string, 0, len(foreignKeys))
+	for name := range foreignKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	validations := make([]ForeignKeyValidation, len(names))
+	for i, name := range names {
+		fk := foreignKeys[name]
+		validations[i] = ForeignKeyValidation{
+			Name:   fk.Name,
+			Table:  mssql.TableRef{Schema: fk.Schema, Table: fk.Table},
+			Parent: mssql.TableRef{Schema: fk.ReferencedSchema, Table: fk.ReferencedTable},
+			Err:    e.targetDB.ValidateForeignKey(ctx, fk),
+		}
+	}
+
+	return validations, nil
+}
@@ -0,0 +1,143 @@
+package copy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// ErrCountMismatch is the failure of a table of which the target has another number of rows than were copied from the source
+var ErrCountMismatch = errors.New("row count mismatch between source and target")
+
+// TableVerification is the comparison of the rows of a table in the source and the target
+type TableVerification struct {
+	Table          mssql.TableRef
+	SourceRows     int64
+	TargetRows     int64
+	SourceChecksum int64
+	TargetChecksum int64
+	// Skipped are the columns left out of the checksum because their type can not be checksummed
+	Skipped []string
+	Err     error
+}
+
+// Passed reports whether the row counts and checksums of source and target are equal
+func (v TableVerification) Passed() bool {
+	return v.Err == nil && v.SourceRows == v.TargetRows && v.SourceChecksum == v.TargetChecksum
+}
+
+// Verify compares the row count and checksum of every table between source and target,
+// the query filter is applied to both sides so filtered copies can be verified as well
+func (e *Engine) Verify(ctx context.Context, tables []mssql.TableRef) []TableVerification {
+	verifications := make([]TableVerification, len(tables))
+	for i, table := range tables {
+		verifications[i] = e.verifyTable(ctx, table)
+	}
+
+	return verifications
+}
+
+func (e *Engine) verifyTable(ctx context.Context, table mssql.TableRef) TableVerification {
+	verification := TableVerification{Table: table}
+
+	columns, skipped, err := e.comparableColumns(ctx, table)
+	if err != nil {
+		verification.Err = err
+		return verification
+	}
+	verification.Skipped = skipped
+	readOpts := e.opts.filter(table)
+
+	verification.SourceRows, verification.SourceChecksum, err = e.sourceDB.GetChecksum(ctx, table, columns, readOpts)
+	if err != nil {
+		verification.Err = fmt.Errorf("failed to checksum the source rows, %w", err)
+		return verification
+	}
+
+	verification.TargetRows, verification.TargetChecksum, err = e.targetDB.GetChecksum(ctx, e.opts.targetTable(table), columns, readOpts)
+	if err != nil {
+		verification.Err = fmt.Errorf("failed to checksum the target rows, %w", err)
+		return verification
+	}
+
+	return verification
+}
+
+// verifyCount compares the target rows with the source rows, less the rows skipped by the transformers and rejected by the target.
+// A truncated target holds only the copied rows, the delete mode replaced the target rows matching the query filter. The other modes
+// keep target rows the source does not have, their count is not verified.
+func (ct *CopyTask) verifyCount(ctx context.Context) error {
+	if !ct.opts.VerifyCounts || (ct.opts.Mode != ModeTruncate && ct.opts.Mode != ModeDelete) {
+		return nil
+	}
+
+	readOpts := ct.opts.filter(ct.table)
+	sourceRows := ct.sourceRows
+	if !ct.sourceCounted {
+		var err error
+		sourceRows, err = ct.sourceDB.GetCount(ctx, ct.table, readOpts)
+		if err != nil {
+			return fmt.Errorf("Failed to count the rows of source table %s, %w", ct.table, err)
+		}
+	}
+
+	// the subset predicates only select the source rows
+	targetOpts := mssql.ReadOptions{}
+	if ct.opts.Mode == ModeDelete {
+		targetOpts.QueryFilter = ct.opts.QueryFilter
+	}
+
+	targetRows, err := ct.targetDB.GetCount(ctx, ct.target, targetOpts)
+	if err != nil {
+		return fmt.Errorf("Failed to count the rows of target table %s, %w", ct.table, err)
+	}
+
+	ct.rejectLock.Lock()
+	expected := sourceRows - ct.rejected - int(ct.skipped.Load())
+	ct.rejectLock.Unlock()
+
+	if targetRows != expected {
+		return fmt.Errorf("%w on table %s, expected %d rows in the target from %d source rows but found %d", ErrCountMismatch, ct.table, expected, sourceRows, targetRows)
+	}
+
+	return nil
+}
+
+// comparableColumns returns the copied columns that can be checksummed and the ones that are left out, sorted by name
+func (e *Engine) comparableColumns(ctx context.Context, table mssql.TableRef) ([]string, []string, error) {
+	sourceSchema, err := e.sourceDB.GetSchemaDefinition(ctx, table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the schema from the source, %w", err)
+	}
+
+	targetSchema, err := e.targetDB.GetSchemaDefinition(ctx, e.opts.targetTable(table))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get the schema from the target, %w", err)
+	}
+
+	copied, err := e.opts.copyColumns(table, sourceSchema, targetSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// only the copied columns are compared, columns converted to another type have another binary checksum
+	copiedSchema := make(mssql.SchemaDefinition, len(copied))
+	converted := make([]string, 0)
+	for _, column := range copied {
+		source, target := sourceSchema[column], targetSchema[column]
+		if source.Type != target.Type || source.MaxLength != target.MaxLength || source.Precision != target.Precision || source.Scale != target.Scale {
+			converted = append(converted, column)
+			continue
+		}
+		copiedSchema[column] = source
+	}
+
+	columns, skipped := mssql.ChecksumColumns(copiedSchema)
+	skipped = append(skipped, converted...)
+	sort.Strings(skipped)
+
+	return columns, skipped, nil
+}
@@ -0,0 +1,150 @@
+package copy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// fannedOut reports whether the rows of the table are inserted by several writers with a connection and transaction each.
+// Like the ranges of a partitioned table the writers commit on their own, so merges, which load a single staging table,
+// and insert-select, which copies in a single statement, use a single writer. Partitioned tables already have a writer per range.
+func (ct *CopyTask) fannedOut() bool {
+	if ct.opts.Writers[ct.table.String()] <= 1 || ct.partitioned() {
+		return false
+	}
+
+	switch ct.opts.Mode {
+	case ModeTruncate, ModeAppend, ModeDelete, ModeIncremental:
+	default:
+		return false
+	}
+
+	return ct.strategy == StrategyBulk || ct.strategy == StrategyInsert
+}
+
+// copyFannedOut reads the table once and inserts the batches with several writers. The target is prepared before the first writer
+// connects and restored when all writers committed, a failing writer stops the others.
+func (ct *CopyTask) copyFannedOut(ctx context.Context, columns []string, schema mssql.SchemaDefinition) {
+	defer ct.wg.Done()
+	defer ct.wg.Done()
+	defer ct.restoreOnFailure()
+
+	readOpts, err := ct.readOptions(ctx)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to get the watermark for table %s from the targetDB, %w", ct.table, err))
+		return
+	}
+
+	numberOfRows, approximate, err := ct.count(ctx, readOpts)
+	if err != nil {
+		ct.fail(fmt.Errorf("Failed to get count for table %s from the sourceDB, %w", ct.table, err))
+		return
+	}
+	ct.eventChan <- monitor.CountUpdateEvent{TotalRows: numberOfRows, Table: ct.table, Approximate: approximate}
+
+	err = ct.startLoad(ctx, true)
+	if err != nil {
+		ct.fail(err)
+		return
+	}
+
+	writers, err := ct.writeFannedOut(ctx, columns, schema, readOpts)
+	ct.progress.flush()
+	if err != nil {
+		ct.fail(err)
+		return
+	}
+
+	err = ct.finishLoad(ctx, writers)
+	if err != nil {
+		ct.fail(err)
+		return
+	}
+
+	ct.eventChan <- monitor.CopyTaskFinishedEvent{Table: ct.table}
+}
+
+// writeFannedOut streams the rows selected by readOpts to the writers of the table, it returns the writers of the committed rows
+func (ct *CopyTask) writeFannedOut(ctx context.Context, columns []string, schema mssql.SchemaDefinition, readOpts mssql.ReadOptions) ([]rowWriter, error) {
+	writeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the writers connect before the reader starts, so a target refusing connections fails before any row is read
+	writers := make([]rowWriter, ct.opts.Writers[ct.table.String()])
+	for i := range writers {
+		writer, err := ct.rowWriter(writeCtx, ct.target, columns, schema)
+		if err != nil {
+			for _, writer := range writers[:i] {
+				writer.Rollback(ctx)
+			}
+			return nil, fmt.Errorf("Failed to prepare the inserts into target table %s, %s", ct.table, err)
+		}
+		writers[i] = writer
+	}
+
+	rows, err := ct.sourceDB.SelectFrom(writeCtx, ct.table, columns, readOpts)
+	if err != nil {
+		for _, writer := range writers {
+			writer.Rollback(ctx)
+		}
+		return nil, fmt.Errorf("Failed to select data from source table %s, %s", ct.table, err)
+	}
+	defer rows.Close()
+
+	dataChan := make(chan rowBatch, ct.opts.bufferBatches())
+	budget := newByteBudget(ct.opts.BufferBytes)
+	var readErr error
+	go func() {
+		defer close(dataChan)
+		readErr = ct.readRange(writeCtx, rows, columns, dataChan, budget)
+	}()
+
+	var failure error
+	var once sync.Once
+	wg := sync.WaitGroup{}
+	for _, writer := range writers {
+		wg.Add(1)
+		go func(writer rowWriter) {
+			defer wg.Done()
+
+			var lastKey []string
+			err := ct.insertBatches(writeCtx, writer, columns, dataChan, budget, &lastKey)
+			if err != nil {
+				// the errors of the other writers are caused by the cancellation
+				once.Do(func() {
+					failure = err
+					cancel()
+				})
+				// the reader stops at its next batch
+				for range dataChan {
+				}
+			}
+		}(writer)
+	}
+	wg.Wait()
+
+	if failure == nil {
+		failure = readErr
+	}
+	if failure != nil {
+		// the writers that did not fail hold the uncommitted rows of their last batches
+		for _, writer := range writers {
+			writer.Rollback(ctx)
+		}
+		return nil, failure
+	}
+
+	for _, writer := range writers {
+		var lastKey []string
+		err = ct.commitRows(ctx, writer, columns, &lastKey)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to commit the transaction into target table %s, %s", ct.table, err)
+		}
+	}
+
+	return writers, nil
+}
@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ciSink writes a progress line for every table that copied rows since the last render and a summary at the end,
+// for logs that do not support redrawing
+type ciSink struct {
+	w         io.Writer
+	templates CITemplates
+
+	// rowsCopied are the rows of the tables at their last progress line, written at lineTimes
+	rowsCopied map[string]int
+	lineTimes  map[string]time.Time
+
+	// interval and percent space out the progress lines of a table, see SetCIHeartbeat
+	interval time.Duration
+	percent  int
+}
+
+func newCISink(w io.Writer) *ciSink {
+	return &ciSink{w: w, templates: defaultCITemplates(), rowsCopied: make(map[string]int), lineTimes: make(map[string]time.Time)}
+}
+
+// SetCIHeartbeat writes the CI progress line of a table at most every interval or when it progressed by percent of its rows,
+// whichever comes first, instead of at every render in which it copied rows. The first and the last line of a table are always
+// written, zero disables either condition.
+func (m *Monitor) SetCIHeartbeat(interval time.Duration, percent int) {
+	if m.ci != nil {
+		m.ci.interval = interval
+		m.ci.percent = percent
+	}
+}
+
+func (c *ciSink) Event(state *State, event Event) {
+	if e, ok := event.(BatchSizeEvent); ok {
+		fmt.Fprintf(c.w, "%s %s: batch size %d, %s\n", time.Now().Format(time.RFC3339), e.Table, e.BatchSize, e.Reason)
+	}
+}
+
+func (c *ciSink) Status(state *State, name, text string) {
+	if text != "" {
+		fmt.Fprintf(c.w, "%s %s: %s\n", time.Now().Format(time.RFC3339), name, text)
+	}
+}
+
+func (c *ciSink) Render(state *State) {
+	now := time.Now()
+	for _, key := range state.keys {
+		p := state.tables[key]
+		if p.RowTotal == 0 {
+			continue
+		}
+
+		lastCount, ok := c.rowsCopied[key]
+		if ok && (lastCount >= p.RowsCopied || !c.due(key, p, now)) {
+			continue
+		}
+
+		c.writeProgress(p)
+		c.rowsCopied[key] = p.RowsCopied
+		c.lineTimes[key] = now
+	}
+}
+
+// due reports whether the next progress line of a table that copied rows since its last line is written
+func (c *ciSink) due(key string, p *ProgressReporter, now time.Time) bool {
+	if p.done || (c.interval == 0 && c.percent == 0) {
+		return true
+	}
+
+	if c.interval > 0 && now.Sub(c.lineTimes[key]) >= c.interval {
+		return true
+	}
+
+	return c.percent > 0 && (p.RowsCopied-c.rowsCopied[key])*100 >= c.percent*p.RowTotal
+}
+
+func (c *ciSink) Close(state *State) {
+	c.Render(state)
+	c.writeSummary(state)
+}
+
+// writeProgress renders the CI progress line of a table
+func (c *ciSink) writeProgress(p *ProgressReporter) {
+	now := time.Now()
+	current, average := p.Throughput(now)
+	err := c.templates.progress.Execute(c.w, ProgressLine{
+		Time:        now,
+		Table:       p.Table.String(),
+		RowsCopied:  p.RowsCopied,
+		RowTotal:    p.RowTotal,
+		Approximate: p.Approximate,
+		Total:       p.total(),
+		BytesCopied: p.BytesCopied,
+		Current:     current,
+		Average:     average,
+	})
+	if err != nil {
+		fmt.Fprintf(c.w, "failed to render progress: %s\n", err)
+	}
+}
+
+// writeSummary renders the CI summary of all tables
+func (c *ciSink) writeSummary(state *State) {
+	err := c.templates.summary.Execute(c.w, state.Summary())
+	if err != nil {
+		fmt.Fprintf(c.w, "failed to render summary: %s\n", err)
+	}
+}
+
+// errorSink writes only the failures of tables as they happen, for runs of which the progress is not wanted
+type errorSink struct {
+	w io.Writer
+}
+
+func (e *errorSink) Event(state *State, event Event) {
+	if failure, ok := event.(ErrorEvent); ok {
+		fmt.Fprintf(e.w, "%s %s FAILED: %s\n", time.Now().Format(time.RFC3339), failure.Table, failure.Err)
+	}
+}
+
+func (e *errorSink) Status(*State, string, string) {}
+
+func (e *errorSink) Render(*State) {}
+
+func (e *errorSink) Close(*State) {}
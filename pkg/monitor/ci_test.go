@@ -0,0 +1,84 @@
+package monitor_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIHeartbeatPercent(t *testing.T) {
+	t.Parallel()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	templates, err := monitor.ParseCITemplates("{{.Table}} {{.RowsCopied}}", "done")
+	require.NoError(t, err)
+
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, true, w)
+	mon.SetCITemplates(templates)
+	mon.SetCIHeartbeat(time.Hour, 50)
+
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(context.Background())
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CountUpdateEvent{Table: orders, TotalRows: 100}
+	for _, rows := range []int{10, 10, 50, 20} {
+		eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: rows}
+		// rendered every 10 milliseconds
+		time.Sleep(30 * time.Millisecond)
+	}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 5}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	require.NoError(t, <-done)
+	w.Close()
+
+	out, _ := io.ReadAll(r)
+
+	// the first line, 50% more rows than at 10 and the last line
+	assert.Equal(t, "[dbo].[orders] 10\n[dbo].[orders] 70\n[dbo].[orders] 95\ndone\n", string(out))
+}
+
+func TestErrorsOutput(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, false, &buf)
+	mon.SetOutput(monitor.OutputErrors)
+
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(context.Background())
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.CountUpdateEvent{Table: orders, TotalRows: 10}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 10}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	eventChan <- monitor.ErrorEvent{Table: lines, Err: errors.New("timeout")}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: lines}
+	require.NoError(t, <-done)
+
+	out := buf.String()
+	assert.Equal(t, 1, strings.Count(out, "\n"))
+	assert.True(t, strings.HasSuffix(out, " [dbo].[lines] FAILED: timeout\n"), out)
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// Output determines what the monitor writes
+type Output string
+
+const (
+	// OutputText renders the progress bars, or the progress lines in CI mode
+	OutputText Output = "text"
+	// OutputJSON writes every event as a JSON line with a timestamp, for log ingestion and scripts
+	OutputJSON Output = "json"
+	// OutputNone writes nothing, for runs of which only the exit code matters
+	OutputNone Output = "none"
+	// OutputErrors writes only the failures of tables, see --quiet
+	OutputErrors Output = "errors"
+)
+
+func ParseOutput(output string) (Output, error) {
+	switch Output(output) {
+	case OutputText, OutputJSON, OutputNone, OutputErrors:
+		return Output(output), nil
+	case "":
+		return OutputText, nil
+	}
+
+	return "", fmt.Errorf("unknown output %q", output)
+}
+
+// SetOutput replaces the built-in output, the sinks added with AddSink are kept
+func (m *Monitor) SetOutput(output Output) {
+	switch output {
+	case OutputJSON:
+		m.sinks[0] = &jsonSink{w: m.w}
+	case OutputNone:
+		m.sinks[0] = NullSink{}
+	case OutputErrors:
+		m.sinks[0] = &errorSink{w: m.w}
+	}
+}
+
+// jsonSink writes every event and status as a JSON line and the summary as the last line
+type jsonSink struct {
+	w io.Writer
+}
+
+func NewJSONSink(w io.Writer) Sink {
+	return &jsonSink{w: w}
+}
+
+// jsonHeader starts every JSON line
+type jsonHeader struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+}
+
+func header(event string) jsonHeader {
+	return jsonHeader{Time: time.Now(), Event: event}
+}
+
+// Event writes event as a JSON line, the fields of the event follow the time and the name of the event
+func (j *jsonSink) Event(state *State, event Event) {
+	switch e := event.(type) {
+	case CopyTaskStartedEvent:
+		j.writeJSON(struct {
+			jsonHeader
+			CopyTaskStartedEvent
+		}{header("started"), e})
+	case CountUpdateEvent:
+		j.writeJSON(struct {
+			jsonHeader
+			CountUpdateEvent
+		}{header("count"), e})
+	case ProgressUpdateEvent:
+		j.writeJSON(struct {
+			jsonHeader
+			ProgressUpdateEvent
+		}{header("progress"), e})
+	case BatchSizeEvent:
+		j.writeJSON(struct {
+			jsonHeader
+			BatchSizeEvent
+		}{header("batch_size"), e})
+	case CopyTaskFinishedEvent:
+		j.writeJSON(struct {
+			jsonHeader
+			CopyTaskFinishedEvent
+		}{header("finished"), e})
+	case ErrorEvent:
+		// errors marshal as an empty object
+		j.writeJSON(struct {
+			jsonHeader
+			Table mssql.TableRef `json:"table"`
+			Error string         `json:"error"`
+		}{header("error"), e.Table, e.Err.Error()})
+	case RetryEvent:
+		j.writeJSON(struct {
+			jsonHeader
+			Table     mssql.TableRef `json:"table"`
+			Operation string         `json:"operation,omitempty"`
+			Attempt   int            `json:"attempt"`
+			Error     string         `json:"error"`
+		}{header("retry"), e.Table, e.Operation, e.Attempt, e.Err.Error()})
+	case ForeignKeysEvent:
+		j.writeJSON(struct {
+			jsonHeader
+			ForeignKeysEvent
+		}{header("foreign_keys"), e})
+	}
+}
+
+func (j *jsonSink) Status(state *State, name, text string) {
+	j.writeJSON(struct {
+		jsonHeader
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	}{header("status"), name, text})
+}
+
+func (j *jsonSink) Render(state *State) {}
+
+// Close writes the summary of the run as the last line
+func (j *jsonSink) Close(state *State) {
+	summary := state.Summary()
+	j.writeJSON(struct {
+		jsonHeader
+		Tables     int       `json:"tables"`
+		Done       int       `json:"done"`
+		Failed     int       `json:"failed"`
+		RowsCopied int       `json:"rows_copied"`
+		Failures   []Failure `json:"failures"`
+	}{header("summary"), summary.Tables, summary.Done, summary.Failed, summary.RowsCopied, summary.Failures})
+}
+
+func (j *jsonSink) writeJSON(line interface{}) {
+	data, err := json.Marshal(line)
+	if err != nil {
+		fmt.Fprintf(j.w, "failed to marshal event: %s\n", err)
+		return
+	}
+
+	j.w.Write(append(data, '\n'))
+}
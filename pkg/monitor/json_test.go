@@ -0,0 +1,73 @@
+package monitor_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitorJSONOutput(t *testing.T) {
+	t.Parallel()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, false, w)
+	mon.SetOutput(monitor.OutputJSON)
+
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(context.Background())
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.CountUpdateEvent{Table: orders, TotalRows: 10}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 10}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	eventChan <- monitor.ErrorEvent{Table: lines, Err: errors.New("timeout")}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: lines}
+	assert.NoError(t, <-done)
+	w.Close()
+
+	out, _ := io.ReadAll(r)
+	var events []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		var event map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+
+		_, err := time.Parse(time.RFC3339Nano, event["time"].(string))
+		assert.NoError(t, err)
+		delete(event, "time")
+
+		events = append(events, event)
+	}
+
+	table := map[string]interface{}{"schema": "dbo", "table": "orders"}
+	assert.Equal(t, []map[string]interface{}{
+		{"event": "started", "table": table},
+		{"event": "started", "table": map[string]interface{}{"schema": "dbo", "table": "lines"}},
+		{"event": "count", "table": table, "total_rows": 10.0, "approximate": false},
+		{"event": "progress", "table": table, "rows_copied": 10.0},
+		{"event": "finished", "table": table},
+		{"event": "error", "table": map[string]interface{}{"schema": "dbo", "table": "lines"}, "error": "timeout"},
+		{"event": "finished", "table": map[string]interface{}{"schema": "dbo", "table": "lines"}},
+		{"event": "summary", "tables": 2.0, "done": 1.0, "failed": 1.0, "rows_copied": 10.0, "failures": []interface{}{
+			map[string]interface{}{"table": "[dbo].[lines]", "error": "timeout"},
+		}},
+	}, events)
+}
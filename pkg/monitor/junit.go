@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// junitTestSuites is the root of a JUnit XML report, as read by Azure DevOps and the GitHub Actions test reporters
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as a JUnit XML test suite named name with a test case per table. Failed tables are failures
+// with their error, unfinished tables are skipped.
+func (r Report) WriteJUnit(w io.Writer, name string) error {
+	suite := junitTestSuite{
+		Name:      name,
+		Tests:     len(r.Tables),
+		Failures:  r.Failed,
+		Skipped:   r.Unfinished,
+		Time:      junitTime(r.Duration),
+		Timestamp: r.Started.UTC().Format(time.RFC3339),
+		Cases:     make([]junitTestCase, 0, len(r.Tables)),
+	}
+
+	for _, t := range r.Tables {
+		testCase := junitTestCase{
+			Name:      t.Table,
+			ClassName: name,
+			Time:      junitTime(t.Duration),
+			SystemOut: fmt.Sprintf("%d of %d rows copied, %d retries, %d foreign keys dropped and %d restored",
+				t.RowsCopied, t.RowsTotal, t.Retries, t.ForeignKeysDropped, t.ForeignKeysRestored),
+		}
+
+		switch t.Status {
+		case TableFailed:
+			testCase.Failure = &junitMessage{Message: firstLine(t.Error), Type: "CopyFailed", Text: t.Error}
+		case TableUnfinished:
+			testCase.Skipped = &junitMessage{Message: "the run ended before the table was copied"}
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(junitTestSuites{
+		Name:     name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// junitTime formats seconds with millisecond precision
+func junitTime(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
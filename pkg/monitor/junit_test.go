@@ -0,0 +1,46 @@
+package monitor_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportJUnit(t *testing.T) {
+	started := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	report := monitor.Report{
+		Started:    started,
+		Duration:   12.5,
+		Failed:     1,
+		Unfinished: 1,
+		Tables: []monitor.TableReport{
+			{Table: "[dbo].[customers]", Status: monitor.TableUnfinished},
+			{Table: "[dbo].[lines]", Status: monitor.TableFailed, RowsCopied: 5, RowsTotal: 10, Duration: 2, Error: "timeout <after 30s>"},
+			{Table: "[dbo].[orders]", Status: monitor.TableCopied, RowsCopied: 100, RowsTotal: 100, Duration: 4.25, Retries: 1},
+		},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteJUnit(&out, "asqlcp"))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="asqlcp" tests="3" failures="1" skipped="1" time="12.500">
+  <testsuite name="asqlcp" tests="3" failures="1" skipped="1" time="12.500" timestamp="2024-05-01T02:00:00Z">
+    <testcase name="[dbo].[customers]" classname="asqlcp" time="0.000">
+      <skipped message="the run ended before the table was copied"></skipped>
+      <system-out>0 of 0 rows copied, 0 retries, 0 foreign keys dropped and 0 restored</system-out>
+    </testcase>
+    <testcase name="[dbo].[lines]" classname="asqlcp" time="2.000">
+      <failure message="timeout &lt;after 30s&gt;" type="CopyFailed">timeout &lt;after 30s&gt;</failure>
+      <system-out>5 of 10 rows copied, 0 retries, 0 foreign keys dropped and 0 restored</system-out>
+    </testcase>
+    <testcase name="[dbo].[orders]" classname="asqlcp" time="4.250">
+      <system-out>100 of 100 rows copied, 1 retries, 0 foreign keys dropped and 0 restored</system-out>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, out.String())
+}
@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogSink writes the events of the run to a structured logger, apart from the rendered progress. Failures and retries are logged
+// as errors and warnings, the start and end of tables at info level and the counts and progress of tables at debug level.
+type LogSink struct {
+	logger *slog.Logger
+}
+
+func NewLogSink(logger *slog.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (s *LogSink) Event(state *State, event Event) {
+	switch e := event.(type) {
+	case CopyTaskStartedEvent:
+		s.logger.Info("copy started", "table", e.Table.String())
+	case CountUpdateEvent:
+		s.logger.Debug("counted rows", "table", e.Table.String(), "rows", e.TotalRows, "approximate", e.Approximate)
+	case ProgressUpdateEvent:
+		s.logger.Debug("copied rows", "table", e.Table.String(), "rows", e.RowsCopied, "bytes", e.BytesCopied)
+	case BatchSizeEvent:
+		s.logger.Info("changed batch size", "table", e.Table.String(), "batch_size", e.BatchSize, "reason", e.Reason)
+	case RetryEvent:
+		if e.Operation != "" {
+			s.logger.Warn("retrying operation", "table", e.Table.String(), "operation", e.Operation, "attempt", e.Attempt, "error", e.Err)
+			return
+		}
+		s.logger.Warn("retrying rows", "table", e.Table.String(), "attempt", e.Attempt, "error", e.Err)
+	case ForeignKeysEvent:
+		if e.Dropped > 0 {
+			s.logger.Info("dropped foreign keys", "table", e.Table.String(), "foreign_keys", e.Dropped)
+		}
+		if e.Restored > 0 {
+			s.logger.Info("restored foreign keys", "table", e.Table.String(), "foreign_keys", e.Restored)
+		}
+	case ErrorEvent:
+		s.logger.Error("copy failed", "table", e.Table.String(), "error", e.Err)
+	case CopyTaskFinishedEvent:
+		// a failed table was logged as such
+		if p := state.Table(e.Table.String()); p != nil && p.Err() != nil {
+			return
+		}
+		s.logger.Info("copy finished", "table", e.Table.String())
+	}
+}
+
+func (s *LogSink) Status(state *State, name, text string) {
+	if text != "" {
+		s.logger.Info("status", "name", name, "text", text)
+	}
+}
+
+func (s *LogSink) Render(state *State) {}
+
+func (s *LogSink) Close(state *State) {
+	summary := state.Summary()
+	failed := make([]string, 0, len(summary.Failures))
+	for _, failure := range summary.Failures {
+		failed = append(failed, failure.Table)
+	}
+
+	level := slog.LevelInfo
+	if summary.Done < summary.Tables {
+		level = slog.LevelError
+	}
+	s.logger.Log(context.Background(), level, "run finished", "tables", summary.Tables, "copied", summary.Done, "failed", failed, "rows", summary.RowsCopied)
+}
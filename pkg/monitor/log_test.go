@@ -0,0 +1,68 @@
+package monitor_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSink(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, true, io.Discard)
+	mon.SetOutput(monitor.OutputNone)
+	mon.AddSink(monitor.NewLogSink(logger))
+
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(context.Background())
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.ForeignKeysEvent{Table: orders, Dropped: 2}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 10}
+	eventChan <- monitor.RetryEvent{Table: orders, Attempt: 1, Err: errors.New("deadlock")}
+	eventChan <- monitor.ForeignKeysEvent{Table: orders, Restored: 2}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	eventChan <- monitor.ErrorEvent{Table: lines, Err: errors.New("timeout")}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: lines}
+	require.NoError(t, <-done)
+
+	var records []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record["level"].(string)+" "+record["msg"].(string))
+	}
+
+	// the progress is logged at debug level
+	assert.Equal(t, []string{
+		"INFO copy started",
+		"INFO copy started",
+		"INFO dropped foreign keys",
+		"WARN retrying rows",
+		"INFO restored foreign keys",
+		"INFO copy finished",
+		"ERROR copy failed",
+		"ERROR run finished",
+	}, records)
+	assert.Contains(t, buf.String(), `"msg":"copy failed","table":"[dbo].[lines]","error":"timeout"`)
+	assert.Contains(t, buf.String(), `"msg":"run finished","tables":2,"copied":1,"failed":["[dbo].[lines]"],"rows":10`)
+}
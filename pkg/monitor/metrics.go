@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// tableMetrics are the metrics of a table
+type tableMetrics struct {
+	table      mssql.TableRef
+	rowsCopied int64
+	rowsTotal  int64
+	bytes      int64
+	errors     int
+	started    time.Time
+	finished   time.Time
+	failed     bool
+}
+
+// MetricsSink serves the progress of the run in the Prometheus text format, it is an http.Handler for /metrics
+type MetricsSink struct {
+	lock    sync.Mutex
+	started time.Time
+	tables  map[string]*tableMetrics
+	// finished is set when the run is done
+	finished time.Time
+}
+
+func NewMetricsSink() *MetricsSink {
+	return &MetricsSink{started: time.Now(), tables: make(map[string]*tableMetrics)}
+}
+
+func (s *MetricsSink) Event(state *State, event Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	switch e := event.(type) {
+	case CopyTaskStartedEvent:
+		s.tables[e.Table.String()] = &tableMetrics{table: e.Table, started: now}
+	case CountUpdateEvent:
+		if t, ok := s.tables[e.Table.String()]; ok {
+			t.rowsTotal = int64(e.TotalRows)
+		}
+	case ProgressUpdateEvent:
+		if t, ok := s.tables[e.Table.String()]; ok {
+			t.rowsCopied += int64(e.RowsCopied)
+			t.bytes += e.BytesCopied
+		}
+	case ErrorEvent:
+		if t, ok := s.tables[e.Table.String()]; ok {
+			t.errors++
+			t.failed = true
+			t.finished = now
+		}
+	case CopyTaskFinishedEvent:
+		if t, ok := s.tables[e.Table.String()]; ok && t.finished.IsZero() {
+			t.finished = now
+		}
+	}
+}
+
+func (s *MetricsSink) Status(state *State, name, text string) {}
+
+func (s *MetricsSink) Render(state *State) {}
+
+func (s *MetricsSink) Close(state *State) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.finished = time.Now()
+}
+
+// ServeHTTP writes the metrics of the tables and of the run
+func (s *MetricsSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.write(w)
+}
+
+func (s *MetricsSink) write(w io.Writer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0, len(s.tables))
+	for key := range s.tables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	metric := func(name, kind, help string, value func(t *tableMetrics) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, key := range keys {
+			t := s.tables[key]
+			fmt.Fprintf(w, "%s{schema=\"%s\",table=\"%s\"} %g\n", name, labelValue(t.table.Schema), labelValue(t.table.Table), value(t))
+		}
+	}
+
+	metric("asqlcp_table_rows_copied_total", "counter", "Rows copied into the table.", func(t *tableMetrics) float64 { return float64(t.rowsCopied) })
+	metric("asqlcp_table_rows", "gauge", "Rows to copy into the table, approximate when counted from the table metadata.", func(t *tableMetrics) float64 { return float64(t.rowsTotal) })
+	metric("asqlcp_table_bytes_copied_total", "counter", "Approximate bytes copied into the table.", func(t *tableMetrics) float64 { return float64(t.bytes) })
+	metric("asqlcp_table_errors_total", "counter", "Errors of the table.", func(t *tableMetrics) float64 { return float64(t.errors) })
+	metric("asqlcp_table_duration_seconds", "gauge", "Time the table is copying or took to copy.", func(t *tableMetrics) float64 {
+		end := t.finished
+		if end.IsZero() {
+			end = now
+		}
+		return end.Sub(t.started).Seconds()
+	})
+	metric("asqlcp_table_done", "gauge", "1 when the table is copied or failed.", func(t *tableMetrics) float64 {
+		if t.finished.IsZero() {
+			return 0
+		}
+		return 1
+	})
+
+	var rows, bytes int64
+	var running, done, failed int
+	for _, t := range s.tables {
+		rows += t.rowsCopied
+		bytes += t.bytes
+		switch {
+		case t.failed:
+			failed++
+		case !t.finished.IsZero():
+			done++
+		default:
+			running++
+		}
+	}
+
+	end := s.finished
+	if end.IsZero() {
+		end = now
+	}
+
+	fmt.Fprintf(w, "# HELP asqlcp_rows_copied_total Rows copied by the run.\n# TYPE asqlcp_rows_copied_total counter\nasqlcp_rows_copied_total %d\n", rows)
+	fmt.Fprintf(w, "# HELP asqlcp_bytes_copied_total Approximate bytes copied by the run.\n# TYPE asqlcp_bytes_copied_total counter\nasqlcp_bytes_copied_total %d\n", bytes)
+	fmt.Fprintf(w, "# HELP asqlcp_tables Tables of the run by state.\n# TYPE asqlcp_tables gauge\n")
+	fmt.Fprintf(w, "asqlcp_tables{state=\"running\"} %d\nasqlcp_tables{state=\"done\"} %d\nasqlcp_tables{state=\"failed\"} %d\n", running, done, failed)
+	fmt.Fprintf(w, "# HELP asqlcp_run_duration_seconds Time the run is copying or took to copy.\n# TYPE asqlcp_run_duration_seconds gauge\nasqlcp_run_duration_seconds %g\n", end.Sub(s.started).Seconds())
+}
+
+// labelValue escapes a label value of the text format
+func labelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,39 @@
+package monitor_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsSink(t *testing.T) {
+	sink := monitor.NewMetricsSink()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	sink.Event(nil, monitor.CopyTaskStartedEvent{Table: orders})
+	sink.Event(nil, monitor.CopyTaskStartedEvent{Table: lines})
+	sink.Event(nil, monitor.CountUpdateEvent{Table: orders, TotalRows: 100})
+	sink.Event(nil, monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 40, BytesCopied: 4000})
+	sink.Event(nil, monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 60, BytesCopied: 6000})
+	sink.Event(nil, monitor.CopyTaskFinishedEvent{Table: orders})
+	sink.Event(nil, monitor.ErrorEvent{Table: lines, Err: errors.New("timeout")})
+
+	recorder := httptest.NewRecorder()
+	sink.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	metrics := recorder.Body.String()
+
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, metrics, "# TYPE asqlcp_table_rows_copied_total counter\n")
+	assert.Contains(t, metrics, `asqlcp_table_rows_copied_total{schema="dbo",table="orders"} 100`+"\n")
+	assert.Contains(t, metrics, `asqlcp_table_rows{schema="dbo",table="orders"} 100`+"\n")
+	assert.Contains(t, metrics, `asqlcp_table_bytes_copied_total{schema="dbo",table="orders"} 10000`+"\n")
+	assert.Contains(t, metrics, `asqlcp_table_errors_total{schema="dbo",table="lines"} 1`+"\n")
+	assert.Contains(t, metrics, `asqlcp_table_done{schema="dbo",table="orders"} 1`+"\n")
+	assert.Contains(t, metrics, "asqlcp_rows_copied_total 100\n")
+	assert.Contains(t, metrics, "asqlcp_tables{state=\"running\"} 0\nasqlcp_tables{state=\"done\"} 1\nasqlcp_tables{state=\"failed\"} 1\n")
+}
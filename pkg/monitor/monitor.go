@@ -2,13 +2,9 @@ package monitor
 
 import (
 	"context"
-	"os"
-
-	// "encoding/json"
 	"fmt"
 	"io"
-	"sort"
-	"strings"
+	"os"
 	"time"
 
 	"github.com/jeff-99/mssqlcopy/pkg/mssql"
@@ -17,9 +13,13 @@ import (
 
 type Event interface{}
 
+// ProgressUpdateEvent reports the rows copied since the previous event of the table, the copy coalesces them into one event
+// every few thousand rows
 type ProgressUpdateEvent struct {
-	RowsCopied int            `json:"rows_copied"`
-	Table      mssql.TableRef `json:"table"`
+	RowsCopied int `json:"rows_copied"`
+	// BytesCopied is the approximate size of the rows
+	BytesCopied int64          `json:"bytes_copied,omitempty"`
+	Table       mssql.TableRef `json:"table"`
 }
 
 type CopyTaskStartedEvent struct {
@@ -33,6 +33,15 @@ type CopyTaskFinishedEvent struct {
 type CountUpdateEvent struct {
 	TotalRows int            `json:"total_rows"`
 	Table     mssql.TableRef `json:"table"`
+	// Approximate is set when TotalRows comes from table metadata instead of an exact count
+	Approximate bool `json:"approximate"`
+}
+
+// BatchSizeEvent reports a change of the number of rows per bulk copy transaction of a table
+type BatchSizeEvent struct {
+	Table     mssql.TableRef `json:"table"`
+	BatchSize int            `json:"batch_size"`
+	Reason    string         `json:"reason"`
 }
 
 type ErrorEvent struct {
@@ -40,117 +49,110 @@ type ErrorEvent struct {
 	Err   error          `json:"error"`
 }
 
-type LastRender struct {
-	managedLines int
-	rowsCopied   map[string]int
+// RetryEvent reports that the pending rows of a table are inserted again after a transient error, or that an Operation on the target
+// table, like its truncate, runs again after a deadlock or a lock timeout
+type RetryEvent struct {
+	Table     mssql.TableRef `json:"table"`
+	Operation string         `json:"operation,omitempty"`
+	Attempt   int            `json:"attempt"`
+	Err       error          `json:"error"`
+}
+
+// ForeignKeysEvent reports the foreign keys referencing a table that were dropped before it was emptied, or restored after it was loaded.
+// Disabled foreign keys count as dropped and enabled ones as restored.
+type ForeignKeysEvent struct {
+	Table    mssql.TableRef `json:"table"`
+	Dropped  int            `json:"dropped,omitempty"`
+	Restored int            `json:"restored,omitempty"`
 }
 
+// Monitor applies the events of a run to its State and passes them on to the sinks rendering them
 type Monitor struct {
 	eventChan    <-chan Event
-	ci           bool
-	monitors     map[string]*ProgressReporter
+	state        *State
 	renderTicker *time.Ticker
 
-	lastRender      *LastRender
-	sortedTableKeys []string
+	// sinks render the state, the first one is the built-in output replaced by SetOutput
+	sinks []Sink
+	// terminal and ci are the built-in sinks configured by the setters, nil when the monitor does not use them
+	terminal *terminalSink
+	ci       *ciSink
+
+	commands chan func()
+	done     chan struct{}
 
 	w io.Writer
 }
 
+// NewMonitor returns a monitor rendering progress bars to w, or progress lines in CI mode. w is stdout when nil.
 func NewMonitor(eventChan <-chan Event, ci bool, w io.Writer) *Monitor {
 	if w == nil {
 		w = os.Stdout
 	}
 
-	return &Monitor{
+	m := &Monitor{
 		eventChan:    eventChan,
-		monitors:     make(map[string]*ProgressReporter),
+		state:        newState(),
 		renderTicker: time.NewTicker(10 * time.Millisecond),
-		ci:           ci,
-		lastRender: &LastRender{
-			managedLines: 0,
-			rowsCopied:   make(map[string]int),
-		},
-		w: w,
+		commands:     make(chan func(), 100),
+		done:         make(chan struct{}),
+		w:            w,
+	}
+
+	if ci {
+		m.ci = newCISink(w)
+		m.sinks = []Sink{m.ci}
+	} else {
+		m.terminal = newTerminalSink(w)
+		m.sinks = []Sink{m.terminal}
 	}
+
+	return m
 }
 
+// AddSink renders the run with the sinks as well, it has to be called before Run
+func (m *Monitor) AddSink(sinks ...Sink) {
+	m.sinks = append(m.sinks, sinks...)
+}
+
+// SetTables sets the tables of the run, Run does not finish before all of them started and finished. Without them the run
+// is finished once the tables started so far are. It has to be called before Run.
+func (m *Monitor) SetTables(tables []mssql.TableRef) {
+	m.state.planned = make([]string, len(tables))
+	for i, table := range tables {
+		m.state.planned[i] = table.String()
+	}
+}
+
+// Done returns a channel that is closed when Run returns
+func (m *Monitor) Done() <-chan struct{} {
+	return m.done
+}
+
+// Run applies the events until all tables are finished or ctx is done, the events sent before ctx was done are applied
+// before it returns
 func (m *Monitor) Run(ctx context.Context) error {
+	defer close(m.done)
+
 	for {
 		select {
 		case <-ctx.Done():
-			m.render()
-			return nil
+			err := m.drain()
+			m.close()
+			return err
 		case event := <-m.eventChan:
-			// m.logEvent(event)
-
-			switch e := event.(type) {
-			case ProgressUpdateEvent:
-				if _, ok := m.monitors[e.Table.String()]; !ok {
-					return fmt.Errorf("no monitor found for table %s", e.Table.String())
-				}
-				m.monitors[e.Table.String()].Update(e.RowsCopied)
-			case CopyTaskStartedEvent:
-				if _, ok := m.monitors[e.Table.String()]; !ok {
-					m.monitors[e.Table.String()] = NewProgressReporter(e.Table)
-				} else {
-					return fmt.Errorf("monitor already exists for table %s", e.Table.String())
-				}
-
-				sortedKeys := make([]string, len(m.monitors))
-				i := 0
-				for k := range m.monitors {
-					sortedKeys[i] = k
-					i++
-				}
-				sort.Strings(sortedKeys)
-				m.sortedTableKeys = sortedKeys
-
-			case CountUpdateEvent:
-				if _, ok := m.monitors[e.Table.String()]; !ok {
-					return fmt.Errorf("no monitor found for table %s", e.Table.String())
-				}
-				m.monitors[e.Table.String()].SetTotalRows(e.TotalRows)
-			case CopyTaskFinishedEvent:
-				if _, ok := m.monitors[e.Table.String()]; !ok {
-					return fmt.Errorf("no monitor found for table %s", e.Table.String())
-				}
-
-				m.monitors[e.Table.String()].done = true
-				anyRunning := false
-				for _, monitor := range m.monitors {
-					if !monitor.done {
-						anyRunning = true
-						break
-					}
-				}
-
-				if !anyRunning {
-					m.renderTicker.Stop()
-					m.render()
-					return nil
-				}
-			case ErrorEvent:
-				if _, ok := m.monitors[e.Table.String()]; !ok {
-					return fmt.Errorf("no monitor found for table %s", e.Table.String())
-				}
-				m.monitors[e.Table.String()].SetError(e.Err)
-
-				anyRunning := false
-				for _, monitor := range m.monitors {
-					if !monitor.done {
-						anyRunning = true
-						break
-					}
-				}
-
-				if !anyRunning {
-					m.renderTicker.Stop()
-					m.render()
-					return nil
-				}
+			finished, err := m.handle(event)
+			if err != nil {
+				return err
 			}
 
+			if finished {
+				m.close()
+				return nil
+			}
+		case command := <-m.commands:
+			command()
+			m.render()
 		case <-m.renderTicker.C:
 			m.render()
 		}
@@ -158,77 +160,72 @@ func (m *Monitor) Run(ctx context.Context) error {
 
 }
 
-func (m *Monitor) render() {
-	if m.ci {
-		for _, key := range m.sortedTableKeys {
-			if m.monitors[key].RowTotal == 0 {
-				continue
-			}
-			if _, ok := m.lastRender.rowsCopied[key]; !ok {
-				m.w.Write([]byte(fmt.Sprintf("%s copied %d of %d\n", key, m.monitors[key].RowsCopied, m.monitors[key].RowTotal)))
-				m.lastRender.rowsCopied[key] = m.monitors[key].RowsCopied
-				continue
-			}
-
-			lastCount := m.lastRender.rowsCopied[key]
-			currentCount := m.monitors[key].RowsCopied
-
-			if lastCount < currentCount {
-				m.w.Write([]byte(fmt.Sprintf("%s copied %d of %d\n", key, currentCount, m.monitors[key].RowTotal)))
-				m.lastRender.rowsCopied[key] = m.monitors[key].RowsCopied
-			}
-		}
-
-		return
-
-	}
-	if m.lastRender.managedLines > 0 {
-		// clear terminal output
-		for i := 0; i < m.lastRender.managedLines; i++ {
-			m.w.Write([]byte(fmt.Sprint("\033[1F\033[2K"))) // Move cursor up and clear line
-		}
+// handle applies event to the state and passes it on to the sinks, it reports whether all tables are finished
+func (m *Monitor) handle(event Event) (bool, error) {
+	finished, err := m.state.apply(event)
+	if err != nil {
+		return false, err
 	}
 
-	newManagedLines := 0
-
-	var output strings.Builder
-
-	output.WriteString(fmt.Sprintf("Copying from %s\n\n", strings.Join(m.sortedTableKeys, ", ")))
-	newManagedLines++
-	newManagedLines++
+	for _, sink := range m.sinks {
+		sink.Event(m.state, event)
+	}
 
-	for _, key := range m.sortedTableKeys {
-		bar := m.monitors[key]
-		barString := bar.bar.String()
+	return finished, nil
+}
 
-		if bar.err == nil {
-			output.WriteString(fmt.Sprintf("%s\n\n", barString))
-			newManagedLines++
-			newManagedLines++
-		} else {
-			output.WriteString(fmt.Sprintf("%s = FAILED : %s\n\n", bar.Table.String(), bar.err))
-			newManagedLines++
-			newManagedLines++
+// drain handles the events waiting in the channel
+func (m *Monitor) drain() error {
+	for {
+		select {
+		case event, ok := <-m.eventChan:
+			if !ok {
+				return nil
+			}
+			_, err := m.handle(event)
+			if err != nil {
+				return err
+			}
+		default:
+			return nil
 		}
-
 	}
+}
 
-	m.w.Write([]byte(output.String()))
-
-	m.lastRender.managedLines = newManagedLines
+func (m *Monitor) render() {
+	for _, sink := range m.sinks {
+		sink.Render(m.state)
+	}
+}
 
+// close renders the final state of the run
+func (m *Monitor) close() {
+	m.renderTicker.Stop()
+	for _, sink := range m.sinks {
+		sink.Close(m.state)
+	}
 }
 
 type ProgressReporter struct {
-	bar        *progressbar.ProgressBar
-	RowTotal   int
-	RowsCopied int
-	Table      mssql.TableRef
-	done       bool
-	err        error
+	bar         *progressbar.ProgressBar
+	RowTotal    int
+	Approximate bool
+	RowsCopied  int
+	// BytesCopied is the approximate size of the rows copied
+	BytesCopied int64
+	Table       mssql.TableRef
+	// counted is set once the rows of the table are counted
+	counted bool
+	done    bool
+	err     error
+
+	// started is the start of the table and samples the recent progress, see Throughput
+	started time.Time
+	samples []throughputSample
 }
 
 func NewProgressReporter(table mssql.TableRef) *ProgressReporter {
+	now := time.Now()
 	return &ProgressReporter{
 		bar: progressbar.NewOptions(10_000,
 			progressbar.OptionSetDescription(table.String()),
@@ -236,7 +233,6 @@ func NewProgressReporter(table mssql.TableRef) *ProgressReporter {
 			progressbar.OptionSetWidth(100),
 			progressbar.OptionThrottle(65*time.Millisecond),
 			progressbar.OptionShowCount(),
-			progressbar.OptionShowIts(),
 			progressbar.OptionSpinnerType(14),
 			progressbar.OptionSetRenderBlankState(true),
 			progressbar.OptionSetPredictTime(true),
@@ -245,20 +241,54 @@ func NewProgressReporter(table mssql.TableRef) *ProgressReporter {
 		RowTotal: 0,
 		Table:    table,
 		done:     false,
+		started:  now,
+		samples:  []throughputSample{{at: now}},
 	}
 }
 
 func (p *ProgressReporter) Update(rowsCopied int) {
 	p.RowsCopied = p.RowsCopied + rowsCopied
+
+	// approximate totals can be too low, grow them instead of overflowing the bar
+	if p.Approximate && p.RowsCopied > p.RowTotal {
+		p.RowTotal = p.RowsCopied
+		p.bar.ChangeMax(p.RowTotal)
+	}
+
 	p.bar.Add(rowsCopied)
 }
 
-func (p *ProgressReporter) SetTotalRows(totalRows int) {
+func (p *ProgressReporter) SetTotalRows(totalRows int, approximate bool) {
 	p.RowTotal = totalRows
+	p.Approximate = approximate
+	p.counted = true
 	p.bar.ChangeMax(totalRows)
 }
 
+func (p *ProgressReporter) total() string {
+	if p.Approximate {
+		return fmt.Sprintf("~%d", p.RowTotal)
+	}
+
+	return fmt.Sprintf("%d", p.RowTotal)
+}
+
+// SetBatchSize shows the number of rows per transaction next to the name of the table
+func (p *ProgressReporter) SetBatchSize(batchSize int) {
+	p.bar.Describe(fmt.Sprintf("%s (batch %d)", p.Table, batchSize))
+}
+
 func (p *ProgressReporter) SetError(err error) {
 	p.done = true
 	p.err = err
 }
+
+// Done reports whether the table is finished or failed
+func (p *ProgressReporter) Done() bool {
+	return p.done
+}
+
+// Err returns the error the table failed with, nil while it is running or when it is copied
+func (p *ProgressReporter) Err() error {
+	return p.err
+}
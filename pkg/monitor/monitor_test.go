@@ -2,6 +2,7 @@ package monitor_test
 
 import (
 	"context"
+	"errors"
 	"io"
 	"os"
 	"strings"
@@ -53,7 +54,7 @@ func TestMonitorSingleStartEvent(t *testing.T) {
 
 	out, _ := io.ReadAll(r)
 
-	assert.Equal(t, "Copying from [dbo].[test]\n\n\r[dbo].[test]   0% |                                                                                                    | (0/10000, 0 it/hr) [0s:0s]", strings.TrimSpace(string(out)),)
+	assert.Equal(t, "Copying from [dbo].[test]\n\nTotal   0% |                                                  | (0/~0 rows) [0s:?]\n\n\r[dbo].[test]   0% |                                                                                                    | (0/10000) [0s:0s]  0 rows/s, 0.0 MB/s (avg 0 rows/s, 0.0 MB/s)", strings.TrimSpace(string(out)),)
 }
 
 func TestMonitorMultipleStartEvent(t *testing.T) {
@@ -93,6 +94,143 @@ func TestMonitorMultipleStartEvent(t *testing.T) {
 	out, _ := io.ReadAll(r)
 
 	assert.Equal(t, 
-		"Copying from [dbo].[test2], [dbo].[test]\n\n\r[dbo].[test2]   0% |                                                                                                    | (0/10000, 0 it/hr) [0s:0s]\n\n\r[dbo].[test]   0% |                                                                                                    | (0/10000, 0 it/hr) [0s:0s]",
+		"Copying from [dbo].[test2], [dbo].[test]\n\nTotal   0% |                                                  | (0/~0 rows) [0s:?]\n\n\r[dbo].[test2]   0% |                                                                                                    | (0/10000) [0s:0s]  0 rows/s, 0.0 MB/s (avg 0 rows/s, 0.0 MB/s)\n\n\r[dbo].[test]   0% |                                                                                                    | (0/10000) [0s:0s]  0 rows/s, 0.0 MB/s (avg 0 rows/s, 0.0 MB/s)",
 		 strings.TrimSpace(string(out)))
 }
+
+func TestMonitorCompactView(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, false, w)
+	mon.SetView(monitor.ViewCompact)
+
+	go mon.Run(ctx)
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.CountUpdateEvent{Table: orders, TotalRows: 10}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 4}
+
+	cancel()
+
+	time.Sleep(10 * time.Millisecond)
+	w.Close()
+
+	out, _ := io.ReadAll(r)
+
+	assert.Contains(t, string(out), "Copying 2 tables: 0 done, 0 failed, 2 running\nCopied 4 of 10 rows\n")
+}
+
+func TestMonitorCITemplates(t *testing.T) {
+	t.Parallel()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := monitor.ParseCITemplates("progress={{.Table}} rows={{.RowsCopied}}/{{.Total}}", "summary done={{.Done}} failed={{.Failed}}{{range .Failures}} {{.Table}}: {{.Error}}{{end}}")
+	assert.NoError(t, err)
+
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, true, w)
+	mon.SetCITemplates(templates)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(ctx)
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.CountUpdateEvent{Table: orders, TotalRows: 10, Approximate: true}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 10}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	eventChan <- monitor.ErrorEvent{Table: lines, Err: errors.New("boom")}
+	cancel()
+
+	assert.NoError(t, <-done)
+	w.Close()
+
+	out, _ := io.ReadAll(r)
+
+	assert.Contains(t, string(out), "progress=[dbo].[orders] rows=10/~10\n")
+	assert.True(t, strings.HasSuffix(string(out), "summary done=1 failed=1 [dbo].[lines]: boom\n"))
+
+	_, err = monitor.ParseCITemplates("{{.Table", "")
+	assert.Error(t, err)
+}
+
+func TestMonitorStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, false, w)
+	mon.SetView(monitor.ViewCompact)
+
+	go mon.Run(ctx)
+
+	eventChan <- monitor.CopyTaskStartedEvent{Table: mssql.TableRef{Schema: "dbo", Table: "orders"}}
+	mon.SetStatus("throttle", "throttled to 500 rows/s")
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+
+	time.Sleep(10 * time.Millisecond)
+	w.Close()
+
+	out, _ := io.ReadAll(r)
+
+	assert.Contains(t, string(out), "Copied 0 of 0 rows\nTotal   0% |                                                  | (0/~0 rows) [0s:?]\n\nthrottle: throttled to 500 rows/s\n\n")
+}
+
+func TestMonitorDrainsWhenStopped(t *testing.T) {
+	t.Parallel()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	notes := mssql.TableRef{Schema: "dbo", Table: "notes"}
+
+	eventChan := make(chan monitor.Event, 10)
+	mon := monitor.NewMonitor(eventChan, true, io.Discard)
+	mon.SetTables([]mssql.TableRef{orders, lines, notes})
+	report := monitor.NewReportSink()
+	mon.AddSink(report)
+
+	// sent before the monitor is stopped, the run stopped before notes started
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.ErrorEvent{Table: lines, Err: errors.New("boom")}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: lines}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(t, mon.Run(ctx))
+
+	summary := report.Report()
+	assert.Equal(t, 1, summary.Copied)
+	assert.Equal(t, 1, summary.Failed)
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// plainInterval is the time between the status lines of the plain renderer
+const plainInterval = 10 * time.Second
+
+// plainSink writes a status line of the whole run every plainInterval and the failures of tables as they happen, without
+// the escape codes the progress bars are redrawn with
+type plainSink struct {
+	w        io.Writer
+	interval time.Duration
+	// last is the time of the last status line
+	last time.Time
+}
+
+func newPlainSink(w io.Writer) *plainSink {
+	return &plainSink{w: w, interval: plainInterval, last: time.Now()}
+}
+
+// DisableANSI replaces the progress bars by a status line of the run every 10 seconds, for terminals and log viewers without
+// support for the escape codes the bars are redrawn with. It has no effect in CI mode, of which the output has no escape codes.
+func (m *Monitor) DisableANSI() {
+	if m.terminal == nil {
+		return
+	}
+
+	// the built-in output may have been replaced by SetOutput
+	if m.sinks[0] == Sink(m.terminal) {
+		m.sinks[0] = newPlainSink(m.w)
+	}
+	m.terminal = nil
+}
+
+func (p *plainSink) Event(state *State, event Event) {
+	if e, ok := event.(ErrorEvent); ok {
+		fmt.Fprintf(p.w, "%s %s FAILED: %s\n", time.Now().Format(time.RFC3339), e.Table, e.Err)
+	}
+}
+
+func (p *plainSink) Status(state *State, name, text string) {
+	if text != "" {
+		fmt.Fprintf(p.w, "%s %s: %s\n", time.Now().Format(time.RFC3339), name, text)
+	}
+}
+
+func (p *plainSink) Render(state *State) {
+	now := time.Now()
+	if now.Sub(p.last) < p.interval {
+		return
+	}
+
+	p.last = now
+	p.writeLine(state, now)
+}
+
+func (p *plainSink) Close(state *State) {
+	p.writeLine(state, time.Now())
+}
+
+// writeLine writes the tables done, failed and running, and the rows copied with the ETA of the run
+func (p *plainSink) writeLine(state *State, now time.Time) {
+	var done, failed, running int
+	for _, key := range state.keys {
+		table := state.tables[key]
+		switch {
+		case table.err != nil:
+			failed++
+		case table.done:
+			done++
+		default:
+			running++
+		}
+	}
+
+	progress := state.Progress()
+	total := fmt.Sprintf("%d", progress.RowTotal)
+	if progress.Approximate {
+		total = "~" + total
+	}
+
+	eta := ""
+	if progress.ETA > 0 && running > 0 {
+		eta = fmt.Sprintf(", ETA %s", progress.ETA.Round(time.Second))
+	}
+
+	fmt.Fprintf(p.w, "%s %d tables done, %d failed, %d running: %d of %s rows%s\n",
+		now.Format(time.RFC3339), done, failed, running, progress.RowsCopied, total, eta)
+}
@@ -0,0 +1,48 @@
+package monitor_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisableANSI(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, false, &buf)
+	mon.DisableANSI()
+
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(context.Background())
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.CountUpdateEvent{Table: orders, TotalRows: 10}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 10}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	eventChan <- monitor.ErrorEvent{Table: lines, Err: errors.New("timeout")}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: lines}
+	require.NoError(t, <-done)
+
+	out := buf.String()
+	assert.NotContains(t, out, "\033")
+
+	// the failure and the last status line
+	output := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	require.Len(t, output, 2, out)
+	assert.True(t, strings.HasSuffix(output[0], " [dbo].[lines] FAILED: timeout"), out)
+	assert.True(t, strings.HasSuffix(output[1], " 1 tables done, 1 failed, 0 running: 10 of 10 rows"), out)
+}
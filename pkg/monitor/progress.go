@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// progressWidth is the width of the bar of the run, the bars of the tables are 100 wide
+const progressWidth = 50
+
+// Progress is the progress of the run over all its tables
+type Progress struct {
+	RowsCopied int
+	// RowTotal is the number of rows of the run, Approximate is set when it includes approximate or expected counts
+	RowTotal    int
+	Approximate bool
+	// Elapsed is the time since the run started, ETA the estimated time until all rows are copied, zero when it is unknown
+	Elapsed time.Duration
+	ETA     time.Duration
+}
+
+// SetExpectedRows sets the approximate rows of the tables by TableRef.String(), which count towards the progress of the run
+// until their tables start and are counted. It has to be called before Run.
+func (m *Monitor) SetExpectedRows(rows map[string]int) {
+	m.state.expected = rows
+}
+
+// Progress returns the progress of the run. The total counts the copied rows of the finished and failed tables, the counts of the
+// running tables and the expected rows of the tables not counted yet. The ETA assumes the rows are copied at the rate so far.
+func (s *State) Progress() Progress {
+	progress := Progress{Elapsed: time.Since(s.started)}
+	for _, key := range s.keys {
+		p := s.tables[key]
+		progress.RowsCopied += p.RowsCopied
+
+		switch {
+		case p.done:
+			progress.RowTotal += p.RowsCopied
+		case p.counted:
+			progress.RowTotal += max(p.RowTotal, p.RowsCopied)
+			progress.Approximate = progress.Approximate || p.Approximate
+		default:
+			progress.RowTotal += max(s.expected[key], p.RowsCopied)
+			progress.Approximate = true
+		}
+	}
+
+	for key, rows := range s.expected {
+		if _, ok := s.tables[key]; !ok {
+			progress.RowTotal += rows
+			progress.Approximate = true
+		}
+	}
+
+	if progress.RowsCopied > 0 && progress.RowTotal > progress.RowsCopied {
+		remaining := float64(progress.RowTotal-progress.RowsCopied) / float64(progress.RowsCopied)
+		progress.ETA = time.Duration(remaining * float64(progress.Elapsed))
+	}
+
+	return progress
+}
+
+// renderProgress returns the line with the bar of the run, in the layout of the bars of the tables
+func renderProgress(progress Progress) string {
+	percent := 0
+	if progress.RowTotal > 0 {
+		percent = progress.RowsCopied * 100 / progress.RowTotal
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	filled := percent * progressWidth / 100
+
+	total := fmt.Sprintf("%d", progress.RowTotal)
+	if progress.Approximate {
+		total = "~" + total
+	}
+
+	eta := "?"
+	if progress.ETA > 0 {
+		eta = progress.ETA.Round(time.Second).String()
+	} else if progress.RowTotal > 0 && progress.RowsCopied >= progress.RowTotal {
+		eta = "0s"
+	}
+
+	return fmt.Sprintf("Total %3d%% |%s%s| (%d/%s rows) [%s:%s]\n",
+		percent, strings.Repeat("█", filled), strings.Repeat(" ", progressWidth-filled),
+		progress.RowsCopied, total, progress.Elapsed.Round(time.Second), eta)
+}
@@ -0,0 +1,76 @@
+package monitor_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// progressSink keeps the progress of the run when it is closed
+type progressSink struct {
+	monitor.NullSink
+	progress monitor.Progress
+}
+
+func (s *progressSink) Close(state *monitor.State) {
+	s.progress = state.Progress()
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+	products := mssql.TableRef{Schema: "dbo", Table: "products"}
+	invoices := mssql.TableRef{Schema: "dbo", Table: "invoices"}
+
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, true, io.Discard)
+	mon.SetOutput(monitor.OutputNone)
+	mon.SetExpectedRows(map[string]int{
+		orders.String():    100,
+		lines.String():     1000,
+		customers.String(): 50,
+		products.String():  20,
+		invoices.String():  300,
+	})
+	sink := &progressSink{}
+	mon.AddSink(sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(ctx)
+	}()
+
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: customers}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: products}
+	// orders finished with fewer rows than expected
+	eventChan <- monitor.CountUpdateEvent{Table: orders, TotalRows: 90}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 80}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	// lines is counted exactly
+	eventChan <- monitor.CountUpdateEvent{Table: lines, TotalRows: 800}
+	eventChan <- monitor.ProgressUpdateEvent{Table: lines, RowsCopied: 200}
+	// customers failed
+	eventChan <- monitor.ProgressUpdateEvent{Table: customers, RowsCopied: 10}
+	eventChan <- monitor.ErrorEvent{Table: customers, Err: errors.New("timeout")}
+	// products is not counted yet and invoices not started
+	cancel()
+	require.NoError(t, <-done)
+
+	progress := sink.progress
+	assert.Equal(t, 290, progress.RowsCopied)
+	assert.Equal(t, 80+800+10+20+300, progress.RowTotal)
+	assert.True(t, progress.Approximate)
+	assert.Greater(t, progress.ETA, progress.Elapsed)
+}
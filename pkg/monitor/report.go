@@ -0,0 +1,264 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+)
+
+// ReportSchemaVersion is the version of the JSON report. Fields are added within a version, it is incremented when a field
+// is removed, renamed or changes its meaning.
+const ReportSchemaVersion = 1
+
+const (
+	// TableCopied, TableFailed and TableUnfinished are the statuses of a table in the report
+	TableCopied     = "copied"
+	TableFailed     = "failed"
+	TableUnfinished = "unfinished"
+
+	// RunSucceeded and RunFailed are the statuses of the run in the report
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+const (
+	// ExitSchemaMismatch is the exit status of a run of which the tables only failed because their target schema does not match,
+	// which happens before the target is changed
+	ExitSchemaMismatch = 3
+	// ExitPartialCopy is the exit status of a run of which tables failed or were not finished, they may have been emptied
+	ExitPartialCopy = 4
+)
+
+// TableReport is the accounting of the copy of a table
+type TableReport struct {
+	Table  string `json:"table"`
+	Status string `json:"status"`
+	// RowsTotal is the number of rows to copy, Approximate is set when it was taken from the table metadata
+	RowsTotal   int64 `json:"rows_total"`
+	Approximate bool  `json:"rows_total_approximate"`
+	RowsCopied  int64 `json:"rows_copied"`
+	BytesCopied int64 `json:"bytes_copied"`
+	// Finished is the time the table finished or failed, or the end of the run for unfinished tables
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// Duration is the time from the start of the table until it finished, failed or the run ended
+	Duration      float64 `json:"duration_seconds"`
+	RowsPerSecond float64 `json:"rows_per_second"`
+	Retries       int     `json:"retries"`
+	Errors        int     `json:"errors"`
+	// ForeignKeysDropped and ForeignKeysRestored count the foreign keys referencing the table that were dropped or disabled and restored
+	ForeignKeysDropped  int    `json:"foreign_keys_dropped"`
+	ForeignKeysRestored int    `json:"foreign_keys_restored"`
+	Error               string `json:"error,omitempty"`
+}
+
+// Report is the end of run accounting of the tables and their totals
+type Report struct {
+	SchemaVersion int `json:"schema_version"`
+	// Status is RunSucceeded when all tables were copied
+	Status        string        `json:"status"`
+	Started       time.Time     `json:"started"`
+	Finished      time.Time     `json:"finished"`
+	Duration      float64       `json:"duration_seconds"`
+	Tables        []TableReport `json:"tables"`
+	Copied        int           `json:"copied"`
+	Failed        int           `json:"failed"`
+	Unfinished    int           `json:"unfinished"`
+	RowsCopied    int64         `json:"rows_copied"`
+	BytesCopied   int64         `json:"bytes_copied"`
+	RowsPerSecond float64       `json:"rows_per_second"`
+	Retries       int           `json:"retries"`
+	Errors        int           `json:"errors"`
+	// ExitStatus is ExitSchemaMismatch when the tables only failed on their schema, ExitPartialCopy when tables failed
+	// or were not finished and 0 otherwise
+	ExitStatus int `json:"exit_status"`
+	// Settings are the settings of the run, set by the caller
+	Settings interface{} `json:"settings,omitempty"`
+}
+
+// ReportSink accounts for the tables of the run, its Report is complete once the monitor closed it
+type ReportSink struct {
+	lock     sync.Mutex
+	started  time.Time
+	finished time.Time
+	tables   map[string]*tableReport
+}
+
+// tableReport is the accounting of a table while it is copied
+type tableReport struct {
+	TableReport
+	finished time.Time
+	err      error
+}
+
+func NewReportSink() *ReportSink {
+	return &ReportSink{started: time.Now(), tables: make(map[string]*tableReport)}
+}
+
+func (s *ReportSink) Event(state *State, event Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	switch e := event.(type) {
+	case CopyTaskStartedEvent:
+		s.tables[e.Table.String()] = &tableReport{TableReport: TableReport{Table: e.Table.String(), Status: TableUnfinished, Started: now}}
+	case CountUpdateEvent:
+		if t, ok := s.tables[e.Table.String()]; ok {
+			t.RowsTotal = int64(e.TotalRows)
+			t.Approximate = e.Approximate
+		}
+	case ProgressUpdateEvent:
+		if t, ok := s.tables[e.Table.String()]; ok {
+			t.RowsCopied += int64(e.RowsCopied)
+			t.BytesCopied += e.BytesCopied
+		}
+	case RetryEvent:
+		if t, ok := s.tables[e.Table.String()]; ok {
+			t.Retries++
+		}
+	case ForeignKeysEvent:
+		if t, ok := s.tables[e.Table.String()]; ok {
+			t.ForeignKeysDropped += e.Dropped
+			t.ForeignKeysRestored += e.Restored
+		}
+	case ErrorEvent:
+		if t, ok := s.tables[e.Table.String()]; ok {
+			t.Errors++
+			t.Status = TableFailed
+			t.Error = e.Err.Error()
+			t.err = e.Err
+			t.finished = now
+		}
+	case CopyTaskFinishedEvent:
+		if t, ok := s.tables[e.Table.String()]; ok && t.Status == TableUnfinished {
+			t.Status = TableCopied
+			t.finished = now
+		}
+	}
+}
+
+func (s *ReportSink) Status(state *State, name, text string) {}
+
+func (s *ReportSink) Render(state *State) {}
+
+func (s *ReportSink) Close(state *State) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.finished = time.Now()
+}
+
+// Report returns the accounting of the tables ordered by name, the tables still running count up to now
+func (s *ReportSink) Report() Report {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	finished := s.finished
+	if finished.IsZero() {
+		finished = time.Now()
+	}
+
+	report := Report{
+		SchemaVersion: ReportSchemaVersion,
+		Status:        RunSucceeded,
+		Started:       s.started,
+		Finished:      finished,
+		Duration:      finished.Sub(s.started).Seconds(),
+		Tables:        make([]TableReport, 0, len(s.tables)),
+	}
+	schemaMismatches := 0
+	for _, t := range s.tables {
+		table := t.TableReport
+		table.Finished = t.finished
+		if table.Finished.IsZero() {
+			table.Finished = finished
+		}
+		table.Duration = table.Finished.Sub(table.Started).Seconds()
+		table.RowsPerSecond = rate(table.RowsCopied, table.Duration)
+		report.Tables = append(report.Tables, table)
+
+		switch table.Status {
+		case TableCopied:
+			report.Copied++
+		case TableFailed:
+			report.Failed++
+			if errors.Is(t.err, mssql.ErrSchemaMismatch) {
+				schemaMismatches++
+			}
+		default:
+			report.Unfinished++
+		}
+		report.RowsCopied += table.RowsCopied
+		report.BytesCopied += table.BytesCopied
+		report.Retries += table.Retries
+		report.Errors += table.Errors
+	}
+	sort.Slice(report.Tables, func(i, j int) bool { return report.Tables[i].Table < report.Tables[j].Table })
+
+	report.RowsPerSecond = rate(report.RowsCopied, report.Duration)
+	if report.Failed > 0 || report.Unfinished > 0 {
+		report.Status = RunFailed
+		report.ExitStatus = ExitPartialCopy
+		if report.Unfinished == 0 && schemaMismatches == report.Failed {
+			report.ExitStatus = ExitSchemaMismatch
+		}
+	}
+
+	return report
+}
+
+func rate(rows int64, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+
+	return float64(rows) / seconds
+}
+
+// WriteText writes the report as a table with a row per table and a row with the totals, followed by the errors of the failed tables
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Table\tStatus\tRows\tDuration\tRows/s\tRetries\tErrors\tFKs dropped\tFKs restored\t")
+	for _, t := range r.Tables {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%.0f\t%d\t%d\t%d\t%d\t\n",
+			t.Table, t.Status, t.RowsCopied, seconds(t.Duration), t.RowsPerSecond, t.Retries, t.Errors, t.ForeignKeysDropped, t.ForeignKeysRestored)
+	}
+	fmt.Fprintf(tw, "Total\t%d of %d copied\t%d\t%s\t%.0f\t%d\t%d\t\t\t\n",
+		r.Copied, len(r.Tables), r.RowsCopied, seconds(r.Duration), r.RowsPerSecond, r.Retries, r.Errors)
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range r.Tables {
+		if t.Error != "" {
+			fmt.Fprintf(w, "%s FAILED: %s\n", t.Table, t.Error)
+		}
+	}
+	if r.Unfinished > 0 {
+		fmt.Fprintf(w, "%d tables were not finished\n", r.Unfinished)
+	}
+	_, err = fmt.Fprintf(w, "Exit status %d\n", r.ExitStatus)
+
+	return err
+}
+
+// WriteJSON writes the report as an indented JSON document
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(100 * time.Millisecond)
+}
@@ -0,0 +1,155 @@
+package monitor_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportSink(t *testing.T) {
+	sink := monitor.NewReportSink()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+	sink.Event(nil, monitor.CopyTaskStartedEvent{Table: orders})
+	sink.Event(nil, monitor.CopyTaskStartedEvent{Table: lines})
+	sink.Event(nil, monitor.CopyTaskStartedEvent{Table: customers})
+	sink.Event(nil, monitor.ForeignKeysEvent{Table: orders, Dropped: 2})
+	sink.Event(nil, monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 40, BytesCopied: 4000})
+	sink.Event(nil, monitor.RetryEvent{Table: orders, Attempt: 1, Err: errors.New("deadlock")})
+	sink.Event(nil, monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 60, BytesCopied: 6000})
+	sink.Event(nil, monitor.ForeignKeysEvent{Table: orders, Restored: 2})
+	sink.Event(nil, monitor.CopyTaskFinishedEvent{Table: orders})
+	sink.Event(nil, monitor.ProgressUpdateEvent{Table: lines, RowsCopied: 5})
+	sink.Event(nil, monitor.ErrorEvent{Table: lines, Err: errors.New("timeout")})
+	sink.Close(nil)
+
+	report := sink.Report()
+	require.Len(t, report.Tables, 3)
+	assert.Equal(t, "[dbo].[customers]", report.Tables[0].Table)
+	assert.Equal(t, monitor.TableUnfinished, report.Tables[0].Status)
+
+	assert.Equal(t, monitor.TableFailed, report.Tables[1].Status)
+	assert.Equal(t, int64(5), report.Tables[1].RowsCopied)
+	assert.Equal(t, 1, report.Tables[1].Errors)
+	assert.Equal(t, "timeout", report.Tables[1].Error)
+
+	assert.Equal(t, monitor.TableCopied, report.Tables[2].Status)
+	assert.Equal(t, int64(100), report.Tables[2].RowsCopied)
+	assert.Equal(t, int64(10000), report.Tables[2].BytesCopied)
+	assert.Equal(t, 1, report.Tables[2].Retries)
+	assert.Equal(t, 2, report.Tables[2].ForeignKeysDropped)
+	assert.Equal(t, 2, report.Tables[2].ForeignKeysRestored)
+
+	assert.Equal(t, 1, report.Copied)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, report.Unfinished)
+	assert.Equal(t, int64(105), report.RowsCopied)
+	assert.Equal(t, 1, report.Retries)
+	assert.Equal(t, monitor.ExitPartialCopy, report.ExitStatus)
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text))
+	assert.Regexp(t, `(?m)^Table\s+Status\s+Rows\s+Duration\s+Rows/s\s+Retries\s+Errors\s+FKs dropped\s+FKs restored\s*$`, text.String())
+	assert.Regexp(t, `(?m)^\[dbo\]\.\[orders\]\s+copied\s+100\s+\S+\s+\d+\s+1\s+0\s+2\s+2\s*$`, text.String())
+	assert.Regexp(t, `(?m)^Total\s+1 of 3 copied\s+105\s+`, text.String())
+	assert.Contains(t, text.String(), "[dbo].[lines] FAILED: timeout\n1 tables were not finished\nExit status 4\n")
+
+	assert.Equal(t, monitor.ReportSchemaVersion, report.SchemaVersion)
+	assert.Equal(t, monitor.RunFailed, report.Status)
+}
+
+func TestReportSchemaMismatch(t *testing.T) {
+	sink := monitor.NewReportSink()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	sink.Event(nil, monitor.CopyTaskStartedEvent{Table: orders})
+	sink.Event(nil, monitor.CopyTaskStartedEvent{Table: lines})
+	sink.Event(nil, monitor.CopyTaskFinishedEvent{Table: orders})
+	sink.Event(nil, monitor.ErrorEvent{Table: lines, Err: fmt.Errorf("Schema check failed on table %s, %w", lines, mssql.ErrSchemaMismatch)})
+	sink.Close(nil)
+
+	assert.Equal(t, monitor.ExitSchemaMismatch, sink.Report().ExitStatus)
+
+	// any other failure may have emptied a table
+	customers := mssql.TableRef{Schema: "dbo", Table: "customers"}
+	sink.Event(nil, monitor.CopyTaskStartedEvent{Table: customers})
+	sink.Event(nil, monitor.ErrorEvent{Table: customers, Err: errors.New("timeout")})
+
+	assert.Equal(t, monitor.ExitPartialCopy, sink.Report().ExitStatus)
+}
+
+// TestReportJSON guards the fields of the report, which only change with the schema version
+func TestReportJSON(t *testing.T) {
+	started := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	report := monitor.Report{
+		SchemaVersion: monitor.ReportSchemaVersion,
+		Status:        monitor.RunSucceeded,
+		Started:       started,
+		Finished:      started.Add(10 * time.Second),
+		Duration:      10,
+		Tables: []monitor.TableReport{{
+			Table:               "[dbo].[orders]",
+			Status:              monitor.TableCopied,
+			RowsTotal:           100,
+			RowsCopied:          100,
+			BytesCopied:         10000,
+			Started:             started,
+			Finished:            started.Add(4 * time.Second),
+			Duration:            4,
+			RowsPerSecond:       25,
+			ForeignKeysDropped:  1,
+			ForeignKeysRestored: 1,
+		}},
+		Copied:        1,
+		RowsCopied:    100,
+		BytesCopied:   10000,
+		RowsPerSecond: 10,
+		Settings:      map[string]string{"mode": "truncate"},
+	}
+
+	var document bytes.Buffer
+	require.NoError(t, report.WriteJSON(&document))
+	assert.JSONEq(t, `{
+		"schema_version": 1,
+		"status": "succeeded",
+		"started": "2024-05-01T02:00:00Z",
+		"finished": "2024-05-01T02:00:10Z",
+		"duration_seconds": 10,
+		"tables": [{
+			"table": "[dbo].[orders]",
+			"status": "copied",
+			"rows_total": 100,
+			"rows_total_approximate": false,
+			"rows_copied": 100,
+			"bytes_copied": 10000,
+			"started": "2024-05-01T02:00:00Z",
+			"finished": "2024-05-01T02:00:04Z",
+			"duration_seconds": 4,
+			"rows_per_second": 25,
+			"retries": 0,
+			"errors": 0,
+			"foreign_keys_dropped": 1,
+			"foreign_keys_restored": 1
+		}],
+		"copied": 1,
+		"failed": 0,
+		"unfinished": 0,
+		"rows_copied": 100,
+		"bytes_copied": 10000,
+		"rows_per_second": 10,
+		"retries": 0,
+		"errors": 0,
+		"exit_status": 0,
+		"settings": {"mode": "truncate"}
+	}`, document.String())
+}
@@ -0,0 +1,59 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SchemaSummary counts the tables of a schema that started
+type SchemaSummary struct {
+	Schema string
+	Tables int
+	Done   int
+	Failed int
+}
+
+func (s SchemaSummary) String() string {
+	text := fmt.Sprintf("%s %d/%d done", s.Schema, s.Done, s.Tables)
+	if s.Failed > 0 {
+		text += fmt.Sprintf(", %d failed", s.Failed)
+	}
+
+	return text
+}
+
+// Schemas counts the tables of the run per schema, ordered by schema
+func (s *State) Schemas() []SchemaSummary {
+	schemas := make([]SchemaSummary, 0)
+	for _, key := range s.keys {
+		p := s.tables[key]
+		if len(schemas) == 0 || schemas[len(schemas)-1].Schema != p.Table.Schema {
+			schemas = append(schemas, SchemaSummary{Schema: p.Table.Schema})
+		}
+
+		schema := &schemas[len(schemas)-1]
+		schema.Tables++
+		switch {
+		case p.err != nil:
+			schema.Failed++
+		case p.done:
+			schema.Done++
+		}
+	}
+
+	return schemas
+}
+
+// renderSchemas returns a line with the counts per schema, nothing when the run copies a single schema
+func renderSchemas(schemas []SchemaSummary) string {
+	if len(schemas) < 2 {
+		return ""
+	}
+
+	counts := make([]string, len(schemas))
+	for i, schema := range schemas {
+		counts[i] = schema.String()
+	}
+
+	return fmt.Sprintf("Schemas: %s\n", strings.Join(counts, ", "))
+}
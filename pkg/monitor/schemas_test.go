@@ -0,0 +1,66 @@
+package monitor_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaGroups(t *testing.T) {
+	t.Parallel()
+
+	for _, view := range []monitor.View{monitor.ViewFull, monitor.ViewCompact} {
+		var buf bytes.Buffer
+		eventChan := make(chan monitor.Event)
+		mon := monitor.NewMonitor(eventChan, false, &buf)
+		mon.SetView(view)
+
+		done := make(chan error)
+		go func() {
+			done <- mon.Run(context.Background())
+		}()
+
+		orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+		lines := mssql.TableRef{Schema: "sales", Table: "lines"}
+		eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+		eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+		eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+		eventChan <- monitor.ErrorEvent{Table: lines, Err: errors.New("timeout")}
+		eventChan <- monitor.CopyTaskFinishedEvent{Table: lines}
+		require.NoError(t, <-done)
+
+		if view == monitor.ViewFull {
+			assert.Contains(t, buf.String(), "Schema dbo 1/1 done\n")
+			assert.Contains(t, buf.String(), "Schema sales 0/1 done, 1 failed\n")
+		} else {
+			assert.Contains(t, buf.String(), "Schemas: dbo 1/1 done, sales 0/1 done, 1 failed\n")
+		}
+	}
+}
+
+func TestSchemaGroupsSingleSchema(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, false, &buf)
+	mon.SetView(monitor.ViewCompact)
+
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(context.Background())
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	require.NoError(t, <-done)
+
+	assert.NotContains(t, buf.String(), "Schema")
+}
@@ -0,0 +1,22 @@
+package monitor
+
+// Sink renders the progress of a run. The monitor calls its methods from the goroutine running it, after it applied
+// the event or status to the State.
+type Sink interface {
+	// Event receives every event of the run
+	Event(state *State, event Event)
+	// Status receives every status line set with SetStatus, an empty text removes the line
+	Status(state *State, name, text string)
+	// Render is called every 10 milliseconds and after every command
+	Render(state *State)
+	// Close is called once, when all tables are finished or the run is cancelled
+	Close(state *State)
+}
+
+// NullSink renders nothing
+type NullSink struct{}
+
+func (NullSink) Event(*State, Event)           {}
+func (NullSink) Status(*State, string, string) {}
+func (NullSink) Render(*State)                 {}
+func (NullSink) Close(*State)                  {}
@@ -0,0 +1,75 @@
+package monitor_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingSink records the calls of the monitor
+type recordingSink struct {
+	events  []monitor.Event
+	status  []string
+	summary monitor.Summary
+	closed  int
+}
+
+func (r *recordingSink) Event(state *monitor.State, event monitor.Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *recordingSink) Status(state *monitor.State, name, text string) {
+	r.status = append(r.status, name+": "+text)
+}
+
+func (r *recordingSink) Render(state *monitor.State) {}
+
+func (r *recordingSink) Close(state *monitor.State) {
+	r.closed++
+	r.summary = state.Summary()
+}
+
+func TestMonitorSinks(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, true, &out)
+	mon.SetOutput(monitor.OutputNone)
+
+	first, second := &recordingSink{}, &recordingSink{}
+	mon.AddSink(first, second)
+
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(context.Background())
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	mon.SetStatus("throttle", "throttled to 500 rows/s")
+	time.Sleep(20 * time.Millisecond)
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 5}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	assert.NoError(t, <-done)
+
+	for _, sink := range []*recordingSink{first, second} {
+		assert.Equal(t, []monitor.Event{
+			monitor.CopyTaskStartedEvent{Table: orders},
+			monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 5},
+			monitor.CopyTaskFinishedEvent{Table: orders},
+		}, sink.events)
+		assert.Equal(t, []string{"throttle: throttled to 500 rows/s"}, sink.status)
+		assert.Equal(t, 1, sink.closed)
+		assert.Equal(t, 5, sink.summary.RowsCopied)
+		assert.Equal(t, 1, sink.summary.Done)
+	}
+
+	// the built-in output is replaced by the null sink
+	assert.Empty(t, out.String())
+}
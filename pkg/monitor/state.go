@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// State is the progress of the tables of a run, the monitor keeps it for its sinks
+type State struct {
+	tables map[string]*ProgressReporter
+	// keys are the names of the tables in order
+	keys []string
+	// status holds the status lines by name, see SetStatus
+	status map[string]string
+	// started is the start of the run and expected the approximate rows of its tables, see Progress
+	started  time.Time
+	expected map[string]int
+	// planned are the tables of the run, see SetTables
+	planned []string
+}
+
+func newState() *State {
+	return &State{
+		tables:  make(map[string]*ProgressReporter),
+		status:  make(map[string]string),
+		started: time.Now(),
+	}
+}
+
+// Keys returns the names of the tables, TableRef.String(), ordered by name
+func (s *State) Keys() []string {
+	return s.keys
+}
+
+// Table returns the progress of the table by TableRef.String()
+func (s *State) Table(key string) *ProgressReporter {
+	return s.tables[key]
+}
+
+// Status returns the status lines by name, the map must not be modified
+func (s *State) Status() map[string]string {
+	return s.status
+}
+
+// Summary counts the tables and rows of the run
+func (s *State) Summary() Summary {
+	summary := Summary{Time: time.Now(), Tables: len(s.keys), Failures: make([]Failure, 0)}
+	for _, key := range s.keys {
+		p := s.tables[key]
+		summary.RowsCopied += p.RowsCopied
+
+		switch {
+		case p.err != nil:
+			summary.Failed++
+			summary.Failures = append(summary.Failures, Failure{Table: key, Error: p.err.Error()})
+		case p.done:
+			summary.Done++
+		}
+	}
+
+	return summary
+}
+
+// apply updates the state with event, it reports whether all tables are finished
+func (s *State) apply(event Event) (bool, error) {
+	switch e := event.(type) {
+	case CopyTaskStartedEvent:
+		if _, ok := s.tables[e.Table.String()]; ok {
+			return false, fmt.Errorf("monitor already exists for table %s", e.Table.String())
+		}
+		s.tables[e.Table.String()] = NewProgressReporter(e.Table)
+
+		s.keys = append(s.keys, e.Table.String())
+		sort.Strings(s.keys)
+	case ProgressUpdateEvent:
+		p, err := s.table(e.Table.String())
+		if err != nil {
+			return false, err
+		}
+		p.Update(e.RowsCopied)
+		p.addBytes(time.Now(), e.BytesCopied)
+	case CountUpdateEvent:
+		p, err := s.table(e.Table.String())
+		if err != nil {
+			return false, err
+		}
+		p.SetTotalRows(e.TotalRows, e.Approximate)
+	case BatchSizeEvent:
+		p, err := s.table(e.Table.String())
+		if err != nil {
+			return false, err
+		}
+		p.SetBatchSize(e.BatchSize)
+	case ErrorEvent:
+		p, err := s.table(e.Table.String())
+		if err != nil {
+			return false, err
+		}
+		p.SetError(e.Err)
+	case CopyTaskFinishedEvent:
+		p, err := s.table(e.Table.String())
+		if err != nil {
+			return false, err
+		}
+		p.done = true
+
+		// the next table may only start after the others finished
+		for _, key := range s.planned {
+			if _, ok := s.tables[key]; !ok {
+				return false, nil
+			}
+		}
+		for _, p := range s.tables {
+			if !p.done {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (s *State) table(key string) (*ProgressReporter, error) {
+	p, ok := s.tables[key]
+	if !ok {
+		return nil, fmt.Errorf("no monitor found for table %s", key)
+	}
+
+	return p, nil
+}
+
+func (s *State) setStatus(name, text string) {
+	if text == "" {
+		delete(s.status, name)
+	} else {
+		s.status[name] = text
+	}
+}
@@ -0,0 +1,43 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SetStatus shows text on a line of its own above the tables, replacing the earlier text of name, an empty text removes the line.
+// In CI and JSON mode every status is written as it is set. It is safe to call from any goroutine.
+func (m *Monitor) SetStatus(name, text string) {
+	m.send(func() {
+		m.setStatus(name, text)
+	})
+}
+
+func (m *Monitor) setStatus(name, text string) {
+	m.state.setStatus(name, text)
+	for _, sink := range m.sinks {
+		sink.Status(m.state, name, text)
+	}
+}
+
+// renderStatus returns the status lines ordered by name
+func renderStatus(status map[string]string) string {
+	if len(status) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(status))
+	for name := range status {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var output strings.Builder
+	for _, name := range names {
+		output.WriteString(fmt.Sprintf("%s: %s\n", name, status[name]))
+	}
+	output.WriteString("\n")
+
+	return output.String()
+}
@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// DefaultProgressTemplate renders the CI progress line of a table
+	DefaultProgressTemplate = "{{.Table}} copied {{.RowsCopied}} of {{.Total}}\n"
+	// DefaultSummaryTemplate renders the CI summary after all tables are done
+	DefaultSummaryTemplate = "{{.Done}} of {{.Tables}} tables copied, {{.Failed}} failed, {{.RowsCopied}} rows\n{{range .Failures}}{{.Table}} FAILED: {{.Error}}\n{{end}}"
+)
+
+// ProgressLine is the data passed to the CI progress template
+type ProgressLine struct {
+	Time        time.Time
+	Table       string
+	RowsCopied  int
+	RowTotal    int
+	Approximate bool
+	// Total is RowTotal prefixed with ~ when it is approximate
+	Total       string
+	BytesCopied int64
+	// Current is the throughput of the last seconds and Average since the table started, like {{printf "%.1f" .Current.MBPerSecond}}
+	Current Throughput
+	Average Throughput
+}
+
+// Summary is the data passed to the CI summary template
+type Summary struct {
+	Time       time.Time
+	Tables     int
+	Done       int
+	Failed     int
+	RowsCopied int
+	Failures   []Failure
+}
+
+// Failure is a table that failed to copy
+type Failure struct {
+	Table string `json:"table"`
+	Error string `json:"error"`
+}
+
+// CITemplates render the progress lines and the summary of the CI output
+type CITemplates struct {
+	progress *template.Template
+	summary  *template.Template
+}
+
+// ParseCITemplates parses Go text/templates for the CI progress lines and summary, an empty template keeps the default.
+// Templates can read environment variables with {{env "NAME"}}, a missing trailing newline is added.
+func ParseCITemplates(progress, summary string) (CITemplates, error) {
+	if progress == "" {
+		progress = DefaultProgressTemplate
+	}
+
+	if summary == "" {
+		summary = DefaultSummaryTemplate
+	}
+
+	funcs := template.FuncMap{"env": os.Getenv}
+
+	var t CITemplates
+	var err error
+	t.progress, err = template.New("progress").Funcs(funcs).Parse(withNewline(progress))
+	if err != nil {
+		return CITemplates{}, fmt.Errorf("invalid progress template, %w", err)
+	}
+
+	t.summary, err = template.New("summary").Funcs(funcs).Parse(withNewline(summary))
+	if err != nil {
+		return CITemplates{}, fmt.Errorf("invalid summary template, %w", err)
+	}
+
+	return t, nil
+}
+
+func withNewline(tmpl string) string {
+	if strings.HasSuffix(tmpl, "\n") {
+		return tmpl
+	}
+
+	return tmpl + "\n"
+}
+
+// SetCITemplates replaces the templates of the CI output
+func (m *Monitor) SetCITemplates(t CITemplates) {
+	if m.ci != nil {
+		m.ci.templates = t
+	}
+}
+
+// defaultCITemplates are always valid
+func defaultCITemplates() CITemplates {
+	t, _ := ParseCITemplates("", "")
+	return t
+}
@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// terminalSink redraws the progress bars of the tables in place
+type terminalSink struct {
+	w io.Writer
+
+	view View
+
+	// managedLines is the number of lines of the last render, which the next render clears
+	managedLines int
+}
+
+func newTerminalSink(w io.Writer) *terminalSink {
+	return &terminalSink{w: w, view: ViewAuto}
+}
+
+func (t *terminalSink) Event(*State, Event) {}
+
+// Status lines are shown by the next render
+func (t *terminalSink) Status(*State, string, string) {}
+
+func (t *terminalSink) Render(state *State) {
+	if t.managedLines > 0 {
+		// clear terminal output
+		for i := 0; i < t.managedLines; i++ {
+			t.w.Write([]byte(fmt.Sprint("\033[1F\033[2K"))) // Move cursor up and clear line
+		}
+	}
+
+	var output string
+	if t.isCompact(state) {
+		output = t.renderCompact(state)
+	} else {
+		output = t.renderFull(state)
+	}
+
+	t.managedLines = strings.Count(output, "\n")
+
+	t.w.Write([]byte(output))
+}
+
+// Close leaves the last render, which already shows the outcome of every table
+func (t *terminalSink) Close(state *State) {
+	t.Render(state)
+}
+
+func (t *terminalSink) renderFull(state *State) string {
+	var output strings.Builder
+
+	output.WriteString(fmt.Sprintf("Copying from %s\n\n", strings.Join(state.keys, ", ")))
+	output.WriteString(renderProgress(state.Progress()) + "\n")
+	output.WriteString(renderStatus(state.status))
+
+	// the tables are grouped under their schema when the run copies several
+	schemas := state.Schemas()
+	grouped := len(schemas) > 1
+	schema := 0
+
+	now := time.Now()
+	for _, key := range state.keys {
+		bar := state.tables[key]
+		barString := bar.renderBar(now)
+
+		if grouped && (schema == 0 || schemas[schema-1].Schema != bar.Table.Schema) {
+			output.WriteString(fmt.Sprintf("Schema %s\n\n", schemas[schema]))
+			schema++
+		}
+
+		if bar.err == nil {
+			output.WriteString(fmt.Sprintf("%s\n\n", barString))
+		} else {
+			output.WriteString(fmt.Sprintf("%s = FAILED : %s\n\n", bar.Table.String(), bar.err))
+		}
+
+	}
+
+	return output.String()
+}
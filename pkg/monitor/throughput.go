@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+// throughputWindow is the period over which the current throughput of a table is measured
+const throughputWindow = 5 * time.Second
+
+// Throughput is the rate at which the rows of a table are copied
+type Throughput struct {
+	RowsPerSecond  float64
+	BytesPerSecond float64
+}
+
+// MBPerSecond returns the bytes per second in megabytes
+func (t Throughput) MBPerSecond() float64 {
+	return t.BytesPerSecond / (1024 * 1024)
+}
+
+func (t Throughput) String() string {
+	return fmt.Sprintf("%.0f rows/s, %.1f MB/s", t.RowsPerSecond, t.MBPerSecond())
+}
+
+// throughputSample is the number of rows and bytes copied of a table at a moment
+type throughputSample struct {
+	at    time.Time
+	rows  int
+	bytes int64
+}
+
+// addBytes counts the bytes of the rows of the last Update and samples the progress for the current throughput
+func (p *ProgressReporter) addBytes(now time.Time, bytes int64) {
+	p.BytesCopied += bytes
+	p.samples = append(p.samples, throughputSample{at: now, rows: p.RowsCopied, bytes: p.BytesCopied})
+	p.trimSamples(now)
+}
+
+// trimSamples drops the samples before the window, except the last of those which is where the window starts
+func (p *ProgressReporter) trimSamples(now time.Time) {
+	start := now.Add(-throughputWindow)
+	drop := 0
+	for drop+1 < len(p.samples) && !p.samples[drop+1].at.After(start) {
+		drop++
+	}
+	p.samples = p.samples[drop:]
+}
+
+// Throughput returns the throughput of the table over the last seconds and since it started. The current throughput drops
+// to zero while no rows are copied, as it does when the target throttles the inserts.
+func (p *ProgressReporter) Throughput(now time.Time) (current Throughput, average Throughput) {
+	average = rates(p.RowsCopied, p.BytesCopied, now.Sub(p.started))
+
+	p.trimSamples(now)
+	if len(p.samples) > 0 {
+		first := p.samples[0]
+		current = rates(p.RowsCopied-first.rows, p.BytesCopied-first.bytes, now.Sub(first.at))
+	}
+
+	return current, average
+}
+
+func rates(rows int, bytes int64, period time.Duration) Throughput {
+	seconds := period.Seconds()
+	if seconds <= 0 {
+		return Throughput{}
+	}
+
+	return Throughput{RowsPerSecond: float64(rows) / seconds, BytesPerSecond: float64(bytes) / seconds}
+}
+
+// renderBar returns the progress bar of the table followed by its throughput, the average only once it is done
+func (p *ProgressReporter) renderBar(now time.Time) string {
+	current, average := p.Throughput(now)
+	if p.done {
+		return fmt.Sprintf("%s  avg %s", p.bar.String(), average)
+	}
+
+	return fmt.Sprintf("%s  %s (avg %s)", p.bar.String(), current, average)
+}
@@ -0,0 +1,60 @@
+package monitor_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateSink keeps the state of the run when it is closed
+type stateSink struct {
+	monitor.NullSink
+	state *monitor.State
+}
+
+func (s *stateSink) Close(state *monitor.State) {
+	s.state = state
+}
+
+func TestThroughput(t *testing.T) {
+	t.Parallel()
+
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, true, io.Discard)
+	mon.SetOutput(monitor.OutputNone)
+	sink := &stateSink{}
+	mon.AddSink(sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(ctx)
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 100, BytesCopied: 1024 * 1024}
+	cancel()
+	require.NoError(t, <-done)
+
+	table := sink.state.Table(orders.String())
+	assert.Equal(t, int64(1024*1024), table.BytesCopied)
+
+	// within the window the current throughput is the average
+	current, average := table.Throughput(time.Now().Add(2 * time.Second))
+	assert.InDelta(t, 50, current.RowsPerSecond, 1)
+	assert.InDelta(t, 0.5, current.MBPerSecond(), 0.01)
+	assert.InDelta(t, 50, average.RowsPerSecond, 1)
+
+	// without progress after the window the current throughput drops to zero
+	current, average = table.Throughput(time.Now().Add(10 * time.Second))
+	assert.Equal(t, monitor.Throughput{}, current)
+	assert.InDelta(t, 10, average.RowsPerSecond, 1)
+	assert.Equal(t, "10 rows/s, 0.1 MB/s", average.String())
+}
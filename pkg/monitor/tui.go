@@ -0,0 +1,452 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// tuiRefresh is the time between the snapshots of the state the TUI redraws
+const tuiRefresh = 100 * time.Millisecond
+
+// tuiErrorLines is the height of the error pane, more errors can be scrolled to
+const tuiErrorLines = 5
+
+// tuiNameWidth limits the width of the table names, longer names are truncated
+const tuiNameWidth = 40
+
+const (
+	tablePending    = "pending"
+	tableRunning    = "running"
+	tableCancelling = "cancelling"
+	tableDone       = "done"
+	tableFailed     = "failed"
+	tableCancelled  = "cancelled"
+)
+
+var (
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	failedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	doneStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	helpStyle     = lipgloss.NewStyle().Faint(true)
+)
+
+// tuiTable is a row of the table of the TUI
+type tuiTable struct {
+	name       string
+	status     string
+	rowsCopied int
+	// total is the number of rows to copy, empty until the table is counted
+	total   string
+	percent int
+	// throughput is the current throughput of a running table and the average one of a finished table
+	throughput Throughput
+	err        error
+}
+
+// tuiSnapshot is the state of the run as rendered by the TUI, it is taken on the goroutine of the monitor
+type tuiSnapshot struct {
+	tables   []tuiTable
+	progress string
+	status   string
+	// schemas counts the tables per schema, empty when the run copies a single schema
+	schemas string
+}
+
+func newTUISnapshot(state *State, now time.Time) tuiSnapshot {
+	snapshot := tuiSnapshot{
+		progress: renderProgress(state.Progress()),
+		status:   renderStatus(state.status),
+		schemas:  renderSchemas(state.Schemas()),
+	}
+
+	for _, key := range state.keys {
+		p := state.tables[key]
+		current, average := p.Throughput(now)
+
+		table := tuiTable{name: key, status: tableRunning, rowsCopied: p.RowsCopied, throughput: current, err: p.err}
+		switch {
+		case p.err != nil:
+			table.status = tableFailed
+		case p.done:
+			table.status = tableDone
+			table.throughput = average
+		}
+
+		if p.counted {
+			table.total = p.total()
+			if p.RowTotal > 0 {
+				table.percent = min(p.RowsCopied*100/p.RowTotal, 100)
+			}
+		}
+		if p.done && p.err == nil {
+			table.percent = 100
+		}
+
+		snapshot.tables = append(snapshot.tables, table)
+	}
+
+	for key := range state.expected {
+		if _, ok := state.tables[key]; !ok {
+			snapshot.tables = append(snapshot.tables, tuiTable{name: key, status: tablePending})
+		}
+	}
+	sort.Slice(snapshot.tables, func(i, j int) bool { return snapshot.tables[i].name < snapshot.tables[j].name })
+
+	return snapshot
+}
+
+// tuiSink renders the run as an interactive table of its tables with a pane of their errors, which fits the height of the terminal
+type tuiSink struct {
+	program *tea.Program
+	// exited is closed when the program exited
+	exited  chan struct{}
+	started bool
+	last    time.Time
+}
+
+// UseTUI renders the run as an interactive table of its tables instead of redrawing the progress bars, with the keys read from in.
+// The keys move the selection, c cancels the selected table with cancelTable, v toggles between all tables and the running and failed
+// ones, tab scrolls the errors instead and q or ctrl-c call cancelRun. It has no effect in CI mode and has to be called before Run.
+func (m *Monitor) UseTUI(in io.Reader, cancelRun func(), cancelTable func(table string)) {
+	if m.terminal == nil {
+		return
+	}
+
+	model := tuiModel{
+		view:        m.terminal.view,
+		cancelled:   make(map[string]bool),
+		cancelRun:   cancelRun,
+		cancelTable: cancelTable,
+	}
+	program := tea.NewProgram(model, tea.WithInput(in), tea.WithOutput(m.w), tea.WithoutSignalHandler())
+
+	m.sinks[0] = &tuiSink{program: program, exited: make(chan struct{})}
+	m.terminal = nil
+}
+
+func (t *tuiSink) Event(*State, Event) {}
+
+// Status lines are shown by the next snapshot
+func (t *tuiSink) Status(*State, string, string) {}
+
+func (t *tuiSink) Render(state *State) {
+	now := time.Now()
+	if now.Sub(t.last) < tuiRefresh {
+		return
+	}
+
+	t.last = now
+	t.start()
+	t.program.Send(newTUISnapshot(state, now))
+}
+
+// Close shows the final state of the run and waits for the program to restore the terminal
+func (t *tuiSink) Close(state *State) {
+	t.start()
+	t.program.Send(newTUISnapshot(state, time.Now()))
+	t.program.Quit()
+	<-t.exited
+}
+
+// start runs the program on its first render
+func (t *tuiSink) start() {
+	if t.started {
+		return
+	}
+
+	t.started = true
+	go func() {
+		defer close(t.exited)
+		if _, err := t.program.Run(); err != nil {
+			log.Printf("WARNING: the interactive progress stopped, %s", err)
+		}
+	}()
+}
+
+// tuiModel is the Bubble Tea model of the TUI
+type tuiModel struct {
+	snapshot      tuiSnapshot
+	width, height int
+
+	view View
+	// selected is the name of the selected table and offset the first table shown
+	selected string
+	offset   int
+	// errorsFocused makes the keys scroll the error pane, errorOffset is the first error shown
+	errorsFocused bool
+	errorOffset   int
+
+	// cancelled are the tables cancelled from the TUI, stopping is set once the run is cancelled
+	cancelled   map[string]bool
+	stopping    bool
+	cancelRun   func()
+	cancelTable func(table string)
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tuiSnapshot:
+		m.snapshot = msg
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			m.move(-1)
+		case "down", "j":
+			m.move(1)
+		case "pgup":
+			m.move(-m.tableLines())
+		case "pgdown":
+			m.move(m.tableLines())
+		case "tab":
+			m.errorsFocused = !m.errorsFocused && len(m.errors()) > 0
+		case "v":
+			if m.compact() {
+				m.view = ViewFull
+			} else {
+				m.view = ViewCompact
+			}
+		case "c":
+			m.cancelSelected()
+		case "q", "ctrl+c":
+			if !m.stopping {
+				m.stopping = true
+				m.cancelRun()
+			}
+		}
+	}
+
+	m.scroll()
+
+	return m, nil
+}
+
+// compact reports whether only the running and failed tables are shown
+func (m tuiModel) compact() bool {
+	return m.view == ViewCompact || (m.view == ViewAuto && len(m.snapshot.tables) > compactThreshold)
+}
+
+// rows returns the tables shown, with the status of the tables cancelled from the TUI
+func (m tuiModel) rows() []tuiTable {
+	rows := make([]tuiTable, 0, len(m.snapshot.tables))
+	for _, table := range m.snapshot.tables {
+		if m.cancelled[table.name] {
+			switch table.status {
+			case tablePending, tableRunning:
+				table.status = tableCancelling
+			case tableFailed:
+				table.status = tableCancelled
+			}
+		}
+
+		if m.compact() && table.status != tableRunning && table.status != tableCancelling && table.status != tableFailed {
+			continue
+		}
+		rows = append(rows, table)
+	}
+
+	return rows
+}
+
+// errors returns the error lines of the failed tables
+func (m tuiModel) errors() []string {
+	errors := make([]string, 0)
+	for _, table := range m.snapshot.tables {
+		if table.err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %s", table.name, table.err))
+		}
+	}
+
+	return errors
+}
+
+// selectedIndex returns the position of the selected table in rows, the first table when it is not shown
+func (m tuiModel) selectedIndex(rows []tuiTable) int {
+	for i, table := range rows {
+		if table.name == m.selected {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// move moves the selection, or scrolls the error pane when it is focused
+func (m *tuiModel) move(lines int) {
+	if m.errorsFocused {
+		m.errorOffset += lines
+		return
+	}
+
+	rows := m.rows()
+	if len(rows) == 0 {
+		return
+	}
+
+	i := min(max(m.selectedIndex(rows)+lines, 0), len(rows)-1)
+	m.selected = rows[i].name
+}
+
+// scroll keeps the selected table and the error offset within view
+func (m *tuiModel) scroll() {
+	rows := m.rows()
+	if len(rows) == 0 {
+		m.offset = 0
+	} else {
+		selected := m.selectedIndex(rows)
+		m.selected = rows[selected].name
+
+		lines := m.tableLines()
+		if selected < m.offset {
+			m.offset = selected
+		}
+		if selected >= m.offset+lines {
+			m.offset = selected - lines + 1
+		}
+		m.offset = min(max(m.offset, 0), max(len(rows)-lines, 0))
+	}
+
+	m.errorOffset = min(max(m.errorOffset, 0), max(len(m.errors())-tuiErrorLines, 0))
+	if len(m.errors()) == 0 {
+		m.errorsFocused = false
+	}
+}
+
+func (m *tuiModel) cancelSelected() {
+	rows := m.rows()
+	if len(rows) == 0 {
+		return
+	}
+
+	table := rows[m.selectedIndex(rows)]
+	if table.status == tablePending || table.status == tableRunning {
+		m.cancelled[table.name] = true
+		m.cancelTable(table.name)
+	}
+}
+
+// header returns the lines above the table
+func (m tuiModel) header() string {
+	counts := make(map[string]int)
+	for _, table := range m.snapshot.tables {
+		counts[table.status]++
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Copying %d tables: %d done, %d failed, %d running, %d pending\n",
+		len(m.snapshot.tables), counts[tableDone], counts[tableFailed], counts[tableRunning], counts[tablePending]))
+	output.WriteString(m.snapshot.schemas)
+	output.WriteString(m.snapshot.progress)
+	output.WriteString("\n")
+	output.WriteString(m.snapshot.status)
+
+	return output.String()
+}
+
+// errorPane returns the lines of the error pane, none without errors
+func (m tuiModel) errorPane() []string {
+	errors := m.errors()
+	if len(errors) == 0 {
+		return nil
+	}
+
+	title := fmt.Sprintf("Errors (%d)", len(errors))
+	if len(errors) > tuiErrorLines {
+		title += fmt.Sprintf(", %d-%d shown", m.errorOffset+1, min(m.errorOffset+tuiErrorLines, len(errors)))
+	}
+
+	lines := []string{"", title}
+	for _, line := range errors[m.errorOffset:min(m.errorOffset+tuiErrorLines, len(errors))] {
+		lines = append(lines, failedStyle.Render(m.truncate(line)))
+	}
+
+	return lines
+}
+
+// tableLines returns the number of tables that fit between the header and the error pane, all of them when the height is unknown
+func (m tuiModel) tableLines() int {
+	if m.height == 0 {
+		return max(len(m.snapshot.tables), 1)
+	}
+
+	// the column headers, the scroll indicator and the help line
+	used := strings.Count(m.header(), "\n") + 3 + len(m.errorPane())
+
+	return max(m.height-used, 1)
+}
+
+func (m tuiModel) truncate(line string) string {
+	if m.width == 0 {
+		return line
+	}
+
+	return ansi.Truncate(line, m.width, "…")
+}
+
+func (m tuiModel) View() string {
+	var output strings.Builder
+	output.WriteString(m.header())
+
+	rows := m.rows()
+	nameWidth := len("Table")
+	for _, table := range rows {
+		nameWidth = max(nameWidth, min(len(table.name), tuiNameWidth))
+	}
+
+	output.WriteString(m.truncate(fmt.Sprintf("  %-*s  %-10s  %22s  %4s  %s", nameWidth, "Table", "Status", "Rows", "%", "Throughput")) + "\n")
+
+	end := min(m.offset+m.tableLines(), len(rows))
+	for _, table := range rows[m.offset:end] {
+		rowsCopied := fmt.Sprintf("%d", table.rowsCopied)
+		if table.total != "" {
+			rowsCopied += "/" + table.total
+		}
+
+		throughput := ""
+		if table.status != tablePending {
+			throughput = table.throughput.String()
+		}
+
+		line := m.truncate(fmt.Sprintf("  %-*s  %-10s  %22s  %3d%%  %s",
+			nameWidth, ansi.Truncate(table.name, nameWidth, "…"), table.status, rowsCopied, table.percent, throughput))
+
+		switch {
+		case table.name == m.selected && !m.errorsFocused:
+			line = selectedStyle.Render(line)
+		case table.err != nil:
+			line = failedStyle.Render(line)
+		case table.status == tableDone:
+			line = doneStyle.Render(line)
+		}
+		output.WriteString(line + "\n")
+	}
+
+	if len(rows) > end || m.offset > 0 {
+		output.WriteString(fmt.Sprintf("  ... %d more above, %d more below\n", m.offset, len(rows)-end))
+	}
+
+	for _, line := range m.errorPane() {
+		output.WriteString(line + "\n")
+	}
+
+	help := "↑/↓ select  c cancel table  v all/running tables  tab scroll errors  q cancel run"
+	if m.stopping {
+		help = "cancelling the run..."
+	}
+	output.WriteString(helpStyle.Render(m.truncate(help)))
+
+	return output.String()
+}
@@ -0,0 +1,63 @@
+package monitor_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTUICancelTable(t *testing.T) {
+	t.Parallel()
+
+	keys, typed := io.Pipe()
+	defer typed.Close()
+
+	var buf bytes.Buffer
+	cancelled := make(chan string, 1)
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, false, &buf)
+	mon.UseTUI(keys, func() {}, func(table string) { cancelled <- table })
+
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(context.Background())
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.CountUpdateEvent{Table: lines, TotalRows: 10}
+	eventChan <- monitor.ProgressUpdateEvent{Table: lines, RowsCopied: 10}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: lines}
+
+	// the tables are shown once the first snapshot is taken, lines is selected first
+	time.Sleep(300 * time.Millisecond)
+	_, err := typed.Write([]byte("j"))
+	require.NoError(t, err)
+	_, err = typed.Write([]byte("c"))
+	require.NoError(t, err)
+
+	select {
+	case table := <-cancelled:
+		assert.Equal(t, orders.String(), table)
+	case <-time.After(time.Second):
+		t.Fatal("the selected table was not cancelled")
+	}
+
+	eventChan <- monitor.ErrorEvent{Table: orders, Err: context.Canceled}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	require.NoError(t, <-done)
+
+	out := buf.String()
+	assert.Contains(t, out, "Copying 2 tables: 1 done, 1 failed, 0 running, 0 pending")
+	assert.Contains(t, out, "cancelled")
+	assert.Contains(t, out, "[dbo].[orders]: context canceled")
+}
@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// View determines how the interactive renderer lays out the tables
+type View string
+
+const (
+	// ViewAuto switches to the compact view when more than compactThreshold tables are copied
+	ViewAuto View = "auto"
+	// ViewFull renders a progress bar for every table
+	ViewFull View = "full"
+	// ViewCompact renders aggregated counts plus progress bars for the running tables only
+	ViewCompact View = "compact"
+)
+
+const compactThreshold = 20
+
+func ParseView(view string) (View, error) {
+	switch View(view) {
+	case ViewAuto, ViewFull, ViewCompact:
+		return View(view), nil
+	case "":
+		return ViewAuto, nil
+	}
+
+	return "", fmt.Errorf("unknown view %q", view)
+}
+
+func (m *Monitor) SetView(view View) {
+	if m.terminal != nil {
+		m.terminal.view = view
+	}
+}
+
+func (m *Monitor) send(command func()) {
+	select {
+	case m.commands <- command:
+	default:
+		// the monitor is busy or stopped, the command is dropped
+	}
+}
+
+func (t *terminalSink) isCompact(state *State) bool {
+	return t.view == ViewCompact || (t.view == ViewAuto && len(state.keys) > compactThreshold)
+}
+
+func (t *terminalSink) renderCompact(state *State) string {
+	var done, failed, running, rowsCopied, rowTotal int
+	approximate := false
+	runningKeys := make([]string, 0)
+	failedKeys := make([]string, 0)
+
+	for _, key := range state.keys {
+		reporter := state.tables[key]
+		rowsCopied += reporter.RowsCopied
+		rowTotal += reporter.RowTotal
+		approximate = approximate || reporter.Approximate
+
+		switch {
+		case reporter.err != nil:
+			failed++
+			failedKeys = append(failedKeys, key)
+		case reporter.done:
+			done++
+		default:
+			running++
+			runningKeys = append(runningKeys, key)
+		}
+	}
+
+	total := fmt.Sprintf("%d", rowTotal)
+	if approximate {
+		total = "~" + total
+	}
+
+	var output strings.Builder
+
+	output.WriteString(fmt.Sprintf("Copying %d tables: %d done, %d failed, %d running\n", len(state.keys), done, failed, running))
+	output.WriteString(fmt.Sprintf("Copied %d of %s rows\n", rowsCopied, total))
+	output.WriteString(renderSchemas(state.Schemas()))
+	output.WriteString(renderProgress(state.Progress()) + "\n")
+	output.WriteString(renderStatus(state.status))
+
+	now := time.Now()
+	for _, key := range runningKeys {
+		output.WriteString(fmt.Sprintf("%s\n", state.tables[key].renderBar(now)))
+	}
+
+	if len(failedKeys) > 0 {
+		output.WriteString("\n")
+		for _, key := range failedKeys {
+			output.WriteString(fmt.Sprintf("%s = FAILED : %s\n", key, state.tables[key].err))
+		}
+	}
+
+	return output.String()
+}
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds a single post, so an unreachable webhook delays the end of a run by little
+	webhookTimeout = 10 * time.Second
+	// webhookQueue is the number of messages waiting to be posted, the failures of tables beyond it are dropped
+	webhookQueue = 100
+)
+
+// WebhookSink posts a message to a Slack or Teams incoming webhook when a table fails and a summary when the run is done.
+// The messages are posted in the background, so a slow webhook does not hold up the copy.
+type WebhookSink struct {
+	url     string
+	title   string
+	client  *http.Client
+	started time.Time
+
+	messages chan string
+	done     chan struct{}
+	// err is the first failure to post, reported on Close
+	err error
+}
+
+// NewWebhookSink returns a sink posting to url, the messages start with title
+func NewWebhookSink(url, title string) *WebhookSink {
+	s := &WebhookSink{
+		url:      url,
+		title:    title,
+		client:   &http.Client{Timeout: webhookTimeout},
+		started:  time.Now(),
+		messages: make(chan string, webhookQueue),
+		done:     make(chan struct{}),
+	}
+	go s.send()
+
+	return s
+}
+
+func (s *WebhookSink) Event(state *State, event Event) {
+	e, ok := event.(ErrorEvent)
+	if !ok {
+		return
+	}
+
+	select {
+	case s.messages <- fmt.Sprintf("%s: table %s failed, %s", s.title, e.Table, e.Err):
+	default:
+	}
+}
+
+func (s *WebhookSink) Status(state *State, name, text string) {}
+
+func (s *WebhookSink) Render(state *State) {}
+
+// Close posts the summary of the run and waits until all messages are posted
+func (s *WebhookSink) Close(state *State) {
+	s.messages <- s.summary(state.Summary())
+	close(s.messages)
+	<-s.done
+
+	if s.err != nil {
+		log.Printf("WARNING: failed to post to the notification webhook, %s", s.err)
+	}
+}
+
+// summary returns the message of the end of the run
+func (s *WebhookSink) summary(summary Summary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: copied %d of %d tables, %d rows in %s", s.title, summary.Done, summary.Tables, summary.RowsCopied, summary.Time.Sub(s.started).Round(time.Second))
+
+	if unfinished := summary.Tables - summary.Done - summary.Failed; unfinished > 0 {
+		fmt.Fprintf(&b, ", %d tables were not finished", unfinished)
+	}
+
+	if summary.Failed > 0 {
+		fmt.Fprintf(&b, ", %d tables failed:", summary.Failed)
+		for _, failure := range summary.Failures {
+			fmt.Fprintf(&b, "\n- %s: %s", failure.Table, failure.Error)
+		}
+	}
+
+	return b.String()
+}
+
+func (s *WebhookSink) send() {
+	defer close(s.done)
+
+	for message := range s.messages {
+		err := s.post(message)
+		if err != nil && s.err == nil {
+			s.err = err
+		}
+	}
+}
+
+// post sends message as the text of the payload, which both Slack and Teams incoming webhooks accept
+func (s *WebhookSink) post(message string) error {
+	payload, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{Text: message})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("the webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
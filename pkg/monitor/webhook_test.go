@@ -0,0 +1,68 @@
+package monitor_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jeff-99/mssqlcopy/pkg/monitor"
+	"github.com/jeff-99/mssqlcopy/pkg/mssql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSink(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var payload struct {
+			Text string `json:"text"`
+		}
+		require.NoError(t, json.Unmarshal(body, &payload))
+
+		lock.Lock()
+		messages = append(messages, payload.Text)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	eventChan := make(chan monitor.Event)
+	mon := monitor.NewMonitor(eventChan, true, io.Discard)
+	mon.SetOutput(monitor.OutputNone)
+	mon.AddSink(monitor.NewWebhookSink(server.URL, "nightly refresh"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- mon.Run(ctx)
+	}()
+
+	orders := mssql.TableRef{Schema: "dbo", Table: "orders"}
+	lines := mssql.TableRef{Schema: "dbo", Table: "lines"}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: orders}
+	eventChan <- monitor.CopyTaskStartedEvent{Table: lines}
+	eventChan <- monitor.ProgressUpdateEvent{Table: orders, RowsCopied: 42}
+	eventChan <- monitor.CopyTaskFinishedEvent{Table: orders}
+	eventChan <- monitor.ErrorEvent{Table: lines, Err: errors.New("timeout")}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	// Close waits until the messages are posted
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "nightly refresh: table [dbo].[lines] failed, timeout", messages[0])
+	assert.Regexp(t, `^nightly refresh: copied 1 of 2 tables, 42 rows in \d+s, 1 tables failed:\n- \[dbo\]\.\[lines\]: timeout$`, messages[1])
+}
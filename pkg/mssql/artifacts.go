@@ -0,0 +1,232 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// artifactTable records the changes a copy run makes to the target that have to be undone when the run crashes
+var artifactTable = TableRef{Schema: "dbo", Table: "__asqlcp_artifacts"}
+
+// ArtifactKind is the kind of change recorded in the artifact table
+type ArtifactKind string
+
+const (
+	// ArtifactDroppedForeignKey is a foreign key that was dropped and has to be added again
+	ArtifactDroppedForeignKey ArtifactKind = "dropped_foreign_key"
+	// ArtifactDisabledForeignKey is a foreign key that was disabled and has to be enabled again
+	ArtifactDisabledForeignKey ArtifactKind = "disabled_foreign_key"
+	// ArtifactDisabledIndexes are the nonclustered indexes of a table that were disabled and have to be rebuilt
+	ArtifactDisabledIndexes ArtifactKind = "disabled_indexes"
+	// ArtifactDisabledTriggers are the triggers of a table that were disabled and have to be enabled again
+	ArtifactDisabledTriggers ArtifactKind = "disabled_triggers"
+	// ArtifactDisabledVersioning is a temporal table of which the system versioning was turned off and has to be turned on again
+	ArtifactDisabledVersioning ArtifactKind = "disabled_versioning"
+)
+
+// Artifact is a recorded foreign key, index, trigger or system versioning change, a composite foreign key has a constraint per column
+type Artifact struct {
+	Kind        ArtifactKind
+	Name        string
+	ForeignKeys []ForeingKeyConstraint
+	Indexes     []Index
+	Triggers    []Trigger
+	Temporal    *TemporalTable
+}
+
+func (db *MSSQLDB) ensureArtifactTable(ctx context.Context) error {
+	query := fmt.Sprintf(`
+	IF OBJECT_ID(@table, 'U') IS NULL
+	CREATE TABLE %s (
+		kind nvarchar(64) NOT NULL,
+		name nvarchar(512) NOT NULL,
+		definition nvarchar(max) NOT NULL,
+		created_at datetime2 NOT NULL,
+		PRIMARY KEY (kind, name)
+	)`, artifactTable)
+
+	_, err := db.db.ExecContext(ctx, query, sql.Named("table", artifactTable.String()))
+	return err
+}
+
+// RecordForeignKeys records foreign keys that are about to be dropped or disabled, so they can be restored after a crash
+func (db *MSSQLDB) RecordForeignKeys(ctx context.Context, kind ArtifactKind, foreignKeys []ForeingKeyConstraint) error {
+	if len(foreignKeys) == 0 {
+		return nil
+	}
+
+	err := db.ensureArtifactTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	for name, columns := range groupForeignKeys(foreignKeys) {
+		err = db.recordArtifact(ctx, kind, name, columns)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordArtifact stores the definition of an artifact as JSON, replacing an earlier record of the same artifact
+func (db *MSSQLDB) recordArtifact(ctx context.Context, kind ArtifactKind, name string, definition interface{}) error {
+	encoded, err := json.Marshal(definition)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+	MERGE %s AS t
+	USING (SELECT @kind AS kind, @name AS name) AS s ON t.kind = s.kind AND t.name = s.name
+	WHEN MATCHED THEN UPDATE SET definition = @definition, created_at = SYSUTCDATETIME()
+	WHEN NOT MATCHED THEN INSERT (kind, name, definition, created_at) VALUES (@kind, @name, @definition, SYSUTCDATETIME());`, artifactTable)
+
+	_, err = db.db.ExecContext(ctx, query, sql.Named("kind", string(kind)), sql.Named("name", name), sql.Named("definition", string(encoded)))
+	return err
+}
+
+func (db *MSSQLDB) forgetArtifact(ctx context.Context, kind ArtifactKind, name string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE kind = @kind AND name = @name", artifactTable)
+	_, err := db.db.ExecContext(ctx, query, sql.Named("kind", string(kind)), sql.Named("name", name))
+	return err
+}
+
+// ForgetForeignKeys removes the records of foreign keys that were restored
+func (db *MSSQLDB) ForgetForeignKeys(ctx context.Context, kind ArtifactKind, foreignKeys []ForeingKeyConstraint) error {
+	if len(foreignKeys) == 0 {
+		return nil
+	}
+
+	for name := range groupForeignKeys(foreignKeys) {
+		err := db.forgetArtifact(ctx, kind, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetArtifacts returns the recorded changes that were not undone, none when no run recorded any. It does not change the database.
+func (db *MSSQLDB) GetArtifacts(ctx context.Context) ([]Artifact, error) {
+	var exists bool
+	err := db.db.QueryRowContext(ctx, "SELECT CAST(CASE WHEN OBJECT_ID(@table, 'U') IS NULL THEN 0 ELSE 1 END AS bit)",
+		sql.Named("table", artifactTable.String())).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return []Artifact{}, nil
+	}
+
+	rows, err := db.db.QueryContext(ctx, fmt.Sprintf("SELECT kind, name, definition FROM %s ORDER BY created_at, name", artifactTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artifacts := make([]Artifact, 0)
+	for rows.Next() {
+		var artifact Artifact
+		var kind, definition string
+		err := rows.Scan(&kind, &artifact.Name, &definition)
+		if err != nil {
+			return nil, err
+		}
+
+		artifact.Kind = ArtifactKind(kind)
+		switch artifact.Kind {
+		case ArtifactDisabledIndexes:
+			err = json.Unmarshal([]byte(definition), &artifact.Indexes)
+		case ArtifactDisabledTriggers:
+			err = json.Unmarshal([]byte(definition), &artifact.Triggers)
+		case ArtifactDisabledVersioning:
+			err = json.Unmarshal([]byte(definition), &artifact.Temporal)
+		default:
+			err = json.Unmarshal([]byte(definition), &artifact.ForeignKeys)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid definition of %s %s, %w", kind, artifact.Name, err)
+		}
+
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts, rows.Err()
+}
+
+// GetRecordedForeignKeys returns the recorded foreign keys of kind referencing table, which a run that did not finish left dropped or disabled
+func (db *MSSQLDB) GetRecordedForeignKeys(ctx context.Context, kind ArtifactKind, table TableRef) ([]ForeingKeyConstraint, error) {
+	artifacts, err := db.GetArtifacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	foreignKeys := make([]ForeingKeyConstraint, 0)
+	for _, artifact := range artifacts {
+		if artifact.Kind != kind {
+			continue
+		}
+		for _, fk := range artifact.ForeignKeys {
+			if strings.EqualFold(fk.ReferencedSchema, table.Schema) && strings.EqualFold(fk.ReferencedTable, table.Table) {
+				foreignKeys = append(foreignKeys, fk)
+			}
+		}
+	}
+
+	return foreignKeys, nil
+}
+
+// GetStagingTables returns the staging tables left behind by merge copies that did not finish
+func (db *MSSQLDB) GetStagingTables(ctx context.Context) ([]TableRef, error) {
+	query := `
+	SELECT SCHEMA_NAME(schema_id), name
+	FROM sys.tables
+	WHERE name LIKE @prefix ESCAPE '\'
+	ORDER BY 1, 2
+	`
+	prefix := strings.ReplaceAll(stagingTablePrefix, "_", `\_`) + "%"
+	rows, err := db.db.QueryContext(ctx, query, sql.Named("prefix", prefix))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make([]TableRef, 0)
+	for rows.Next() {
+		var table TableRef
+		err := rows.Scan(&table.Schema, &table.Table)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+// StagedTable returns the table a staging table was created for
+func StagedTable(staging TableRef) TableRef {
+	return TableRef{Schema: staging.Schema, Table: strings.TrimPrefix(staging.Table, stagingTablePrefix)}
+}
+
+// CountForeignKeys returns the number of foreign keys of the per column constraints
+func CountForeignKeys(foreignKeys []ForeingKeyConstraint) int {
+	return len(groupForeignKeys(foreignKeys))
+}
+
+// groupForeignKeys groups the per column constraints by their qualified constraint name
+func groupForeignKeys(foreignKeys []ForeingKeyConstraint) map[string][]ForeingKeyConstraint {
+	grouped := make(map[string][]ForeingKeyConstraint)
+	for _, fk := range foreignKeys {
+		name := TableRef{Schema: fk.Schema, Table: fk.Name}.String()
+		grouped[name] = append(grouped[name], fk)
+	}
+
+	return grouped
+}
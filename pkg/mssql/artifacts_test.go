@@ -0,0 +1,47 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupForeignKeys(t *testing.T) {
+	grouped := groupForeignKeys([]ForeingKeyConstraint{
+		{Name: "FK_lines_orders", Schema: "dbo", Table: "lines", Column: "order_id"},
+		{Name: "FK_lines_orders", Schema: "dbo", Table: "lines", Column: "order_version"},
+		{Name: "FK_notes_orders", Schema: "dbo", Table: "notes", Column: "order_id"},
+	})
+
+	assert.Len(t, grouped, 2)
+	assert.Len(t, grouped["[dbo].[FK_lines_orders]"], 2)
+	assert.Len(t, grouped["[dbo].[FK_notes_orders]"], 1)
+}
+
+func TestStagedTable(t *testing.T) {
+	staged := StagedTable(TableRef{Schema: "sales", Table: stagingTablePrefix + "orders"})
+	assert.Equal(t, TableRef{Schema: "sales", Table: "orders"}, staged)
+}
+
+func TestAlterIndexStatement(t *testing.T) {
+	index := Index{Schema: "sales", Table: "orders", Name: "IX_orders_customer"}
+	assert.Equal(t, "ALTER INDEX [IX_orders_customer] ON [sales].[orders] DISABLE", alterIndexStatement(index, "DISABLE"))
+	assert.Equal(t, "ALTER INDEX [IX_orders_customer] ON [sales].[orders] REBUILD", alterIndexStatement(index, "REBUILD"))
+}
+
+func TestTriggerStatement(t *testing.T) {
+	trigger := Trigger{Schema: "sales", Table: "orders", Name: "TR_orders_audit"}
+	assert.Equal(t, "DISABLE TRIGGER [sales].[TR_orders_audit] ON [sales].[orders]", triggerStatement(trigger, "DISABLE"))
+}
+
+func TestSystemVersioningStatements(t *testing.T) {
+	orders := TemporalTable{
+		Table:       TableRef{Schema: "sales", Table: "orders"},
+		History:     TableRef{Schema: "history", Table: "orders"},
+		PeriodStart: "valid_from",
+		PeriodEnd:   "valid_to",
+	}
+
+	assert.Equal(t, "ALTER TABLE [sales].[orders] SET (SYSTEM_VERSIONING = OFF); ALTER TABLE [sales].[orders] DROP PERIOD FOR SYSTEM_TIME;", disableSystemVersioningStatement(orders))
+	assert.Equal(t, "ALTER TABLE [sales].[orders] ADD PERIOD FOR SYSTEM_TIME ([valid_from], [valid_to]); ALTER TABLE [sales].[orders] SET (SYSTEM_VERSIONING = ON (HISTORY_TABLE = [history].[orders], DATA_CONSISTENCY_CHECK = ON));", enableSystemVersioningStatement(orders))
+}
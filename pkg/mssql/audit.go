@@ -0,0 +1,72 @@
+package mssql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditJournal records the destructive statements run on a database before they are executed, with the DDL restoring
+// what they drop, so the operator can restore the foreign keys by hand when a run dies before it restored them itself
+type AuditJournal struct {
+	lock    *sync.Mutex
+	w       io.Writer
+	encoder *json.Encoder
+}
+
+// AuditRecord is a line of the audit journal
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Host      string    `json:"host"`
+	Database  string    `json:"database"`
+	Statement string    `json:"statement"`
+	// Restore is the DDL undoing the statement, empty for the statements removing rows
+	Restore string `json:"restore,omitempty"`
+}
+
+// NewAuditJournal returns the journal written to w as JSON lines, every record is synced to disk before the statement
+// runs when w is a file
+func NewAuditJournal(w io.Writer) *AuditJournal {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return &AuditJournal{lock: &sync.Mutex{}, w: w, encoder: encoder}
+}
+
+// Record appends record to the journal
+func (j *AuditJournal) Record(record AuditRecord) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	err := j.encoder.Encode(record)
+	if err != nil {
+		return err
+	}
+
+	if f, ok := j.w.(interface{ Sync() error }); ok {
+		return f.Sync()
+	}
+
+	return nil
+}
+
+// SetAuditJournal records the TRUNCATE, DELETE, DROP CONSTRAINT and NOCHECK CONSTRAINT statements in journal before they run
+func (db *MSSQLDB) SetAuditJournal(journal *AuditJournal) {
+	db.journal = journal
+}
+
+// execDestructive records a statement dropping rows or constraints in the audit journal and executes it, restore is the DDL
+// undoing it. The statement does not run when it can not be recorded.
+func (db *MSSQLDB) execDestructive(ctx context.Context, query string, restore string) error {
+	if db.journal != nil {
+		err := db.journal.Record(AuditRecord{Time: time.Now(), Host: db.host, Database: db.database, Statement: query, Restore: restore})
+		if err != nil {
+			return fmt.Errorf("refusing to run %s, it could not be recorded in the audit journal: %w", query, err)
+		}
+	}
+
+	return db.execStatement(ctx, query)
+}
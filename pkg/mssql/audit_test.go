@@ -0,0 +1,47 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditJournal(t *testing.T) {
+	var buf bytes.Buffer
+	journal := NewAuditJournal(&buf)
+
+	at := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	require.NoError(t, journal.Record(AuditRecord{Time: at, Host: "target", Database: "sales", Statement: "TRUNCATE TABLE dbo.orders"}))
+	require.NoError(t, journal.Record(AuditRecord{
+		Time:      at,
+		Host:      "target",
+		Database:  "sales",
+		Statement: "ALTER TABLE [dbo].[lines] DROP CONSTRAINT [fk_lines_orders]",
+		Restore:   "ALTER TABLE [dbo].[lines] WITH NOCHECK ADD CONSTRAINT [fk_lines_orders] FOREIGN KEY ([order_id]) REFERENCES [dbo].[orders] ([id]) ON DELETE NO ACTION ON UPDATE NO ACTION",
+	}))
+
+	assert.Equal(t, `{"time":"2024-05-01T02:00:00Z","host":"target","database":"sales","statement":"TRUNCATE TABLE dbo.orders"}
+{"time":"2024-05-01T02:00:00Z","host":"target","database":"sales","statement":"ALTER TABLE [dbo].[lines] DROP CONSTRAINT [fk_lines_orders]","restore":"ALTER TABLE [dbo].[lines] WITH NOCHECK ADD CONSTRAINT [fk_lines_orders] FOREIGN KEY ([order_id]) REFERENCES [dbo].[orders] ([id]) ON DELETE NO ACTION ON UPDATE NO ACTION"}
+`, buf.String())
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestExecDestructiveRefusesUnrecordedStatements(t *testing.T) {
+	// without a connection the statement would panic if it ran
+	db := &MSSQLDB{host: "target", database: "sales", journal: NewAuditJournal(failingWriter{})}
+
+	err := db.EmptyTable(context.Background(), TableRef{Schema: "dbo", Table: "orders"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+}
@@ -0,0 +1,84 @@
+package mssql
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	azuresql "github.com/microsoft/go-mssqldb/azuread"
+)
+
+// AuthMethod is how a connection authenticates
+type AuthMethod string
+
+const (
+	// AuthDefault uses the default Azure credential chain: the environment, a managed identity or the Azure CLI login
+	AuthDefault AuthMethod = "default"
+	// AuthAzCLI uses the login of the Azure CLI
+	AuthAzCLI AuthMethod = "azcli"
+	// AuthManagedIdentity uses the managed identity of the host, the user assigned one of which User is the client id when it is set
+	AuthManagedIdentity AuthMethod = "managedIdentity"
+	// AuthServicePrincipal uses the client id[@tenant id] in User with the client secret in the PasswordEnv variable
+	AuthServicePrincipal AuthMethod = "servicePrincipal"
+	// AuthPassword uses the SQL login in User with the password in the PasswordEnv variable
+	AuthPassword AuthMethod = "password"
+)
+
+func ParseAuthMethod(method string) (AuthMethod, error) {
+	switch AuthMethod(method) {
+	case AuthDefault, AuthAzCLI, AuthManagedIdentity, AuthServicePrincipal, AuthPassword:
+		return AuthMethod(method), nil
+	case "":
+		return AuthDefault, nil
+	}
+
+	return "", fmt.Errorf("unknown auth method %q, use default, azcli, managedIdentity, servicePrincipal or password", method)
+}
+
+// Auth are the credentials of a connection, the zero value uses the default Azure credential chain
+type Auth struct {
+	Method AuthMethod
+	// User is the client id of a managed identity or service principal, or the SQL login
+	User string
+	// PasswordEnv is the environment variable holding the client secret or the password, secrets are never passed as flags or stored
+	PasswordEnv string
+}
+
+// dsn returns the connection string of database on host with the credentials
+func (a Auth) dsn(host, database string) (string, error) {
+	method, err := ParseAuthMethod(string(a.Method))
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("database", database)
+	u := url.URL{Scheme: "sqlserver", Host: host}
+
+	switch method {
+	case AuthDefault:
+		query.Set("fedauth", azuresql.ActiveDirectoryDefault)
+	case AuthAzCLI:
+		query.Set("fedauth", azuresql.ActiveDirectoryAzCli)
+	case AuthManagedIdentity:
+		query.Set("fedauth", azuresql.ActiveDirectoryManagedIdentity)
+		if a.User != "" {
+			u.User = url.User(a.User)
+		}
+	case AuthServicePrincipal, AuthPassword:
+		if a.User == "" || a.PasswordEnv == "" {
+			return "", fmt.Errorf("auth %s needs a user and the environment variable holding its secret", method)
+		}
+		secret, ok := os.LookupEnv(a.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s with the secret of %s is not set", a.PasswordEnv, a.User)
+		}
+		if method == AuthServicePrincipal {
+			query.Set("fedauth", azuresql.ActiveDirectoryServicePrincipal)
+		}
+		u.User = url.UserPassword(a.User, secret)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
@@ -3,9 +3,14 @@ package mssql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	mssqlDriver "github.com/microsoft/go-mssqldb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type BulkInsert struct {
@@ -17,34 +22,128 @@ type BulkInsert struct {
 	count int
 	stmt  *sql.Stmt
 	tx    *sql.Tx
+
+	// span traces the current batch from its first row until it is committed or rolled back
+	span           trace.Span
+	spanAttributes []attribute.KeyValue
+	// database and started are logged with the batches at debug level, started is the time of the first row of the current batch
+	database string
+	started  time.Time
+
+	onCommit func() error
+	// onBatch is called with the number of rows of every committed batch and the time since its first row
+	onBatch func(rows int, took time.Duration)
+
+	// pending holds the rows given since the last commit when keepPending is set
+	pending     [][]interface{}
+	keepPending bool
+
+	// converters convert the values read from the source per column, see bulkConverters
+	converters []bulkConverter
+	// identity is set when the identity values in the columns are kept, see BulkOptions.KeepIdentity
+	identity bool
+	opts     BulkOptions
+
+	// sent counts the rows of the committed batches, accepted the rows the server reported as copied
+	sent     int64
+	accepted int64
+}
+
+const (
+	// identityStagingTable is the temporary table the batches of a table with an identity column are loaded into
+	identityStagingTable = "#asqlcp_identity"
+
+	// DefaultCommitCount is the number of rows per batch, every batch is committed in its own transaction
+	DefaultCommitCount = 50_000
+	// maxCellsPerCommit bounds the number of values buffered in a single batch, so wide tables commit more often
+	maxCellsPerCommit = 2_500_000
+)
+
+// BulkOptions tune a bulk copy, the zero value commits every DefaultCommitCount rows with the defaults of the server
+type BulkOptions struct {
+	// CommitCount is the number of rows per transaction, it is lowered for tables with many columns
+	CommitCount int
+	// Tablock takes a table lock for the duration of every batch instead of row locks
+	Tablock bool
+	// KeepNulls inserts NULL values as is instead of the default values of their columns
+	KeepNulls bool
+	// CheckConstraints checks the check and foreign key constraints of the table, which the server skips by default
+	CheckConstraints bool
+	// FireTriggers runs the insert triggers of the table, which the server skips by default
+	FireTriggers bool
+	// KeepIdentity keeps the values of the identity column instead of generating new ones. The driver does not support KEEP_IDENTITY,
+	// so the batches of a table with an identity column are loaded into a temporary table and inserted with IDENTITY_INSERT,
+	// which writes the rows twice, is fully logged and runs the insert triggers of the table.
+	KeepIdentity bool
+	// RowsPerBatch hints the server at the number of rows of every batch
+	RowsPerBatch int
 }
 
-func NewBulkInsert(table TableRef, columns []string, db *sql.DB) *BulkInsert {
-	commitCount := 50_000
+func NewBulkInsert(table TableRef, columns []string, db *sql.DB, opts BulkOptions) *BulkInsert {
+	commitCount := opts.CommitCount
+	if commitCount <= 0 {
+		commitCount = DefaultCommitCount
+	}
 
 	return &BulkInsert{
 		table:       table,
 		columns:     columns,
 		db:          db,
-		commitCount: commitCount,
+		commitCount: effectiveCommitCount(commitCount, len(columns)),
+		opts:        opts,
+	}
+}
+
+// effectiveCommitCount lowers the number of rows per batch for tables with many columns
+func effectiveCommitCount(commitCount, columnCount int) int {
+	if columnCount == 0 {
+		return commitCount
 	}
+
+	limit := maxCellsPerCommit / columnCount
+	if limit < 1 {
+		limit = 1
+	}
+
+	if commitCount > limit {
+		return limit
+	}
+
+	return commitCount
 }
 
 func (bi *BulkInsert) getStmt(ctx context.Context) (*sql.Stmt, error) {
 	if bi.stmt == nil {
+		ctx, bi.span = startSpan(ctx, "bulk copy batch", bi.spanAttributes)
+		bi.started = time.Now()
 		tx, err := bi.db.BeginTx(ctx, nil)
 		if err != nil {
+			bi.endSpan(err)
 			return nil, err
 		}
 
-		query := mssqlDriver.CopyIn(fmt.Sprintf("%s.%s", bi.table.Schema, bi.table.Table), mssqlDriver.BulkOptions{}, bi.columns...)
+		if bi.identity {
+			// the driver does not support KEEP_IDENTITY, without it the server generates new identity values. The batch is loaded
+			// into a temporary table of the same connection instead, and inserted with IDENTITY_INSERT on commit.
+			_, err = loggedQuerier{tx, bi.database}.ExecContext(ctx, identityStagingQuery(bi.table, bi.columns))
+			if err != nil {
+				tx.Rollback()
+				bi.endSpan(err)
+				return nil, err
+			}
+		}
+
+		query := mssqlDriver.CopyIn(bi.loadTable(), bi.driverOptions(), bi.columns...)
 		stmt, err := tx.Prepare(query)
 		if err != nil {
+			tx.Rollback()
+			bi.endSpan(err)
 			return nil, err
 		}
 
 		bi.stmt = stmt
 		bi.tx = tx
+		logStatement(ctx, bi.database, query, bi.started, "table", bi.table.String(), "bulk_copy", "begin")
 
 	}
 
@@ -52,16 +151,62 @@ func (bi *BulkInsert) getStmt(ctx context.Context) (*sql.Stmt, error) {
 
 }
 
-func (bi *BulkInsert) Insert(ctx context.Context, row []interface{}) error {
-	// decimals are read as []uint8 by the driver, []uint8 is a byte slice (alias for []byte) but the same driver does not support []byte for bulk insert so we need to convert it to string
-	for i, value := range row {
-		if v, ok := value.(*interface{}); ok {
-			if b, ok := (*v).([]uint8); ok {
-				row[i] = string(b)
+// InsertBatch inserts the rows, committing every commit count rows like Insert
+func (bi *BulkInsert) InsertBatch(ctx context.Context, rows [][]interface{}) error {
+	if bi.keepPending {
+		bi.pending = append(bi.pending, rows...)
+	}
+
+	for len(rows) > 0 {
+		// the commit count can be lowered below the rows of the current batch
+		if bi.count >= bi.commitCount {
+			err := bi.Commit(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		stmt, err := bi.getStmt(ctx)
+		if err != nil {
+			return err
+		}
+
+		n := min(len(rows), bi.commitCount-bi.count)
+		for _, row := range rows[:n] {
+			err = convertRow(bi.converters, bi.columns, row)
+			if err != nil {
+				return err
+			}
+
+			_, err = stmt.ExecContext(ctx, row...)
+			if err != nil {
+				return err
+			}
+		}
+		bi.count += n
+		rows = rows[n:]
+
+		if bi.count == bi.commitCount {
+			err = bi.Commit(ctx)
+			if err != nil {
+				return err
 			}
 		}
 	}
 
+	return nil
+}
+
+func (bi *BulkInsert) Insert(ctx context.Context, row []interface{}) error {
+	if bi.keepPending {
+		bi.pending = append(bi.pending, row)
+	}
+
+	err := convertRow(bi.converters, bi.columns, row)
+	if err != nil {
+		return err
+	}
+
 	stmt, err := bi.getStmt(ctx)
 	if err != nil {
 		return err
@@ -73,7 +218,7 @@ func (bi *BulkInsert) Insert(ctx context.Context, row []interface{}) error {
 	}
 
 	bi.count++
-	if bi.count%bi.commitCount == 0 {
+	if bi.count >= bi.commitCount {
 		err = bi.Commit(ctx)
 		if err != nil {
 			return err
@@ -88,31 +233,174 @@ func (bi *BulkInsert) Commit(ctx context.Context) error {
 		return nil
 	}
 
-	_, err := bi.stmt.Exec()
+	// the final Exec sends the batch and returns the number of rows the server copied
+	result, err := bi.stmt.Exec()
 	if err != nil {
-		return err
+		return bi.endSpan(err)
+	}
+
+	accepted, err := result.RowsAffected()
+	if err != nil {
+		return bi.endSpan(err)
 	}
 
 	err = bi.stmt.Close()
 	if err != nil {
-		return err
+		return bi.endSpan(err)
+	}
+
+	if bi.identity {
+		_, err = loggedQuerier{bi.tx, bi.database}.ExecContext(ctx, identityInsertQuery(bi.table, bi.columns))
+		if err != nil {
+			bi.tx.Rollback()
+			return bi.endSpan(err)
+		}
 	}
 
 	err = bi.tx.Commit()
 	if err != nil {
-		return err
+		return bi.endSpan(commitError(err))
+	}
+	bi.endSpan(nil)
+	logStatement(ctx, bi.database, "COMMIT", bi.started, "table", bi.table.String(), "bulk_copy", "commit", "rows", bi.count, "accepted", accepted)
+
+	bi.sent += int64(bi.count)
+	bi.accepted += accepted
+
+	if bi.onBatch != nil {
+		// the rows are sent while the batch is inserted, its duration includes the time since its first row
+		bi.onBatch(bi.count, time.Since(bi.started))
+	}
+
+	if bi.keepPending {
+		bi.pending = append([][]interface{}(nil), bi.pending[bi.count:]...)
 	}
 
 	bi.count = 0
 	bi.stmt = nil
 	bi.tx = nil
 
+	if bi.onCommit != nil {
+		return bi.onCommit()
+	}
+
 	return nil
 }
 
+// identityStagingQuery creates the temporary table for a batch, the UNION ALL prevents SELECT INTO from copying the IDENTITY property
+func identityStagingQuery(table TableRef, columns []string) string {
+	quoted := quoteColumns(columns)
+	return fmt.Sprintf("SELECT TOP 0 %s INTO %s FROM %s UNION ALL SELECT TOP 0 %s FROM %s", quoted, identityStagingTable, table, quoted, table)
+}
+
+// identityInsertQuery moves a loaded batch into the table with the identity values of the source
+func identityInsertQuery(table TableRef, columns []string) string {
+	quoted := quoteColumns(columns)
+	return fmt.Sprintf("SET IDENTITY_INSERT %s ON; INSERT INTO %s (%s) SELECT %s FROM %s; SET IDENTITY_INSERT %s OFF; DROP TABLE %s;",
+		table, table, quoted, quoted, identityStagingTable, table, identityStagingTable)
+}
+
+// loadTable returns the quoted name of the table the batches are bulk copied into
+func (bi *BulkInsert) loadTable() string {
+	if bi.identity {
+		return identityStagingTable
+	}
+
+	return bi.table.String()
+}
+
+func quoteColumns(columns []string) string {
+	quoter := mssqlDriver.TSQLQuoter{}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoter.ID(column)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// RowsSent returns the number of rows sent in the committed batches
+func (bi *BulkInsert) RowsSent() int64 {
+	return bi.sent
+}
+
+// RowsAccepted returns the number of rows the server reported as copied for the committed batches
+func (bi *BulkInsert) RowsAccepted() int64 {
+	return bi.accepted
+}
+
+func (bi *BulkInsert) driverOptions() mssqlDriver.BulkOptions {
+	return mssqlDriver.BulkOptions{
+		Tablock:          bi.opts.Tablock,
+		KeepNulls:        bi.opts.KeepNulls,
+		CheckConstraints: bi.opts.CheckConstraints,
+		FireTriggers:     bi.opts.FireTriggers,
+		RowsPerBatch:     bi.opts.RowsPerBatch,
+	}
+}
+
+// OnCommit registers fn to be called after every committed batch
+func (bi *BulkInsert) OnCommit(fn func() error) {
+	bi.onCommit = fn
+}
+
+// OnBatch registers fn to be called with the number of rows of every committed batch and the time since its first row
+func (bi *BulkInsert) OnBatch(fn func(rows int, took time.Duration)) {
+	bi.onBatch = fn
+}
+
+// CommitCount returns the number of rows per transaction
+func (bi *BulkInsert) CommitCount() int {
+	return bi.commitCount
+}
+
+// SetCommitCount changes the number of rows per transaction from the current batch on, it is lowered for tables with many columns
+func (bi *BulkInsert) SetCommitCount(commitCount int) {
+	bi.commitCount = effectiveCommitCount(max(commitCount, 1), len(bi.columns))
+}
+
+// KeepPending keeps the rows given since the last commit, so they can be inserted again after a failed batch
+func (bi *BulkInsert) KeepPending() {
+	bi.keepPending = true
+}
+
+// Pending returns the rows given since the last commit, including the rows not sent because of a failure. It requires KeepPending.
+func (bi *BulkInsert) Pending() [][]interface{} {
+	return bi.pending
+}
+
+// Rollback discards the uncommitted batch, after which the inserter starts a new one
 func (bi *BulkInsert) Rollback(ctx context.Context) error {
 	if bi.tx == nil {
 		return fmt.Errorf("no active transaction to rollback")
 	}
-	return bi.tx.Rollback()
+
+	err := bi.tx.Rollback()
+	bi.endSpan(fmt.Errorf("rolled back"))
+	logStatement(ctx, bi.database, "ROLLBACK", bi.started, "table", bi.table.String(), "bulk_copy", "rollback", "rows", bi.count)
+
+	bi.count = 0
+	bi.stmt = nil
+	bi.tx = nil
+	bi.pending = nil
+
+	// a failed commit can already have ended the transaction
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+
+	return err
+}
+
+// endSpan ends the span of the current batch with the outcome of its commit and returns err
+func (bi *BulkInsert) endSpan(err error) error {
+	if bi.span == nil {
+		return err
+	}
+
+	bi.span.SetAttributes(attribute.Int("asqlcp.rows", bi.count))
+	endSpan(bi.span, err)
+	bi.span = nil
+
+	return err
 }
@@ -0,0 +1,49 @@
+package mssql
+
+import (
+	"testing"
+
+	mssqlDriver "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectiveCommitCount(t *testing.T) {
+	assert.Equal(t, 50_000, effectiveCommitCount(50_000, 10))
+	assert.Equal(t, 50_000, effectiveCommitCount(50_000, 0))
+	assert.Equal(t, 2_500, effectiveCommitCount(50_000, 1_000))
+	assert.Equal(t, 1, effectiveCommitCount(50_000, 5_000_000))
+}
+
+func TestIdentityQueries(t *testing.T) {
+	orders := TableRef{Schema: "dbo", Table: "orders"}
+	columns := []string{"id", "total"}
+
+	assert.Equal(t, "SELECT TOP 0 [id], [total] INTO #asqlcp_identity FROM [dbo].[orders] UNION ALL SELECT TOP 0 [id], [total] FROM [dbo].[orders]", identityStagingQuery(orders, columns))
+	assert.Equal(t, "SET IDENTITY_INSERT [dbo].[orders] ON; INSERT INTO [dbo].[orders] ([id], [total]) SELECT [id], [total] FROM #asqlcp_identity; SET IDENTITY_INSERT [dbo].[orders] OFF; DROP TABLE #asqlcp_identity;", identityInsertQuery(orders, columns))
+}
+
+func TestBulkOptions(t *testing.T) {
+	orders := TableRef{Schema: "dbo", Table: "orders"}
+
+	bi := NewBulkInsert(orders, []string{"id", "total"}, nil, BulkOptions{})
+	assert.Equal(t, DefaultCommitCount, bi.commitCount)
+	assert.Equal(t, mssqlDriver.BulkOptions{}, bi.driverOptions())
+
+	bi = NewBulkInsert(orders, []string{"id", "total"}, nil, BulkOptions{CommitCount: 1_000, Tablock: true, KeepNulls: true, RowsPerBatch: 1_000})
+	assert.Equal(t, 1_000, bi.commitCount)
+	assert.Equal(t, mssqlDriver.BulkOptions{Tablock: true, KeepNulls: true, RowsPerBatch: 1_000}, bi.driverOptions())
+
+	wide := NewBulkInsert(orders, make([]string, 1_000), nil, BulkOptions{CommitCount: 500_000})
+	assert.Equal(t, 2_500, wide.commitCount)
+}
+
+func TestLoadTable(t *testing.T) {
+	// a table mapped to a target of which the name needs quoting
+	target := TableRef{Schema: "staging", Table: "Orders copy"}
+
+	bi := NewBulkInsert(target, []string{"id", "total"}, nil, BulkOptions{})
+	assert.Equal(t, "[staging].[Orders copy]", bi.loadTable())
+
+	bi.identity = true
+	assert.Equal(t, identityStagingTable, bi.loadTable())
+}
@@ -0,0 +1,156 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// syncStateTable stores the change tracking version each table was last synced to, on the target database
+var syncStateTable = TableRef{Schema: "dbo", Table: "__asqlcp_sync_state"}
+
+// ChangeTrackingVersion returns the current change tracking version of the database
+func (db *MSSQLDB) ChangeTrackingVersion(ctx context.Context) (int64, error) {
+	var version sql.NullInt64
+	err := db.db.QueryRowContext(ctx, "SELECT CHANGE_TRACKING_CURRENT_VERSION()").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	if !version.Valid {
+		return 0, fmt.Errorf("change tracking is not enabled on the database")
+	}
+
+	return version.Int64, nil
+}
+
+// ChangeTrackingMinValidVersion returns the oldest version changes of table can be read from
+func (db *MSSQLDB) ChangeTrackingMinValidVersion(ctx context.Context, table TableRef) (int64, error) {
+	var version sql.NullInt64
+	err := db.db.QueryRowContext(ctx, "SELECT CHANGE_TRACKING_MIN_VALID_VERSION(OBJECT_ID(@table))", sql.Named("table", table.String())).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	if !version.Valid {
+		return 0, fmt.Errorf("change tracking is not enabled on table %s", table)
+	}
+
+	return version.Int64, nil
+}
+
+// CountChanges returns the number of rows of table that changed since version
+func (db *MSSQLDB) CountChanges(ctx context.Context, table TableRef, version int64) (int, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM CHANGETABLE(CHANGES %s, @version) AS ct", table)
+
+	var count int
+	err := db.db.QueryRowContext(ctx, query, sql.Named("version", version)).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// SelectChanges reads the rows of table that changed since version. Every row starts with the change operation (I, U or D),
+// followed by the key columns and the current values of columns, which are NULL for deleted rows.
+func (db *MSSQLDB) SelectChanges(ctx context.Context, table TableRef, columns []string, keys []string, version int64) (*RowIterator, error) {
+	rows, err := db.reader.QueryContext(ctx, selectChangesQuery(table, columns, keys), sql.Named("version", version))
+	if err != nil {
+		return nil, err
+	}
+
+	return &RowIterator{
+		columnCount: 1 + len(keys) + len(columns),
+		rows:        rows,
+	}, nil
+}
+
+func selectChangesQuery(table TableRef, columns []string, keys []string) string {
+	quoter := mssql.TSQLQuoter{}
+
+	selected := make([]string, 0, 1+len(keys)+len(columns))
+	selected = append(selected, "ct.SYS_CHANGE_OPERATION")
+
+	conditions := make([]string, len(keys))
+	for i, key := range keys {
+		selected = append(selected, "ct."+quoter.ID(key))
+		conditions[i] = fmt.Sprintf("t.%s = ct.%s", quoter.ID(key), quoter.ID(key))
+	}
+
+	for _, column := range columns {
+		selected = append(selected, "t."+quoter.ID(column))
+	}
+
+	return fmt.Sprintf(
+		"SELECT %s FROM CHANGETABLE(CHANGES %s, @version) AS ct LEFT JOIN %s AS t ON %s",
+		strings.Join(selected, ", "), table, table, strings.Join(conditions, " AND "),
+	)
+}
+
+// DeleteByKey deletes the row of table identified by the values of its key columns
+func (db *MSSQLDB) DeleteByKey(ctx context.Context, table TableRef, keys []string, values []interface{}) error {
+	quoter := mssql.TSQLQuoter{}
+
+	conditions := make([]string, len(keys))
+	for i, key := range keys {
+		conditions[i] = fmt.Sprintf("%s = @p%d", quoter.ID(key), i+1)
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(conditions, " AND "))
+	_, err := db.db.ExecContext(ctx, query, values...)
+	return err
+}
+
+func (db *MSSQLDB) ensureSyncStateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`
+	IF OBJECT_ID(@table, 'U') IS NULL
+	CREATE TABLE %s (
+		table_name nvarchar(512) NOT NULL PRIMARY KEY,
+		sync_version bigint NOT NULL,
+		synced_at datetime2 NOT NULL
+	)`, syncStateTable)
+
+	_, err := db.db.ExecContext(ctx, query, sql.Named("table", syncStateTable.String()))
+	return err
+}
+
+// GetSyncVersion returns the change tracking version table was last synced to, the boolean is false if it was never synced
+func (db *MSSQLDB) GetSyncVersion(ctx context.Context, table TableRef) (int64, bool, error) {
+	err := db.ensureSyncStateTable(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var version int64
+	query := fmt.Sprintf("SELECT sync_version FROM %s WHERE table_name = @table", syncStateTable)
+	err = db.db.QueryRowContext(ctx, query, sql.Named("table", table.String())).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return version, true, nil
+}
+
+// SetSyncVersion records the change tracking version table has been synced to
+func (db *MSSQLDB) SetSyncVersion(ctx context.Context, table TableRef, version int64) error {
+	err := db.ensureSyncStateTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+	MERGE %s AS t
+	USING (SELECT @table AS table_name) AS s ON t.table_name = s.table_name
+	WHEN MATCHED THEN UPDATE SET sync_version = @version, synced_at = SYSUTCDATETIME()
+	WHEN NOT MATCHED THEN INSERT (table_name, sync_version, synced_at) VALUES (@table, @version, SYSUTCDATETIME());`, syncStateTable)
+
+	_, err = db.db.ExecContext(ctx, query, sql.Named("table", table.String()), sql.Named("version", version))
+	return err
+}
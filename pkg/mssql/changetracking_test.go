@@ -0,0 +1,12 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectChangesQuery(t *testing.T) {
+	query := selectChangesQuery(TableRef{Schema: "dbo", Table: "orders"}, []string{"id", "status"}, []string{"id"})
+	assert.Equal(t, "SELECT ct.SYS_CHANGE_OPERATION, ct.[id], t.[id], t.[status] FROM CHANGETABLE(CHANGES [dbo].[orders], @version) AS ct LEFT JOIN [dbo].[orders] AS t ON t.[id] = ct.[id]", query)
+}
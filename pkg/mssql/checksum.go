@@ -0,0 +1,203 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// noncomparableTypes can not be passed to BINARY_CHECKSUM, types unknown to the tool are skipped as well
+var noncomparableTypes = map[string]bool{
+	"text": true, "ntext": true, "image": true, "xml": true,
+	"geography": true, "geometry": true,
+}
+
+// ChecksumColumns splits the columns of a schema into the ones that can be checksummed and the ones that can not, both sorted by name
+func ChecksumColumns(schema SchemaDefinition) ([]string, []string) {
+	columns := make([]string, 0, len(schema))
+	skipped := make([]string, 0)
+	for column, definition := range schema {
+		if noncomparableTypes[definition.Type] || !knownTypes[definition.Type] {
+			skipped = append(skipped, column)
+			continue
+		}
+		columns = append(columns, column)
+	}
+
+	sort.Strings(columns)
+	sort.Strings(skipped)
+
+	return columns, skipped
+}
+
+// GetChecksum counts the rows of table and aggregates their BINARY_CHECKSUM over the given columns, the aggregate does not depend on the row order.
+// Different data can result in the same checksum, equal checksums make differences unlikely rather than impossible.
+func (db *MSSQLDB) GetChecksum(ctx context.Context, table TableRef, columns []string, opts ReadOptions) (int64, int64, error) {
+	where, err := opts.where()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	query := checksumQuery(table, columns, where)
+
+	var rows int64
+	var checksum sql.NullInt64
+	err = db.reader.QueryRowContext(ctx, query).Scan(&rows, &checksum)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return rows, checksum.Int64, nil
+}
+
+func checksumQuery(table TableRef, columns []string, where string) string {
+	quoter := mssql.TSQLQuoter{}
+
+	checksum := "0"
+	if len(columns) > 0 {
+		quotedColumns := make([]string, len(columns))
+		for i, column := range columns {
+			quotedColumns[i] = quoter.ID(column)
+		}
+		checksum = fmt.Sprintf("CHECKSUM_AGG(BINARY_CHECKSUM(%s))", strings.Join(quotedColumns, ", "))
+	}
+
+	return fmt.Sprintf("SELECT COUNT_BIG(*), CAST(%s AS bigint) FROM %s WHERE %s", checksum, table, where)
+}
+
+// BucketChecksum aggregates the SHA-256 hashes of the rows in a bucket of primary keys, Sum adds up the first four bytes
+// of the hashes and Xor combines the next four, neither depends on the row order
+type BucketChecksum struct {
+	Rows int64
+	Sum  int64
+	Xor  int64
+}
+
+// GetBucketChecksums hashes every row of table over the given columns and aggregates the hashes per bucket, the rows are spread over
+// the buckets by the hash of their key. Without a key all rows end up in bucket 0.
+func (db *MSSQLDB) GetBucketChecksums(ctx context.Context, table TableRef, key, columns []string, buckets int, opts ReadOptions) (map[int]BucketChecksum, error) {
+	where, err := opts.where()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.reader.QueryContext(ctx, bucketChecksumQuery(table, key, columns, buckets, where))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checksums := make(map[int]BucketChecksum)
+	for rows.Next() {
+		var bucket int
+		var checksum BucketChecksum
+		err = rows.Scan(&bucket, &checksum.Rows, &checksum.Sum, &checksum.Xor)
+		if err != nil {
+			return nil, err
+		}
+		checksums[bucket] = checksum
+	}
+
+	return checksums, rows.Err()
+}
+
+// RowHash is the SHA-256 hash of a row by the values of its key
+type RowHash struct {
+	Key  []interface{}
+	Hash []byte
+}
+
+// GetRowHashes returns the SHA-256 hashes of the rows in a bucket of GetBucketChecksums
+func (db *MSSQLDB) GetRowHashes(ctx context.Context, table TableRef, key, columns []string, buckets, bucket int, opts ReadOptions) ([]RowHash, error) {
+	where, err := opts.where()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.queryValues(ctx, rowHashQuery(table, key, columns, buckets, bucket, where), len(key)+1)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]RowHash, len(rows))
+	for i, values := range rows {
+		hash, _ := values[len(key)].([]byte)
+		hashes[i] = RowHash{Key: values[:len(key)], Hash: hash}
+	}
+
+	return hashes, nil
+}
+
+// GetBucketRows returns the values of the key followed by the values of the columns of the rows in a bucket of GetBucketChecksums
+func (db *MSSQLDB) GetBucketRows(ctx context.Context, table TableRef, key, columns []string, buckets, bucket int, opts ReadOptions) ([][]interface{}, error) {
+	where, err := opts.where()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.queryValues(ctx, bucketRowsQuery(table, key, columns, buckets, bucket, where), len(key)+len(columns))
+}
+
+// queryValues reads the rows of a query with the given number of columns
+func (db *MSSQLDB) queryValues(ctx context.Context, query string, columns int) ([][]interface{}, error) {
+	rows, err := db.reader.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([][]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, columns)
+		pointers := make([]interface{}, columns)
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, values)
+	}
+
+	return result, rows.Err()
+}
+
+// rowHash hashes the columns of a row as an XML element, which keeps NULL apart from empty values and does not depend on the collation.
+// The select without a FROM refers to the columns of the enclosing query.
+func rowHash(columns []string) string {
+	if len(columns) == 0 {
+		return "HASHBYTES('SHA2_256', '')"
+	}
+
+	return fmt.Sprintf("HASHBYTES('SHA2_256', (SELECT %s FOR XML RAW, BINARY BASE64))", quoteColumns(columns))
+}
+
+// bucketOf spreads the rows over the buckets by the first four bytes of the hash of their key
+func bucketOf(key []string, buckets int) string {
+	if len(key) == 0 || buckets <= 1 {
+		return "0"
+	}
+
+	return fmt.Sprintf("ABS(CAST(CAST(SUBSTRING(%s, 1, 4) AS int) AS bigint)) %% %d", rowHash(key), buckets)
+}
+
+func bucketChecksumQuery(table TableRef, key, columns []string, buckets int, where string) string {
+	return fmt.Sprintf("SELECT bucket, COUNT_BIG(*), SUM(CAST(CAST(SUBSTRING(h, 1, 4) AS int) AS bigint)), CAST(CHECKSUM_AGG(CAST(SUBSTRING(h, 5, 4) AS int)) AS bigint) "+
+		"FROM (SELECT %s AS bucket, %s AS h FROM %s WHERE %s) AS hashed GROUP BY bucket", bucketOf(key, buckets), rowHash(columns), table, where)
+}
+
+func rowHashQuery(table TableRef, key, columns []string, buckets, bucket int, where string) string {
+	quotedKey := quoteColumns(key)
+
+	return fmt.Sprintf("SELECT %s, h FROM (SELECT %s, %s AS bucket, %s AS h FROM %s WHERE %s) AS hashed WHERE bucket = %d",
+		quotedKey, quotedKey, bucketOf(key, buckets), rowHash(columns), table, where, bucket)
+}
+
+func bucketRowsQuery(table TableRef, key, columns []string, buckets, bucket int, where string) string {
+	return fmt.Sprintf("SELECT %s, %s FROM %s WHERE ( %s ) AND %s = %d", quoteColumns(key), quoteColumns(columns), table, where, bucketOf(key, buckets), bucket)
+}
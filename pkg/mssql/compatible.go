@@ -0,0 +1,103 @@
+package mssql
+
+import (
+	"fmt"
+	"sort"
+)
+
+// integerDigits is the number of decimal digits every value of an integer type fits in
+var integerDigits = map[string]int{"tinyint": 3, "smallint": 5, "int": 10, "bigint": 19}
+
+// CompatibleWith returns why the rows of a table with this schema can not be inserted into target, nothing when they can.
+// The target may have more columns when they are nullable, identity, computed or rowversion columns, and its columns may have a type
+// the source type converts to implicitly without losing data, like int to bigint or varchar(50) to varchar(100).
+func (s SchemaDefinition) CompatibleWith(target SchemaDefinition) []string {
+	problems := make([]string, 0)
+	for name, column := range s {
+		targetColumn, ok := target[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("column %s is missing in the target", name))
+			continue
+		}
+
+		if targetColumn.Computed != "" {
+			problems = append(problems, fmt.Sprintf("column %s is computed in the target", name))
+			continue
+		}
+
+		if !convertible(column, targetColumn) {
+			problems = append(problems, fmt.Sprintf("column %s can not be converted from %s to %s", name, columnTypeSQL(column), columnTypeSQL(targetColumn)))
+		}
+
+		if column.Nullable && !targetColumn.Nullable {
+			problems = append(problems, fmt.Sprintf("column %s is nullable in the source but not in the target", name))
+		}
+	}
+
+	for name, column := range target {
+		if _, ok := s[name]; ok {
+			continue
+		}
+
+		if !column.Nullable && !column.Identity && column.Insertable() {
+			problems = append(problems, fmt.Sprintf("column %s only exists in the target and does not allow NULL", name))
+		}
+	}
+
+	sort.Strings(problems)
+
+	return problems
+}
+
+// convertible reports whether every value of the source column fits the type of the target column
+func convertible(source, target ColumnDefinition) bool {
+	if source.Type == target.Type && columnTypeSQL(source) == columnTypeSQL(target) {
+		return true
+	}
+
+	switch source.Type {
+	case "tinyint", "smallint", "int", "bigint":
+		if digits, ok := integerDigits[target.Type]; ok {
+			return digits >= integerDigits[source.Type]
+		}
+		if target.Type == "decimal" || target.Type == "numeric" {
+			return target.Precision-target.Scale >= integerDigits[source.Type]
+		}
+	case "decimal", "numeric":
+		if target.Type == "decimal" || target.Type == "numeric" {
+			return target.Scale >= source.Scale && target.Precision-target.Scale >= source.Precision-source.Scale
+		}
+	case "real", "float":
+		return target.Type == "float" && target.Precision >= source.Precision
+	case "char", "varchar":
+		if target.Type == "nvarchar" || target.Type == "nchar" && source.Type == "char" {
+			// unicode lengths are in bytes of two per character
+			return target.MaxLength == -1 || source.MaxLength != -1 && target.MaxLength >= source.MaxLength*2
+		}
+		return sameOrVariable(source.Type, target.Type, "char", "varchar") && fitsLength(source.MaxLength, target.MaxLength)
+	case "nchar", "nvarchar":
+		return sameOrVariable(source.Type, target.Type, "nchar", "nvarchar") && fitsLength(source.MaxLength, target.MaxLength)
+	case "binary", "varbinary":
+		return sameOrVariable(source.Type, target.Type, "binary", "varbinary") && fitsLength(source.MaxLength, target.MaxLength)
+	case "date", "smalldatetime":
+		return target.Type == "datetime2"
+	case "datetime":
+		// datetime has a precision of about 3 milliseconds
+		return target.Type == "datetime2" && target.Scale >= 3
+	case "datetime2", "time", "datetimeoffset":
+		return target.Type == source.Type && target.Scale >= source.Scale
+	}
+
+	return false
+}
+
+// sameOrVariable reports whether the target type is the source type or the variable length variant of the fixed length type,
+// a variable length source would be padded by the fixed length type
+func sameOrVariable(source, target, fixed, variable string) bool {
+	return target == source || source == fixed && target == variable
+}
+
+// fitsLength reports whether a value of the source length fits the target length, -1 is max
+func fitsLength(source, target int) bool {
+	return target == -1 || source != -1 && target >= source
+}
@@ -0,0 +1,39 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompatibleWith(t *testing.T) {
+	source := SchemaDefinition{
+		"id":     {Name: "id", Type: "int", Precision: 10, Identity: true},
+		"code":   {Name: "code", Type: "varchar", MaxLength: 50},
+		"amount": {Name: "amount", Type: "decimal", Precision: 10, Scale: 2},
+		"at":     {Name: "at", Type: "datetime", Nullable: true},
+	}
+
+	target := SchemaDefinition{
+		"id":     {Name: "id", Type: "bigint", Precision: 19},
+		"code":   {Name: "code", Type: "nvarchar", MaxLength: 200},
+		"amount": {Name: "amount", Type: "decimal", Precision: 18, Scale: 4},
+		"at":     {Name: "at", Type: "datetime2", Scale: 7, Nullable: true},
+		"note":   {Name: "note", Type: "nvarchar", MaxLength: -1, Nullable: true},
+	}
+	assert.Empty(t, source.CompatibleWith(target))
+
+	target["code"] = ColumnDefinition{Name: "code", Type: "varchar", MaxLength: 10}
+	target["amount"] = ColumnDefinition{Name: "amount", Type: "int", Precision: 10}
+	target["at"] = ColumnDefinition{Name: "at", Type: "datetime2", Scale: 7}
+	target["flag"] = ColumnDefinition{Name: "flag", Type: "bit"}
+	assert.Equal(t, []string{
+		"column amount can not be converted from decimal(10, 2) to int",
+		"column at is nullable in the source but not in the target",
+		"column code can not be converted from varchar(50) to varchar(10)",
+		"column flag only exists in the target and does not allow NULL",
+	}, source.CompatibleWith(target))
+
+	delete(target, "id")
+	assert.Contains(t, source.CompatibleWith(target), "column id is missing in the target")
+}
@@ -0,0 +1,134 @@
+package mssql
+
+import (
+	"fmt"
+	"strconv"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// bulkConverter converts a value read from the source to a value the bulk copy of the driver accepts for a target column
+type bulkConverter func(value interface{}) (interface{}, error)
+
+// bulkConverters returns the converter of every column by the type of its target column, nil for the columns passed as is.
+// The driver reads decimal, money and uniqueidentifier values as bytes, and only accepts some Go types per column type.
+func bulkConverters(columns []string, schema SchemaDefinition) []bulkConverter {
+	converters := make([]bulkConverter, len(columns))
+	for i, column := range columns {
+		switch schema[column].Type {
+		case "decimal", "numeric":
+			converters[i] = decimalValue
+		case "float", "real":
+			converters[i] = floatValue
+		case "bit":
+			converters[i] = bitValue
+		case "tinyint", "smallint", "int", "bigint":
+			converters[i] = intValue
+		case "binary", "varbinary":
+			converters[i] = binaryValue
+		case "uniqueidentifier":
+			converters[i] = uniqueidentifierValue
+		case "char", "varchar", "text", "nchar", "nvarchar", "ntext":
+			converters[i] = textValue
+		}
+		// date and time values are read as time.Time, which the driver writes to every date and time type
+		// keeping the offset of datetimeoffset values
+	}
+
+	return converters
+}
+
+// convertRow converts the values of row in place
+func convertRow(converters []bulkConverter, columns []string, row []interface{}) error {
+	for i, convert := range converters {
+		if convert == nil || row[i] == nil {
+			continue
+		}
+
+		value, err := convert(row[i])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", columns[i], err)
+		}
+		row[i] = value
+	}
+
+	return nil
+}
+
+// decimalValue passes decimals as their text, which the driver parses with the precision and scale of the column
+func decimalValue(value interface{}) (interface{}, error) {
+	if b, ok := value.([]byte); ok {
+		return string(b), nil
+	}
+
+	return value, nil
+}
+
+func floatValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	case string:
+		return strconv.ParseFloat(v, 64)
+	case int64:
+		return float64(v), nil
+	}
+
+	return value, nil
+}
+
+func bitValue(value interface{}) (interface{}, error) {
+	if v, ok := value.(int64); ok {
+		return v != 0, nil
+	}
+
+	return value, nil
+}
+
+func intValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+
+	return value, nil
+}
+
+func binaryValue(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		return []byte(s), nil
+	}
+
+	return value, nil
+}
+
+// uniqueidentifierValue passes the bytes read from a uniqueidentifier column as is, they are in the byte order of the server.
+// The text of a uniqueidentifier is converted to that byte order.
+func uniqueidentifierValue(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	var id mssql.UniqueIdentifier
+	err := id.Scan(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return id.Value()
+}
+
+// textValue passes the values read as bytes as text, the driver would write bytes to nvarchar columns without encoding them as UCS-2
+func textValue(value interface{}) (interface{}, error) {
+	if b, ok := value.([]byte); ok {
+		return string(b), nil
+	}
+
+	return value, nil
+}
@@ -0,0 +1,84 @@
+package mssql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func convertValue(t *testing.T, columnType string, value interface{}) interface{} {
+	row := []interface{}{value}
+	err := convertRow(bulkConverters([]string{"value"}, SchemaDefinition{"value": {Type: columnType}}), []string{"value"}, row)
+	assert.NoError(t, err)
+
+	return row[0]
+}
+
+func TestConvertDecimal(t *testing.T) {
+	assert.Equal(t, "1234.5600", convertValue(t, "decimal", []byte("1234.5600")))
+	assert.Equal(t, "-0.10", convertValue(t, "numeric", []byte("-0.10")))
+	assert.Equal(t, 1.5, convertValue(t, "decimal", 1.5))
+}
+
+func TestBulkUnsupportedMoney(t *testing.T) {
+	// the bulk copy of the driver does not write money, the values are inserted as text
+	assert.Equal(t, []string{"price"}, BulkUnsupportedColumns(SchemaDefinition{"id": {Type: "int"}, "price": {Type: "money"}}, []string{"id", "price"}))
+}
+
+func TestConvertText(t *testing.T) {
+	assert.Equal(t, "12.3400", convertValue(t, "nvarchar", []byte("12.3400")))
+	assert.Equal(t, "abc", convertValue(t, "varchar", "abc"))
+}
+
+func TestConvertFloat(t *testing.T) {
+	assert.Equal(t, 12.34, convertValue(t, "float", []byte("12.3400")))
+	assert.Equal(t, 1.5, convertValue(t, "float", 1.5))
+	assert.Equal(t, float64(3), convertValue(t, "real", int64(3)))
+}
+
+func TestConvertDatetimeoffset(t *testing.T) {
+	value := time.Date(2024, 3, 1, 12, 30, 0, 123456700, time.FixedZone("", 2*60*60))
+	converted := convertValue(t, "datetimeoffset", value)
+	assert.Equal(t, value, converted)
+
+	_, offset := converted.(time.Time).Zone()
+	assert.Equal(t, 2*60*60, offset)
+}
+
+func TestConvertTime(t *testing.T) {
+	value := time.Date(1, 1, 1, 23, 59, 59, 999999900, time.UTC)
+	assert.Equal(t, value, convertValue(t, "time", value))
+}
+
+func TestConvertUniqueidentifier(t *testing.T) {
+	// the bytes read are in the byte order of the server and passed as is
+	raw := []byte{0xFF, 0x19, 0x96, 0x6F, 0x86, 0x8B, 0x11, 0xD0, 0xB4, 0x2D, 0x00, 0xC0, 0x4F, 0xC9, 0x64, 0xFF}
+	assert.Equal(t, raw, convertValue(t, "uniqueidentifier", raw))
+	assert.Equal(t, raw, convertValue(t, "uniqueidentifier", "6F9619FF-8B86-D011-B42D-00C04FC964FF"))
+
+	row := []interface{}{"not a uniqueidentifier"}
+	err := convertRow(bulkConverters([]string{"id"}, SchemaDefinition{"id": {Type: "uniqueidentifier"}}), []string{"id"}, row)
+	assert.ErrorContains(t, err, "column id")
+}
+
+func TestConvertNvarcharMax(t *testing.T) {
+	long := strings.Repeat("ü", 10_000)
+	assert.Equal(t, long, convertValue(t, "nvarchar", long))
+	assert.Equal(t, long, convertValue(t, "nvarchar", []byte(long)))
+}
+
+func TestConvertBinary(t *testing.T) {
+	// binary values stay bytes, the driver rejects text for binary columns
+	assert.Equal(t, []byte{0x00, 0x01}, convertValue(t, "varbinary", []byte{0x00, 0x01}))
+	assert.Equal(t, []byte("abc"), convertValue(t, "binary", "abc"))
+}
+
+func TestConvertIntegers(t *testing.T) {
+	assert.Equal(t, true, convertValue(t, "bit", int64(1)))
+	assert.Equal(t, false, convertValue(t, "bit", int64(0)))
+	assert.Equal(t, int64(1), convertValue(t, "int", true))
+	assert.Equal(t, int64(42), convertValue(t, "bigint", int64(42)))
+	assert.Nil(t, convertValue(t, "int", nil))
+}
@@ -0,0 +1,433 @@
+package mssql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// ColumnDefinition is the definition of a column as needed to recreate it
+type ColumnDefinition struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	MaxLength int    `json:"max_length"`
+	Precision int    `json:"precision"`
+	Scale     int    `json:"scale"`
+	Nullable  bool   `json:"nullable"`
+	Collation string `json:"collation,omitempty"`
+	// Computed holds the expression of a computed column
+	Computed  string `json:"computed,omitempty"`
+	Persisted bool   `json:"persisted,omitempty"`
+	Identity  bool   `json:"identity"`
+	Seed      string `json:"seed,omitempty"`
+	Increment string `json:"increment,omitempty"`
+}
+
+// Insertable reports whether values can be inserted into the column, computed and rowversion columns are set by the server
+func (c ColumnDefinition) Insertable() bool {
+	return c.Computed == "" && c.Type != "timestamp"
+}
+
+// PrimaryKeyDefinition is the primary key constraint of a table
+type PrimaryKeyDefinition struct {
+	Name      string
+	Clustered bool
+	Columns   []string
+	// Descending holds the key columns sorted in descending order
+	Descending map[string]bool
+}
+
+// IndexDefinition is a clustered or nonclustered index of a table other than its primary key
+type IndexDefinition struct {
+	Name      string
+	Clustered bool
+	Unique    bool
+	Columns   []string
+	// Descending holds the key columns sorted in descending order
+	Descending map[string]bool
+	Included   []string
+	// Filter is the WHERE clause of a filtered index
+	Filter string
+}
+
+// SchemaDefinition holds the definition of every column of a table by column name
+type SchemaDefinition map[string]ColumnDefinition
+
+// ErrSchemaMismatch is wrapped by the errors of tables which are not copied because their schema in the target does not match the source
+var ErrSchemaMismatch = errors.New("schema mismatch between source and target")
+
+// Matches reports whether target has the same columns as the schema with the same type, length, precision, scale,
+// nullability, identity and computed expression. Collations and identity seeds may differ.
+func (s SchemaDefinition) Matches(target SchemaDefinition) bool {
+	if len(s) != len(target) {
+		return false
+	}
+
+	for name, column := range s {
+		targetColumn, ok := target[name]
+		if !ok {
+			return false
+		}
+
+		if column.Type != targetColumn.Type ||
+			column.MaxLength != targetColumn.MaxLength ||
+			column.Precision != targetColumn.Precision ||
+			column.Scale != targetColumn.Scale ||
+			column.Nullable != targetColumn.Nullable ||
+			column.Identity != targetColumn.Identity ||
+			column.Computed != targetColumn.Computed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Fingerprint returns a hash of the columns and all their properties, which changes whenever a column is added, removed or altered
+func (s SchemaDefinition) Fingerprint() string {
+	// maps are encoded ordered by key
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// TableDefinition is the definition of a table as needed to create it in another database
+type TableDefinition struct {
+	Table      TableRef
+	Columns    []ColumnDefinition
+	PrimaryKey *PrimaryKeyDefinition
+	Indexes    []IndexDefinition
+}
+
+// TableExists reports whether table exists
+func (db *MSSQLDB) TableExists(ctx context.Context, table TableRef) (bool, error) {
+	var objectID sql.NullInt64
+	err := db.db.QueryRowContext(ctx, "SELECT OBJECT_ID(@table, 'U')", sql.Named("table", table.String())).Scan(&objectID)
+	if err != nil {
+		return false, err
+	}
+
+	return objectID.Valid, nil
+}
+
+// GetTableDefinition reads the columns, identity, primary key and indexes of table
+func (db *MSSQLDB) GetTableDefinition(ctx context.Context, table TableRef) (TableDefinition, error) {
+	definition := TableDefinition{Table: table}
+
+	columns, err := db.getColumnDefinitions(ctx, table)
+	if err != nil {
+		return definition, err
+	}
+	definition.Columns = columns
+
+	if len(definition.Columns) == 0 {
+		return definition, fmt.Errorf("table %s does not exist", table)
+	}
+
+	definition.PrimaryKey, err = db.getPrimaryKeyDefinition(ctx, table)
+	if err != nil {
+		return definition, err
+	}
+
+	definition.Indexes, err = db.getIndexDefinitions(ctx, table)
+	if err != nil {
+		return definition, err
+	}
+
+	return definition, nil
+}
+
+// getColumnDefinitions reads the columns of table in their order, a table that does not exist has no columns
+func (db *MSSQLDB) getColumnDefinitions(ctx context.Context, table TableRef) ([]ColumnDefinition, error) {
+	query := `
+	SELECT
+		c.name,
+		CASE WHEN ty.is_user_defined = 1 AND ty.is_assembly_type = 0 THEN TYPE_NAME(c.system_type_id) ELSE ty.name END,
+		c.max_length,
+		c.precision,
+		c.scale,
+		c.is_nullable,
+		ISNULL(c.collation_name, ''),
+		ISNULL(cc.definition, ''),
+		ISNULL(cc.is_persisted, 0),
+		c.is_identity,
+		ISNULL(CONVERT(varchar(40), ic.seed_value), ''),
+		ISNULL(CONVERT(varchar(40), ic.increment_value), '')
+	FROM sys.columns c
+	INNER JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+	LEFT JOIN sys.computed_columns cc ON cc.object_id = c.object_id AND cc.column_id = c.column_id
+	LEFT JOIN sys.identity_columns ic ON ic.object_id = c.object_id AND ic.column_id = c.column_id
+	WHERE c.object_id = OBJECT_ID(@table)
+	ORDER BY c.column_id
+	`
+	rows, err := db.db.QueryContext(ctx, query, sql.Named("table", table.String()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]ColumnDefinition, 0)
+	for rows.Next() {
+		var column ColumnDefinition
+		err := rows.Scan(&column.Name, &column.Type, &column.MaxLength, &column.Precision, &column.Scale, &column.Nullable, &column.Collation, &column.Computed, &column.Persisted, &column.Identity, &column.Seed, &column.Increment)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+func (db *MSSQLDB) getPrimaryKeyDefinition(ctx context.Context, table TableRef) (*PrimaryKeyDefinition, error) {
+	query := `
+	SELECT kc.name, i.type, c.name, ic.is_descending_key
+	FROM sys.key_constraints kc
+	INNER JOIN sys.indexes i ON i.object_id = kc.parent_object_id AND i.index_id = kc.unique_index_id
+	INNER JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+	INNER JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+	WHERE kc.type = 'PK'
+	AND kc.parent_object_id = OBJECT_ID(@table)
+	ORDER BY ic.key_ordinal
+	`
+	rows, err := db.db.QueryContext(ctx, query, sql.Named("table", table.String()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var primaryKey *PrimaryKeyDefinition
+	for rows.Next() {
+		var name, column string
+		var indexType int
+		var descending bool
+		err := rows.Scan(&name, &indexType, &column, &descending)
+		if err != nil {
+			return nil, err
+		}
+
+		if primaryKey == nil {
+			// index type 1 is a clustered index
+			primaryKey = &PrimaryKeyDefinition{Name: name, Clustered: indexType == 1, Descending: make(map[string]bool)}
+		}
+		primaryKey.Columns = append(primaryKey.Columns, column)
+		primaryKey.Descending[column] = descending
+	}
+
+	return primaryKey, rows.Err()
+}
+
+// getIndexDefinitions reads the clustered and nonclustered indexes of table, unique constraints are read as unique indexes.
+// Other index types, like columnstore, spatial and XML indexes, are left out.
+func (db *MSSQLDB) getIndexDefinitions(ctx context.Context, table TableRef) ([]IndexDefinition, error) {
+	query := `
+	SELECT i.name, i.type, i.is_unique, c.name, ic.is_descending_key, ic.is_included_column, ISNULL(i.filter_definition, '')
+	FROM sys.indexes i
+	INNER JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+	INNER JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+	WHERE i.object_id = OBJECT_ID(@table)
+	AND i.is_primary_key = 0
+	AND i.is_hypothetical = 0
+	AND i.type IN (1, 2)
+	ORDER BY i.index_id, ic.is_included_column, ic.key_ordinal, ic.index_column_id
+	`
+	rows, err := db.db.QueryContext(ctx, query, sql.Named("table", table.String()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := make([]IndexDefinition, 0)
+	for rows.Next() {
+		var name, column, filter string
+		var indexType int
+		var unique, descending, included bool
+		err := rows.Scan(&name, &indexType, &unique, &column, &descending, &included, &filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != name {
+			// index type 1 is a clustered index
+			indexes = append(indexes, IndexDefinition{Name: name, Clustered: indexType == 1, Unique: unique, Descending: make(map[string]bool), Filter: filter})
+		}
+
+		index := &indexes[len(indexes)-1]
+		if included {
+			index.Included = append(index.Included, column)
+			continue
+		}
+		index.Columns = append(index.Columns, column)
+		index.Descending[column] = descending
+	}
+
+	return indexes, rows.Err()
+}
+
+// CreateTable creates the table (and its schema when missing) and its indexes from definition
+func (db *MSSQLDB) CreateTable(ctx context.Context, definition TableDefinition) error {
+	_, err := db.db.ExecContext(ctx, "IF SCHEMA_ID(@schema) IS NULL EXEC('CREATE SCHEMA ' + QUOTENAME(@schema))", sql.Named("schema", definition.Table.Schema))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.db.ExecContext(ctx, createTableStatement(definition))
+	if err != nil {
+		return err
+	}
+
+	for _, index := range definition.Indexes {
+		_, err = db.db.ExecContext(ctx, createIndexStatement(definition.Table, index))
+		if err != nil {
+			return fmt.Errorf("failed to create index %s, %w", index.Name, err)
+		}
+	}
+
+	// a lookup before the table existed cached an empty schema
+	db.schemaDefLock.Lock()
+	delete(db.schemaDefs, definition.Table.String())
+	delete(db.primaryKeys, definition.Table.String())
+	db.schemaDefLock.Unlock()
+
+	return nil
+}
+
+func createTableStatement(definition TableDefinition) string {
+	quoter := mssql.TSQLQuoter{}
+
+	lines := make([]string, 0, len(definition.Columns)+1)
+	for _, column := range definition.Columns {
+		lines = append(lines, "\t"+columnDefinitionSQL(column))
+	}
+
+	if pk := definition.PrimaryKey; pk != nil {
+		columns := make([]string, len(pk.Columns))
+		for i, column := range pk.Columns {
+			columns[i] = quoter.ID(column)
+			if pk.Descending[column] {
+				columns[i] += " DESC"
+			}
+		}
+
+		clustered := "NONCLUSTERED"
+		if pk.Clustered {
+			clustered = "CLUSTERED"
+		}
+
+		constraint := ""
+		if pk.Name != "" {
+			constraint = fmt.Sprintf("CONSTRAINT %s ", quoter.ID(pk.Name))
+		}
+		lines = append(lines, fmt.Sprintf("\t%sPRIMARY KEY %s (%s)", constraint, clustered, strings.Join(columns, ", ")))
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n)", definition.Table, strings.Join(lines, ",\n"))
+}
+
+func createIndexStatement(table TableRef, index IndexDefinition) string {
+	quoter := mssql.TSQLQuoter{}
+
+	columns := make([]string, len(index.Columns))
+	for i, column := range index.Columns {
+		columns[i] = quoter.ID(column)
+		if index.Descending[column] {
+			columns[i] += " DESC"
+		}
+	}
+
+	kind := "NONCLUSTERED"
+	if index.Clustered {
+		kind = "CLUSTERED"
+	}
+	if index.Unique {
+		kind = "UNIQUE " + kind
+	}
+
+	statement := fmt.Sprintf("CREATE %s INDEX %s ON %s (%s)", kind, quoter.ID(index.Name), table, strings.Join(columns, ", "))
+
+	if len(index.Included) > 0 {
+		included := make([]string, len(index.Included))
+		for i, column := range index.Included {
+			included[i] = quoter.ID(column)
+		}
+		statement += fmt.Sprintf(" INCLUDE (%s)", strings.Join(included, ", "))
+	}
+
+	if index.Filter != "" {
+		statement += " WHERE " + index.Filter
+	}
+
+	return statement
+}
+
+func columnDefinitionSQL(column ColumnDefinition) string {
+	quoter := mssql.TSQLQuoter{}
+
+	if column.Computed != "" {
+		persisted := ""
+		if column.Persisted {
+			persisted = " PERSISTED"
+		}
+		return fmt.Sprintf("%s AS %s%s", quoter.ID(column.Name), column.Computed, persisted)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(quoter.ID(column.Name))
+	sb.WriteString(" ")
+	sb.WriteString(columnTypeSQL(column))
+
+	if column.Collation != "" {
+		sb.WriteString(" COLLATE ")
+		sb.WriteString(column.Collation)
+	}
+
+	if column.Identity {
+		sb.WriteString(fmt.Sprintf(" IDENTITY(%s, %s)", column.Seed, column.Increment))
+	}
+
+	if column.Nullable {
+		sb.WriteString(" NULL")
+	} else {
+		sb.WriteString(" NOT NULL")
+	}
+
+	return sb.String()
+}
+
+// columnTypeSQL renders the type of a column with its length, precision or scale
+func columnTypeSQL(column ColumnDefinition) string {
+	length := func(bytesPerChar int) string {
+		if column.MaxLength == -1 {
+			return "max"
+		}
+		return fmt.Sprintf("%d", column.MaxLength/bytesPerChar)
+	}
+
+	switch column.Type {
+	case "char", "varchar", "binary", "varbinary":
+		return fmt.Sprintf("%s(%s)", column.Type, length(1))
+	case "nchar", "nvarchar":
+		return fmt.Sprintf("%s(%s)", column.Type, length(2))
+	case "decimal", "numeric":
+		return fmt.Sprintf("%s(%d, %d)", column.Type, column.Precision, column.Scale)
+	case "datetime2", "time", "datetimeoffset":
+		return fmt.Sprintf("%s(%d)", column.Type, column.Scale)
+	case "float":
+		return fmt.Sprintf("float(%d)", column.Precision)
+	case "timestamp":
+		return "rowversion"
+	}
+
+	return column.Type
+}
@@ -0,0 +1,79 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTableStatement(t *testing.T) {
+	statement := createTableStatement(TableDefinition{
+		Table: TableRef{Schema: "sales", Table: "orders"},
+		Columns: []ColumnDefinition{
+			{Name: "id", Type: "bigint", Identity: true, Seed: "1000", Increment: "1"},
+			{Name: "reference", Type: "nvarchar", MaxLength: 100, Collation: "Latin1_General_CI_AS", Nullable: true},
+			{Name: "notes", Type: "varchar", MaxLength: -1, Nullable: true},
+			{Name: "amount", Type: "decimal", Precision: 18, Scale: 2},
+			{Name: "created", Type: "datetime2", Scale: 3},
+			{Name: "total", Computed: "([amount]*(2))", Persisted: true},
+		},
+		PrimaryKey: &PrimaryKeyDefinition{Name: "PK_orders", Clustered: true, Columns: []string{"id"}, Descending: map[string]bool{}},
+	})
+
+	assert.Equal(t, `CREATE TABLE [sales].[orders] (
+	[id] bigint IDENTITY(1000, 1) NOT NULL,
+	[reference] nvarchar(50) COLLATE Latin1_General_CI_AS NULL,
+	[notes] varchar(max) NULL,
+	[amount] decimal(18, 2) NOT NULL,
+	[created] datetime2(3) NOT NULL,
+	[total] AS ([amount]*(2)) PERSISTED,
+	CONSTRAINT [PK_orders] PRIMARY KEY CLUSTERED ([id])
+)`, statement)
+}
+
+func TestCreateTableStatementWithoutPrimaryKeyName(t *testing.T) {
+	statement := createTableStatement(TableDefinition{
+		Table:      TableRef{Schema: "staging", Table: "orders_copy"},
+		Columns:    []ColumnDefinition{{Name: "id", Type: "int"}},
+		PrimaryKey: &PrimaryKeyDefinition{Columns: []string{"id"}, Descending: map[string]bool{}},
+	})
+
+	assert.Equal(t, `CREATE TABLE [staging].[orders_copy] (
+	[id] int NOT NULL,
+	PRIMARY KEY NONCLUSTERED ([id])
+)`, statement)
+}
+
+func TestCreateIndexStatement(t *testing.T) {
+	orders := TableRef{Schema: "sales", Table: "orders"}
+
+	statement := createIndexStatement(orders, IndexDefinition{
+		Name:       "IX_orders_customer",
+		Columns:    []string{"customer_id", "created"},
+		Descending: map[string]bool{"created": true},
+		Included:   []string{"amount"},
+		Filter:     "([deleted]=(0))",
+	})
+	assert.Equal(t, "CREATE NONCLUSTERED INDEX [IX_orders_customer] ON [sales].[orders] ([customer_id], [created] DESC) INCLUDE ([amount]) WHERE ([deleted]=(0))", statement)
+
+	statement = createIndexStatement(orders, IndexDefinition{Name: "UX_orders_reference", Clustered: true, Unique: true, Columns: []string{"reference"}})
+	assert.Equal(t, "CREATE UNIQUE CLUSTERED INDEX [UX_orders_reference] ON [sales].[orders] ([reference])", statement)
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	schema := SchemaDefinition{
+		"id":   {Name: "id", Type: "int"},
+		"name": {Name: "name", Type: "nvarchar", MaxLength: 100, Nullable: true},
+	}
+	same := SchemaDefinition{
+		"name": {Name: "name", Type: "nvarchar", MaxLength: 100, Nullable: true},
+		"id":   {Name: "id", Type: "int"},
+	}
+	widened := SchemaDefinition{
+		"id":   {Name: "id", Type: "int"},
+		"name": {Name: "name", Type: "nvarchar", MaxLength: 200, Nullable: true},
+	}
+
+	assert.Equal(t, schema.Fingerprint(), same.Fingerprint())
+	assert.NotEqual(t, schema.Fingerprint(), widened.Fingerprint())
+}
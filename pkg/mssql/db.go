@@ -4,14 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"database/sql"
 
 	mssql "github.com/microsoft/go-mssqldb"
 	azuresql "github.com/microsoft/go-mssqldb/azuread"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type TableRef struct {
@@ -24,16 +29,41 @@ func (t TableRef) String() string {
 	return fmt.Sprintf("%s.%s", quoter.ID(t.Schema), quoter.ID(t.Table))
 }
 
-type MSSQLDB struct {
-	db *sql.DB
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
 
-	schemaDefs    map[string]map[string]string
+type MSSQLDB struct {
+	db       *loggedDB
+	host     string
+	database string
+	info     ServerInfo
+	// journal records the destructive statements before they run, nil unless set with SetAuditJournal
+	journal *AuditJournal
+
+	// reader runs the row reads, which is the pool itself or a snapshot transaction
+	reader querier
+	tx     *sql.Tx
+
+	// schemaDefs and primaryKeys cache the table metadata, guarded by schemaDefLock
+	schemaDefs    map[string]SchemaDefinition
+	primaryKeys   map[string][]string
 	schemaDefLock *sync.Mutex
 }
 
 func Connect(host string, database string) (*MSSQLDB, error) {
+	return ConnectWith(host, database, Auth{})
+}
+
+// ConnectWith connects to database on host with the credentials of auth
+func ConnectWith(host string, database string, auth Auth) (*MSSQLDB, error) {
+	dsn, err := auth.dsn(host, database)
+	if err != nil {
+		return nil, err
+	}
 
-	dsn := fmt.Sprintf("%s://%s?database=%s&fedauth=%s", "sqlserver", host, database, azuresql.ActiveDirectoryDefault)
 	db, err := sql.Open(azuresql.DriverName, dsn)
 	if err != nil {
 		return nil, err
@@ -41,14 +71,28 @@ func Connect(host string, database string) (*MSSQLDB, error) {
 
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	return &MSSQLDB{
-		db:            db,
-		schemaDefs:    make(map[string]map[string]string),
+	pool := &loggedDB{DB: db, database: database}
+	mssqlDB := &MSSQLDB{
+		db:            pool,
+		host:          host,
+		database:      database,
+		reader:        pool,
+		schemaDefs:    make(map[string]SchemaDefinition),
+		primaryKeys:   make(map[string][]string),
 		schemaDefLock: &sync.Mutex{},
-	}, nil
+	}
+
+	mssqlDB.info, err = mssqlDB.probeServerInfo(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to determine server version of %s: %w", host, err)
+	}
+
+	return mssqlDB, nil
 }
 
 func (db *MSSQLDB) GetTablesFromFilter(ctx context.Context, schema string, filter string) ([]string, error) {
@@ -71,16 +115,33 @@ func (db *MSSQLDB) GetTablesFromFilter(ctx context.Context, schema string, filte
 	return tables, nil
 }
 
-func (db *MSSQLDB) GetCount(ctx context.Context, table TableRef, queryFilter string) (int, error) {
-	filter, err := parseFilter(queryFilter)
+func (db *MSSQLDB) GetCount(ctx context.Context, table TableRef, opts ReadOptions) (int, error) {
+	ctx, span := db.startSpan(ctx, "count", table)
+	count, err := db.getCount(ctx, table, opts)
+	return count, endSpan(span, err)
+}
+
+func (db *MSSQLDB) getCount(ctx context.Context, table TableRef, opts ReadOptions) (int, error) {
+	where, err := opts.where()
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s%s WHERE %s", table.String(), opts.tablesample(), where)
+	if opts.Limit > 0 {
+		// the order does not matter for the number of rows
+		query = fmt.Sprintf("SELECT COUNT(*) FROM (SELECT TOP (%d) 1 AS sampled FROM %s%s WHERE %s) AS sample", opts.Limit, table.String(), opts.tablesample(), where)
+	}
+
+	option, err := opts.option()
 	if err != nil {
 		return 0, err
 	}
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table.String(), filter.String())
-	rows, err := db.db.QueryContext(ctx, query)
+	query += option
+	rows, err := db.reader.QueryContext(ctx, query)
 	if err != nil {
 		return 0, err
 	}
+	defer rows.Close()
 
 	var count int
 	for rows.Next() {
@@ -90,98 +151,521 @@ func (db *MSSQLDB) GetCount(ctx context.Context, table TableRef, queryFilter str
 		}
 	}
 
-	return count, nil
+	return count, rows.Err()
 }
 
-func (db *MSSQLDB) GetSchemaDefinition(ctx context.Context, table TableRef) (map[string]string, error) {
-	db.schemaDefLock.Lock()
-	defer db.schemaDefLock.Unlock()
+// GetApproximateCount returns the row count of table from the partition statistics, which is instant but may lag behind
+func (db *MSSQLDB) GetApproximateCount(ctx context.Context, table TableRef) (int, error) {
+	query := "SELECT COALESCE(SUM(row_count), 0) FROM sys.dm_db_partition_stats WHERE object_id = OBJECT_ID(@table) AND index_id IN (0, 1)"
 
-	if _, ok := db.schemaDefs[table.String()]; !ok {
-		query := fmt.Sprintf("SELECT COLUMN_NAME, DATA_TYPE FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s'", table.Schema, table.Table)
-		rows, err := db.db.QueryContext(ctx, query)
-		if err != nil {
-			return nil, err
-		}
+	ctx, span := db.startSpan(ctx, "approximate count", table)
+	var count int64
+	err := db.db.QueryRowContext(ctx, query, sql.Named("table", table.String())).Scan(&count)
+	if endSpan(span, err) != nil {
+		return 0, err
+	}
 
-		schemaMap := make(map[string]string)
-		for rows.Next() {
-			var column, dataType string
-			err := rows.Scan(&column, &dataType)
-			if err != nil {
-				return nil, err
-			}
-			schemaMap[column] = dataType
-		}
+	return int(count), nil
+}
+
+// GetDataSize returns the number of bytes used by the rows of table, including their large object and row-overflow pages
+func (db *MSSQLDB) GetDataSize(ctx context.Context, table TableRef) (int64, error) {
+	query := "SELECT COALESCE(SUM(used_page_count), 0) * 8192 FROM sys.dm_db_partition_stats WHERE object_id = OBJECT_ID(@table) AND index_id IN (0, 1)"
 
-		db.schemaDefs[table.String()] = schemaMap
+	var size int64
+	err := db.db.QueryRowContext(ctx, query, sql.Named("table", table.String())).Scan(&size)
+	if err != nil {
+		return 0, err
 	}
 
-	schema, _ := db.schemaDefs[table.String()]
+	return size, nil
+}
+
+// GetSchemaDefinition returns the definition per column of table, the result is cached.
+// The lookup runs outside the cache lock, so definitions of different tables are fetched concurrently.
+func (db *MSSQLDB) GetSchemaDefinition(ctx context.Context, table TableRef) (SchemaDefinition, error) {
+	db.schemaDefLock.Lock()
+	schema, ok := db.schemaDefs[table.String()]
+	db.schemaDefLock.Unlock()
+	if ok {
+		return schema, nil
+	}
+
+	columns, err := db.getColumnDefinitions(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	schema = make(SchemaDefinition, len(columns))
+	for _, column := range columns {
+		schema[column.Name] = column
+	}
+
+	db.schemaDefLock.Lock()
+	db.schemaDefs[table.String()] = schema
+	db.schemaDefLock.Unlock()
 
 	return schema, nil
 }
 
 func (db *MSSQLDB) EmptyTable(ctx context.Context, table TableRef) error {
-	query := fmt.Sprintf("TRUNCATE TABLE %s.%s", table.Schema, table.Table)
-	_, err := db.db.ExecContext(ctx, query)
+	query := fmt.Sprintf("TRUNCATE TABLE %s", table)
+	ctx, span := db.startSpan(ctx, "truncate", table)
+	return endSpan(span, db.execDestructive(ctx, query, ""))
+}
+
+// DeleteAll deletes all rows of table, which unlike TRUNCATE is allowed on tables referenced by foreign keys
+func (db *MSSQLDB) DeleteAll(ctx context.Context, table TableRef) error {
+	ctx, span := db.startSpan(ctx, "delete", table)
+	return endSpan(span, db.execDestructive(ctx, fmt.Sprintf("DELETE FROM %s", table.String()), ""))
+}
+
+// DeleteWhere deletes the rows of table matching the query filter, an empty filter is refused so the whole table is never deleted by accident
+func (db *MSSQLDB) DeleteWhere(ctx context.Context, table TableRef, queryFilter string) error {
+	if queryFilter == "" {
+		return fmt.Errorf("refusing to delete from %s without a query filter", table)
+	}
+
+	filter, err := parseFilter(queryFilter)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", table.String(), filter.String())
+	ctx, span := db.startSpan(ctx, "delete", table)
+	return endSpan(span, db.execDestructive(ctx, query, ""))
+}
+
+// DeleteRange deletes the rows of table selected by the query filter and the conditions of opts, options selecting all rows are refused
+func (db *MSSQLDB) DeleteRange(ctx context.Context, table TableRef, opts ReadOptions) error {
+	if opts.Unfiltered() {
+		return fmt.Errorf("refusing to delete from %s without a query filter or conditions", table)
+	}
+
+	where, err := opts.where()
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", table.String(), where)
+	ctx, span := db.startSpan(ctx, "delete", table)
+	return endSpan(span, db.execDestructive(ctx, query, ""))
 }
 
 type RowIterator struct {
 	columnCount int
 	rows        *sql.Rows
+	// page reads the pages of a paged read, nil when the rows are read with a single query
+	page *pageReader
+	// dest are the scan destinations, reused for every row
+	dest []interface{}
+	// tx is the snapshot transaction of the read, ended when the rows are read or closed
+	tx *sql.Tx
+	// span traces the read until its rows are read or closed, read counts the rows scanned
+	span trace.Span
+	read int64
+	// table and started are logged with the rows read at debug level when the read ends
+	table   TableRef
+	started time.Time
+}
+
+// scan reads the current row into row
+func (ri *RowIterator) scan(row []interface{}) error {
+	if ri.dest == nil {
+		ri.dest = make([]interface{}, ri.columnCount)
+	}
+	for i := range row {
+		ri.dest[i] = &row[i]
+	}
+
+	err := ri.rows.Scan(ri.dest...)
+	if err != nil {
+		return err
+	}
+	ri.read++
+
+	if ri.page != nil {
+		return ri.page.track(row)
+	}
+
+	return nil
+}
+
+// advance moves to the next row, the next page of a paged read is selected when the rows of the current one are read
+func (ri *RowIterator) advance() (bool, error) {
+	for {
+		if ri.rows.Next() {
+			return true, nil
+		}
+
+		err := ri.rows.Err()
+		if err != nil || ri.page == nil || !ri.page.more() {
+			ri.end(err)
+			return false, err
+		}
+
+		ri.rows.Close()
+		rows, err := ri.page.query()
+		if err != nil {
+			// the closed rows of the last page are kept for Close
+			ri.end(err)
+			return false, err
+		}
+		ri.rows = rows
+	}
 }
 
 func (ri *RowIterator) Next() ([]interface{}, error) {
-	if !ri.rows.Next() {
+	ok, err := ri.advance()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return []interface{}{}, nil
 	}
 
 	values := make([]interface{}, ri.columnCount)
-	for i := range values {
-		values[i] = new(interface{})
-	}
-
-	err := ri.rows.Scan(values...)
+	err = ri.scan(values)
 	if err != nil {
 		return nil, err
 	}
+
 	return values, nil
 }
 
-func (db *MSSQLDB) SelectFrom(ctx context.Context, table TableRef, columns []string, queryFilter string) (*RowIterator, error) {
+// NextBatch reads up to n rows, it returns no rows when all rows are read. The rows of a batch share a single allocation,
+// which is not reused as the writers keep the rows of a batch until it is committed.
+func (ri *RowIterator) NextBatch(n int) ([][]interface{}, error) {
+	batch := make([][]interface{}, 0, n)
+
+	var values []interface{}
+	for len(batch) < n {
+		ok, err := ri.advance()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		if values == nil {
+			values = make([]interface{}, (n-len(batch))*ri.columnCount)
+		}
+		row := values[:ri.columnCount:ri.columnCount]
+		values = values[ri.columnCount:]
+
+		err = ri.scan(row)
+		if err != nil {
+			return nil, err
+		}
+
+		batch = append(batch, row)
+	}
+
+	return batch, nil
+}
+
+// Close releases the rows when they are not read until the end
+func (ri *RowIterator) Close() error {
+	err := ri.rows.Close()
+	ri.end(nil)
+
+	return err
+}
+
+// end ends the snapshot transaction of the read, which only read rows, and its span
+func (ri *RowIterator) end(err error) {
+	if ri.tx != nil {
+		ri.tx.Rollback()
+		ri.tx = nil
+	}
+
+	if ri.span != nil {
+		ri.span.SetAttributes(attribute.Int64("asqlcp.rows", ri.read))
+		endSpan(ri.span, err)
+		ri.span = nil
+	}
+
+	if !ri.started.IsZero() {
+		slog.Debug("read rows", "table", ri.table.String(), "rows", ri.read, "duration", time.Since(ri.started), "error", err)
+		ri.started = time.Time{}
+	}
+}
+
+// SelectFrom reads the columns of the rows of table selected by opts, the span of the read ends when its rows are read or closed
+func (db *MSSQLDB) SelectFrom(ctx context.Context, table TableRef, columns []string, opts ReadOptions) (*RowIterator, error) {
+	start := time.Now()
+	ctx, span := db.startSpan(ctx, "select", table)
+	rows, err := db.selectFrom(ctx, table, columns, opts)
+	if err != nil {
+		return nil, endSpan(span, err)
+	}
+	rows.span = span
+	rows.table = table
+	rows.started = start
+
+	return rows, nil
+}
+
+func (db *MSSQLDB) selectFrom(ctx context.Context, table TableRef, columns []string, opts ReadOptions) (*RowIterator, error) {
 
 	quoter := mssql.TSQLQuoter{}
 
+	schema, err := db.GetSchemaDefinition(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
 	// Copy columns to avoid modifying the original slice
 	columnsCopy := make([]string, len(columns))
 
 	for i, column := range columns {
 		columnsCopy[i] = quoter.ID(column)
+		if definition, ok := schema[column]; ok && !knownTypes[definition.Type] {
+			// the driver can not read the type, its text representation is passed through instead
+			columnsCopy[i] = fmt.Sprintf("CONVERT(nvarchar(max), %s) AS %s", quoter.ID(column), quoter.ID(column))
+		}
 	}
 
-	filter, err := parseFilter(queryFilter)
+	reader, tx, err := db.snapshotReader(ctx, opts.Isolation)
 	if err != nil {
 		return nil, err
 	}
 
-	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(columnsCopy, ", "), table.String(), filter.String())
-	rows, err := db.db.QueryContext(ctx, query)
+	if opts.PageSize > 0 {
+		page, err := newPageReader(ctx, reader, table, columns, columnsCopy, opts)
+		if err != nil {
+			rollback(tx)
+			return nil, err
+		}
+
+		rows, err := page.query()
+		if err != nil {
+			rollback(tx)
+			return nil, err
+		}
+
+		return &RowIterator{
+			columnCount: len(columnsCopy),
+			rows:        rows,
+			page:        page,
+			tx:          tx,
+		}, nil
+	}
+
+	query, err := selectQuery(table, columnsCopy, opts)
 	if err != nil {
+		rollback(tx)
+		return nil, err
+	}
+
+	rows, err := reader.QueryContext(ctx, query)
+	if err != nil {
+		rollback(tx)
 		return nil, err
 	}
 
 	return &RowIterator{
 		columnCount: len(columnsCopy),
 		rows:        rows,
+		tx:          tx,
 	}, nil
 }
 
+func selectQuery(table TableRef, quotedColumns []string, opts ReadOptions) (string, error) {
+	where, err := opts.where()
+	if err != nil {
+		return "", err
+	}
+
+	top := ""
+	if opts.Limit > 0 {
+		top = fmt.Sprintf("TOP (%d) ", opts.Limit)
+	}
+
+	query := fmt.Sprintf("SELECT %s%s FROM %s%s%s WHERE %s", top, strings.Join(quotedColumns, ", "), table.String(), opts.tablesample(), opts.Isolation.tableHint(), where)
+
+	if len(opts.OrderBy) > 0 {
+		quoter := mssql.TSQLQuoter{}
+		orderBy := make([]string, len(opts.OrderBy))
+		for i, column := range opts.OrderBy {
+			orderBy[i] = quoter.ID(column)
+		}
+		query = fmt.Sprintf("%s ORDER BY %s", query, strings.Join(orderBy, ", "))
+	}
+
+	option, err := opts.option()
+	if err != nil {
+		return "", err
+	}
+
+	return query + option, nil
+}
+
+// SelectQuery returns the SELECT statement that would be used to read all columns of table with the given query filter.
+func SelectQuery(table TableRef, queryFilter string) (string, error) {
+	return selectQuery(table, []string{"*"}, ReadOptions{QueryFilter: queryFilter})
+}
+
+// GetMaxValue returns the highest value of column as a string literal SQL Server can convert back to the column type,
+// the boolean is false when the table is empty.
+func (db *MSSQLDB) GetMaxValue(ctx context.Context, table TableRef, column string) (string, bool, error) {
+	schemaDef, err := db.GetSchemaDefinition(ctx, table)
+	if err != nil {
+		return "", false, err
+	}
+
+	definition, ok := schemaDef[column]
+	if !ok {
+		return "", false, fmt.Errorf("column %s does not exist in table %s", column, table)
+	}
+
+	var value sql.NullString
+	err = db.db.QueryRowContext(ctx, fmt.Sprintf("SELECT %s FROM %s", maxValueConversion(column, definition.Type), table.String())).Scan(&value)
+	if err != nil {
+		return "", false, err
+	}
+
+	return value.String, value.Valid, nil
+}
+
+// maxValueConversion returns the expression converting the highest value of a column of dataType to a string without losing precision,
+// the default styles round floats to 6 digits and money to 2 decimals
+func maxValueConversion(column, dataType string) string {
+	quoter := mssql.TSQLQuoter{}
+	switch {
+	case strings.Contains(dataType, "date") || dataType == "time":
+		// ISO8601 keeps the full precision of date and time types
+		return fmt.Sprintf("CONVERT(nvarchar(64), MAX(%s), 126)", quoter.ID(column))
+	case dataType == "float" || dataType == "real":
+		// 17 significant digits convert back to the same value
+		return fmt.Sprintf("CONVERT(nvarchar(64), MAX(%s), 3)", quoter.ID(column))
+	case dataType == "money" || dataType == "smallmoney":
+		return fmt.Sprintf("CONVERT(nvarchar(64), MAX(%s), 2)", quoter.ID(column))
+	}
+
+	return fmt.Sprintf("CONVERT(nvarchar(64), MAX(%s))", quoter.ID(column))
+}
+
+// FilterSQL parses a query filter and returns the generated WHERE clause.
+func FilterSQL(queryFilter string) (string, error) {
+	filter, err := parseFilter(queryFilter)
+	if err != nil {
+		return "", err
+	}
+
+	return filter.String(), nil
+}
+
+// Explain returns the estimated execution plan of the SELECT statement generated for table and queryFilter, without executing it.
+func (db *MSSQLDB) Explain(ctx context.Context, table TableRef, queryFilter string) ([]string, error) {
+	query, err := SelectQuery(table, queryFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	// SHOWPLAN is a session setting, so everything needs to run on the same connection
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SET SHOWPLAN_TEXT ON")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), "SET SHOWPLAN_TEXT OFF")
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plan := make([]string, 0)
+	for {
+		for rows.Next() {
+			var line string
+			err := rows.Scan(&line)
+			if err != nil {
+				return nil, err
+			}
+			plan = append(plan, line)
+		}
+
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// EstimateRows compiles the SELECT statement of table with the read options without executing it and returns the estimated number of rows,
+// invalid filters (for example on a column that does not exist) fail here instead of during the copy
+func (db *MSSQLDB) EstimateRows(ctx context.Context, table TableRef, opts ReadOptions) (float64, error) {
+	query, err := selectQuery(table, []string{"*"}, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	// SHOWPLAN is a session setting, so everything needs to run on the same connection
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SET SHOWPLAN_ALL ON")
+	if err != nil {
+		return 0, err
+	}
+	defer conn.ExecContext(context.Background(), "SET SHOWPLAN_ALL OFF")
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	// the first row describes the statement as a whole
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("no plan returned for table %s", table)
+	}
+
+	values := make([]interface{}, len(columns))
+	var estimate sql.NullFloat64
+	for i, column := range columns {
+		if column == "EstimateRows" {
+			values[i] = &estimate
+		} else {
+			values[i] = new(interface{})
+		}
+	}
+
+	err = rows.Scan(values...)
+	if err != nil {
+		return 0, err
+	}
+
+	return estimate.Float64, nil
+}
+
+// ForeingKeyConstraint is one column of a foreign key, a composite foreign key has a constraint per column with the same name
+// in the order of the key
 type ForeingKeyConstraint struct {
 	Name             string
 	Schema           string
@@ -191,10 +675,16 @@ type ForeingKeyConstraint struct {
 	ReferencedTable  string
 	ReferencedColumn string
 	NoCheck          string
+	// OnDelete and OnUpdate are the referential actions, NO_ACTION, CASCADE, SET_NULL or SET_DEFAULT
+	OnDelete string `json:",omitempty"`
+	OnUpdate string `json:",omitempty"`
+	// NotTrusted is set when the server did not validate the existing rows against the foreign key
+	NotTrusted bool `json:",omitempty"`
 }
 
-func (db *MSSQLDB) GetForeignKeys(ctx context.Context, table TableRef) ([]ForeingKeyConstraint, error) {
-	query := `
+// foreignKeyColumns selects the columns of the foreign keys matching the condition on the object id of @table in the order of their key.
+// The constraint may be in another schema than the tables it is on.
+const foreignKeyColumns = `
 	SELECT 
 		fk.name AS 'fk_name',
 		OBJECT_SCHEMA_NAME(fk.parent_object_id) AS 'schema',
@@ -203,102 +693,139 @@ func (db *MSSQLDB) GetForeignKeys(ctx context.Context, table TableRef) ([]Forein
 		OBJECT_SCHEMA_NAME(fk.referenced_object_id) AS 'referenced_schema',
 		OBJECT_NAME(fk.referenced_object_id) AS 'referenced_table',
 		COL_NAME(fkc.referenced_object_id, fkc.referenced_column_id) AS 'referenced_column_name',
-		is_disabled as "no_check"
+		is_disabled as "no_check",
+		fk.delete_referential_action_desc,
+		fk.update_referential_action_desc,
+		fk.is_not_trusted
 	FROM sys.foreign_keys fk
 	INNER JOIN sys.foreign_key_columns fkc ON fk.object_id = fkc.constraint_object_id
-	WHERE OBJECT_NAME(fk.parent_object_id) = @table
-	AND SCHEMA_NAME(fk.schema_id) =  @schema
+	WHERE %s = OBJECT_ID(@table)
 	AND fk.type = 'F'
+	ORDER BY fk.name, fkc.constraint_column_id
 	`
-	rows, err := db.db.QueryContext(ctx, query, sql.Named("table", table.Table), sql.Named("schema", table.Schema))
-	if err != nil {
-		return nil, err
-	}
-
-	foreingKeys := make([]ForeingKeyConstraint, 0)
-	for rows.Next() {
-		var fk ForeingKeyConstraint
-		err := rows.Scan(&fk.Name, &fk.Schema, &fk.Table, &fk.Column, &fk.ReferencedSchema, &fk.ReferencedTable, &fk.ReferencedColumn, &fk.NoCheck)
-		if err != nil {
-			return nil, err
-		}
-		foreingKeys = append(foreingKeys, fk)
-	}
-
-	return foreingKeys, nil
 
+func (db *MSSQLDB) GetForeignKeys(ctx context.Context, table TableRef) ([]ForeingKeyConstraint, error) {
+	return db.getForeignKeys(ctx, fmt.Sprintf(foreignKeyColumns, "fk.parent_object_id"), table)
 }
 
 func (db *MSSQLDB) GetReferencedForeignKeys(ctx context.Context, table TableRef) ([]ForeingKeyConstraint, error) {
-	query := `
-	SELECT 
-		fk.name AS 'fk_name',
-		OBJECT_SCHEMA_NAME(fk.parent_object_id) AS 'schema',
-        OBJECT_NAME(fk.parent_object_id) AS 'table',
-		COL_NAME(fkc.parent_object_id, fkc.parent_column_id) AS 'column',
-		OBJECT_SCHEMA_NAME(fk.referenced_object_id) AS 'referenced_schema',
-		OBJECT_NAME(fk.referenced_object_id) AS 'referenced_table',
-		COL_NAME(fkc.referenced_object_id, fkc.referenced_column_id) AS 'referenced_column_name',
-		is_disabled as "no_check"
-	FROM sys.foreign_keys fk
-	INNER JOIN sys.foreign_key_columns fkc ON fk.object_id = fkc.constraint_object_id
-	WHERE OBJECT_NAME(fk.referenced_object_id) = @table
-	AND SCHEMA_NAME(fk.schema_id) =  @schema
-	AND fk.type = 'F'
-	`
-	rows, err := db.db.QueryContext(ctx, query, sql.Named("table", table.Table), sql.Named("schema", table.Schema))
+	return db.getForeignKeys(ctx, fmt.Sprintf(foreignKeyColumns, "fk.referenced_object_id"), table)
+}
+
+func (db *MSSQLDB) getForeignKeys(ctx context.Context, query string, table TableRef) ([]ForeingKeyConstraint, error) {
+	rows, err := db.db.QueryContext(ctx, query, sql.Named("table", table.String()))
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	foreingKeys := make([]ForeingKeyConstraint, 0)
 	for rows.Next() {
 		var fk ForeingKeyConstraint
-		err := rows.Scan(&fk.Name, &fk.Schema, &fk.Table, &fk.Column, &fk.ReferencedSchema, &fk.ReferencedTable, &fk.ReferencedColumn, &fk.NoCheck)
+		err := rows.Scan(&fk.Name, &fk.Schema, &fk.Table, &fk.Column, &fk.ReferencedSchema, &fk.ReferencedTable, &fk.ReferencedColumn, &fk.NoCheck, &fk.OnDelete, &fk.OnUpdate, &fk.NotTrusted)
 		if err != nil {
 			return nil, err
 		}
 		foreingKeys = append(foreingKeys, fk)
 	}
 
-	return foreingKeys, nil
-
+	return foreingKeys, rows.Err()
 }
 
+// AddForeignKeys creates the foreign keys again WITH NOCHECK, the columns of a composite foreign key form a single constraint.
+// Foreign keys that were disabled are disabled again.
 func (db *MSSQLDB) AddForeignKeys(ctx context.Context, foreignKeys []ForeingKeyConstraint) error {
-	for _, fk := range foreignKeys {
-		err := db.AddForeignKey(ctx, fk)
+	ctx, span := db.startForeignKeySpan(ctx, "add foreign keys", foreignKeys)
+	return endSpan(span, db.addForeignKeys(ctx, foreignKeys))
+}
+
+func (db *MSSQLDB) addForeignKeys(ctx context.Context, foreignKeys []ForeingKeyConstraint) error {
+	for _, columns := range foreignKeysInOrder(foreignKeys) {
+		err := db.execStatement(ctx, addForeignKeyStatement(columns))
 		if err != nil {
 			return err
 		}
+
+		if columns[0].NoCheck == "true" {
+			err = db.DisableForeignKeys(ctx, columns[:1])
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
-func (db *MSSQLDB) AddForeignKey(ctx context.Context, foreignKey ForeingKeyConstraint) error {
-	query := `
-	ALTER TABLE @schema.@table 
-	WITH NOCHECK
-	ADD CONSTRAINT @fk_name
-	FOREIGN KEY (@column) REFERENCES @referenced_schema.@referenced_table(@referenced_column)`
-
-	_, err := db.db.ExecContext(
-		ctx,
-		query,
-		sql.Named("schema", foreignKey.Schema),
-		sql.Named("table", foreignKey.Table),
-		sql.Named("column", foreignKey.Table),
-		sql.Named("fk_name", foreignKey.Name),
-		sql.Named("referenced_column", foreignKey.Column),
-		sql.Named("referenced_schema", foreignKey.ReferencedSchema),
-		sql.Named("referenced_table", foreignKey.ReferencedTable),
+// foreignKeysInOrder groups the per column constraints into foreign keys, in the order their first column appears
+func foreignKeysInOrder(foreignKeys []ForeingKeyConstraint) [][]ForeingKeyConstraint {
+	grouped := groupForeignKeys(foreignKeys)
+
+	ordered := make([][]ForeingKeyConstraint, 0, len(grouped))
+	for _, fk := range foreignKeys {
+		name := TableRef{Schema: fk.Schema, Table: fk.Name}.String()
+		if columns, ok := grouped[name]; ok {
+			ordered = append(ordered, columns)
+			delete(grouped, name)
+		}
+	}
+
+	return ordered
+}
+
+// RestoreForeignKeyStatements returns the statements creating the foreign keys again after they were dropped,
+// or enabling them after they were disabled
+func RestoreForeignKeyStatements(foreignKeys []ForeingKeyConstraint, disabled bool) []string {
+	quoter := mssql.TSQLQuoter{}
+	statements := make([]string, 0)
+	for _, columns := range foreignKeysInOrder(foreignKeys) {
+		fk := columns[0]
+		table := TableRef{Schema: fk.Schema, Table: fk.Table}
+		if disabled {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s CHECK CONSTRAINT %s", table, quoter.ID(fk.Name)))
+			continue
+		}
+
+		statements = append(statements, addForeignKeyStatement(columns))
+		if fk.NoCheck == "true" {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s NOCHECK CONSTRAINT %s", table, quoter.ID(fk.Name)))
+		}
+	}
+
+	return statements
+}
+
+// addForeignKeyStatement returns the DDL creating the foreign key of which columns are the per column constraints
+func addForeignKeyStatement(columns []ForeingKeyConstraint) string {
+	quoter := mssql.TSQLQuoter{}
+	fk := columns[0]
+
+	childColumns := make([]string, len(columns))
+	parentColumns := make([]string, len(columns))
+	for i, column := range columns {
+		childColumns[i] = quoter.ID(column.Column)
+		parentColumns[i] = quoter.ID(column.ReferencedColumn)
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s WITH NOCHECK ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE %s ON UPDATE %s",
+		TableRef{Schema: fk.Schema, Table: fk.Table},
+		quoter.ID(fk.Name),
+		strings.Join(childColumns, ", "),
+		TableRef{Schema: fk.ReferencedSchema, Table: fk.ReferencedTable},
+		strings.Join(parentColumns, ", "),
+		referentialAction(fk.OnDelete),
+		referentialAction(fk.OnUpdate),
 	)
-	if err != nil {
-		return err
+}
+
+// referentialAction returns the DDL of an action of sys.foreign_keys, records of foreign keys read without actions have none
+func referentialAction(action string) string {
+	switch action {
+	case "CASCADE", "SET_NULL", "SET_DEFAULT":
+		return strings.ReplaceAll(action, "_", " ")
 	}
 
-	return nil
+	return "NO ACTION"
 }
 
 func (db *MSSQLDB) DropForeignKeys(ctx context.Context, table TableRef) error {
@@ -307,8 +834,9 @@ func (db *MSSQLDB) DropForeignKeys(ctx context.Context, table TableRef) error {
 		return err
 	}
 
-	for _, fk := range foreingKeys {
-		err := db.DropForeignKey(ctx, fk)
+	// a composite foreign key is dropped once
+	for _, columns := range foreignKeysInOrder(foreingKeys) {
+		err := db.dropForeignKey(ctx, columns)
 		if err != nil {
 			return err
 		}
@@ -318,13 +846,19 @@ func (db *MSSQLDB) DropForeignKeys(ctx context.Context, table TableRef) error {
 }
 
 func (db *MSSQLDB) DropReferencedForeignKeys(ctx context.Context, table TableRef) error {
+	ctx, span := db.startSpan(ctx, "drop foreign keys", table)
+	return endSpan(span, db.dropReferencedForeignKeys(ctx, table))
+}
+
+func (db *MSSQLDB) dropReferencedForeignKeys(ctx context.Context, table TableRef) error {
 	foreingKeys, err := db.GetReferencedForeignKeys(ctx, table)
 	if err != nil {
 		return err
 	}
 
-	for _, fk := range foreingKeys {
-		err := db.DropForeignKey(ctx, fk)
+	// a composite foreign key is dropped once
+	for _, columns := range foreignKeysInOrder(foreingKeys) {
+		err := db.dropForeignKey(ctx, columns)
 		if err != nil {
 			return err
 		}
@@ -334,29 +868,119 @@ func (db *MSSQLDB) DropReferencedForeignKeys(ctx context.Context, table TableRef
 }
 
 func (db *MSSQLDB) DropForeignKey(ctx context.Context, foreignKey ForeingKeyConstraint) error {
-	query := fmt.Sprintf("ALTER TABLE %s.%s DROP CONSTRAINT %s", foreignKey.Schema, foreignKey.Table, foreignKey.Name)
+	return db.dropForeignKey(ctx, []ForeingKeyConstraint{foreignKey})
+}
+
+// dropForeignKey drops the foreign key of which columns are the per column constraints, the audit journal records the DDL creating it again
+func (db *MSSQLDB) dropForeignKey(ctx context.Context, columns []ForeingKeyConstraint) error {
+	quoter := mssql.TSQLQuoter{}
+	fk := columns[0]
+	table := TableRef{Schema: fk.Schema, Table: fk.Table}
+	query := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, quoter.ID(fk.Name))
+
+	restore := addForeignKeyStatement(columns)
+	if fk.NoCheck == "true" {
+		restore = fmt.Sprintf("%s; ALTER TABLE %s NOCHECK CONSTRAINT %s", restore, table, quoter.ID(fk.Name))
+	}
+
+	return db.execDestructive(ctx, query, restore)
+}
+
+func (db *MSSQLDB) DisableForeignKeys(ctx context.Context, foreignKeys []ForeingKeyConstraint) error {
+	ctx, span := db.startForeignKeySpan(ctx, "disable foreign keys", foreignKeys)
+	return endSpan(span, db.checkForeignKeys(ctx, foreignKeys, "NOCHECK"))
+}
+
+// EnableForeignKeys enables the foreign keys again without validating the existing rows
+func (db *MSSQLDB) EnableForeignKeys(ctx context.Context, foreignKeys []ForeingKeyConstraint) error {
+	ctx, span := db.startForeignKeySpan(ctx, "enable foreign keys", foreignKeys)
+	return endSpan(span, db.checkForeignKeys(ctx, foreignKeys, "CHECK"))
+}
+
+// checkForeignKeys enables (CHECK) or disables (NOCHECK) the foreign keys
+func (db *MSSQLDB) checkForeignKeys(ctx context.Context, foreignKeys []ForeingKeyConstraint, action string) error {
+	quoter := mssql.TSQLQuoter{}
+	for _, fk := range foreignKeys {
+		table := TableRef{Schema: fk.Schema, Table: fk.Table}
+		query := fmt.Sprintf("ALTER TABLE %s %s CONSTRAINT %s", table, action, quoter.ID(fk.Name))
+
+		var err error
+		if action == "NOCHECK" {
+			err = db.execDestructive(ctx, query, fmt.Sprintf("ALTER TABLE %s CHECK CONSTRAINT %s", table, quoter.ID(fk.Name)))
+		} else {
+			err = db.execStatement(ctx, query)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startForeignKeySpan starts the span of an operation on foreign keys, which belong to the tables referencing a table
+func (db *MSSQLDB) startForeignKeySpan(ctx context.Context, name string, foreignKeys []ForeingKeyConstraint) (context.Context, trace.Span) {
+	var table TableRef
+	if len(foreignKeys) > 0 {
+		table = TableRef{Schema: foreignKeys[0].ReferencedSchema, Table: foreignKeys[0].ReferencedTable}
+	}
+
+	return db.startSpan(ctx, name, table, attribute.Int("asqlcp.foreign_keys", len(foreignKeysInOrder(foreignKeys))))
+}
+
+// ValidateForeignKey checks the existing rows against the foreign key, after which the server trusts it again
+func (db *MSSQLDB) ValidateForeignKey(ctx context.Context, foreignKey ForeingKeyConstraint) error {
+	quoter := mssql.TSQLQuoter{}
+	query := fmt.Sprintf("ALTER TABLE %s WITH CHECK CHECK CONSTRAINT %s", TableRef{Schema: foreignKey.Schema, Table: foreignKey.Table}, quoter.ID(foreignKey.Name))
 	_, err := db.db.ExecContext(ctx, query)
+	return err
+}
+
+// BulkInsert returns an inserter that bulk copies rows with opts, the server generates the values of an identity column in columns unless KeepIdentity is set
+func (db *MSSQLDB) BulkInsert(ctx context.Context, table TableRef, columns []string, opts BulkOptions) (*BulkInsert, error) {
+	schemaDef, err := db.GetSchemaDefinition(ctx, table)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	bi := NewBulkInsert(table, columns, db.db.DB, opts)
+	bi.database = db.database
+	bi.spanAttributes = db.spanAttributes(table)
+	bi.converters = bulkConverters(columns, schemaDef)
+	for _, column := range columns {
+		if schemaDef[column].Identity && opts.KeepIdentity {
+			bi.identity = true
+		}
+	}
+
+	return bi, nil
 }
 
-func (db *MSSQLDB) BulkInsert(ctx context.Context, table TableRef, columns []string) (*BulkInsert, error) {
+// Snapshot returns a MSSQLDB of which the row counts and selects run in a single SNAPSHOT isolation transaction,
+// so all tables are read as of the same moment. These reads share one connection and can not run concurrently.
+func (db *MSSQLDB) Snapshot(ctx context.Context) (*MSSQLDB, error) {
+	if !db.info.SnapshotIsolation {
+		return nil, fmt.Errorf("snapshot isolation is not allowed on the database, enable it with ALTER DATABASE ... SET ALLOW_SNAPSHOT_ISOLATION ON")
+	}
 
-	// schemaDef, err := db.GetSchemaDefinition(ctx, table)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// columns := make([]string, 0, len(schemaDef))
-	// for column := range schemaDef {
-	// 	columns = append(columns, column)
-	// }
-	return NewBulkInsert(table, columns, db.db), nil
+	tx, err := db.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSnapshot})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := *db
+	snapshot.reader = loggedQuerier{tx, db.database}
+	snapshot.tx = tx
+
+	return &snapshot, nil
 }
 
+// Close closes the connection pool, or for a snapshot only ends its transaction
 func (db *MSSQLDB) Close() error {
+	if db.tx != nil {
+		return db.tx.Rollback()
+	}
+
 	return db.db.Close()
 }
 
@@ -457,3 +1081,105 @@ func parseFilter(queryFilter string) (filter, error) {
 
 	return f, nil
 }
+
+// Condition is an additional predicate that is combined with the query filter
+type Condition struct {
+	Column   string
+	Operator string
+	Value    string
+}
+
+var conditionOperators = map[string]bool{"=": true, "<>": true, "<": true, ">": true, "<=": true, ">=": true}
+
+// ReadOptions determines which rows of a table are read
+type ReadOptions struct {
+	QueryFilter string
+	Conditions  []Condition
+	// OrderBy reads the rows ordered by these columns, which should form a unique key when After is used
+	OrderBy []string
+	// After only reads the rows ordered after the row with these OrderBy values
+	After []string
+	// Predicate is a generated WHERE clause, like the one of a Subset, which is added as is
+	Predicate string
+	// Limit reads at most this many rows
+	Limit int
+	// SamplePercent reads a random sample of about this percentage of the pages of the table with TABLESAMPLE
+	SamplePercent float64
+	// Hints are added to the OPTION clause of the select, like RECOMPILE or MAXDOP 4
+	Hints []string
+	// Isolation determines how the read interacts with concurrent writes to the table
+	Isolation ReadIsolation
+	// PageSize reads the rows ordered by OrderBy in pages of this many rows, every page a query of its own continuing after the last row
+	// of the previous one. This bounds the work of a query and keeps no cursor open for the whole read. OrderBy has to be a unique key.
+	PageSize int
+}
+
+// Unfiltered reports whether all rows of the table are read
+func (o ReadOptions) Unfiltered() bool {
+	return o.QueryFilter == "" && len(o.Conditions) == 0 && len(o.After) == 0 && o.Predicate == "" && !o.Sampled()
+}
+
+// Sampled reports whether only a subset of the table is read
+func (o ReadOptions) Sampled() bool {
+	return o.Limit > 0 || o.SamplePercent > 0
+}
+
+// option returns the OPTION clause of the hints, the hints are added as is so they can not contain comments or separators
+func (o ReadOptions) option() (string, error) {
+	if len(o.Hints) == 0 {
+		return "", nil
+	}
+
+	for _, hint := range o.Hints {
+		if strings.TrimSpace(hint) == "" || strings.ContainsAny(hint, ";") || strings.Contains(hint, "--") || strings.Contains(hint, "/*") {
+			return "", fmt.Errorf("invalid query hint %q", hint)
+		}
+	}
+
+	return fmt.Sprintf(" OPTION (%s)", strings.Join(o.Hints, ", ")), nil
+}
+
+func (o ReadOptions) tablesample() string {
+	if o.SamplePercent <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" TABLESAMPLE (%s PERCENT)", strconv.FormatFloat(o.SamplePercent, 'f', -1, 64))
+}
+
+func (o ReadOptions) where() (string, error) {
+	filter, err := parseFilter(o.QueryFilter)
+	if err != nil {
+		return "", err
+	}
+
+	if len(o.Conditions) == 0 && len(o.After) == 0 && o.Predicate == "" {
+		return filter.String(), nil
+	}
+
+	parts := make([]string, 0, len(o.Conditions)+3)
+	if len(filter.expressions) > 0 {
+		parts = append(parts, fmt.Sprintf("( %s )", filter.String()))
+	}
+
+	if o.Predicate != "" {
+		parts = append(parts, fmt.Sprintf("( %s )", o.Predicate))
+	}
+
+	if len(o.After) > 0 {
+		keyset, err := keysetPredicate(o.OrderBy, o.After)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("( %s )", keyset))
+	}
+
+	for _, condition := range o.Conditions {
+		if !conditionOperators[condition.Operator] {
+			return "", fmt.Errorf("invalid condition on column %s with operator %q", condition.Column, condition.Operator)
+		}
+		parts = append(parts, expression{column: condition.Column, operator: condition.Operator, value: condition.Value}.String())
+	}
+
+	return strings.Join(parts, " AND "), nil
+}
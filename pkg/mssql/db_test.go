@@ -26,4 +26,159 @@ func TestParseFilterSQLInjection(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, filter.String(), "( [column1] = '; DROP TABLE users --' )")
 	
-}
\ No newline at end of file
+}
+func TestSelectQuery(t *testing.T) {
+	query, err := SelectQuery(TableRef{Schema: "dbo", Table: "orders"}, "status = 'open'")
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM [dbo].[orders] WHERE ( [status] = 'open' )", query)
+}
+
+func TestFilterSQLInvalidExpression(t *testing.T) {
+	_, err := FilterSQL("status")
+	assert.Error(t, err)
+}
+
+func TestCompatibilityWarnings(t *testing.T) {
+	source := ServerInfo{Edition: "SQL Azure", EngineEdition: 5, CompatibilityLevel: 150}
+
+	assert.Empty(t, CompatibilityWarnings(source, source))
+	assert.Len(t, CompatibilityWarnings(source, ServerInfo{Edition: "Standard Edition", EngineEdition: 2, CompatibilityLevel: 110}), 4)
+}
+
+func TestSameDatabase(t *testing.T) {
+	source := ServerInfo{ServerName: "prod-sql", Database: "Sales"}
+
+	assert.True(t, SameDatabase(source, ServerInfo{ServerName: "PROD-SQL", Database: "sales"}))
+	assert.False(t, SameDatabase(source, ServerInfo{ServerName: "prod-sql", Database: "Sales_copy"}))
+	assert.False(t, SameDatabase(source, ServerInfo{ServerName: "staging-sql", Database: "Sales"}))
+	// without a server name the servers can not be told apart
+	assert.True(t, SameDatabase(ServerInfo{Database: "Sales"}, ServerInfo{ServerName: "staging-sql", Database: "Sales"}))
+	assert.False(t, SameDatabase(ServerInfo{Database: "Sales"}, ServerInfo{Database: "Sales_copy"}))
+
+	// the guids decide when both are known
+	guid := "6F9619FF-8B86-D011-B42D-00C04FC964FF"
+	assert.False(t, SameDatabase(ServerInfo{Database: "Sales", DatabaseGUID: guid},
+		ServerInfo{Database: "Sales", DatabaseGUID: "0B5E8E4C-7B2A-4C55-9D3E-2F1A6C7D8E9F"}))
+	assert.True(t, SameDatabase(ServerInfo{Database: "Sales", DatabaseGUID: guid},
+		ServerInfo{Database: "Sales", DatabaseGUID: "6f9619ff-8b86-d011-b42d-00c04fc964ff"}))
+}
+
+func TestReadOptionsWhereWithConditions(t *testing.T) {
+	where, err := ReadOptions{
+		QueryFilter: "a = 1 OR b = 2",
+		Conditions:  []Condition{{Column: "id", Operator: ">", Value: "10"}},
+	}.where()
+	assert.NoError(t, err)
+	assert.Equal(t, "( ( [a] = '1' ) OR ( [b] = '2' ) ) AND ( [id] > '10' )", where)
+
+	_, err = ReadOptions{Conditions: []Condition{{Column: "id", Operator: "; DROP", Value: "10"}}}.where()
+	assert.Error(t, err)
+}
+
+func TestSelectQueryAfterKey(t *testing.T) {
+	query, err := selectQuery(TableRef{Schema: "dbo", Table: "lines"}, []string{"[id]"}, ReadOptions{
+		OrderBy: []string{"order_id", "line"},
+		After:   []string{"7", "3"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT [id] FROM [dbo].[lines] WHERE ( ( [order_id] > '7' ) OR ( [order_id] = '7' AND [line] > '3' ) ) ORDER BY [order_id], [line]", query)
+
+	_, err = ReadOptions{OrderBy: []string{"id"}, After: []string{"1", "2"}}.where()
+	assert.Error(t, err)
+}
+
+func TestChecksumColumns(t *testing.T) {
+	columns, skipped := ChecksumColumns(SchemaDefinition{"name": {Type: "nvarchar"}, "id": {Type: "int"}, "notes": {Type: "ntext"}, "doc": {Type: "xml"}})
+	assert.Equal(t, []string{"id", "name"}, columns)
+	assert.Equal(t, []string{"doc", "notes"}, skipped)
+
+	query := checksumQuery(TableRef{Schema: "dbo", Table: "orders"}, columns, "1=1")
+	assert.Equal(t, "SELECT COUNT_BIG(*), CAST(CHECKSUM_AGG(BINARY_CHECKSUM([id], [name])) AS bigint) FROM [dbo].[orders] WHERE 1=1", query)
+}
+
+func TestSelectQuerySample(t *testing.T) {
+	query, err := selectQuery(TableRef{Schema: "dbo", Table: "orders"}, []string{"[id]"}, ReadOptions{Limit: 100, SamplePercent: 2.5})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT TOP (100) [id] FROM [dbo].[orders] TABLESAMPLE (2.5 PERCENT) WHERE 1=1", query)
+}
+
+func TestUnknownTypeColumns(t *testing.T) {
+	columns := UnknownTypeColumns(SchemaDefinition{"id": {Type: "int"}, "embedding": {Type: "vector"}, "doc": {Type: "json"}, "name": {Type: "nvarchar"}})
+	assert.Equal(t, []string{"doc", "embedding"}, columns)
+}
+
+func TestReadOnlyStatement(t *testing.T) {
+	assert.NoError(t, checkReadOnly("select top 10 * from dbo.orders"))
+	assert.NoError(t, checkReadOnly("  WITH x AS (SELECT 1 AS a) SELECT * FROM x"))
+	assert.NoError(t, checkReadOnly("SELECT 'a;b', [drop] FROM dbo.orders -- delete; commit\nWHERE note = 'it''s; EXEC x'"))
+	assert.NoError(t, checkReadOnly("SELECT /* nested /* ; */ commit */ id INTO #copy FROM dbo.orders"))
+	assert.Error(t, checkReadOnly("DELETE FROM dbo.orders"))
+	assert.Error(t, checkReadOnly("EXEC sp_who"))
+	assert.Error(t, checkReadOnly("SELECT 1; COMMIT; DROP TABLE x"))
+	assert.Error(t, checkReadOnly("SELECT 1 COMMIT DROP TABLE x"))
+	assert.Error(t, checkReadOnly("SELECT 1\nGO\nDROP TABLE x"))
+	assert.Error(t, checkReadOnly("SELECT 1 EXEC('DROP TABLE x')"))
+	assert.Error(t, checkReadOnly("SELECT 'x' /* */ ; DELETE FROM y"))
+}
+
+func TestSelectQueryHints(t *testing.T) {
+	query, err := selectQuery(TableRef{Schema: "dbo", Table: "orders"}, []string{"[id]"}, ReadOptions{Hints: []string{"RECOMPILE", "MAXDOP 4"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT [id] FROM [dbo].[orders] WHERE 1=1 OPTION (RECOMPILE, MAXDOP 4)", query)
+
+	_, err = selectQuery(TableRef{Schema: "dbo", Table: "orders"}, []string{"[id]"}, ReadOptions{Hints: []string{"RECOMPILE); DROP TABLE x --"}})
+	assert.Error(t, err)
+}
+
+func TestAddForeignKeyStatement(t *testing.T) {
+	columns := []ForeingKeyConstraint{
+		{Name: "FK_lines_orders", Schema: "dbo", Table: "lines", Column: "order_id", ReferencedSchema: "dbo", ReferencedTable: "orders", ReferencedColumn: "id", OnDelete: "CASCADE", OnUpdate: "NO_ACTION"},
+		{Name: "FK_lines_orders", Schema: "dbo", Table: "lines", Column: "order_version", ReferencedSchema: "dbo", ReferencedTable: "orders", ReferencedColumn: "version", OnDelete: "CASCADE", OnUpdate: "NO_ACTION"},
+	}
+
+	assert.Equal(t, "ALTER TABLE [dbo].[lines] WITH NOCHECK ADD CONSTRAINT [FK_lines_orders] FOREIGN KEY ([order_id], [order_version]) REFERENCES [dbo].[orders] ([id], [version]) ON DELETE CASCADE ON UPDATE NO ACTION", addForeignKeyStatement(columns))
+
+	other := ForeingKeyConstraint{Name: "FK_lines_products", Schema: "dbo", Table: "lines", Column: "product_id", ReferencedSchema: "dbo", ReferencedTable: "products", ReferencedColumn: "id", OnDelete: "SET_NULL"}
+	grouped := foreignKeysInOrder([]ForeingKeyConstraint{columns[0], other, columns[1]})
+	assert.Equal(t, [][]ForeingKeyConstraint{columns, {other}}, grouped)
+	assert.Contains(t, addForeignKeyStatement(grouped[1]), "ON DELETE SET NULL ON UPDATE NO ACTION")
+}
+
+func TestRestoreForeignKeyStatements(t *testing.T) {
+	lines := ForeingKeyConstraint{Name: "FK_lines_orders", Schema: "dbo", Table: "lines", Column: "order_id", ReferencedSchema: "dbo", ReferencedTable: "orders", ReferencedColumn: "id", NoCheck: "true"}
+
+	assert.Equal(t, []string{
+		addForeignKeyStatement([]ForeingKeyConstraint{lines}),
+		"ALTER TABLE [dbo].[lines] NOCHECK CONSTRAINT [FK_lines_orders]",
+	}, RestoreForeignKeyStatements([]ForeingKeyConstraint{lines}, false))
+	assert.Equal(t, []string{"ALTER TABLE [dbo].[lines] CHECK CONSTRAINT [FK_lines_orders]"}, RestoreForeignKeyStatements([]ForeingKeyConstraint{lines}, true))
+}
+
+func TestBucketChecksumQuery(t *testing.T) {
+	orders := TableRef{Schema: "dbo", Table: "orders"}
+
+	query := bucketChecksumQuery(orders, []string{"id"}, []string{"id", "name"}, 8, "1=1")
+	assert.Equal(t, "SELECT bucket, COUNT_BIG(*), SUM(CAST(CAST(SUBSTRING(h, 1, 4) AS int) AS bigint)), CAST(CHECKSUM_AGG(CAST(SUBSTRING(h, 5, 4) AS int)) AS bigint) "+
+		"FROM (SELECT ABS(CAST(CAST(SUBSTRING(HASHBYTES('SHA2_256', (SELECT [id] FOR XML RAW, BINARY BASE64)), 1, 4) AS int) AS bigint)) % 8 AS bucket, "+
+		"HASHBYTES('SHA2_256', (SELECT [id], [name] FOR XML RAW, BINARY BASE64)) AS h FROM [dbo].[orders] WHERE 1=1) AS hashed GROUP BY bucket", query)
+
+	// without a primary key the table is hashed as a whole
+	query = bucketChecksumQuery(orders, nil, []string{"name"}, 8, "1=1")
+	assert.Contains(t, query, "SELECT 0 AS bucket")
+
+	query = rowHashQuery(orders, []string{"id"}, []string{"id", "name"}, 8, 3, "1=1")
+	assert.Equal(t, "SELECT [id], h FROM (SELECT [id], ABS(CAST(CAST(SUBSTRING(HASHBYTES('SHA2_256', (SELECT [id] FOR XML RAW, BINARY BASE64)), 1, 4) AS int) AS bigint)) % 8 AS bucket, "+
+		"HASHBYTES('SHA2_256', (SELECT [id], [name] FOR XML RAW, BINARY BASE64)) AS h FROM [dbo].[orders] WHERE 1=1) AS hashed WHERE bucket = 3", query)
+
+	query = bucketRowsQuery(orders, []string{"id"}, []string{"id", "name"}, 8, 3, "1=1")
+	assert.Equal(t, "SELECT [id], [id], [name] FROM [dbo].[orders] WHERE ( 1=1 ) AND ABS(CAST(CAST(SUBSTRING(HASHBYTES('SHA2_256', (SELECT [id] FOR XML RAW, BINARY BASE64)), 1, 4) AS int) AS bigint)) % 8 = 3", query)
+}
+
+func TestMaxValueConversion(t *testing.T) {
+	assert.Equal(t, "CONVERT(nvarchar(64), MAX([UpdatedAt]), 126)", maxValueConversion("UpdatedAt", "datetime2"))
+	assert.Equal(t, "CONVERT(nvarchar(64), MAX([At]), 126)", maxValueConversion("At", "time"))
+	assert.Equal(t, "CONVERT(nvarchar(64), MAX([Version]), 3)", maxValueConversion("Version", "float"))
+	assert.Equal(t, "CONVERT(nvarchar(64), MAX([Version]), 3)", maxValueConversion("Version", "real"))
+	assert.Equal(t, "CONVERT(nvarchar(64), MAX([Amount]), 2)", maxValueConversion("Amount", "money"))
+	assert.Equal(t, "CONVERT(nvarchar(64), MAX([Id]))", maxValueConversion("Id", "bigint"))
+}
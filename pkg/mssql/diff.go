@@ -0,0 +1,112 @@
+package mssql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColumnDifference is an attribute of a column that differs between the source and the target
+type ColumnDifference struct {
+	Column string `json:"column"`
+	// Attribute is one of type, length, precision, scale, nullable, identity, computed or collation
+	Attribute string `json:"attribute"`
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+}
+
+// TableDiff describes how the definition of a table differs between the source and the target
+type TableDiff struct {
+	Table           string `json:"table"`
+	MissingInTarget bool   `json:"missing_in_target,omitempty"`
+	MissingInSource bool   `json:"missing_in_source,omitempty"`
+	// MissingColumns exist in the source but not in the target, ExtraColumns only exist in the target
+	MissingColumns []string           `json:"missing_columns,omitempty"`
+	ExtraColumns   []string           `json:"extra_columns,omitempty"`
+	Differences    []ColumnDifference `json:"differences,omitempty"`
+}
+
+// Equal reports whether the table has the same definition in the source and the target
+func (d TableDiff) Equal() bool {
+	return !d.MissingInTarget && !d.MissingInSource && len(d.MissingColumns) == 0 && len(d.ExtraColumns) == 0 && len(d.Differences) == 0
+}
+
+// DiffTableDefinitions compares the columns of a table in the source and the target, column names are matched case-insensitively
+func DiffTableDefinitions(source, target TableDefinition) TableDiff {
+	diff := TableDiff{Table: source.Table.Schema + "." + source.Table.Table}
+
+	targetColumns := make(map[string]ColumnDefinition, len(target.Columns))
+	for _, column := range target.Columns {
+		targetColumns[strings.ToLower(column.Name)] = column
+	}
+
+	sourceColumns := make(map[string]bool, len(source.Columns))
+	for _, column := range source.Columns {
+		sourceColumns[strings.ToLower(column.Name)] = true
+
+		targetColumn, ok := targetColumns[strings.ToLower(column.Name)]
+		if !ok {
+			diff.MissingColumns = append(diff.MissingColumns, column.Name)
+			continue
+		}
+
+		diff.Differences = append(diff.Differences, diffColumns(column, targetColumn)...)
+	}
+
+	for _, column := range target.Columns {
+		if !sourceColumns[strings.ToLower(column.Name)] {
+			diff.ExtraColumns = append(diff.ExtraColumns, column.Name)
+		}
+	}
+
+	return diff
+}
+
+func diffColumns(source, target ColumnDefinition) []ColumnDifference {
+	differences := make([]ColumnDifference, 0)
+	add := func(attribute, sourceValue, targetValue string) {
+		if sourceValue != targetValue {
+			differences = append(differences, ColumnDifference{Column: source.Name, Attribute: attribute, Source: sourceValue, Target: targetValue})
+		}
+	}
+
+	if !strings.EqualFold(source.Type, target.Type) {
+		add("type", columnTypeSQL(source), columnTypeSQL(target))
+	} else {
+		add(typeAttribute(source.Type), columnTypeSQL(source), columnTypeSQL(target))
+	}
+
+	add("nullable", nullability(source), nullability(target))
+	add("identity", identity(source), identity(target))
+	add("computed", source.Computed, target.Computed)
+	add("collation", source.Collation, target.Collation)
+
+	return differences
+}
+
+// typeAttribute names what differs when the rendered types of two columns of the same type differ
+func typeAttribute(dataType string) string {
+	switch strings.ToLower(dataType) {
+	case "char", "varchar", "nchar", "nvarchar", "binary", "varbinary":
+		return "length"
+	case "datetime2", "time", "datetimeoffset":
+		return "scale"
+	}
+
+	return "precision"
+}
+
+func nullability(column ColumnDefinition) string {
+	if column.Nullable {
+		return "NULL"
+	}
+
+	return "NOT NULL"
+}
+
+func identity(column ColumnDefinition) string {
+	if !column.Identity {
+		return ""
+	}
+
+	return fmt.Sprintf("IDENTITY(%s, %s)", column.Seed, column.Increment)
+}
@@ -0,0 +1,60 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTableDefinitions(t *testing.T) {
+	table := TableRef{Schema: "dbo", Table: "Orders"}
+	source := TableDefinition{Table: table, Columns: []ColumnDefinition{
+		{Name: "Id", Type: "int", Precision: 10, Identity: true, Seed: "1", Increment: "1"},
+		{Name: "Code", Type: "varchar", MaxLength: 10},
+		{Name: "Amount", Type: "decimal", Precision: 18, Scale: 2, Nullable: true},
+		{Name: "Note", Type: "nvarchar", MaxLength: -1, Nullable: true},
+	}}
+	target := TableDefinition{Table: table, Columns: []ColumnDefinition{
+		{Name: "id", Type: "int", Precision: 10},
+		{Name: "Code", Type: "varchar", MaxLength: -1},
+		{Name: "Amount", Type: "decimal", Precision: 18, Scale: 4},
+		{Name: "Legacy", Type: "bit", Nullable: true},
+	}}
+
+	diff := DiffTableDefinitions(source, target)
+
+	assert.False(t, diff.Equal())
+	assert.Equal(t, "dbo.Orders", diff.Table)
+	assert.Equal(t, []string{"Note"}, diff.MissingColumns)
+	assert.Equal(t, []string{"Legacy"}, diff.ExtraColumns)
+	assert.Equal(t, []ColumnDifference{
+		{Column: "Id", Attribute: "identity", Source: "IDENTITY(1, 1)", Target: ""},
+		{Column: "Code", Attribute: "length", Source: "varchar(10)", Target: "varchar(max)"},
+		{Column: "Amount", Attribute: "precision", Source: "decimal(18, 2)", Target: "decimal(18, 4)"},
+		{Column: "Amount", Attribute: "nullable", Source: "NULL", Target: "NOT NULL"},
+	}, diff.Differences)
+
+	assert.True(t, DiffTableDefinitions(source, source).Equal())
+}
+
+func TestSchemaDefinitionMatches(t *testing.T) {
+	source := SchemaDefinition{
+		"id":   {Name: "id", Type: "int", Precision: 10, Identity: true, Seed: "1", Increment: "1"},
+		"code": {Name: "code", Type: "varchar", MaxLength: 10, Collation: "Latin1_General_CI_AS"},
+	}
+
+	target := SchemaDefinition{
+		"id":   {Name: "id", Type: "int", Precision: 10, Identity: true, Seed: "1000", Increment: "1"},
+		"code": {Name: "code", Type: "varchar", MaxLength: 10, Collation: "SQL_Latin1_General_CP1_CI_AS"},
+	}
+	assert.True(t, source.Matches(target))
+
+	target["code"] = ColumnDefinition{Name: "code", Type: "varchar", MaxLength: -1}
+	assert.False(t, source.Matches(target))
+
+	target["code"] = ColumnDefinition{Name: "code", Type: "varchar", MaxLength: 10, Nullable: true}
+	assert.False(t, source.Matches(target))
+
+	delete(target, "code")
+	assert.False(t, source.Matches(target))
+}
@@ -0,0 +1,62 @@
+package mssql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TableExclusions are the patterns of the tables left out of a copy
+type TableExclusions []*regexp.Regexp
+
+// ParseTableExclusions parses the patterns of the tables to leave out. A pattern between slashes is a regular expression,
+// the others are globs in which * matches any text and ? a single character. Both are case insensitive.
+func ParseTableExclusions(patterns []string) (TableExclusions, error) {
+	exclusions := make(TableExclusions, 0, len(patterns))
+	for _, pattern := range patterns {
+		expression := globExpression(pattern)
+		if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			expression = pattern[1 : len(pattern)-1]
+		}
+
+		re, err := regexp.Compile("(?i)" + expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid table pattern %q to exclude, %w", pattern, err)
+		}
+		exclusions = append(exclusions, re)
+	}
+
+	return exclusions, nil
+}
+
+// globExpression returns the regular expression matching the whole of the text glob matches
+func globExpression(glob string) string {
+	expression := regexp.QuoteMeta(glob)
+	expression = strings.ReplaceAll(expression, `\*`, ".*")
+	expression = strings.ReplaceAll(expression, `\?`, ".")
+
+	return "^" + expression + "$"
+}
+
+// Excludes reports whether a pattern matches the name of table or schema.table
+func (e TableExclusions) Excludes(table TableRef) bool {
+	for _, re := range e {
+		if re.MatchString(table.Table) || re.MatchString(table.Schema+"."+table.Table) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply returns the tables no pattern matches, in the same order, and the number of tables left out
+func (e TableExclusions) Apply(tables []TableRef) ([]TableRef, int) {
+	kept := make([]TableRef, 0, len(tables))
+	for _, table := range tables {
+		if !e.Excludes(table) {
+			kept = append(kept, table)
+		}
+	}
+
+	return kept, len(tables) - len(kept)
+}
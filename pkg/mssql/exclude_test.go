@@ -0,0 +1,30 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableExclusions(t *testing.T) {
+	exclusions, err := ParseTableExclusions([]string{"audit_*", "sales.Log?", "/_(bak|old)$/"})
+	require.NoError(t, err)
+
+	assert.True(t, exclusions.Excludes(TableRef{Schema: "dbo", Table: "Audit_Orders"}))
+	assert.True(t, exclusions.Excludes(TableRef{Schema: "sales", Table: "log1"}))
+	assert.True(t, exclusions.Excludes(TableRef{Schema: "dbo", Table: "Orders_old"}))
+	assert.False(t, exclusions.Excludes(TableRef{Schema: "dbo", Table: "Orders"}))
+	assert.False(t, exclusions.Excludes(TableRef{Schema: "dbo", Table: "Log1"}))
+	assert.False(t, exclusions.Excludes(TableRef{Schema: "dbo", Table: "Orders_audit_x"}))
+
+	orders := TableRef{Schema: "dbo", Table: "Orders"}
+	kept, excluded := exclusions.Apply([]TableRef{orders, {Schema: "dbo", Table: "audit_log"}})
+	assert.Equal(t, []TableRef{orders}, kept)
+	assert.Equal(t, 1, excluded)
+}
+
+func TestParseTableExclusionsInvalidRegex(t *testing.T) {
+	_, err := ParseTableExclusions([]string{"/(audit/"})
+	assert.Error(t, err)
+}
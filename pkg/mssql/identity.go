@@ -0,0 +1,64 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// IdentityColumn describes the identity column of a table, values are kept as strings as they can exceed int64
+type IdentityColumn struct {
+	Name      string
+	Seed      string
+	Increment string
+	// Current is the last identity value generated for the table, empty when none was generated yet
+	Current string
+}
+
+// GetIdentityColumn returns the identity column of table, or nil when the table has none
+func (db *MSSQLDB) GetIdentityColumn(ctx context.Context, table TableRef) (*IdentityColumn, error) {
+	query := `
+	SELECT
+		name,
+		CONVERT(varchar(40), IDENT_SEED(@table)),
+		CONVERT(varchar(40), IDENT_INCR(@table)),
+		CONVERT(varchar(40), IDENT_CURRENT(@table)),
+		CONVERT(varchar(40), last_value)
+	FROM sys.identity_columns
+	WHERE object_id = OBJECT_ID(@table)
+	`
+
+	var identity IdentityColumn
+	var current, lastValue sql.NullString
+	err := db.db.QueryRowContext(ctx, query, sql.Named("table", table.String())).Scan(&identity.Name, &identity.Seed, &identity.Increment, &current, &lastValue)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// IDENT_CURRENT returns the seed for tables that never generated a value, last_value is NULL for those
+	if lastValue.Valid {
+		identity.Current = current.String
+	}
+
+	return &identity, nil
+}
+
+// ReseedIdentity sets the current identity value of table, so the next insert continues from value
+func (db *MSSQLDB) ReseedIdentity(ctx context.Context, table TableRef, value string) error {
+	quoter := mssql.TSQLQuoter{}
+
+	var reseed sql.NullString
+	// the value is validated by the server, a non numeric value fails the conversion
+	err := db.db.QueryRowContext(ctx, "SELECT CONVERT(varchar(40), CONVERT(numeric(38, 0), @value))", sql.Named("value", value)).Scan(&reseed)
+	if err != nil {
+		return fmt.Errorf("invalid identity value %q, %w", value, err)
+	}
+
+	_, err = db.db.ExecContext(ctx, fmt.Sprintf("DBCC CHECKIDENT (%s, RESEED, %s) WITH NO_INFOMSGS", quoter.Value(table.String()), reseed.String))
+	return err
+}
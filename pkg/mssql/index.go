@@ -0,0 +1,146 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// Index is a nonclustered index of a table
+type Index struct {
+	Schema string
+	Table  string
+	Name   string
+}
+
+// GetNonclusteredIndexes returns the nonclustered indexes of table that can be disabled during a load. Unique indexes are left out,
+// as they enforce constraints and can be referenced by foreign keys, and so are disabled indexes, which rebuilding would enable.
+func (db *MSSQLDB) GetNonclusteredIndexes(ctx context.Context, table TableRef) ([]Index, error) {
+	return db.nonclusteredIndexes(ctx, table, false)
+}
+
+func (db *MSSQLDB) nonclusteredIndexes(ctx context.Context, table TableRef, disabled bool) ([]Index, error) {
+	query := `
+	SELECT name
+	FROM sys.indexes
+	WHERE object_id = OBJECT_ID(@table)
+	AND type_desc = 'NONCLUSTERED'
+	AND is_unique = 0
+	AND is_primary_key = 0
+	AND is_unique_constraint = 0
+	AND is_hypothetical = 0
+	AND is_disabled = @disabled
+	ORDER BY name
+	`
+	rows, err := db.db.QueryContext(ctx, query, sql.Named("table", table.String()), sql.Named("disabled", disabled))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := make([]Index, 0)
+	for rows.Next() {
+		index := Index{Schema: table.Schema, Table: table.Table}
+		err := rows.Scan(&index.Name)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+
+	return indexes, rows.Err()
+}
+
+// DisableIndexes disables the indexes, their data is dropped until they are rebuilt
+func (db *MSSQLDB) DisableIndexes(ctx context.Context, indexes []Index) error {
+	for _, index := range indexes {
+		_, err := db.db.ExecContext(ctx, alterIndexStatement(index, "DISABLE"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RebuildIndexes builds the indexes from the rows of their table, which enables disabled indexes again
+func (db *MSSQLDB) RebuildIndexes(ctx context.Context, indexes []Index) error {
+	for _, index := range indexes {
+		_, err := db.db.ExecContext(ctx, alterIndexStatement(index, "REBUILD"))
+		if err != nil {
+			return fmt.Errorf("failed to rebuild index %s, %w", index.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// RebuildIndexStatements returns the statements RebuildIndexes runs, for when they have to be run by hand
+func RebuildIndexStatements(indexes []Index) []string {
+	statements := make([]string, len(indexes))
+	for i, index := range indexes {
+		statements[i] = alterIndexStatement(index, "REBUILD")
+	}
+
+	return statements
+}
+
+func alterIndexStatement(index Index, action string) string {
+	quoter := mssql.TSQLQuoter{}
+	return fmt.Sprintf("ALTER INDEX %s ON %s %s", quoter.ID(index.Name), TableRef{Schema: index.Schema, Table: index.Table}, action)
+}
+
+// RecordIndexes records the indexes of table that are about to be disabled, so they can be rebuilt after a crash
+func (db *MSSQLDB) RecordIndexes(ctx context.Context, table TableRef, indexes []Index) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	err := db.ensureArtifactTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.recordArtifact(ctx, ArtifactDisabledIndexes, table.String(), indexes)
+}
+
+// GetRecordedIndexes returns the recorded indexes of table that are still disabled, which a run that did not finish left disabled
+func (db *MSSQLDB) GetRecordedIndexes(ctx context.Context, table TableRef) ([]Index, error) {
+	artifacts, err := db.GetArtifacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make([]Index, 0)
+	for _, artifact := range artifacts {
+		if artifact.Kind == ArtifactDisabledIndexes && strings.EqualFold(artifact.Name, table.String()) {
+			recorded = append(recorded, artifact.Indexes...)
+		}
+	}
+	if len(recorded) == 0 {
+		return recorded, nil
+	}
+
+	disabled, err := db.nonclusteredIndexes(ctx, table, true)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]Index, 0, len(recorded))
+	for _, index := range recorded {
+		if slices.ContainsFunc(disabled, func(d Index) bool { return strings.EqualFold(d.Name, index.Name) }) {
+			indexes = append(indexes, index)
+		}
+	}
+
+	return indexes, nil
+}
+
+// ForgetIndexes removes the record of the indexes of table that were rebuilt
+func (db *MSSQLDB) ForgetIndexes(ctx context.Context, table TableRef) error {
+	return db.forgetArtifact(ctx, ArtifactDisabledIndexes, table.String())
+}
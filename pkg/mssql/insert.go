@@ -0,0 +1,256 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxInsertRows is the maximum number of rows of a single INSERT ... VALUES statement
+	maxInsertRows = 1000
+	// maxInsertParameters stays below the limit of 2100 parameters per statement
+	maxInsertParameters = 2000
+)
+
+// BatchInsert inserts rows with multi row INSERT statements, which unlike the bulk copy protocol fires the triggers of the table
+type BatchInsert struct {
+	table TableRef
+	// columns are the columns inserted, without the identity column at skip when its values are generated by the server
+	columns  []string
+	skip     int
+	decimals map[int]bool
+	// identity is set when the identity values in the columns are kept with IDENTITY_INSERT
+	identity  bool
+	db        querier
+	batchSize int
+
+	// spanAttributes identify the table of the spans of the statements
+	spanAttributes []attribute.KeyValue
+
+	rows     [][]interface{}
+	onCommit func() error
+}
+
+// BatchInsert returns an inserter that writes rows in batches of INSERT statements, the server generates the values of an identity
+// column in columns unless keepIdentity is set, like BulkInsert
+func (db *MSSQLDB) BatchInsert(ctx context.Context, table TableRef, columns []string, keepIdentity bool) (*BatchInsert, error) {
+	schema, err := db.GetSchemaDefinition(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	bi := newBatchInsert(table, columns, schema, keepIdentity, db.db)
+	bi.spanAttributes = db.spanAttributes(table)
+
+	return bi, nil
+}
+
+func newBatchInsert(table TableRef, columns []string, schema SchemaDefinition, keepIdentity bool, db querier) *BatchInsert {
+	// decimals are read as []uint8, which would be sent as varbinary instead of their textual value
+	decimals := make(map[int]bool)
+	for i, column := range columns {
+		switch schema[column].Type {
+		case "decimal", "numeric", "money", "smallmoney":
+			decimals[i] = true
+		}
+	}
+
+	identity, skip := false, -1
+	for i, column := range columns {
+		if schema[column].Identity {
+			identity, skip = keepIdentity, i
+		}
+	}
+	insertColumns := columns
+	if identity {
+		skip = -1
+	} else if skip >= 0 {
+		insertColumns = slices.Delete(slices.Clone(columns), skip, skip+1)
+	}
+
+	batchSize := maxInsertRows
+	if len(columns) > 0 && maxInsertParameters/len(columns) < batchSize {
+		batchSize = maxInsertParameters / len(columns)
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	return &BatchInsert{
+		table:     table,
+		columns:   insertColumns,
+		skip:      skip,
+		decimals:  decimals,
+		identity:  identity,
+		db:        db,
+		batchSize: batchSize,
+	}
+}
+
+func (bi *BatchInsert) Insert(ctx context.Context, row []interface{}) error {
+	for i := range row {
+		if b, ok := row[i].([]uint8); ok && bi.decimals[i] {
+			row[i] = string(b)
+		}
+	}
+
+	bi.rows = append(bi.rows, row)
+	if len(bi.rows) >= bi.batchSize {
+		return bi.Commit(ctx)
+	}
+
+	return nil
+}
+
+// InsertBatch buffers the rows, inserting them whenever a statement is full like Insert
+func (bi *BatchInsert) InsertBatch(ctx context.Context, rows [][]interface{}) error {
+	for i, row := range rows {
+		err := bi.Insert(ctx, row)
+		if err != nil {
+			// the rows that were not given yet are pending as well
+			bi.rows = append(bi.rows, rows[i+1:]...)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Commit inserts the buffered rows in a single statement
+func (bi *BatchInsert) Commit(ctx context.Context) error {
+	if len(bi.rows) == 0 {
+		return nil
+	}
+
+	query := batchInsertStatement(bi.table, bi.columns, len(bi.rows))
+	if bi.identity {
+		query = fmt.Sprintf("SET IDENTITY_INSERT %s ON; %s SET IDENTITY_INSERT %s OFF;", bi.table, query, bi.table)
+	}
+
+	args := make([]interface{}, 0, len(bi.rows)*len(bi.columns))
+	for _, row := range bi.rows {
+		if bi.skip >= 0 {
+			args = append(append(args, row[:bi.skip]...), row[bi.skip+1:]...)
+		} else {
+			args = append(args, row...)
+		}
+	}
+
+	ctx, span := startSpan(ctx, "insert batch", append(bi.spanAttributes, attribute.Int("asqlcp.rows", len(bi.rows))))
+	// the statement commits on its own
+	_, err := bi.db.ExecContext(ctx, query, args...)
+	if endSpan(span, err) != nil {
+		return commitError(err)
+	}
+
+	bi.rows = bi.rows[:0]
+
+	if bi.onCommit != nil {
+		return bi.onCommit()
+	}
+
+	return nil
+}
+
+// Pending returns the buffered rows, which are the rows of the failed statement after an error
+func (bi *BatchInsert) Pending() [][]interface{} {
+	return bi.rows
+}
+
+// Rollback discards the buffered rows, committed batches are kept
+func (bi *BatchInsert) Rollback(ctx context.Context) error {
+	bi.rows = nil
+	return nil
+}
+
+// OnCommit registers fn to be called after every committed batch
+func (bi *BatchInsert) OnCommit(fn func() error) {
+	bi.onCommit = fn
+}
+
+func batchInsertStatement(table TableRef, columns []string, rowCount int) string {
+	quoter := mssql.TSQLQuoter{}
+
+	quotedColumns := make([]string, len(columns))
+	for i, column := range columns {
+		quotedColumns[i] = quoter.ID(column)
+	}
+
+	values := make([]string, rowCount)
+	parameter := 1
+	for i := range values {
+		parameters := make([]string, len(columns))
+		for j := range parameters {
+			parameters[j] = fmt.Sprintf("@p%d", parameter)
+			parameter++
+		}
+		values[i] = fmt.Sprintf("(%s)", strings.Join(parameters, ", "))
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", table, strings.Join(quotedColumns, ", "), strings.Join(values, ", "))
+}
+
+// InsertSelect copies the rows of sourceTable from source into table with a single INSERT ... SELECT on the target server,
+// which requires the source database to be on the same server as the target database. The server generates the values of an
+// identity column in columns unless keepIdentity is set.
+func (db *MSSQLDB) InsertSelect(ctx context.Context, source *MSSQLDB, sourceTable, table TableRef, columns []string, opts ReadOptions, keepIdentity bool) (int64, error) {
+	ctx, span := db.startSpan(ctx, "insert select", table)
+	rows, err := db.insertSelect(ctx, source, sourceTable, table, columns, opts, keepIdentity)
+	span.SetAttributes(attribute.Int64("asqlcp.rows", rows))
+	return rows, endSpan(span, err)
+}
+
+func (db *MSSQLDB) insertSelect(ctx context.Context, source *MSSQLDB, sourceTable, table TableRef, columns []string, opts ReadOptions, keepIdentity bool) (int64, error) {
+	if !strings.EqualFold(db.host, source.host) {
+		return 0, fmt.Errorf("insert-select requires the source database to be on the target server %s, not %s", db.host, source.host)
+	}
+
+	where, err := opts.where()
+	if err != nil {
+		return 0, err
+	}
+
+	schema, err := db.GetSchemaDefinition(ctx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	query := insertSelectStatement(table, source.database, sourceTable, columns, where, schema, keepIdentity)
+
+	result, err := db.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// insertSelectStatement returns the INSERT ... SELECT of the rows of sourceTable in sourceDatabase matching where into table, an identity
+// column is inserted with IDENTITY_INSERT when keepIdentity is set and left out otherwise
+func insertSelectStatement(table TableRef, sourceDatabase string, sourceTable TableRef, columns []string, where string, schema SchemaDefinition, keepIdentity bool) string {
+	quoter := mssql.TSQLQuoter{}
+	identity := false
+	quotedColumns := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if schema[column].Identity {
+			identity = keepIdentity
+			if !keepIdentity {
+				continue
+			}
+		}
+		quotedColumns = append(quotedColumns, quoter.ID(column))
+	}
+	columnList := strings.Join(quotedColumns, ", ")
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s.%s WHERE %s;", table, columnList, columnList, quoter.ID(sourceDatabase), sourceTable, where)
+	if identity {
+		query = fmt.Sprintf("SET IDENTITY_INSERT %s ON; %s SET IDENTITY_INSERT %s OFF;", table, query, table)
+	}
+
+	return query
+}
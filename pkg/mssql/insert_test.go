@@ -0,0 +1,64 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchInsertStatement(t *testing.T) {
+	statement := batchInsertStatement(TableRef{Schema: "dbo", Table: "orders"}, []string{"id", "status"}, 2)
+	assert.Equal(t, "INSERT INTO [dbo].[orders] ([id], [status]) VALUES (@p1, @p2), (@p3, @p4);", statement)
+}
+
+// recordingQuerier records the statements and their arguments
+type recordingQuerier struct {
+	querier
+	queries []string
+	args    [][]any
+}
+
+func (q *recordingQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	q.queries = append(q.queries, query)
+	q.args = append(q.args, args)
+	return driver.RowsAffected(len(args)), nil
+}
+
+func TestBatchInsertIdentity(t *testing.T) {
+	orders := TableRef{Schema: "dbo", Table: "orders"}
+	schema := SchemaDefinition{
+		"id":     {Name: "id", Type: "int", Identity: true},
+		"status": {Name: "status", Type: "nvarchar"},
+	}
+	columns := []string{"id", "status"}
+
+	// the table falls back to INSERT statements from a bulk copy, which generates new identity values without KeepIdentity
+	q := &recordingQuerier{}
+	bi := newBatchInsert(orders, columns, schema, false, q)
+	require.NoError(t, bi.InsertBatch(context.Background(), [][]interface{}{{1, "open"}, {2, "closed"}}))
+	require.NoError(t, bi.Commit(context.Background()))
+	assert.Equal(t, []string{"INSERT INTO [dbo].[orders] ([status]) VALUES (@p1), (@p2);"}, q.queries)
+	assert.Equal(t, [][]any{{"open", "closed"}}, q.args)
+
+	q = &recordingQuerier{}
+	bi = newBatchInsert(orders, columns, schema, true, q)
+	require.NoError(t, bi.InsertBatch(context.Background(), [][]interface{}{{1, "open"}}))
+	require.NoError(t, bi.Commit(context.Background()))
+	assert.Equal(t, []string{"SET IDENTITY_INSERT [dbo].[orders] ON; INSERT INTO [dbo].[orders] ([id], [status]) VALUES (@p1, @p2); SET IDENTITY_INSERT [dbo].[orders] OFF;"}, q.queries)
+	assert.Equal(t, [][]any{{1, "open"}}, q.args)
+}
+
+func TestInsertSelectStatement(t *testing.T) {
+	orders := TableRef{Schema: "dbo", Table: "orders"}
+	schema := SchemaDefinition{"id": {Name: "id", Type: "int", Identity: true}, "status": {Name: "status", Type: "nvarchar"}}
+	columns := []string{"id", "status"}
+
+	assert.Equal(t, "INSERT INTO [dbo].[orders] ([status]) SELECT [status] FROM [sales].[dbo].[orders] WHERE 1=1;",
+		insertSelectStatement(orders, "sales", orders, columns, "1=1", schema, false))
+	assert.Equal(t, "SET IDENTITY_INSERT [dbo].[orders] ON; INSERT INTO [dbo].[orders] ([id], [status]) SELECT [id], [status] FROM [sales].[dbo].[orders] WHERE 1=1; SET IDENTITY_INSERT [dbo].[orders] OFF;",
+		insertSelectStatement(orders, "sales", orders, columns, "1=1", schema, true))
+}
@@ -0,0 +1,72 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ReadIsolation determines how the reads of the source rows interact with concurrent writes
+type ReadIsolation string
+
+const (
+	// ReadCommitted reads with the isolation of the connection, READ COMMITTED by default, which waits for the locks of writers
+	ReadCommitted ReadIsolation = "committed"
+	// ReadNoLock reads with the NOLOCK table hint, which neither waits for nor takes locks but can read uncommitted,
+	// missing or duplicate rows
+	ReadNoLock ReadIsolation = "nolock"
+	// ReadPast reads with the READPAST table hint, which skips the rows locked by writers
+	ReadPast ReadIsolation = "readpast"
+	// ReadSnapshot reads every table in a SNAPSHOT isolation transaction of its own, a consistent view of the table
+	// that does not wait for writers. The database has to allow snapshot isolation.
+	ReadSnapshot ReadIsolation = "snapshot"
+)
+
+func ParseReadIsolation(isolation string) (ReadIsolation, error) {
+	switch ReadIsolation(isolation) {
+	case ReadCommitted, ReadNoLock, ReadPast, ReadSnapshot:
+		return ReadIsolation(isolation), nil
+	case "":
+		return ReadCommitted, nil
+	}
+
+	return "", fmt.Errorf("unknown read isolation %q, expected committed, nolock, readpast or snapshot", isolation)
+}
+
+// tableHint returns the WITH clause of the table hint of the isolation
+func (i ReadIsolation) tableHint() string {
+	switch i {
+	case ReadNoLock:
+		return " WITH (NOLOCK)"
+	case ReadPast:
+		return " WITH (READPAST)"
+	}
+
+	return ""
+}
+
+// snapshotReader returns the querier of the reads of a table, a new SNAPSHOT transaction for ReadSnapshot unless the reads
+// already run in the snapshot transaction of Snapshot. The transaction is nil when none is started.
+func (db *MSSQLDB) snapshotReader(ctx context.Context, isolation ReadIsolation) (querier, *sql.Tx, error) {
+	if isolation != ReadSnapshot || db.tx != nil {
+		return db.reader, nil, nil
+	}
+
+	if !db.info.SnapshotIsolation {
+		return nil, nil, fmt.Errorf("snapshot isolation is not allowed on the database, enable it with ALTER DATABASE ... SET ALLOW_SNAPSHOT_ISOLATION ON")
+	}
+
+	tx, err := db.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSnapshot})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return loggedQuerier{tx, db.database}, tx, nil
+}
+
+// rollback ends a transaction of snapshotReader when the read fails to start
+func rollback(tx *sql.Tx) {
+	if tx != nil {
+		tx.Rollback()
+	}
+}
@@ -0,0 +1,37 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReadIsolation(t *testing.T) {
+	isolation, err := ParseReadIsolation("")
+	assert.NoError(t, err)
+	assert.Equal(t, ReadCommitted, isolation)
+
+	isolation, err = ParseReadIsolation("nolock")
+	assert.NoError(t, err)
+	assert.Equal(t, ReadNoLock, isolation)
+
+	_, err = ParseReadIsolation("uncommitted")
+	assert.Error(t, err)
+}
+
+func TestSelectQueryIsolation(t *testing.T) {
+	orders := TableRef{Schema: "dbo", Table: "orders"}
+
+	query, err := selectQuery(orders, []string{"[id]"}, ReadOptions{Isolation: ReadNoLock, SamplePercent: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT [id] FROM [dbo].[orders] TABLESAMPLE (10 PERCENT) WITH (NOLOCK) WHERE 1=1", query)
+
+	query, err = selectQuery(orders, []string{"[id]"}, ReadOptions{Isolation: ReadPast})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT [id] FROM [dbo].[orders] WITH (READPAST) WHERE 1=1", query)
+
+	// snapshot reads run in a transaction instead
+	query, err = selectQuery(orders, []string{"[id]"}, ReadOptions{Isolation: ReadSnapshot})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT [id] FROM [dbo].[orders] WHERE 1=1", query)
+}
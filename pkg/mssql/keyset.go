@@ -0,0 +1,73 @@
+package mssql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// keysetTypes are the data types whose values round trip through a string literal with the same ordering,
+// only keys made of these types can be used to continue reading after a given row
+var keysetTypes = map[string]bool{
+	"tinyint": true, "smallint": true, "int": true, "bigint": true,
+	"decimal": true, "numeric": true,
+	"char": true, "varchar": true, "nchar": true, "nvarchar": true,
+	"date": true, "datetime2": true,
+}
+
+// KeysetSupported reports whether all key columns have a type that can be used to continue reading after a row
+func KeysetSupported(schema SchemaDefinition, key []string) bool {
+	if len(key) == 0 {
+		return false
+	}
+
+	for _, column := range key {
+		if !keysetTypes[schema[column].Type] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// KeyValue converts a value read from a key column to the literal used to continue reading after it
+func KeyValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("key values can not be NULL")
+	case string:
+		return v, nil
+	case []uint8:
+		return string(v), nil
+	case int64:
+		return fmt.Sprintf("%d", v), nil
+	case time.Time:
+		return v.Format("2006-01-02T15:04:05.9999999"), nil
+	}
+
+	return "", fmt.Errorf("unsupported key value of type %T", value)
+}
+
+// keysetPredicate selects the rows ordered after the given key values, ( a > 1 ) OR ( a = 1 AND b > 2 )
+func keysetPredicate(columns []string, after []string) (string, error) {
+	if len(columns) != len(after) {
+		return "", fmt.Errorf("expected %d key values, got %d", len(columns), len(after))
+	}
+
+	quoter := mssql.TSQLQuoter{}
+
+	alternatives := make([]string, len(columns))
+	for i := range columns {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = %s", quoter.ID(columns[j]), quoter.Value(after[j])))
+		}
+		parts = append(parts, fmt.Sprintf("%s > %s", quoter.ID(columns[i]), quoter.Value(after[i])))
+
+		alternatives[i] = fmt.Sprintf("( %s )", strings.Join(parts, " AND "))
+	}
+
+	return strings.Join(alternatives, " OR "), nil
+}
@@ -0,0 +1,66 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// lockResourcePrefix namespaces the application locks taken by a copy run
+const lockResourcePrefix = "asqlcp:"
+
+// RunLock holds exclusive application locks on a set of tables for as long as its connection is open
+type RunLock struct {
+	conn      *sql.Conn
+	resources []string
+}
+
+// LockTables takes an exclusive application lock per table, so concurrent runs can not copy into the same tables.
+// It fails immediately when another run holds a lock on one of the tables.
+func (db *MSSQLDB) LockTables(ctx context.Context, tables []TableRef) (*RunLock, error) {
+	resources := make([]string, len(tables))
+	for i, table := range tables {
+		resources[i] = lockResourcePrefix + table.String()
+	}
+	// a fixed order prevents two runs from deadlocking on each other's locks
+	sort.Strings(resources)
+
+	// session locks are bound to a connection and released when it closes
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &RunLock{conn: conn}
+	for _, resource := range resources {
+		var result int
+		err := conn.QueryRowContext(ctx, `
+		DECLARE @result int;
+		EXEC @result = sp_getapplock @Resource = @resource, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0;
+		SELECT @result`, sql.Named("resource", resource)).Scan(&result)
+		if err == nil && result < 0 {
+			err = fmt.Errorf("%s is locked by another copy run", resource[len(lockResourcePrefix):])
+		}
+		if err != nil {
+			lock.Release()
+			return nil, err
+		}
+
+		lock.resources = append(lock.resources, resource)
+	}
+
+	return lock, nil
+}
+
+func (l *RunLock) Release() error {
+	for _, resource := range l.resources {
+		_, err := l.conn.ExecContext(context.Background(), "EXEC sp_releaseapplock @Resource = @resource, @LockOwner = 'Session'", sql.Named("resource", resource))
+		if err != nil {
+			// closing the connection releases the locks as well
+			break
+		}
+	}
+
+	return l.conn.Close()
+}
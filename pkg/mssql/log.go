@@ -0,0 +1,94 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// execStatement executes a statement emptying a table or changing its foreign keys and logs it, those are the statements
+// to look for when a target is left in an unexpected state
+func (db *MSSQLDB) execStatement(ctx context.Context, query string) error {
+	_, err := db.db.ExecContext(ctx, query)
+	if err != nil {
+		slog.WarnContext(ctx, "statement failed", "database", db.database, "statement", query, "error", err)
+		return err
+	}
+
+	slog.InfoContext(ctx, "executed statement", "database", db.database, "statement", query)
+
+	return nil
+}
+
+// loggedDB is the connection pool of a database, of which the statements are logged at debug level
+type loggedDB struct {
+	*sql.DB
+	database string
+}
+
+func (db *loggedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return loggedQuerier{db.DB, db.database}.ExecContext(ctx, query, args...)
+}
+
+func (db *loggedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return loggedQuerier{db.DB, db.database}.QueryContext(ctx, query, args...)
+}
+
+func (db *loggedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return loggedQuerier{db.DB, db.database}.QueryRowContext(ctx, query, args...)
+}
+
+// loggedQuerier logs the statements run by a querier at debug level with their duration, and the number of rows affected by
+// the statements changing rows. The rows read by a select are logged when its RowIterator ends.
+type loggedQuerier struct {
+	querier
+	database string
+}
+
+func (q loggedQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	result, err := q.querier.ExecContext(ctx, query, args...)
+	if err != nil {
+		logStatement(ctx, q.database, query, start, "error", err)
+		return result, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		rows = -1
+	}
+	logStatement(ctx, q.database, query, start, "rows", rows)
+
+	return result, nil
+}
+
+func (q loggedQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := q.querier.QueryContext(ctx, query, args...)
+	if err != nil {
+		logStatement(ctx, q.database, query, start, "error", err)
+		return rows, err
+	}
+	logStatement(ctx, q.database, query, start)
+
+	return rows, nil
+}
+
+func (q loggedQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := q.querier.QueryRowContext(ctx, query, args...)
+	logStatement(ctx, q.database, query, start)
+
+	return row
+}
+
+// logStatement logs query at debug level with the time since start and attrs, like the rows it affected or its error
+func logStatement(ctx context.Context, database, query string, start time.Time, attrs ...any) {
+	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	took := time.Since(start)
+	slog.DebugContext(ctx, "statement", append([]any{"database", database, "statement", query, "duration", took}, attrs...)...)
+}
@@ -0,0 +1,69 @@
+package mssql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// execQuerier returns result or err for every statement
+type execQuerier struct {
+	querier
+	result sql.Result
+	err    error
+}
+
+func (q execQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return q.result, q.err
+}
+
+func TestLoggedQuerier(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(defaultLogger)
+
+	ctx := context.Background()
+	_, err := loggedQuerier{execQuerier{result: driver.RowsAffected(3)}, "sales"}.ExecContext(ctx, "DELETE FROM [dbo].[orders]")
+	require.NoError(t, err)
+	_, err = loggedQuerier{execQuerier{err: errors.New("deadlock")}, "sales"}.ExecContext(ctx, "TRUNCATE TABLE dbo.lines")
+	require.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var records []map[string]interface{}
+	for _, line := range lines {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, "DEBUG", record["level"])
+		assert.Equal(t, "sales", record["database"])
+		assert.Contains(t, record, "duration")
+		records = append(records, record)
+	}
+
+	assert.Equal(t, "DELETE FROM [dbo].[orders]", records[0]["statement"])
+	assert.Equal(t, float64(3), records[0]["rows"])
+	assert.Equal(t, "TRUNCATE TABLE dbo.lines", records[1]["statement"])
+	assert.Equal(t, "deadlock", records[1]["error"])
+}
+
+func TestLoggedQuerierAtInfoLevel(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	defer slog.SetDefault(defaultLogger)
+
+	_, err := loggedQuerier{execQuerier{result: driver.RowsAffected(3)}, "sales"}.ExecContext(context.Background(), "DELETE FROM [dbo].[orders]")
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,149 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// stagingTablePrefix is prepended to the name of tables created to stage rows before they are merged into the target
+const stagingTablePrefix = "__asqlcp_stage_"
+
+// GetPrimaryKey returns the primary key columns of table in key order, the result is cached
+func (db *MSSQLDB) GetPrimaryKey(ctx context.Context, table TableRef) ([]string, error) {
+	db.schemaDefLock.Lock()
+	primaryKey, ok := db.primaryKeys[table.String()]
+	db.schemaDefLock.Unlock()
+	if ok {
+		return primaryKey, nil
+	}
+
+	primaryKey, err := db.getPrimaryKey(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	db.schemaDefLock.Lock()
+	db.primaryKeys[table.String()] = primaryKey
+	db.schemaDefLock.Unlock()
+
+	return primaryKey, nil
+}
+
+func (db *MSSQLDB) getPrimaryKey(ctx context.Context, table TableRef) ([]string, error) {
+	query := `
+	SELECT c.name
+	FROM sys.indexes i
+	INNER JOIN sys.index_columns ic ON i.object_id = ic.object_id AND i.index_id = ic.index_id
+	INNER JOIN sys.columns c ON ic.object_id = c.object_id AND ic.column_id = c.column_id
+	WHERE i.is_primary_key = 1
+	AND i.object_id = OBJECT_ID(@table)
+	ORDER BY ic.key_ordinal
+	`
+	rows, err := db.db.QueryContext(ctx, query, sql.Named("table", table.String()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]string, 0)
+	for rows.Next() {
+		var column string
+		err := rows.Scan(&column)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+func (db *MSSQLDB) hasIdentity(ctx context.Context, table TableRef) (bool, error) {
+	var hasIdentity sql.NullInt64
+	err := db.db.QueryRowContext(ctx, "SELECT OBJECTPROPERTY(OBJECT_ID(@table), 'TableHasIdentity')", sql.Named("table", table.String())).Scan(&hasIdentity)
+	if err != nil {
+		return false, err
+	}
+
+	return hasIdentity.Int64 == 1, nil
+}
+
+// CreateStagingTable creates an empty copy of table, without identity columns, to bulk load rows into before merging them
+func (db *MSSQLDB) CreateStagingTable(ctx context.Context, table TableRef) (TableRef, error) {
+	staging := TableRef{Schema: table.Schema, Table: stagingTablePrefix + table.Table}
+
+	err := db.DropTable(ctx, staging)
+	if err != nil {
+		return TableRef{}, err
+	}
+
+	// the UNION ALL prevents SELECT INTO from copying the IDENTITY property
+	query := fmt.Sprintf("SELECT TOP 0 * INTO %s FROM %s UNION ALL SELECT TOP 0 * FROM %s", staging, table, table)
+	_, err = db.db.ExecContext(ctx, query)
+	if err != nil {
+		return TableRef{}, err
+	}
+
+	return staging, nil
+}
+
+func (db *MSSQLDB) DropTable(ctx context.Context, table TableRef) error {
+	_, err := db.db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+	return err
+}
+
+// Merge updates the rows of target that match a row in staging on the key columns and inserts the others
+func (db *MSSQLDB) Merge(ctx context.Context, staging, target TableRef, columns []string, keys []string) error {
+	ctx, span := db.startSpan(ctx, "merge", target)
+	return endSpan(span, db.merge(ctx, staging, target, columns, keys))
+}
+
+func (db *MSSQLDB) merge(ctx context.Context, staging, target TableRef, columns []string, keys []string) error {
+	hasIdentity, err := db.hasIdentity(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	query := mergeStatement(staging, target, columns, keys)
+	if hasIdentity {
+		query = fmt.Sprintf("SET IDENTITY_INSERT %s ON; %s SET IDENTITY_INSERT %s OFF;", target, query, target)
+	}
+
+	_, err = db.db.ExecContext(ctx, query)
+	return err
+}
+
+func mergeStatement(staging, target TableRef, columns []string, keys []string) string {
+	quoter := mssql.TSQLQuoter{}
+
+	isKey := make(map[string]bool, len(keys))
+	conditions := make([]string, len(keys))
+	for i, key := range keys {
+		isKey[key] = true
+		conditions[i] = fmt.Sprintf("t.%s = s.%s", quoter.ID(key), quoter.ID(key))
+	}
+
+	quotedColumns := make([]string, len(columns))
+	sourceColumns := make([]string, len(columns))
+	updates := make([]string, 0, len(columns))
+	for i, column := range columns {
+		quotedColumns[i] = quoter.ID(column)
+		sourceColumns[i] = "s." + quoter.ID(column)
+		if !isKey[column] {
+			updates = append(updates, fmt.Sprintf("t.%s = s.%s", quoter.ID(column), quoter.ID(column)))
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("MERGE %s AS t USING %s AS s ON %s", target, staging, strings.Join(conditions, " AND ")))
+	if len(updates) > 0 {
+		sb.WriteString(fmt.Sprintf(" WHEN MATCHED THEN UPDATE SET %s", strings.Join(updates, ", ")))
+	}
+	sb.WriteString(fmt.Sprintf(" WHEN NOT MATCHED BY TARGET THEN INSERT (%s) VALUES (%s);", strings.Join(quotedColumns, ", "), strings.Join(sourceColumns, ", ")))
+
+	return sb.String()
+}
@@ -0,0 +1,23 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeStatement(t *testing.T) {
+	target := TableRef{Schema: "dbo", Table: "orders"}
+	staging := TableRef{Schema: "dbo", Table: stagingTablePrefix + "orders"}
+
+	query := mergeStatement(staging, target, []string{"id", "status"}, []string{"id"})
+	assert.Equal(t, "MERGE [dbo].[orders] AS t USING [dbo].[__asqlcp_stage_orders] AS s ON t.[id] = s.[id] WHEN MATCHED THEN UPDATE SET t.[status] = s.[status] WHEN NOT MATCHED BY TARGET THEN INSERT ([id], [status]) VALUES (s.[id], s.[status]);", query)
+}
+
+func TestMergeStatementKeyOnly(t *testing.T) {
+	target := TableRef{Schema: "dbo", Table: "link"}
+	staging := TableRef{Schema: "dbo", Table: stagingTablePrefix + "link"}
+
+	query := mergeStatement(staging, target, []string{"a", "b"}, []string{"a", "b"})
+	assert.Equal(t, "MERGE [dbo].[link] AS t USING [dbo].[__asqlcp_stage_link] AS s ON t.[a] = s.[a] AND t.[b] = s.[b] WHEN NOT MATCHED BY TARGET THEN INSERT ([a], [b]) VALUES (s.[a], s.[b]);", query)
+}
@@ -0,0 +1,109 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// pageReader reads the rows of a paged read with a query per page, see ReadOptions.PageSize
+type pageReader struct {
+	// reader runs the queries of the pages
+	reader        querier
+	ctx           context.Context
+	table         TableRef
+	quotedColumns []string
+	opts          ReadOptions
+
+	// keyIndexes are the positions of the OrderBy columns in the selected columns
+	keyIndexes []int
+	// lastKey is the key of the last row read, the next page starts after it
+	lastKey []string
+	// limit is the number of rows requested for the current page, count the number read of it and total the number read of all pages
+	limit int
+	count int
+	total int
+}
+
+func newPageReader(ctx context.Context, reader querier, table TableRef, columns, quotedColumns []string, opts ReadOptions) (*pageReader, error) {
+	if len(opts.OrderBy) == 0 {
+		return nil, fmt.Errorf("paged reads of table %s require a key to order by", table)
+	}
+	if opts.SamplePercent > 0 {
+		return nil, fmt.Errorf("paged reads of table %s can not be sampled", table)
+	}
+
+	keyIndexes := make([]int, len(opts.OrderBy))
+	for i, keyColumn := range opts.OrderBy {
+		keyIndexes[i] = -1
+		for j, column := range columns {
+			if column == keyColumn {
+				keyIndexes[i] = j
+				break
+			}
+		}
+
+		if keyIndexes[i] < 0 {
+			return nil, fmt.Errorf("key column %s of the paged read of table %s is not selected", keyColumn, table)
+		}
+	}
+
+	return &pageReader{
+		reader:        reader,
+		ctx:           ctx,
+		table:         table,
+		quotedColumns: quotedColumns,
+		opts:          opts,
+		keyIndexes:    keyIndexes,
+		lastKey:       opts.After,
+	}, nil
+}
+
+// pageOptions returns the read options of the next page
+func (p *pageReader) pageOptions() ReadOptions {
+	opts := p.opts
+	opts.Limit = p.opts.PageSize
+	if p.opts.Limit > 0 && p.opts.Limit-p.total < opts.Limit {
+		opts.Limit = p.opts.Limit - p.total
+	}
+	opts.After = p.lastKey
+
+	return opts
+}
+
+// query selects the next page
+func (p *pageReader) query() (*sql.Rows, error) {
+	opts := p.pageOptions()
+	query, err := selectQuery(p.table, p.quotedColumns, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p.limit = opts.Limit
+	p.count = 0
+
+	return p.reader.QueryContext(p.ctx, query)
+}
+
+// more reports whether there may be rows after the current page, a page returning fewer rows than requested is the last one
+func (p *pageReader) more() bool {
+	return p.count == p.limit && (p.opts.Limit == 0 || p.total < p.opts.Limit)
+}
+
+// track records the key of a row read
+func (p *pageReader) track(row []interface{}) error {
+	key := make([]string, len(p.keyIndexes))
+	for i, index := range p.keyIndexes {
+		value, err := KeyValue(row[index])
+		if err != nil {
+			return err
+		}
+		key[i] = value
+	}
+
+	p.lastKey = key
+	p.count++
+	p.total++
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package mssql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageReader(t *testing.T) {
+	orders := TableRef{Schema: "dbo", Table: "orders"}
+
+	_, err := newPageReader(context.Background(), nil, orders, []string{"id"}, []string{"[id]"}, ReadOptions{PageSize: 2})
+	assert.Error(t, err)
+	_, err = newPageReader(context.Background(), nil, orders, []string{"total"}, []string{"[total]"}, ReadOptions{PageSize: 2, OrderBy: []string{"id"}})
+	assert.Error(t, err)
+
+	page, err := newPageReader(context.Background(), nil, orders, []string{"total", "id"}, []string{"[total]", "[id]"}, ReadOptions{PageSize: 2, OrderBy: []string{"id"}, Limit: 3})
+	assert.NoError(t, err)
+
+	query, err := selectQuery(orders, page.quotedColumns, page.pageOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT TOP (2) [total], [id] FROM [dbo].[orders] WHERE 1=1 ORDER BY [id]", query)
+
+	page.limit = 2
+	assert.NoError(t, page.track([]interface{}{"10", int64(1)}))
+	assert.NoError(t, page.track([]interface{}{"20", int64(2)}))
+	assert.True(t, page.more())
+
+	// the next page continues after the last row and stops at the limit
+	query, err = selectQuery(orders, page.quotedColumns, page.pageOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT TOP (1) [total], [id] FROM [dbo].[orders] WHERE ( ( [id] > '2' ) ) ORDER BY [id]", query)
+
+	page.limit, page.count = 1, 0
+	assert.NoError(t, page.track([]interface{}{"30", int64(3)}))
+	assert.False(t, page.more())
+
+	// a page with fewer rows than requested is the last one
+	page, err = newPageReader(context.Background(), nil, orders, []string{"id"}, []string{"[id]"}, ReadOptions{PageSize: 2, OrderBy: []string{"id"}})
+	assert.NoError(t, err)
+	page.limit = 2
+	assert.NoError(t, page.track([]interface{}{int64(1)}))
+	assert.False(t, page.more())
+}
@@ -0,0 +1,128 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+var integerTypes = map[string]bool{"tinyint": true, "smallint": true, "int": true, "bigint": true}
+
+// PartitionBoundaries returns the upper bounds of the first parts-1 of parts ranges of the values of column in the rows selected by opts,
+// the last range holds the values above the last bound. Integer columns are split into ranges of equal width, other columns
+// into ranges of about the same number of rows. Fewer bounds are returned when the column has too few distinct values.
+func (db *MSSQLDB) PartitionBoundaries(ctx context.Context, table TableRef, column string, parts int, opts ReadOptions) ([]string, error) {
+	schema, err := db.GetSchemaDefinition(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	definition, ok := schema[column]
+	if !ok {
+		return nil, fmt.Errorf("column %s does not exist in table %s", column, table)
+	}
+	if definition.Nullable || !keysetTypes[definition.Type] {
+		return nil, fmt.Errorf("column %s of table %s can not be partitioned on, it has to be a NOT NULL number, text, date or datetime2 column", column, table)
+	}
+
+	where, err := opts.where()
+	if err != nil {
+		return nil, err
+	}
+
+	if integerTypes[definition.Type] {
+		var low, high sql.NullInt64
+		err = db.reader.QueryRowContext(ctx, partitionRangeQuery(table, column, where)).Scan(&low, &high)
+		if err != nil {
+			return nil, err
+		}
+		if !low.Valid {
+			return []string{}, nil
+		}
+
+		return integerBoundaries(low.Int64, high.Int64, parts), nil
+	}
+
+	rows, err := db.reader.QueryContext(ctx, partitionTilesQuery(table, column, where), sql.Named("parts", parts))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boundaries := make([]string, 0, parts)
+	for rows.Next() {
+		var value interface{}
+		err := rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+
+		boundary, err := KeyValue(value)
+		if err != nil {
+			return nil, err
+		}
+		boundaries = append(boundaries, boundary)
+	}
+
+	// the last tile ends at the highest value
+	if len(boundaries) > 0 {
+		boundaries = boundaries[:len(boundaries)-1]
+	}
+
+	return boundaries, rows.Err()
+}
+
+func partitionRangeQuery(table TableRef, column, where string) string {
+	quoter := mssql.TSQLQuoter{}
+	return fmt.Sprintf("SELECT MIN(%s), MAX(%s) FROM %s WHERE %s", quoter.ID(column), quoter.ID(column), table, where)
+}
+
+func partitionTilesQuery(table TableRef, column, where string) string {
+	quoter := mssql.TSQLQuoter{}
+	return fmt.Sprintf("SELECT MAX(v) FROM (SELECT %s AS v, NTILE(@parts) OVER (ORDER BY %s) AS tile FROM %s WHERE %s) AS tiles GROUP BY tile ORDER BY tile",
+		quoter.ID(column), quoter.ID(column), table, where)
+}
+
+// integerBoundaries splits low to high into parts ranges of equal width
+func integerBoundaries(low, high int64, parts int) []string {
+	// the number of values does not fit an int64 for the extremes of bigint, the unsigned arithmetic wraps around correctly
+	width := (uint64(high-low) + 1) / uint64(parts)
+	if uint64(high-low)+1 == 0 {
+		width = math.MaxUint64 / uint64(parts)
+	}
+	if width == 0 {
+		width = 1
+	}
+
+	boundaries := make([]string, 0, parts-1)
+	for i := 1; i < parts; i++ {
+		boundary := low + int64(width*uint64(i)) - 1
+		if boundary >= high {
+			break
+		}
+		boundaries = append(boundaries, strconv.FormatInt(boundary, 10))
+	}
+
+	return boundaries
+}
+
+// PartitionConditions returns the conditions selecting the ranges ending at the boundaries of PartitionBoundaries, and the range after them
+func PartitionConditions(column string, boundaries []string) [][]Condition {
+	ranges := make([][]Condition, 0, len(boundaries)+1)
+	for i := 0; i <= len(boundaries); i++ {
+		conditions := make([]Condition, 0, 2)
+		if i > 0 {
+			conditions = append(conditions, Condition{Column: column, Operator: ">", Value: boundaries[i-1]})
+		}
+		if i < len(boundaries) {
+			conditions = append(conditions, Condition{Column: column, Operator: "<=", Value: boundaries[i]})
+		}
+		ranges = append(ranges, conditions)
+	}
+
+	return ranges
+}
@@ -0,0 +1,34 @@
+package mssql
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegerBoundaries(t *testing.T) {
+	assert.Equal(t, []string{"25", "50", "75"}, integerBoundaries(1, 100, 4))
+	assert.Equal(t, []string{"-2", "0"}, integerBoundaries(-3, 2, 3))
+	// fewer values than parts
+	assert.Equal(t, []string{"1"}, integerBoundaries(1, 2, 4))
+	assert.Equal(t, []string{}, integerBoundaries(7, 7, 4))
+	assert.Equal(t, []string{"-2"}, integerBoundaries(math.MinInt64, math.MaxInt64, 2))
+}
+
+func TestPartitionQueries(t *testing.T) {
+	orders := TableRef{Schema: "dbo", Table: "orders"}
+
+	assert.Equal(t, "SELECT MIN([id]), MAX([id]) FROM [dbo].[orders] WHERE 1=1", partitionRangeQuery(orders, "id", "1=1"))
+	assert.Equal(t, "SELECT MAX(v) FROM (SELECT [created_at] AS v, NTILE(@parts) OVER (ORDER BY [created_at]) AS tile FROM [dbo].[orders] WHERE 1=1) AS tiles GROUP BY tile ORDER BY tile",
+		partitionTilesQuery(orders, "created_at", "1=1"))
+}
+
+func TestPartitionConditions(t *testing.T) {
+	assert.Equal(t, [][]Condition{
+		{{Column: "id", Operator: "<=", Value: "25"}},
+		{{Column: "id", Operator: ">", Value: "25"}, {Column: "id", Operator: "<=", Value: "50"}},
+		{{Column: "id", Operator: ">", Value: "50"}},
+	}, PartitionConditions("id", []string{"25", "50"}))
+	assert.Equal(t, [][]Condition{{}}, PartitionConditions("id", []string{}))
+}
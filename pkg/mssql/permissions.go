@@ -0,0 +1,119 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+const (
+	// PermissionObject, PermissionSchema and PermissionDatabase are the classes of securables a PermissionCheck applies to
+	PermissionObject   = "OBJECT"
+	PermissionSchema   = "SCHEMA"
+	PermissionDatabase = "DATABASE"
+)
+
+// permissionChecksPerQuery keeps the parameters of a query below the limit of 2100
+const permissionChecksPerQuery = 500
+
+// PermissionCheck is a permission on a table, a schema or the database, and whether the current user has it
+type PermissionCheck struct {
+	// Class is one of PermissionObject, PermissionSchema or PermissionDatabase, Securable is the table or the schema, empty for the database
+	Class      string
+	Securable  string
+	Permission string
+	// Reason tells what the permission is needed for, without an Optional permission only a feature is unavailable or slower
+	Reason   string
+	Optional bool
+	Granted  bool
+	// Unknown is set when the securable does not exist, like a table the copy creates
+	Unknown bool
+}
+
+// On returns the securable the permission applies to, as shown to the user
+func (c PermissionCheck) On() string {
+	switch c.Class {
+	case PermissionDatabase:
+		return "the database"
+	case PermissionSchema:
+		return "schema " + c.Securable
+	}
+
+	return c.Securable
+}
+
+// Grant returns the statement granting the permission to principal
+func (c PermissionCheck) Grant(principal string) string {
+	quoter := mssql.TSQLQuoter{}
+
+	switch c.Class {
+	case PermissionDatabase:
+		return fmt.Sprintf("GRANT %s TO %s;", c.Permission, quoter.ID(principal))
+	case PermissionSchema:
+		return fmt.Sprintf("GRANT %s ON SCHEMA::%s TO %s;", c.Permission, quoter.ID(c.Securable), quoter.ID(principal))
+	}
+
+	return fmt.Sprintf("GRANT %s ON %s TO %s;", c.Permission, c.Securable, quoter.ID(principal))
+}
+
+// CheckPermissions sets Granted on the checks the current user has a permission for, a securable that does not exist has none
+func (db *MSSQLDB) CheckPermissions(ctx context.Context, checks []PermissionCheck) error {
+	for start := 0; start < len(checks); start += permissionChecksPerQuery {
+		chunk := checks[start:min(start+permissionChecksPerQuery, len(checks))]
+
+		query, args := permissionsQuery(chunk)
+		rows, err := db.reader.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var i int
+			var granted sql.NullInt64
+			err = rows.Scan(&i, &granted)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			chunk[i].Granted = granted.Int64 == 1
+			chunk[i].Unknown = !granted.Valid
+		}
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func permissionsQuery(checks []PermissionCheck) (string, []interface{}) {
+	values := make([]string, len(checks))
+	args := make([]interface{}, 0, 3*len(checks))
+	for i, check := range checks {
+		// the database is the current one
+		securable := interface{}(check.Securable)
+		if check.Class == PermissionDatabase {
+			securable = nil
+		}
+
+		values[i] = fmt.Sprintf("(%d, @s%d, @c%d, @p%d)", i, i, i, i)
+		args = append(args, sql.Named(fmt.Sprintf("s%d", i), securable), sql.Named(fmt.Sprintf("c%d", i), check.Class), sql.Named(fmt.Sprintf("p%d", i), check.Permission))
+	}
+
+	query := fmt.Sprintf("SELECT id, HAS_PERMS_BY_NAME(securable, class, permission) FROM (VALUES %s) AS checks(id, securable, class, permission)", strings.Join(values, ", "))
+
+	return query, args
+}
+
+// CurrentUser returns the database user the connection runs as
+func (db *MSSQLDB) CurrentUser(ctx context.Context) (string, error) {
+	var user string
+	err := db.reader.QueryRowContext(ctx, "SELECT USER_NAME()").Scan(&user)
+
+	return user, err
+}
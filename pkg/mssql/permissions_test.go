@@ -0,0 +1,27 @@
+package mssql
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionGrant(t *testing.T) {
+	assert.Equal(t, "GRANT SELECT ON [dbo].[orders] TO [copy user];", PermissionCheck{Class: PermissionObject, Securable: "[dbo].[orders]", Permission: "SELECT"}.Grant("copy user"))
+	assert.Equal(t, "GRANT ALTER ON SCHEMA::[sales] TO [loader];", PermissionCheck{Class: PermissionSchema, Securable: "sales", Permission: "ALTER"}.Grant("loader"))
+	assert.Equal(t, "GRANT CREATE TABLE TO [loader];", PermissionCheck{Class: PermissionDatabase, Permission: "CREATE TABLE"}.Grant("loader"))
+}
+
+func TestPermissionsQuery(t *testing.T) {
+	query, args := permissionsQuery([]PermissionCheck{
+		{Class: PermissionObject, Securable: "[dbo].[orders]", Permission: "INSERT"},
+		{Class: PermissionDatabase, Permission: "CREATE TABLE"},
+	})
+
+	assert.Equal(t, "SELECT id, HAS_PERMS_BY_NAME(securable, class, permission) FROM (VALUES (0, @s0, @c0, @p0), (1, @s1, @c1, @p1)) AS checks(id, securable, class, permission)", query)
+	assert.Equal(t, []interface{}{
+		sql.Named("s0", "[dbo].[orders]"), sql.Named("c0", PermissionObject), sql.Named("p0", "INSERT"),
+		sql.Named("s1", nil), sql.Named("c1", PermissionDatabase), sql.Named("p1", "CREATE TABLE"),
+	}, args)
+}
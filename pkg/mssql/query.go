@@ -0,0 +1,139 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// readOnlyStatement matches the statements allowed by ReadOnlyQuery
+var readOnlyStatement = regexp.MustCompile(`(?is)^\s*(SELECT|WITH)\s`)
+
+// writingKeyword matches the keywords of statements that change data or end the transaction, which a SELECT never contains
+var writingKeyword = regexp.MustCompile(`(?i)\b(COMMIT|ROLLBACK|SAVE|BEGIN|EXEC|EXECUTE|GO|INSERT|UPDATE|DELETE|MERGE|TRUNCATE|DROP|ALTER|CREATE|GRANT|REVOKE|DENY|USE|SET|DECLARE|WAITFOR|DBCC|KILL|SHUTDOWN|RECONFIGURE|BACKUP|RESTORE|BULK|OPENROWSET|OPENDATASOURCE|OPENQUERY)\b`)
+
+// checkReadOnly accepts a single SELECT statement, without a ; or a keyword of a statement that could escape the rolled back transaction.
+// Strings, quoted names and comments are left out of the check.
+func checkReadOnly(query string) error {
+	code := stripLiterals(query)
+	if !readOnlyStatement.MatchString(code) {
+		return fmt.Errorf("only SELECT statements are allowed")
+	}
+	if strings.Contains(code, ";") {
+		return fmt.Errorf("only a single statement without ; is allowed")
+	}
+	if keyword := writingKeyword.FindString(code); keyword != "" {
+		return fmt.Errorf("%s is not allowed in a SELECT statement", strings.ToUpper(keyword))
+	}
+
+	return nil
+}
+
+// stripLiterals replaces the strings, quoted names and comments of a batch by a space
+func stripLiterals(query string) string {
+	var code strings.Builder
+	for i := 0; i < len(query); i++ {
+		var end string
+		switch {
+		case query[i] == '\'':
+			end = "'"
+		case query[i] == '"':
+			end = `"`
+		case query[i] == '[':
+			end = "]"
+		case strings.HasPrefix(query[i:], "--"):
+			end = "\n"
+		case strings.HasPrefix(query[i:], "/*"):
+			// block comments nest
+			depth := 0
+			for ; i < len(query); i++ {
+				if strings.HasPrefix(query[i:], "/*") {
+					depth++
+					i++
+				} else if strings.HasPrefix(query[i:], "*/") {
+					depth--
+					i++
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			code.WriteByte(' ')
+			continue
+		default:
+			code.WriteByte(query[i])
+			continue
+		}
+
+		// a doubled closing character is part of the literal
+		for i++; i < len(query); i++ {
+			if strings.HasPrefix(query[i:], end) {
+				if end != "\n" && strings.HasPrefix(query[i+1:], end) {
+					i++
+					continue
+				}
+				break
+			}
+		}
+		code.WriteByte(' ')
+	}
+
+	return code.String()
+}
+
+// ReadOnlyQuery runs an ad-hoc SELECT and returns its columns and at most maxRows rows formatted as text.
+// The query runs in a transaction that is always rolled back, so a SELECT ... INTO does not leave anything behind, and
+// batches of more than one statement are refused so none can commit or leave the transaction.
+func (db *MSSQLDB) ReadOnlyQuery(ctx context.Context, query string, maxRows int) ([]string, [][]string, error) {
+	err := checkReadOnly(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := make([][]string, 0)
+	for rows.Next() && len(result) < maxRows {
+		values := make([]interface{}, len(columns))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+
+		err := rows.Scan(values...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		row := make([]string, len(columns))
+		for i, value := range values {
+			switch v := (*(value.(*interface{}))).(type) {
+			case nil:
+				row[i] = "NULL"
+			case []uint8:
+				// decimals are read as their text
+				row[i] = string(v)
+			default:
+				row[i] = fmt.Sprint(v)
+			}
+		}
+		result = append(result, row)
+	}
+
+	return columns, result, rows.Err()
+}
@@ -0,0 +1,37 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ResourceUsage is the utilization of the limits of the service tier of an Azure SQL database in percent
+type ResourceUsage struct {
+	CPU      float64
+	DataIO   float64
+	LogWrite float64
+	Memory   float64
+}
+
+// Max returns the utilization of the most used limit
+func (u ResourceUsage) Max() float64 {
+	return max(u.CPU, u.DataIO, u.LogWrite, u.Memory)
+}
+
+// GetResourceUsage returns the latest utilization recorded in sys.dm_db_resource_stats, which Azure SQL Database updates every 15 seconds.
+// Other servers do not have the view and return an error.
+func (db *MSSQLDB) GetResourceUsage(ctx context.Context) (ResourceUsage, error) {
+	query := `
+	SELECT TOP (1) avg_cpu_percent, avg_data_io_percent, avg_log_write_percent, avg_memory_usage_percent
+	FROM sys.dm_db_resource_stats
+	ORDER BY end_time DESC
+	`
+
+	var cpu, dataIO, logWrite, memory sql.NullFloat64
+	err := db.db.QueryRowContext(ctx, query).Scan(&cpu, &dataIO, &logWrite, &memory)
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+
+	return ResourceUsage{CPU: cpu.Float64, DataIO: dataIO.Float64, LogWrite: logWrite.Float64, Memory: memory.Float64}, nil
+}
@@ -0,0 +1,98 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrCommitUnknown wraps the failures of a commit the server did not answer, the rows may have been committed and are not inserted again
+var ErrCommitUnknown = errors.New("the commit was not answered, its rows may have been committed")
+
+// commitError wraps err with ErrCommitUnknown unless the server answered the commit with an error, which rolled it back
+func commitError(err error) error {
+	var sqlErr interface{ SQLErrorNumber() int32 }
+	if err == nil || errors.As(err, &sqlErr) {
+		return err
+	}
+
+	return fmt.Errorf("%w, %w", ErrCommitUnknown, err)
+}
+
+// transientErrors are the error numbers of failures that can succeed when retried, like deadlocks and the throttling of Azure SQL
+var transientErrors = map[int32]bool{
+	1205:  true, // deadlock victim
+	1222:  true, // lock request timeout
+	40197: true, // service error processing the request
+	40501: true, // service busy
+	40613: true, // database unavailable
+	49918: true, // not enough resources to process the request
+	49919: true, // too many create or update operations
+	49920: true, // too many operations
+	10928: true, // resource limit reached
+	10929: true, // resource limit reached
+	10053: true, // transport-level error
+	10054: true, // transport-level error
+	10060: true, // network-related error
+	233:   true, // no process on the other end of the pipe
+	64:    true, // connection closed by the server
+}
+
+// lockConflicts are the error numbers of statements that lost or waited too long for a lock held by another workload
+var lockConflicts = map[int32]bool{
+	1205: true, // deadlock victim
+	1222: true, // lock request timeout
+}
+
+// rowErrors are the error numbers of statements that failed on the values of a row, which fail again for that row alone
+var rowErrors = map[int32]bool{
+	245:  true, // conversion failed
+	515:  true, // NULL into a NOT NULL column
+	547:  true, // foreign key or check constraint conflict
+	2601: true, // duplicate key in a unique index
+	2627: true, // duplicate key in a primary key or unique constraint
+	2628: true, // string or binary data would be truncated
+	8114: true, // error converting the data type
+	8115: true, // arithmetic overflow
+	8152: true, // string or binary data would be truncated
+}
+
+// IsRowError reports whether err is a failure caused by the values of a row, like a constraint violation or a conversion error
+func IsRowError(err error) bool {
+	var sqlErr interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &sqlErr) {
+		return rowErrors[sqlErr.SQLErrorNumber()]
+	}
+
+	return false
+}
+
+// IsLockConflict reports whether err is a deadlock or a lock timeout, the statement was rolled back and can be run again
+func IsLockConflict(err error) bool {
+	var sqlErr interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &sqlErr) {
+		return lockConflicts[sqlErr.SQLErrorNumber()]
+	}
+
+	return false
+}
+
+// IsTransient reports whether err is a deadlock, throttling or connection failure, which can succeed when retried
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var sqlErr interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &sqlErr) {
+		return transientErrors[sqlErr.SQLErrorNumber()]
+	}
+
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
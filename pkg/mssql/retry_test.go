@@ -0,0 +1,44 @@
+package mssql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	mssqlDriver "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, IsTransient(mssqlDriver.Error{Number: 1205}))
+	assert.True(t, IsTransient(fmt.Errorf("commit failed, %w", mssqlDriver.Error{Number: 40501})))
+	assert.False(t, IsTransient(mssqlDriver.Error{Number: 2627}))
+	assert.False(t, IsTransient(errors.New("bulkcopy: unsupported type")))
+	assert.False(t, IsTransient(nil))
+}
+
+func TestIsRowError(t *testing.T) {
+	assert.True(t, IsRowError(mssqlDriver.Error{Number: 2627}))
+	assert.True(t, IsRowError(fmt.Errorf("insert failed, %w", mssqlDriver.Error{Number: 515})))
+	assert.False(t, IsRowError(mssqlDriver.Error{Number: 40501}))
+	assert.False(t, IsRowError(errors.New("connection reset")))
+	assert.False(t, IsRowError(nil))
+}
+
+func TestIsLockConflict(t *testing.T) {
+	assert.True(t, IsLockConflict(mssqlDriver.Error{Number: 1205}))
+	assert.True(t, IsLockConflict(fmt.Errorf("truncate failed, %w", mssqlDriver.Error{Number: 1222})))
+	assert.False(t, IsLockConflict(mssqlDriver.Error{Number: 40501}))
+	assert.False(t, IsLockConflict(nil))
+}
+
+func TestCommitError(t *testing.T) {
+	// the server answered, the transaction was rolled back
+	assert.Equal(t, mssqlDriver.Error{Number: 1205}, commitError(mssqlDriver.Error{Number: 1205}))
+
+	err := commitError(driver.ErrBadConn)
+	assert.ErrorIs(t, err, ErrCommitUnknown)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.NoError(t, commitError(nil))
+}
@@ -0,0 +1,121 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generatedRows is a database/sql driver returning the number of rows given as query, with an integer and a string column
+type generatedRows struct{}
+
+func (generatedRows) Open(string) (driver.Conn, error) { return generatedConn{}, nil }
+
+type generatedConn struct{}
+
+func (generatedConn) Prepare(query string) (driver.Stmt, error) { return generatedStmt(query), nil }
+func (generatedConn) Close() error                              { return nil }
+func (generatedConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("not supported") }
+
+type generatedStmt string
+
+func (generatedStmt) Close() error  { return nil }
+func (generatedStmt) NumInput() int { return 0 }
+func (generatedStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (s generatedStmt) Query([]driver.Value) (driver.Rows, error) {
+	count, err := strconv.Atoi(string(s))
+	if err != nil {
+		return nil, err
+	}
+	return &generatedResult{count: count}, nil
+}
+
+type generatedResult struct {
+	count int
+	row   int
+}
+
+func (r *generatedResult) Columns() []string { return []string{"id", "name"} }
+func (r *generatedResult) Close() error      { return nil }
+func (r *generatedResult) Next(dest []driver.Value) error {
+	if r.row == r.count {
+		return io.EOF
+	}
+	r.row++
+	dest[0] = int64(r.row)
+	dest[1] = "row"
+	return nil
+}
+
+func init() {
+	sql.Register("generated", generatedRows{})
+}
+
+func generatedIterator(tb testing.TB, count int) *RowIterator {
+	db, err := sql.Open("generated", "")
+	require.NoError(tb, err)
+	tb.Cleanup(func() { db.Close() })
+
+	rows, err := db.QueryContext(context.Background(), strconv.Itoa(count))
+	require.NoError(tb, err)
+
+	return &RowIterator{columnCount: 2, rows: rows}
+}
+
+func TestRowIteratorNextBatch(t *testing.T) {
+	rows := generatedIterator(t, 5)
+	defer rows.Close()
+
+	batch, err := rows.NextBatch(2)
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(1), "row"}, {int64(2), "row"}}, batch)
+	first := batch
+
+	batch, err = rows.NextBatch(2)
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(3), "row"}, {int64(4), "row"}}, batch)
+	// the rows of a batch are kept by the writers, the next batch does not overwrite them
+	assert.Equal(t, [][]interface{}{{int64(1), "row"}, {int64(2), "row"}}, first)
+
+	batch, err = rows.NextBatch(2)
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(5), "row"}}, batch)
+}
+
+func BenchmarkRowIteratorNext(b *testing.B) {
+	rows := generatedIterator(b, b.N)
+	defer rows.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := rows.Next()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRowIteratorNextBatch(b *testing.B) {
+	rows := generatedIterator(b, b.N)
+	defer rows.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for read := 0; read < b.N; {
+		batch, err := rows.NextBatch(250)
+		if err != nil {
+			b.Fatal(err)
+		}
+		read += len(batch)
+	}
+}
@@ -0,0 +1,87 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// toolTablePattern matches the tables asqlcp keeps its state in and the staging tables of merges, which are never copied
+const toolTablePattern = `\_\_asqlcp\_%`
+
+// likeEscaper escapes the characters LIKE treats as wildcards, with \ as the escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `[`, `\[`)
+
+// schemaCondition returns the condition selecting the schemas and its parameters. A name with a * matches the schemas the * can
+// be replaced by any text in, the other names match a single schema.
+func schemaCondition(schemas []string) (string, []any) {
+	conditions := make([]string, len(schemas))
+	args := make([]any, len(schemas))
+	for i, schema := range schemas {
+		name := fmt.Sprintf("schema%d", i)
+		if strings.Contains(schema, "*") {
+			conditions[i] = fmt.Sprintf(`TABLE_SCHEMA LIKE @%s ESCAPE '\'`, name)
+			schema = strings.ReplaceAll(likeEscaper.Replace(schema), "*", "%")
+		} else {
+			conditions[i] = fmt.Sprintf("TABLE_SCHEMA = @%s", name)
+		}
+		args[i] = sql.Named(name, schema)
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// tablesQuery returns the query listing the tables of the schemas of which the name is LIKE filter and its parameters.
+// System tables are the tables shipped with the server (change data capture, replication), the history tables of temporal tables
+// and the database diagram table sysdiagrams, which is marked by the tools creating it.
+func tablesQuery(schemas []string, filter string, includeSystem bool) (string, []any) {
+	condition, args := schemaCondition(schemas)
+	query := fmt.Sprintf(`
+	SELECT TABLE_SCHEMA, TABLE_NAME
+	FROM (
+		SELECT
+			s.name AS TABLE_SCHEMA,
+			t.name AS TABLE_NAME,
+			CASE WHEN t.is_ms_shipped = 1 OR t.temporal_type = 1 OR EXISTS (
+				SELECT 1
+				FROM sys.extended_properties ep
+				WHERE ep.class = 1 AND ep.major_id = t.object_id AND ep.minor_id = 0 AND ep.name = 'microsoft_database_tools_support'
+			) THEN 1 ELSE 0 END AS system_table
+		FROM sys.tables t
+		JOIN sys.schemas s ON s.schema_id = t.schema_id
+	) listed
+	WHERE %s AND TABLE_NAME LIKE @table_filter AND TABLE_NAME NOT LIKE @tool_tables ESCAPE '\' AND (system_table = 0 OR @include_system = 1)
+	ORDER BY TABLE_SCHEMA, TABLE_NAME
+	`, condition)
+	args = append(args, sql.Named("table_filter", filter), sql.Named("tool_tables", toolTablePattern), sql.Named("include_system", includeSystem))
+
+	return query, args
+}
+
+// GetTables returns the tables of the schemas of which the name is LIKE filter, ordered by schema and name.
+// The schemas may contain * wildcards, the tables of asqlcp itself are left out and system tables unless includeSystem is set.
+func (db *MSSQLDB) GetTables(ctx context.Context, schemas []string, filter string, includeSystem bool) ([]TableRef, error) {
+	if len(schemas) == 0 {
+		return nil, fmt.Errorf("no schemas to list the tables of")
+	}
+
+	query, args := tablesQuery(schemas, filter, includeSystem)
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make([]TableRef, 0)
+	for rows.Next() {
+		var table TableRef
+		err = rows.Scan(&table.Schema, &table.Table)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
@@ -0,0 +1,30 @@
+package mssql
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaCondition(t *testing.T) {
+	condition, args := schemaCondition([]string{"dbo", "sales_*"})
+
+	assert.Equal(t, `(TABLE_SCHEMA = @schema0 OR TABLE_SCHEMA LIKE @schema1 ESCAPE '\')`, condition)
+	assert.Equal(t, []any{sql.Named("schema0", "dbo"), sql.Named("schema1", `sales\_%`)}, args)
+}
+
+func TestSchemaConditionAllSchemas(t *testing.T) {
+	condition, args := schemaCondition([]string{"*"})
+
+	assert.Equal(t, `(TABLE_SCHEMA LIKE @schema0 ESCAPE '\')`, condition)
+	assert.Equal(t, []any{sql.Named("schema0", "%")}, args)
+}
+
+func TestTablesQuery(t *testing.T) {
+	query, args := tablesQuery([]string{"dbo"}, "%", false)
+
+	assert.Contains(t, query, "WHERE (TABLE_SCHEMA = @schema0) AND TABLE_NAME LIKE @table_filter")
+	assert.Contains(t, query, "t.temporal_type = 1")
+	assert.Equal(t, []any{sql.Named("schema0", "dbo"), sql.Named("table_filter", "%"), sql.Named("tool_tables", toolTablePattern), sql.Named("include_system", false)}, args)
+}
@@ -0,0 +1,96 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Minimum database compatibility levels for features that affect a copy
+const (
+	compatibilityLevelColumnstore = 120
+	compatibilityLevelTemporal    = 130
+)
+
+type ServerInfo struct {
+	// ServerName and Database are the names the server gives itself and the database connected to, which are the same
+	// for every host name and alias of the server
+	ServerName string `json:"server_name"`
+	Database   string `json:"database"`
+	// DatabaseGUID identifies the database itself, a restored or copied database gets another one
+	DatabaseGUID       string `json:"database_guid"`
+	Version            string `json:"version"`
+	Edition            string `json:"edition"`
+	EngineEdition      int    `json:"engine_edition"`
+	CompatibilityLevel int    `json:"compatibility_level"`
+	SnapshotIsolation  bool   `json:"snapshot_isolation"`
+}
+
+func (db *MSSQLDB) probeServerInfo(ctx context.Context) (ServerInfo, error) {
+	query := `
+	SELECT
+		COALESCE(@@SERVERNAME, ''),
+		DB_NAME(),
+		COALESCE(CAST(rs.database_guid AS nvarchar(36)), ''),
+		@@VERSION,
+		CAST(SERVERPROPERTY('Edition') AS nvarchar(128)),
+		CAST(SERVERPROPERTY('EngineEdition') AS int),
+		d.compatibility_level,
+		d.snapshot_isolation_state
+	FROM sys.databases d
+	LEFT JOIN sys.database_recovery_status rs ON rs.database_id = d.database_id
+	WHERE d.name = DB_NAME()
+	`
+
+	var info ServerInfo
+	var snapshotIsolationState int
+	err := db.db.QueryRowContext(ctx, query).Scan(&info.ServerName, &info.Database, &info.DatabaseGUID, &info.Version, &info.Edition, &info.EngineEdition, &info.CompatibilityLevel, &snapshotIsolationState)
+	if err != nil {
+		return ServerInfo{}, err
+	}
+	info.SnapshotIsolation = snapshotIsolationState == 1
+
+	return info, nil
+}
+
+// ServerInfo returns the version and feature information captured when connecting
+func (db *MSSQLDB) ServerInfo() ServerInfo {
+	return db.info
+}
+
+// SameDatabase reports whether source and target are connections to the same database, also when they were reached
+// through other host names. Without the guid of both databases it compares the names, and when a server has no name
+// only the database names, so an unknown server counts as the same one.
+func SameDatabase(source, target ServerInfo) bool {
+	if source.DatabaseGUID != "" && target.DatabaseGUID != "" {
+		return strings.EqualFold(source.DatabaseGUID, target.DatabaseGUID)
+	}
+	if source.ServerName != "" && target.ServerName != "" && !strings.EqualFold(source.ServerName, target.ServerName) {
+		return false
+	}
+
+	return strings.EqualFold(source.Database, target.Database)
+}
+
+// CompatibilityWarnings lists the feature gaps between source and target that may make a copy fail or behave differently
+func CompatibilityWarnings(source, target ServerInfo) []string {
+	warnings := make([]string, 0)
+
+	if target.CompatibilityLevel < source.CompatibilityLevel {
+		warnings = append(warnings, fmt.Sprintf("target compatibility level %d is lower than source compatibility level %d", target.CompatibilityLevel, source.CompatibilityLevel))
+	}
+
+	if source.CompatibilityLevel >= compatibilityLevelTemporal && target.CompatibilityLevel < compatibilityLevelTemporal {
+		warnings = append(warnings, fmt.Sprintf("target compatibility level %d does not support temporal tables", target.CompatibilityLevel))
+	}
+
+	if source.CompatibilityLevel >= compatibilityLevelColumnstore && target.CompatibilityLevel < compatibilityLevelColumnstore {
+		warnings = append(warnings, fmt.Sprintf("target compatibility level %d does not support updatable columnstore indexes", target.CompatibilityLevel))
+	}
+
+	if source.EngineEdition != target.EngineEdition {
+		warnings = append(warnings, fmt.Sprintf("source edition (%s) differs from target edition (%s)", source.Edition, target.Edition))
+	}
+
+	return warnings
+}
@@ -0,0 +1,131 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+)
+
+const (
+	// pageSize is the number of bytes of a page, the unit sys.database_files counts its sizes in
+	pageSize = 8192
+
+	fileTypeLog = 1
+)
+
+// DatabaseSpace is the space used by the data and the log of a database and the size they can grow to, a Max of 0 is not limited
+// by the database but by the disk it is on
+type DatabaseSpace struct {
+	DataUsed int64
+	DataMax  int64
+	LogUsed  int64
+	LogMax   int64
+}
+
+// DataFree returns the number of bytes the data can grow, false when it is only limited by the disk
+func (s DatabaseSpace) DataFree() (int64, bool) {
+	return free(s.DataUsed, s.DataMax)
+}
+
+// LogFree returns the number of bytes the log can grow, false when it is only limited by the disk
+func (s DatabaseSpace) LogFree() (int64, bool) {
+	return free(s.LogUsed, s.LogMax)
+}
+
+func free(used, limit int64) (int64, bool) {
+	if limit == 0 {
+		return 0, false
+	}
+
+	return max(limit-used, 0), true
+}
+
+// databaseFile is a row of sys.database_files, its sizes are in pages
+type databaseFile struct {
+	fileType int
+	size     int64
+	maxSize  int64
+	growth   int64
+	used     int64
+}
+
+// limit returns the number of pages the file can grow to, 0 when it grows until the disk is full
+func (f databaseFile) limit() int64 {
+	switch {
+	case f.growth == 0:
+		return f.size
+	case f.maxSize == -1:
+		return 0
+	}
+
+	return f.maxSize
+}
+
+// databaseSpace sums the files of the data and the log, a file without a limit lifts the limit of its kind.
+// maxSizeInBytes is the maximum size of an Azure SQL database, which caps its data files.
+func databaseSpace(files []databaseFile, maxSizeInBytes int64) DatabaseSpace {
+	var space DatabaseSpace
+	dataLimited, logLimited := true, true
+	for _, file := range files {
+		limit := file.limit()
+		if file.fileType == fileTypeLog {
+			space.LogUsed += file.used * pageSize
+			space.LogMax += limit * pageSize
+			logLimited = logLimited && limit > 0
+			continue
+		}
+
+		space.DataUsed += file.used * pageSize
+		space.DataMax += limit * pageSize
+		dataLimited = dataLimited && limit > 0
+	}
+
+	if !dataLimited {
+		space.DataMax = 0
+	}
+	if !logLimited {
+		space.LogMax = 0
+	}
+	if maxSizeInBytes > 0 && (space.DataMax == 0 || maxSizeInBytes < space.DataMax) {
+		space.DataMax = maxSizeInBytes
+	}
+
+	return space
+}
+
+// GetDatabaseSpace returns the space used by the database and the size its files and, on Azure SQL Database, its service tier allow
+func (db *MSSQLDB) GetDatabaseSpace(ctx context.Context) (DatabaseSpace, error) {
+	query := `
+	SELECT type, CAST(size AS bigint), CAST(max_size AS bigint), CAST(growth AS bigint), COALESCE(CAST(FILEPROPERTY(name, 'SpaceUsed') AS bigint), 0)
+	FROM sys.database_files
+	WHERE type IN (0, 1)
+	`
+
+	rows, err := db.db.QueryContext(ctx, query)
+	if err != nil {
+		return DatabaseSpace{}, err
+	}
+	defer rows.Close()
+
+	files := make([]databaseFile, 0)
+	for rows.Next() {
+		var file databaseFile
+		err = rows.Scan(&file.fileType, &file.size, &file.maxSize, &file.growth, &file.used)
+		if err != nil {
+			return DatabaseSpace{}, err
+		}
+		files = append(files, file)
+	}
+	err = rows.Err()
+	if err != nil {
+		return DatabaseSpace{}, err
+	}
+
+	// only set on Azure SQL Database
+	var maxSizeInBytes sql.NullInt64
+	err = db.db.QueryRowContext(ctx, "SELECT CAST(DATABASEPROPERTYEX(DB_NAME(), 'MaxSizeInBytes') AS bigint)").Scan(&maxSizeInBytes)
+	if err != nil {
+		return DatabaseSpace{}, err
+	}
+
+	return databaseSpace(files, maxSizeInBytes.Int64), nil
+}
@@ -0,0 +1,42 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseSpace(t *testing.T) {
+	files := []databaseFile{
+		{fileType: 0, size: 1000, maxSize: 4000, growth: 128, used: 800},
+		{fileType: 0, size: 500, maxSize: -1, growth: 0, used: 100},
+		{fileType: fileTypeLog, size: 200, maxSize: 2000, growth: 10, used: 50},
+	}
+
+	space := databaseSpace(files, 0)
+	assert.Equal(t, int64(900*pageSize), space.DataUsed)
+	assert.Equal(t, int64(4500*pageSize), space.DataMax)
+	free, limited := space.DataFree()
+	assert.True(t, limited)
+	assert.Equal(t, int64(3600*pageSize), free)
+	free, limited = space.LogFree()
+	assert.True(t, limited)
+	assert.Equal(t, int64(1950*pageSize), free)
+}
+
+func TestDatabaseSpaceUnlimited(t *testing.T) {
+	files := []databaseFile{
+		{fileType: 0, size: 1000, maxSize: 4000, growth: 128, used: 800},
+		{fileType: 0, size: 1000, maxSize: -1, growth: 128, used: 800},
+	}
+
+	space := databaseSpace(files, 0)
+	_, limited := space.DataFree()
+	assert.False(t, limited)
+
+	// the maximum size of an Azure SQL database caps the files
+	space = databaseSpace(files, 2000*pageSize)
+	free, limited := space.DataFree()
+	assert.True(t, limited)
+	assert.Equal(t, int64(400*pageSize), free)
+}
@@ -0,0 +1,252 @@
+package mssql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// Reference is a (composite) foreign key from Child to Parent
+type Reference struct {
+	Child         TableRef
+	Parent        TableRef
+	ChildColumns  []string
+	ParentColumns []string
+}
+
+// References groups the per column foreign key constraints into one reference per foreign key
+func References(foreignKeys []ForeingKeyConstraint) []Reference {
+	byName := make(map[string]*Reference)
+	names := make([]string, 0)
+	for _, fk := range foreignKeys {
+		name := TableRef{Schema: fk.Schema, Table: fk.Name}.String()
+		if _, ok := byName[name]; !ok {
+			byName[name] = &Reference{
+				Child:  TableRef{Schema: fk.Schema, Table: fk.Table},
+				Parent: TableRef{Schema: fk.ReferencedSchema, Table: fk.ReferencedTable},
+			}
+			names = append(names, name)
+		}
+		byName[name].ChildColumns = append(byName[name].ChildColumns, fk.Column)
+		byName[name].ParentColumns = append(byName[name].ParentColumns, fk.ReferencedColumn)
+	}
+
+	sort.Strings(names)
+	references := make([]Reference, len(names))
+	for i, name := range names {
+		references[i] = *byName[name]
+	}
+
+	return references
+}
+
+// Subset builds the predicates that select a referentially consistent subset of a set of tables.
+//
+// The query filter selects the rows of the tables that have all of its columns, the root tables. The rows of other tables are
+// selected by following foreign keys: parent rows referenced by a selected row are always included, child rows of selected rows
+// only when includeChildren is set, otherwise tables below a root table stay empty so they can not reference rows that are not
+// copied. Tables that are not below a root table are copied completely.
+type Subset struct {
+	filter          filter
+	schemas         map[string]SchemaDefinition
+	references      []Reference
+	includeChildren bool
+}
+
+// NewSubset validates the query filter and finds the root tables of the subset in schemas, which holds the schema definition per table
+func NewSubset(queryFilter string, schemas map[string]SchemaDefinition, references []Reference, includeChildren bool) (*Subset, error) {
+	f, err := parseFilter(queryFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.expressions) == 0 {
+		return nil, fmt.Errorf("a subset requires a query filter")
+	}
+
+	s := &Subset{filter: f, schemas: schemas, includeChildren: includeChildren}
+
+	// references to tables outside the copy set can not be followed
+	for _, reference := range references {
+		_, childCopied := schemas[reference.Child.String()]
+		_, parentCopied := schemas[reference.Parent.String()]
+		if childCopied && parentCopied {
+			s.references = append(s.references, reference)
+		}
+	}
+
+	roots := 0
+	for table := range schemas {
+		if s.isRoot(table) {
+			roots++
+		}
+	}
+
+	if roots == 0 {
+		return nil, fmt.Errorf("none of the tables has all columns of the query filter")
+	}
+
+	return s, nil
+}
+
+// Predicate returns the WHERE clause that selects the subset of table, an empty predicate selects all rows
+func (s *Subset) Predicate(table TableRef) string {
+	return s.selected(table.String(), table.String(), map[string]bool{})
+}
+
+func (s *Subset) isRoot(table string) bool {
+	for _, expression := range s.filter.expressions {
+		found := false
+		for column := range s.schemas[table] {
+			if strings.EqualFold(column, expression.column) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// belowRoot reports whether table is a root table or references one, directly or through other tables
+func (s *Subset) belowRoot(table string, visiting map[string]bool) bool {
+	if s.isRoot(table) {
+		return true
+	}
+
+	visiting[table] = true
+	defer delete(visiting, table)
+
+	for _, reference := range s.references {
+		if reference.Child.String() != table || visiting[reference.Parent.String()] {
+			continue
+		}
+
+		if s.belowRoot(reference.Parent.String(), visiting) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// aboveRoot reports whether table is referenced by a table that is related to a root table, directly or through other tables
+func (s *Subset) aboveRoot(table string, visiting map[string]bool) bool {
+	visiting[table] = true
+	defer delete(visiting, table)
+
+	for _, reference := range s.references {
+		child := reference.Child.String()
+		if reference.Parent.String() != table || visiting[child] {
+			continue
+		}
+
+		if s.belowRoot(child, map[string]bool{}) || s.aboveRoot(child, visiting) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// down selects the rows of a table below a root table: the rows chosen by the query filter for a root table and,
+// with includeChildren, the rows referencing the selected rows of their parents for the other tables
+func (s *Subset) down(table string, alias string, visiting map[string]bool) string {
+	if s.isRoot(table) {
+		return s.filter.String()
+	}
+
+	if !s.includeChildren {
+		return "1=0"
+	}
+
+	visiting[table] = true
+	defer delete(visiting, table)
+
+	parts := make([]string, 0)
+	for i, reference := range s.references {
+		parent := reference.Parent.String()
+		if reference.Child.String() != table || visiting[parent] || !s.belowRoot(parent, map[string]bool{}) {
+			continue
+		}
+
+		parentAlias := fmt.Sprintf("p%d_%d", len(visiting), i)
+		predicate := s.down(parent, parentAlias, visiting)
+		if predicate == "1=0" {
+			continue
+		}
+		parts = append(parts, exists(reference.Parent, parentAlias, join(parentAlias, reference.ParentColumns, alias, reference.ChildColumns), predicate))
+	}
+
+	if len(parts) == 0 {
+		return "1=0"
+	}
+
+	return strings.Join(parts, " OR ")
+}
+
+// selected adds the rows referenced by the selected rows of the child tables to the rows selected by down,
+// it returns an empty predicate for tables that are not related to a root table
+func (s *Subset) selected(table string, alias string, visiting map[string]bool) string {
+	belowRoot := s.belowRoot(table, map[string]bool{})
+	if !belowRoot && !s.aboveRoot(table, map[string]bool{}) {
+		return ""
+	}
+
+	parts := make([]string, 0)
+	if belowRoot {
+		predicate := s.down(table, alias, map[string]bool{})
+		if predicate != "1=0" {
+			parts = append(parts, fmt.Sprintf("( %s )", predicate))
+		}
+	}
+
+	visiting[table] = true
+	defer delete(visiting, table)
+
+	for i, reference := range s.references {
+		child := reference.Child.String()
+		if reference.Parent.String() != table || visiting[child] {
+			continue
+		}
+
+		childAlias := fmt.Sprintf("c%d_%d", len(visiting), i)
+		predicate := s.selected(child, childAlias, visiting)
+		if predicate == "1=0" {
+			continue
+		}
+		if predicate == "" {
+			// all rows of an unrelated child are copied, so are the rows they reference
+			predicate = "1=1"
+		}
+		parts = append(parts, exists(reference.Child, childAlias, join(childAlias, reference.ChildColumns, alias, reference.ParentColumns), predicate))
+	}
+
+	if len(parts) == 0 {
+		return "1=0"
+	}
+
+	return strings.Join(parts, " OR ")
+}
+
+// exists is an EXISTS subquery on table, the predicate of the table uses unqualified columns which resolve to the innermost table
+func exists(table TableRef, alias string, on string, predicate string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM %s AS %s WHERE %s AND ( %s ))", table, alias, on, predicate)
+}
+
+func join(leftAlias string, leftColumns []string, rightAlias string, rightColumns []string) string {
+	quoter := mssql.TSQLQuoter{}
+
+	conditions := make([]string, len(leftColumns))
+	for i := range leftColumns {
+		conditions[i] = fmt.Sprintf("%s.%s = %s.%s", leftAlias, quoter.ID(leftColumns[i]), rightAlias, quoter.ID(rightColumns[i]))
+	}
+
+	return strings.Join(conditions, " AND ")
+}
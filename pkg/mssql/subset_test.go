@@ -0,0 +1,47 @@
+package mssql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func subsetSchemas() map[string]SchemaDefinition {
+	integer := ColumnDefinition{Type: "int"}
+	return map[string]SchemaDefinition{
+		"[dbo].[countries]": {"id": integer},
+		"[dbo].[customers]": {"id": integer, "tenant_id": integer, "country_id": integer},
+		"[dbo].[orders]":    {"id": integer, "customer_id": integer},
+		"[dbo].[settings]":  {"id": integer},
+	}
+}
+
+func subsetReferences() []Reference {
+	return References([]ForeingKeyConstraint{
+		{Name: "FK_customers_countries", Schema: "dbo", Table: "customers", Column: "country_id", ReferencedSchema: "dbo", ReferencedTable: "countries", ReferencedColumn: "id"},
+		{Name: "FK_orders_customers", Schema: "dbo", Table: "orders", Column: "customer_id", ReferencedSchema: "dbo", ReferencedTable: "customers", ReferencedColumn: "id"},
+	})
+}
+
+func TestSubsetParentsOnly(t *testing.T) {
+	subset, err := NewSubset("tenant_id = 5", subsetSchemas(), subsetReferences(), false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "( ( [tenant_id] = '5' ) )", subset.Predicate(TableRef{Schema: "dbo", Table: "customers"}))
+	assert.Equal(t, "EXISTS (SELECT 1 FROM [dbo].[customers] AS c1_0 WHERE c1_0.[country_id] = [dbo].[countries].[id] AND ( ( ( [tenant_id] = '5' ) ) ))", subset.Predicate(TableRef{Schema: "dbo", Table: "countries"}))
+	// orders would reference customers of other tenants
+	assert.Equal(t, "1=0", subset.Predicate(TableRef{Schema: "dbo", Table: "orders"}))
+	assert.Equal(t, "", subset.Predicate(TableRef{Schema: "dbo", Table: "settings"}))
+}
+
+func TestSubsetWithChildren(t *testing.T) {
+	subset, err := NewSubset("tenant_id = 5", subsetSchemas(), subsetReferences(), true)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "( EXISTS (SELECT 1 FROM [dbo].[customers] AS p1_1 WHERE p1_1.[id] = [dbo].[orders].[customer_id] AND ( ( [tenant_id] = '5' ) )) )", subset.Predicate(TableRef{Schema: "dbo", Table: "orders"}))
+}
+
+func TestSubsetRequiresRootTable(t *testing.T) {
+	_, err := NewSubset("region = 'eu'", subsetSchemas(), subsetReferences(), false)
+	assert.Error(t, err)
+}
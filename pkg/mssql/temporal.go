@@ -0,0 +1,90 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// TemporalTable is a system-versioned table with its history table and period columns
+type TemporalTable struct {
+	Table       TableRef
+	History     TableRef
+	PeriodStart string
+	PeriodEnd   string
+}
+
+// GetTemporalTables returns the system-versioned tables of the database
+func (db *MSSQLDB) GetTemporalTables(ctx context.Context) ([]TemporalTable, error) {
+	query := `
+	SELECT
+		OBJECT_SCHEMA_NAME(t.object_id),
+		t.name,
+		OBJECT_SCHEMA_NAME(t.history_table_id),
+		OBJECT_NAME(t.history_table_id),
+		COL_NAME(p.object_id, p.start_column_id),
+		COL_NAME(p.object_id, p.end_column_id)
+	FROM sys.tables t
+	INNER JOIN sys.periods p ON p.object_id = t.object_id
+	WHERE t.temporal_type = 2
+	ORDER BY 1, 2
+	`
+	rows, err := db.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make([]TemporalTable, 0)
+	for rows.Next() {
+		var t TemporalTable
+		err := rows.Scan(&t.Table.Schema, &t.Table.Table, &t.History.Schema, &t.History.Table, &t.PeriodStart, &t.PeriodEnd)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, rows.Err()
+}
+
+// DisableSystemVersioning turns system versioning off and drops the period, so the table can be truncated and the period columns
+// and the history table loaded like the other columns and tables
+func (db *MSSQLDB) DisableSystemVersioning(ctx context.Context, table TemporalTable) error {
+	_, err := db.db.ExecContext(ctx, disableSystemVersioningStatement(table))
+	return err
+}
+
+// EnableSystemVersioning adds the period again and turns system versioning on with the same history table,
+// the server checks the consistency of the periods of the rows
+func (db *MSSQLDB) EnableSystemVersioning(ctx context.Context, table TemporalTable) error {
+	_, err := db.db.ExecContext(ctx, enableSystemVersioningStatement(table))
+	return err
+}
+
+func disableSystemVersioningStatement(table TemporalTable) string {
+	return fmt.Sprintf("ALTER TABLE %s SET (SYSTEM_VERSIONING = OFF); ALTER TABLE %s DROP PERIOD FOR SYSTEM_TIME;", table.Table, table.Table)
+}
+
+func enableSystemVersioningStatement(table TemporalTable) string {
+	quoter := mssql.TSQLQuoter{}
+	return fmt.Sprintf("ALTER TABLE %s ADD PERIOD FOR SYSTEM_TIME (%s, %s); ALTER TABLE %s SET (SYSTEM_VERSIONING = ON (HISTORY_TABLE = %s, DATA_CONSISTENCY_CHECK = ON));",
+		table.Table, quoter.ID(table.PeriodStart), quoter.ID(table.PeriodEnd), table.Table, table.History)
+}
+
+// RecordSystemVersioning records a temporal table of which the system versioning is about to be turned off,
+// so it can be turned on again after a crash
+func (db *MSSQLDB) RecordSystemVersioning(ctx context.Context, table TemporalTable) error {
+	err := db.ensureArtifactTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.recordArtifact(ctx, ArtifactDisabledVersioning, table.Table.String(), table)
+}
+
+// ForgetSystemVersioning removes the record of a temporal table of which the system versioning was turned on again
+func (db *MSSQLDB) ForgetSystemVersioning(ctx context.Context, table TemporalTable) error {
+	return db.forgetArtifact(ctx, ArtifactDisabledVersioning, table.Table.String())
+}
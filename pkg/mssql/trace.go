@@ -0,0 +1,44 @@
+package mssql
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the spans of the database operations, it does nothing unless a tracer provider is configured
+var tracer = otel.Tracer("github.com/jeff-99/mssqlcopy/pkg/mssql")
+
+// spanAttributes identify the database and the table of an operation
+func (db *MSSQLDB) spanAttributes(table TableRef) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.DBSystemMSSQL,
+		semconv.ServerAddress(db.host),
+		semconv.DBNamespace(db.database),
+		semconv.DBCollectionName(table.String()),
+	}
+}
+
+// startSpan starts the span of the operation name on table
+func (db *MSSQLDB) startSpan(ctx context.Context, name string, table TableRef, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return startSpan(ctx, name, append(db.spanAttributes(table), attributes...))
+}
+
+func startSpan(ctx context.Context, name string, attributes []attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// endSpan ends span with the outcome of its operation and returns err
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	return err
+}
@@ -0,0 +1,52 @@
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestReadSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	db := &MSSQLDB{host: "source.database.windows.net", database: "sales"}
+	table := TableRef{Schema: "dbo", Table: "orders"}
+
+	rows := generatedIterator(t, 3)
+	_, rows.span = db.startSpan(context.Background(), "select", table)
+
+	for {
+		batch, err := rows.NextBatch(2)
+		require.NoError(t, err)
+		if len(batch) == 0 {
+			break
+		}
+	}
+	// the span ended when the rows were read
+	require.Len(t, recorder.Ended(), 1)
+	rows.Close()
+	require.Len(t, recorder.Ended(), 1)
+
+	span := recorder.Ended()[0]
+	assert.Equal(t, "select", span.Name())
+	assert.Equal(t, codes.Unset, span.Status().Code)
+	assert.Contains(t, span.Attributes(), attribute.Int64("asqlcp.rows", 3))
+	assert.Contains(t, span.Attributes(), attribute.String("db.collection.name", "[dbo].[orders]"))
+	assert.Contains(t, span.Attributes(), attribute.String("db.namespace", "sales"))
+
+	_, failed := db.startSpan(context.Background(), "truncate", table)
+	err := endSpan(failed, fmt.Errorf("permission denied"))
+	assert.EqualError(t, err, "permission denied")
+	require.Len(t, recorder.Ended(), 2)
+	assert.Equal(t, codes.Error, recorder.Ended()[1].Status().Code)
+	assert.Equal(t, "permission denied", recorder.Ended()[1].Status().Description)
+}
@@ -0,0 +1,101 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Trigger is a DML trigger of a table
+type Trigger struct {
+	Schema string
+	Table  string
+	Name   string
+}
+
+// GetTriggers returns the enabled DML triggers of table
+func (db *MSSQLDB) GetTriggers(ctx context.Context, table TableRef) ([]Trigger, error) {
+	query := `
+	SELECT name
+	FROM sys.triggers
+	WHERE parent_id = OBJECT_ID(@table)
+	AND is_disabled = 0
+	AND is_ms_shipped = 0
+	ORDER BY name
+	`
+	rows, err := db.db.QueryContext(ctx, query, sql.Named("table", table.String()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	triggers := make([]Trigger, 0)
+	for rows.Next() {
+		trigger := Trigger{Schema: table.Schema, Table: table.Table}
+		err := rows.Scan(&trigger.Name)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	return triggers, rows.Err()
+}
+
+// DisableTriggers disables the triggers, so loading their table has no side effects
+func (db *MSSQLDB) DisableTriggers(ctx context.Context, triggers []Trigger) error {
+	for _, trigger := range triggers {
+		_, err := db.db.ExecContext(ctx, triggerStatement(trigger, "DISABLE"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnableTriggers enables the triggers again, they do not run for the rows loaded while they were disabled
+func (db *MSSQLDB) EnableTriggers(ctx context.Context, triggers []Trigger) error {
+	for _, trigger := range triggers {
+		_, err := db.db.ExecContext(ctx, triggerStatement(trigger, "ENABLE"))
+		if err != nil {
+			return fmt.Errorf("failed to enable trigger %s, %w", trigger.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// EnableTriggerStatements returns the statements EnableTriggers runs, for when they have to be run by hand
+func EnableTriggerStatements(triggers []Trigger) []string {
+	statements := make([]string, len(triggers))
+	for i, trigger := range triggers {
+		statements[i] = triggerStatement(trigger, "ENABLE")
+	}
+
+	return statements
+}
+
+// triggerStatement returns the DDL to enable or disable a trigger, which is in the schema of its table
+func triggerStatement(trigger Trigger, action string) string {
+	return fmt.Sprintf("%s TRIGGER %s ON %s", action, TableRef{Schema: trigger.Schema, Table: trigger.Name}, TableRef{Schema: trigger.Schema, Table: trigger.Table})
+}
+
+// RecordTriggers records the triggers of table that are about to be disabled, so they can be enabled after a crash
+func (db *MSSQLDB) RecordTriggers(ctx context.Context, table TableRef, triggers []Trigger) error {
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	err := db.ensureArtifactTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.recordArtifact(ctx, ArtifactDisabledTriggers, table.String(), triggers)
+}
+
+// ForgetTriggers removes the record of the triggers of table that were enabled again
+func (db *MSSQLDB) ForgetTriggers(ctx context.Context, table TableRef) error {
+	return db.forgetArtifact(ctx, ArtifactDisabledTriggers, table.String())
+}
@@ -0,0 +1,50 @@
+package mssql
+
+import (
+	"sort"
+)
+
+// knownTypes are the column types supported by the driver for reading and bulk copy
+var knownTypes = map[string]bool{
+	"bigint": true, "int": true, "smallint": true, "tinyint": true, "bit": true,
+	"decimal": true, "numeric": true, "money": true, "smallmoney": true, "float": true, "real": true,
+	"date": true, "time": true, "datetime": true, "datetime2": true, "datetimeoffset": true, "smalldatetime": true,
+	"char": true, "varchar": true, "text": true, "nchar": true, "nvarchar": true, "ntext": true,
+	"binary": true, "varbinary": true, "image": true, "timestamp": true,
+	"uniqueidentifier": true, "xml": true, "sql_variant": true,
+	"geography": true, "geometry": true, "hierarchyid": true,
+}
+
+// bulkUnsupportedTypes are the known types the bulk copy of the driver can not write
+var bulkUnsupportedTypes = map[string]bool{
+	"money": true, "smallmoney": true, "image": true, "xml": true, "sql_variant": true,
+	"geography": true, "geometry": true, "hierarchyid": true,
+}
+
+// BulkUnsupportedColumns returns the columns of schema the bulk copy of the driver can not write, including the columns of unknown types.
+// These columns are inserted with INSERT statements, the server converts their values to the column type.
+func BulkUnsupportedColumns(schema SchemaDefinition, columns []string) []string {
+	unsupported := make([]string, 0)
+	for _, column := range columns {
+		definition, ok := schema[column]
+		if ok && (bulkUnsupportedTypes[definition.Type] || !knownTypes[definition.Type]) {
+			unsupported = append(unsupported, column)
+		}
+	}
+
+	return unsupported
+}
+
+// UnknownTypeColumns returns the columns of schema with a type the driver does not support, like the json and vector types
+// of newer servers. These columns are read as nvarchar(max) and inserted as text, which the server converts back.
+func UnknownTypeColumns(schema SchemaDefinition) []string {
+	columns := make([]string, 0)
+	for column, definition := range schema {
+		if !knownTypes[definition.Type] {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+
+	return columns
+}